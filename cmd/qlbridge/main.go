@@ -0,0 +1,186 @@
+// qlbridge is an interactive sql shell for exploring schemas made up
+// of configured sources (csv/json files, sqlite, etc).
+//
+//	qlbridge -config=sources.json -schema=baseball
+//
+//	qlbridge> SHOW TABLES;
+//	qlbridge> SELECT playerid, yearid
+//	       ->   FROM appearances
+//	       ->   WHERE yearid = "1871";
+//	qlbridge> \format csv
+//	qlbridge> \explain SELECT count(*) FROM appearances;
+//
+// The config file is json, either a single schema.ConfigSource or a list of them.
+// With -store the sources created by CREATE SOURCE and the tables created
+// by CREATE TABLE are saved to that file and reloaded on the next start.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	u "github.com/araddon/gou"
+	"github.com/chzyer/readline"
+
+	// Side-Effect Import the qlbridge sql driver and source types
+	_ "github.com/fuhongbo/qlbridge/datasource/files"
+	_ "github.com/fuhongbo/qlbridge/datasource/sqlite"
+	_ "github.com/fuhongbo/qlbridge/qlbdriver"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/expr/builtins"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+const (
+	prompt     = "qlbridge> "
+	promptCont = "       -> "
+)
+
+var (
+	configFiles string
+	schemaName  string
+	format      string
+	sqlText     string
+	historyFile string
+	timing      bool
+	logging     string
+	storeFile   string
+)
+
+func init() {
+	home, _ := os.UserHomeDir()
+	flag.StringVar(&configFiles, "config", "", "comma delimited list of json source config files")
+	flag.StringVar(&schemaName, "schema", "", "schema to use, defaults to first configured schema")
+	flag.StringVar(&format, "format", FormatTable, "output format [table,csv,json]")
+	flag.StringVar(&sqlText, "e", "", "execute statement(s) and exit")
+	flag.StringVar(&historyFile, "history", filepath.Join(home, ".qlbridge_history"), "history file")
+	flag.BoolVar(&timing, "timing", false, "show query timing")
+	flag.StringVar(&logging, "logging", "error", "logging [ debug,info,warn,error ]")
+	flag.StringVar(&storeFile, "store", "", "file to persist created sources and tables to")
+}
+
+func main() {
+	flag.Parse()
+
+	u.SetupLogging(logging)
+	u.SetColorOutput()
+
+	builtins.LoadAllBuiltins()
+
+	reg := schema.DefaultRegistry()
+	for _, cf := range strings.Split(configFiles, ",") {
+		if cf = strings.TrimSpace(cf); cf == "" {
+			continue
+		}
+		confs, err := loadConfig(cf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not load config %q: %v\n", cf, err)
+			os.Exit(1)
+		}
+		for _, conf := range confs {
+			if err := reg.SchemaAddFromConfig(conf); err != nil {
+				fmt.Fprintf(os.Stderr, "could not add source %q: %v\n", conf.Name, err)
+				os.Exit(1)
+			}
+			if schemaName == "" {
+				schemaName = conf.Name
+				if conf.Schema != "" {
+					schemaName = conf.Schema
+				}
+			}
+		}
+	}
+
+	if storeFile != "" {
+		pa := schema.NewPersistentApplyer(schema.NewApplyer(datasource.SchemaDBStoreProvider),
+			schema.NewFileSchemaStore(storeFile))
+		reg.SetApplyer(pa)
+		if err := pa.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "could not load store %q: %v\n", storeFile, err)
+			os.Exit(1)
+		}
+	}
+
+	sh := newShell(reg, os.Stdout)
+	sh.schema = strings.ToLower(schemaName)
+	sh.timing = timing
+	if err := sh.setFormat(format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if sqlText != "" {
+		for _, line := range strings.Split(sqlText, "\n") {
+			sh.Line(line)
+		}
+		sh.Flush()
+		return
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:                 prompt,
+		HistoryFile:            historyFile,
+		DisableAutoSaveHistory: true,
+		InterruptPrompt:        "^C",
+		EOFPrompt:              `\q`,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer rl.Close()
+
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			// ctrl-c clears any partial statement
+			sh.Reset()
+			rl.SetPrompt(prompt)
+			continue
+		} else if err == io.EOF {
+			return
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		for _, stmt := range sh.Line(line) {
+			rl.SaveHistory(stmt)
+		}
+		if sh.quit {
+			return
+		}
+		if sh.Pending() {
+			rl.SetPrompt(promptCont)
+		} else {
+			rl.SetPrompt(prompt)
+		}
+	}
+}
+
+// loadConfig reads a json file of either a single source config or list of them.
+func loadConfig(file string) ([]*schema.ConfigSource, error) {
+	by, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	by = []byte(strings.TrimSpace(string(by)))
+	if len(by) > 0 && by[0] == '[' {
+		confs := make([]*schema.ConfigSource, 0)
+		if err := json.Unmarshal(by, &confs); err != nil {
+			return nil, err
+		}
+		return confs, nil
+	}
+	conf := &schema.ConfigSource{}
+	if err := json.Unmarshal(by, conf); err != nil {
+		return nil, err
+	}
+	return []*schema.ConfigSource{conf}, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// FormatTable mysql client like tabular output
+	FormatTable = "table"
+	// FormatCsv comma delimited with header row
+	FormatCsv = "csv"
+	// FormatJson newline delimited json objects
+	FormatJson = "json"
+)
+
+// rowWriter writes result rows in a given output format.
+type rowWriter interface {
+	Row(vals []interface{})
+	Close() error
+}
+
+func newRowWriter(format string, w io.Writer, cols []string) rowWriter {
+	switch format {
+	case FormatCsv:
+		cw := csv.NewWriter(w)
+		cw.Write(cols)
+		return &csvWriter{w: cw, row: make([]string, len(cols))}
+	case FormatJson:
+		return &jsonWriter{w: w, cols: cols}
+	}
+	return &tableWriter{w: w, cols: cols}
+}
+
+type tableWriter struct {
+	w    io.Writer
+	cols []string
+	rows [][]string
+}
+
+func (m *tableWriter) Row(vals []interface{}) {
+	row := make([]string, len(vals))
+	for i, v := range vals {
+		if v == nil {
+			row[i] = "NULL"
+		} else {
+			row[i] = valString(v)
+		}
+	}
+	m.rows = append(m.rows, row)
+}
+
+// Close writes the buffered rows as a table, as the column widths
+// are not known until all rows are read.
+func (m *tableWriter) Close() error {
+	widths := make([]int, len(m.cols))
+	for i, col := range m.cols {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	for _, row := range m.rows {
+		for i, v := range row {
+			if l := utf8.RuneCountInString(v); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	sep := &strings.Builder{}
+	sep.WriteByte('+')
+	for _, w := range widths {
+		sep.WriteString(strings.Repeat("-", w+2))
+		sep.WriteByte('+')
+	}
+	sep.WriteByte('\n')
+
+	io.WriteString(m.w, sep.String())
+	m.writeRow(m.cols, widths)
+	io.WriteString(m.w, sep.String())
+	for _, row := range m.rows {
+		m.writeRow(row, widths)
+	}
+	if len(m.rows) > 0 {
+		io.WriteString(m.w, sep.String())
+	}
+	_, err := fmt.Fprintf(m.w, "%d rows in set\n", len(m.rows))
+	return err
+}
+
+func (m *tableWriter) writeRow(row []string, widths []int) {
+	line := &strings.Builder{}
+	line.WriteByte('|')
+	for i, v := range row {
+		line.WriteByte(' ')
+		line.WriteString(v)
+		line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)+1))
+		line.WriteByte('|')
+	}
+	line.WriteByte('\n')
+	io.WriteString(m.w, line.String())
+}
+
+type csvWriter struct {
+	w   *csv.Writer
+	row []string
+}
+
+func (m *csvWriter) Row(vals []interface{}) {
+	for i, v := range vals {
+		if v == nil {
+			m.row[i] = ""
+		} else {
+			m.row[i] = valString(v)
+		}
+	}
+	m.w.Write(m.row)
+}
+func (m *csvWriter) Close() error {
+	m.w.Flush()
+	return m.w.Error()
+}
+
+type jsonWriter struct {
+	w    io.Writer
+	cols []string
+	err  error
+}
+
+func (m *jsonWriter) Row(vals []interface{}) {
+	if m.err != nil {
+		return
+	}
+	row := make(map[string]interface{}, len(vals))
+	for i, v := range vals {
+		if by, ok := v.([]byte); ok {
+			v = string(by)
+		}
+		row[m.cols[i]] = v
+	}
+	by, err := json.Marshal(row)
+	if err != nil {
+		m.err = err
+		return
+	}
+	by = append(by, '\n')
+	_, m.err = m.w.Write(by)
+}
+func (m *jsonWriter) Close() error { return m.err }
+
+func valString(v interface{}) string {
+	switch vt := v.(type) {
+	case []byte:
+		return string(vt)
+	case string:
+		return vt
+	case time.Time:
+		return vt.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", v)
+}
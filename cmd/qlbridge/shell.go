@@ -0,0 +1,360 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+const helpText = `Statements are terminated by ";" and may span multiple lines.
+
+  \q                 quit
+  \use <schema>      change current schema (same as USE schema;)
+  \format <fmt>      output format [table,csv,json]
+  \timing [on|off]   toggle display of query timing
+  \explain <sql>     show the query plan for a statement
+  \schemas           list schemas
+  \h                 this help
+`
+
+// shell holds the state of an interactive session, statements are fed to it
+// line by line so partial, multi-line statements are buffered until terminated.
+type shell struct {
+	reg     *schema.Registry
+	out     io.Writer
+	schema  string
+	format  string
+	timing  bool
+	quit    bool
+	pending []string
+	dbs     map[string]*sql.DB
+}
+
+func newShell(reg *schema.Registry, out io.Writer) *shell {
+	return &shell{
+		reg:    reg,
+		out:    out,
+		format: FormatTable,
+		dbs:    make(map[string]*sql.DB),
+	}
+}
+
+// Pending is there a partial (un-terminated) statement buffered?
+func (m *shell) Pending() bool { return len(m.pending) > 0 }
+
+// Reset discard any partial statement.
+func (m *shell) Reset() { m.pending = m.pending[:0] }
+
+// Line accepts a line of input, running any completed statements.  Returns
+// the list of completed statements (for history).
+func (m *shell) Line(line string) []string {
+
+	trimmed := strings.TrimSpace(line)
+	if !m.Pending() && strings.HasPrefix(trimmed, `\`) {
+		m.meta(trimmed)
+		return []string{trimmed}
+	}
+	if trimmed == "" && !m.Pending() {
+		return nil
+	}
+
+	m.pending = append(m.pending, line)
+	stmts, rest := splitStatements(strings.Join(m.pending, "\n"))
+	m.pending = m.pending[:0]
+	if strings.TrimSpace(rest) != "" {
+		m.pending = append(m.pending, rest)
+	}
+	for _, stmt := range stmts {
+		m.run(stmt)
+	}
+	return stmts
+}
+
+// Flush run any buffered statement even if not terminated.
+func (m *shell) Flush() {
+	if !m.Pending() {
+		return
+	}
+	stmt := strings.TrimSpace(strings.Join(m.pending, "\n"))
+	m.Reset()
+	if stmt != "" {
+		m.run(stmt)
+	}
+}
+
+func (m *shell) meta(cmd string) {
+	parts := strings.Fields(cmd)
+	arg := strings.TrimSpace(strings.TrimPrefix(cmd, parts[0]))
+	arg = strings.TrimSuffix(arg, ";")
+	switch strings.ToLower(parts[0]) {
+	case `\q`, `\quit`:
+		m.quit = true
+	case `\h`, `\help`, `\?`:
+		io.WriteString(m.out, helpText)
+	case `\use`:
+		m.use(arg)
+	case `\format`:
+		if err := m.setFormat(arg); err != nil {
+			fmt.Fprintln(m.out, err)
+		}
+	case `\timing`:
+		switch strings.ToLower(arg) {
+		case "":
+			m.timing = !m.timing
+		case "on":
+			m.timing = true
+		case "off":
+			m.timing = false
+		}
+		fmt.Fprintf(m.out, "timing is %v\n", onOff(m.timing))
+	case `\explain`:
+		if err := m.explain(arg); err != nil {
+			fmt.Fprintf(m.out, "ERROR: %v\n", err)
+		}
+	case `\schemas`:
+		names := m.reg.Schemas()
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(m.out, name)
+		}
+	default:
+		fmt.Fprintf(m.out, "unrecognized command %s, try \\h for help\n", parts[0])
+	}
+}
+
+func (m *shell) setFormat(f string) error {
+	f = strings.ToLower(strings.TrimSpace(f))
+	switch f {
+	case FormatTable, FormatCsv, FormatJson:
+		m.format = f
+		return nil
+	}
+	return fmt.Errorf("unrecognized format %q expected one of [table,csv,json]", f)
+}
+
+func (m *shell) use(name string) {
+	name = strings.ToLower(strings.Trim(strings.TrimSpace(name), "`"))
+	if _, ok := m.reg.Schema(name); !ok {
+		fmt.Fprintf(m.out, "ERROR: unknown schema %q\n", name)
+		return
+	}
+	m.schema = name
+	fmt.Fprintf(m.out, "schema changed to %s\n", name)
+}
+
+func (m *shell) db() (*sql.DB, error) {
+	if m.schema == "" {
+		return nil, fmt.Errorf("no schema selected, see \\use")
+	}
+	if db, ok := m.dbs[m.schema]; ok {
+		return db, nil
+	}
+	db, err := sql.Open("qlbridge", m.schema)
+	if err != nil {
+		return nil, err
+	}
+	m.dbs[m.schema] = db
+	return db, nil
+}
+
+// run a single complete statement.
+func (m *shell) run(stmt string) {
+	stmt = strings.TrimSpace(stmt)
+	if stmt == "" {
+		return
+	}
+	fields := strings.Fields(stmt)
+	switch strings.ToLower(fields[0]) {
+	case "use":
+		if len(fields) > 1 {
+			m.use(fields[1])
+		}
+		return
+	}
+	start := time.Now()
+	rowCt, err := m.execute(stmt, strings.ToLower(fields[0]))
+	if err != nil {
+		fmt.Fprintf(m.out, "ERROR: %v\n", err)
+		return
+	}
+	if m.timing {
+		fmt.Fprintf(m.out, "%d rows (%v)\n", rowCt, time.Since(start))
+	}
+}
+
+func (m *shell) execute(stmt, keyword string) (int64, error) {
+	db, err := m.db()
+	if err != nil {
+		return 0, err
+	}
+	switch keyword {
+	case "select", "show", "describe", "desc":
+	default:
+		result, err := db.Exec(stmt)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		fmt.Fprintf(m.out, "Query OK, %d rows affected\n", affected)
+		return affected, nil
+	}
+
+	rows, err := db.Query(stmt)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	w := newRowWriter(m.format, m.out, cols)
+	vals := make([]interface{}, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	var rowCt int64
+	for rows.Next() {
+		for i := range vals {
+			vals[i] = nil
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return rowCt, err
+		}
+		w.Row(vals)
+		rowCt++
+	}
+	if err := w.Close(); err != nil {
+		return rowCt, err
+	}
+	return rowCt, rows.Err()
+}
+
+// explain writes the plan dag for given statement.
+func (m *shell) explain(sqlText string) error {
+	if m.schema == "" {
+		return fmt.Errorf("no schema selected, see \\use")
+	}
+	s, ok := m.reg.Schema(m.schema)
+	if !ok {
+		return fmt.Errorf("unknown schema %q", m.schema)
+	}
+	stmt, err := rel.ParseSql(sqlText)
+	if err != nil {
+		return err
+	}
+	ctx := plan.NewContext(sqlText)
+	ctx.Schema = s
+	ctx.Session = datasource.NewMySqlSessionVars()
+	ctx.Stmt = stmt
+	p, err := plan.WalkStmt(ctx, stmt, plan.NewPlanner(ctx))
+	if err != nil {
+		return err
+	}
+	writePlan(m.out, p, 0)
+	return nil
+}
+
+func writePlan(w io.Writer, p plan.Task, depth int) {
+	indent := strings.Repeat("  ", depth)
+	mode := "sequential"
+	if p.IsParallel() {
+		mode = "parallel"
+	}
+	fmt.Fprintf(w, "%s%s (%s)%s\n", indent, planName(p), mode, planDetail(p))
+	if jm, ok := p.(*plan.JoinMerge); ok {
+		writePlan(w, jm.Left, depth+1)
+		writePlan(w, jm.Right, depth+1)
+	}
+	for _, c := range p.Children() {
+		writePlan(w, c, depth+1)
+	}
+}
+
+func planName(p plan.Task) string {
+	name := fmt.Sprintf("%T", p)
+	return strings.TrimPrefix(name, "*plan.")
+}
+
+func planDetail(p plan.Task) string {
+	switch pt := p.(type) {
+	case *plan.Source:
+		if pt.Stmt == nil {
+			return ""
+		}
+		detail := fmt.Sprintf(" table=%s", pt.Stmt.SourceName())
+		if pt.Stmt.Source != nil {
+			detail += fmt.Sprintf(" sql=%q", pt.Stmt.Source.String())
+		}
+		return detail
+	case *plan.Where:
+		if pt.Stmt != nil && pt.Stmt.Where != nil {
+			return fmt.Sprintf(" filter=%q", pt.Stmt.Where.String())
+		}
+	case *plan.Having:
+		if pt.Stmt != nil && pt.Stmt.Having != nil {
+			return fmt.Sprintf(" filter=%q", pt.Stmt.Having.String())
+		}
+	case *plan.GroupBy:
+		if pt.Stmt != nil {
+			return fmt.Sprintf(" by=%q", pt.Stmt.GroupBy.String())
+		}
+	case *plan.Order:
+		if pt.Stmt != nil {
+			return fmt.Sprintf(" by=%q", pt.Stmt.OrderBy.String())
+		}
+	case *plan.Projection:
+		if pt.Final {
+			return " final"
+		}
+	}
+	return ""
+}
+
+// splitStatements splits text on ";" that are not inside quotes, returning
+// complete statements and any remaining un-terminated text.
+func splitStatements(text string) ([]string, string) {
+	stmts := make([]string, 0)
+	var quote rune
+	escaped := false
+	start := 0
+	for i, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != 0:
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ';':
+			if stmt := strings.TrimSpace(text[start:i]); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			start = i + 1
+		}
+	}
+	return stmts, text[start:]
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		text  string
+		stmts []string
+		rest  string
+	}{
+		{"SELECT 1;", []string{"SELECT 1"}, ""},
+		{"SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}, ""},
+		{"SELECT 1;\nSELECT a\nFROM b", []string{"SELECT 1"}, "\nSELECT a\nFROM b"},
+		{`SELECT "a;b", 'c;d' FROM x;`, []string{`SELECT "a;b", 'c;d' FROM x`}, ""},
+		{`SELECT 'it\'s;' FROM x;`, []string{`SELECT 'it\'s;' FROM x`}, ""},
+		{"SELECT `a;b` FROM x", []string{}, "SELECT `a;b` FROM x"},
+		{";;", []string{}, ""},
+	}
+	for _, tt := range tests {
+		stmts, rest := splitStatements(tt.text)
+		assert.Equal(t, tt.stmts, stmts, "text=%q", tt.text)
+		assert.Equal(t, tt.rest, rest, "text=%q", tt.text)
+	}
+}
+
+func TestRowWriters(t *testing.T) {
+	cols := []string{"id", "name"}
+
+	buf := &bytes.Buffer{}
+	w := newRowWriter(FormatTable, buf, cols)
+	w.Row([]interface{}{int64(1), []byte("bob")})
+	w.Row([]interface{}{int64(22), nil})
+	assert.Equal(t, nil, w.Close())
+	assert.Equal(t, `+----+------+
+| id | name |
++----+------+
+| 1  | bob  |
+| 22 | NULL |
++----+------+
+2 rows in set
+`, buf.String())
+
+	buf.Reset()
+	w = newRowWriter(FormatCsv, buf, cols)
+	w.Row([]interface{}{int64(1), "bob, jr"})
+	assert.Equal(t, nil, w.Close())
+	assert.Equal(t, "id,name\n1,\"bob, jr\"\n", buf.String())
+
+	buf.Reset()
+	w = newRowWriter(FormatJson, buf, cols)
+	w.Row([]interface{}{int64(1), []byte("bob")})
+	assert.Equal(t, nil, w.Close())
+	assert.Equal(t, `{"id":1,"name":"bob"}`+"\n", buf.String())
+}
+
+func TestShellBuffering(t *testing.T) {
+	sh := newShell(nil, &bytes.Buffer{})
+	assert.Equal(t, 0, len(sh.Line("SELECT a")))
+	assert.True(t, sh.Pending())
+	sh.Reset()
+	assert.True(t, !sh.Pending())
+
+	out := &bytes.Buffer{}
+	sh = newShell(nil, out)
+	sh.Line(`\format csv`)
+	assert.Equal(t, FormatCsv, sh.format)
+	sh.Line(`\timing on`)
+	assert.True(t, sh.timing)
+	sh.Line(`\q`)
+	assert.True(t, sh.quit)
+}
@@ -6,6 +6,7 @@ import (
 	"compress/gzip"
 	"database/sql/driver"
 	"encoding/csv"
+	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -22,11 +23,51 @@ var (
 	_ schema.ConnScanner = (*CsvDataSource)(nil)
 )
 
+// CsvDefaultSampleRows is default number of rows sampled for csv type inference
+var CsvDefaultSampleRows = 100
+
+// CsvOptions describe the csv dialect and column types of a csv source
+type CsvOptions struct {
+	Delimiter  rune                       // field delimiter, default ','
+	Quote      rune                       // quote character, default '"'
+	Nulls      []string                   // field values to treat as null, ie "NULL", "\N", ""
+	NoHeader   bool                       // first row is data instead of column names
+	Columns    []string                   // column names if NoHeader, default col1, col2 ...
+	Infer      bool                       // infer column types from sample of rows
+	SampleRows int                        // number of rows to sample for inference
+	Types      map[string]value.ValueType // explicit per-column types, win over inferred
+	Follow     bool                       // keep reading rows appended at EOF until exit, Infer is ignored
+}
+
+func (m *CsvOptions) validate() error {
+	if m.Delimiter == 0 {
+		m.Delimiter = ','
+	}
+	if m.Quote == 0 {
+		m.Quote = '"'
+	}
+	if m.Quote == m.Delimiter {
+		return fmt.Errorf("csv quote and delimiter must differ")
+	}
+	if m.Quote != '"' && (m.Quote > 127 || m.Delimiter == '"') {
+		return fmt.Errorf("csv quote %q must be ascii, and delimiter may not be a double quote", m.Quote)
+	}
+	if m.SampleRows <= 0 {
+		m.SampleRows = CsvDefaultSampleRows
+	}
+	if m.Follow {
+		// a followed file has no end, the sample would wait on rows
+		// which have not been written yet
+		m.Infer = false
+	}
+	return nil
+}
+
 // Csv DataSource, implements qlbridge schema DataSource, SourceConn, Scanner
 //   to allow csv files to be full featured databases.
 //   - very, very naive scanner, forward only single pass
 //   - can open a file with .Open()
-//   - assumes comma delimited
+//   - delimiter, quote, nulls, header and types via CsvOptions
 //   - not thread-safe
 //   - does not implement write operations
 type CsvDataSource struct {
@@ -41,16 +82,41 @@ type CsvDataSource struct {
 	colindex map[string]int
 	indexCol int
 	filter   expr.Node
+	opts     *CsvOptions
+	nulls    map[string]struct{}
+	types    []csvColumnType  // per column types, nil if all strings
+	sample   [][]driver.Value // rows read for inference not yet returned
 }
 
 // NewCsvSource reader assumes we are getting first row as headers
 // - optionally may be gzipped
 func NewCsvSource(table string, indexCol int, ior io.Reader, exit <-chan bool) (*CsvDataSource, error) {
+	return NewCsvSourceOptions(table, indexCol, ior, exit, nil)
+}
+
+// NewCsvSourceOptions create csv source reader with dialect and type options,
+// nil options are the same as NewCsvSource.
+func NewCsvSourceOptions(table string, indexCol int, ior io.Reader, exit <-chan bool, opts *CsvOptions) (*CsvDataSource, error) {
 
-	m := CsvDataSource{table: table, indexCol: indexCol}
+	if opts == nil {
+		opts = &CsvOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	m := CsvDataSource{table: table, indexCol: indexCol, opts: opts, exit: exit}
+	if len(opts.Nulls) > 0 {
+		m.nulls = make(map[string]struct{}, len(opts.Nulls))
+		for _, null := range opts.Nulls {
+			m.nulls[null] = struct{}{}
+		}
+	}
 	if rc, ok := ior.(io.ReadCloser); ok {
 		m.rc = rc
 	}
+	if opts.Follow {
+		ior = NewFollowReader(ior, exit)
+	}
 
 	buf := bufio.NewReader(ior)
 
@@ -68,22 +134,31 @@ func NewCsvSource(table string, indexCol int, ior io.Reader, exit <-chan bool) (
 			return nil, err
 		}
 		m.gz = gr
-		m.csvr = csv.NewReader(gr)
+		m.csvr = csv.NewReader(quoteReader(gr, opts.Quote))
 	} else {
-		m.csvr = csv.NewReader(buf)
+		m.csvr = csv.NewReader(quoteReader(buf, opts.Quote))
 	}
 
 	m.csvr.TrailingComma = true // allow empty fields
-	// if flagCsvDelimiter == "|" {
-	// 	m.csvr.Comma = '|'
-	// } else if flagCsvDelimiter == "\t" || flagCsvDelimiter == "t" {
-	// 	m.csvr.Comma = '\t'
-	// }
+	m.csvr.Comma = opts.Delimiter
 	headers, err := m.csvr.Read()
 	if err != nil {
 		u.Warnf("err csv %v", err)
 		return nil, err
 	}
+	if opts.NoHeader {
+		m.sample = append(m.sample, m.values(headers))
+		headers = make([]string, len(headers))
+		for i := range headers {
+			if i < len(opts.Columns) {
+				headers[i] = opts.Columns[i]
+			} else {
+				headers[i] = fmt.Sprintf("col%d", i+1)
+			}
+		}
+	} else {
+		headers = m.unquote(headers)
+	}
 	//u.Debugf("headers: %v", headers)
 	m.headers = headers
 	m.colindex = make(map[string]int, len(headers))
@@ -92,6 +167,9 @@ func NewCsvSource(table string, indexCol int, ior io.Reader, exit <-chan bool) (
 		m.colindex[key] = i
 		m.headers[i] = key
 	}
+	if err := m.loadTypes(); err != nil {
+		return nil, err
+	}
 	m.loadTable()
 	//u.Infof("csv headers: %v colIndex: %v", headers, m.colindex)
 	return &m, nil
@@ -112,12 +190,152 @@ func (m *CsvDataSource) loadTable() error {
 	columns := m.Columns()
 	for i := range columns {
 		columns[i] = strings.ToLower(columns[i])
-		tbl.AddField(schema.NewFieldBase(columns[i], value.StringType, 64, "string"))
+		vt := value.StringType
+		if m.types != nil {
+			vt = m.types[i].vt
+		}
+		tbl.AddField(schema.NewFieldBase(columns[i], vt, 64, vt.String()))
 	}
 	tbl.SetColumns(columns)
 	m.tbl = tbl
 	return nil
 }
+
+// loadTypes read sample rows for inference and build per column types
+func (m *CsvDataSource) loadTypes() error {
+	if !m.opts.Infer && len(m.opts.Types) == 0 {
+		return nil
+	}
+	var types []csvColumnType
+	if m.opts.Infer {
+		for len(m.sample) < m.opts.SampleRows {
+			row, err := m.readRow()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			if row != nil {
+				m.sample = append(m.sample, row)
+			}
+		}
+		types = inferCsvTypes(len(m.headers), m.sample)
+	} else {
+		types = make([]csvColumnType, len(m.headers))
+		for i := range types {
+			types[i].vt = value.StringType
+		}
+	}
+	for col, vt := range m.opts.Types {
+		idx, ok := m.colindex[strings.ToLower(col)]
+		if !ok {
+			return fmt.Errorf("csv type for unknown column %q in %q", col, m.table)
+		}
+		types[idx] = csvColumnType{vt: vt}
+	}
+	m.types = types
+	for _, row := range m.sample {
+		m.convert(row)
+	}
+	return nil
+}
+
+// values of a csv row, with nulls applied
+func (m *CsvDataSource) values(row []string) []driver.Value {
+	row = m.unquote(row)
+	vals := make([]driver.Value, len(row))
+	for i, val := range row {
+		if _, isNull := m.nulls[val]; isNull {
+			continue
+		}
+		vals[i] = val
+	}
+	return vals
+}
+
+// convert string values to the column types.  The types are fixed once
+// rows are returned, so a value not of the type of its column, ie a float
+// in an int column after the sample, is null rather than a different type.
+func (m *CsvDataSource) convert(vals []driver.Value) {
+	if m.types == nil {
+		return
+	}
+	for i, v := range vals {
+		if s, ok := v.(string); ok && i < len(m.types) {
+			vals[i], _ = m.types[i].convert(s)
+		}
+	}
+}
+
+// readRow read next row, nil row (with nil error) if row is invalid
+func (m *CsvDataSource) readRow() ([]driver.Value, error) {
+	row, err := m.csvr.Read()
+	if err != nil {
+		if _, isParseErr := err.(*csv.ParseError); !isParseErr {
+			// io.EOF or the reader failed, ie closed while following
+			return nil, err
+		}
+		u.Warnf("could not read row? %v", err)
+		return nil, nil
+	}
+	if len(row) != len(m.headers) {
+		u.Warnf("headers/cols dont match, dropping expected:%d got:%d vals=%v", len(m.headers), len(row), row)
+		return nil, nil
+	}
+	return m.values(row), nil
+}
+
+// unquote the row swapped back from quoteReader
+func (m *CsvDataSource) unquote(row []string) []string {
+	if m.opts.Quote == '"' {
+		return row
+	}
+	for i, val := range row {
+		row[i] = strings.Map(func(r rune) rune {
+			return swapQuote(r, m.opts.Quote)
+		}, val)
+	}
+	return row
+}
+
+// quoteReader encoding/csv only supports '"' quotes, so we swap
+// the custom quote char and '"' in the stream, and swap them back
+// in the parsed values.
+func quoteReader(r io.Reader, quote rune) io.Reader {
+	if quote == '"' {
+		return r
+	}
+	return &quoteSwapReader{r: r, quote: byte(quote)}
+}
+
+type quoteSwapReader struct {
+	r     io.Reader
+	quote byte
+}
+
+func (m *quoteSwapReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	for i := 0; i < n; i++ {
+		switch p[i] {
+		case m.quote:
+			p[i] = '"'
+		case '"':
+			p[i] = m.quote
+		}
+	}
+	return n, err
+}
+
+func swapQuote(r, quote rune) rune {
+	switch r {
+	case quote:
+		return '"'
+	case '"':
+		return quote
+	}
+	return r
+}
+
 func (m *CsvDataSource) Open(connInfo string) (schema.Conn, error) {
 	if connInfo == "stdio" || connInfo == "stdin" {
 		connInfo = "/dev/stdin"
@@ -127,6 +345,10 @@ func (m *CsvDataSource) Open(connInfo string) (schema.Conn, error) {
 		return nil, err
 	}
 	exit := make(<-chan bool, 1)
+	if m.opts != nil {
+		opts := *m.opts
+		return NewCsvSourceOptions(connInfo, 0, f, exit, &opts)
+	}
 	return NewCsvSource(connInfo, 0, f, exit)
 }
 
@@ -150,25 +372,24 @@ func (m *CsvDataSource) Next() schema.Message {
 	case <-m.exit:
 		return nil
 	default:
+		if len(m.sample) > 0 {
+			vals := m.sample[0]
+			m.sample = m.sample[1:]
+			m.rowct++
+			return NewSqlDriverMessageMap(m.rowct, vals, m.colindex)
+		}
 		for {
-			row, err := m.csvr.Read()
-
+			vals, err := m.readRow()
 			if err != nil {
-				if err == io.EOF {
-					return nil
+				if err != io.EOF {
+					u.Warnf("could not read csv %q %v", m.table, err)
 				}
-				u.Warnf("could not read row? %v", err)
+				return nil
+			} else if vals == nil {
 				continue
 			}
 			m.rowct++
-			if len(row) != len(m.headers) {
-				u.Warnf("headers/cols dont match, dropping expected:%d got:%d vals=%v", len(m.headers), len(row), row)
-				continue
-			}
-			vals := make([]driver.Value, len(row))
-			for i, val := range row {
-				vals[i] = val
-			}
+			m.convert(vals)
 			//u.Debugf("headers: %#v \n\trows:  %#v", m.headers, row)
 			return NewSqlDriverMessageMap(m.rowct, vals, m.colindex)
 		}
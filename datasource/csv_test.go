@@ -1,15 +1,20 @@
 package datasource_test
 
 import (
+	"database/sql/driver"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	u "github.com/araddon/gou"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/fuhongbo/qlbridge/datasource"
 	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
 )
 
 var (
@@ -57,3 +62,132 @@ func TestCsvDataSource(t *testing.T) {
 	assert.Equal(t, nil, err)
 	csvIn.Close()
 }
+
+func TestCsvOptions(t *testing.T) {
+	data := `1|'bob ''b'' smith'|9.5|true|2012-10-17|{"a":1}|NULL
+2|'alice, "al"'|10|false|2009-12-11|[1,2]|x
+3|eve|NULL|TRUE|2009-12-12|{}|
+`
+	csvIn, err := datasource.NewCsvSourceOptions("users", 0, strings.NewReader(data), make(<-chan bool, 1), &datasource.CsvOptions{
+		Delimiter: '|',
+		Quote:     '\'',
+		Nulls:     []string{"NULL", ""},
+		NoHeader:  true,
+		Columns:   []string{"id", "name", "score", "active", "reg_date", "attrs"},
+		Infer:     true,
+		Types:     map[string]value.ValueType{"col7": value.IntType},
+	})
+	assert.Equal(t, nil, err)
+	tbl, err := csvIn.Table("users")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "name", "score", "active", "reg_date", "attrs", "col7"}, tbl.Columns())
+	types := make([]string, 0)
+	for _, col := range tbl.Columns() {
+		types = append(types, tbl.FieldMap[col].ValueType().String())
+	}
+	assert.Equal(t, []string{"int", "string", "number", "bool", "time", "json", "int"}, types)
+
+	rows := make([][]driver.Value, 0)
+	for msg := csvIn.Next(); msg != nil; msg = csvIn.Next() {
+		rows = append(rows, msg.(*datasource.SqlDriverMessageMap).Values())
+	}
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, int64(1), rows[0][0])
+	assert.Equal(t, "bob 'b' smith", rows[0][1])
+	assert.Equal(t, `alice, "al"`, rows[1][1])
+	assert.Equal(t, float64(10), rows[1][2])
+	assert.Equal(t, nil, rows[2][2])
+	assert.Equal(t, true, rows[2][3])
+	assert.Equal(t, time.Date(2012, 10, 17, 0, 0, 0, 0, time.UTC), rows[0][4])
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, rows[0][5])
+	// declared int, not convertible
+	assert.Equal(t, nil, rows[0][6])
+	assert.Equal(t, nil, rows[1][6])
+
+	// without inference only declared types are converted
+	csvIn, err = datasource.NewCsvSourceOptions("user.csv", 0, strings.NewReader(testData["user.csv"]), make(<-chan bool, 1), &datasource.CsvOptions{
+		Types: map[string]value.ValueType{"item_count": value.IntType},
+	})
+	assert.Equal(t, nil, err)
+	msg := csvIn.Next().(*datasource.SqlDriverMessageMap)
+	assert.Equal(t, []driver.Value{"9Ip1aKbeZe2njCDM", "aaron@email.com", "fishing", "2012-10-17T17:29:39.738Z", int64(82)}, msg.Values())
+
+	_, err = datasource.NewCsvSourceOptions("user.csv", 0, strings.NewReader(testData["user.csv"]), make(<-chan bool, 1), &datasource.CsvOptions{
+		Types: map[string]value.ValueType{"not_a_column": value.IntType},
+	})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestCsvInferLateValue(t *testing.T) {
+	data := "id,amount\n1,10\n2,20\n3,1.5\n"
+	csvIn, err := datasource.NewCsvSourceOptions("orders", 0, strings.NewReader(data), make(<-chan bool, 1), &datasource.CsvOptions{
+		Infer:      true,
+		SampleRows: 2,
+	})
+	assert.Equal(t, nil, err)
+	tbl, err := csvIn.Table("orders")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, value.IntType, tbl.FieldMap["amount"].ValueType())
+
+	rows := make([][]driver.Value, 0)
+	for msg := csvIn.Next(); msg != nil; msg = csvIn.Next() {
+		rows = append(rows, msg.(*datasource.SqlDriverMessageMap).Values())
+	}
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, int64(20), rows[1][1])
+	// a float after the sampled ints is null, every value of the column
+	// and the table keep the type the rows were read as
+	assert.Equal(t, nil, rows[2][1])
+	assert.Equal(t, value.IntType, tbl.FieldMap["amount"].ValueType())
+
+	// which an explicit type overrides
+	csvIn, err = datasource.NewCsvSourceOptions("orders", 0, strings.NewReader(data), make(<-chan bool, 1), &datasource.CsvOptions{
+		Infer:      true,
+		SampleRows: 2,
+		Types:      map[string]value.ValueType{"amount": value.NumberType},
+	})
+	assert.Equal(t, nil, err)
+	rows = rows[:0]
+	for msg := csvIn.Next(); msg != nil; msg = csvIn.Next() {
+		rows = append(rows, msg.(*datasource.SqlDriverMessageMap).Values())
+	}
+	assert.Equal(t, []driver.Value{float64(10), float64(20), float64(1.5)}, []driver.Value{rows[0][1], rows[1][1], rows[2][1]})
+}
+
+func TestCsvFollow(t *testing.T) {
+	f, err := ioutil.TempFile("", "qlb_follow")
+	assert.Equal(t, nil, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("path,status\n/a,200\n")
+	assert.Equal(t, nil, err)
+
+	r, err := os.Open(f.Name())
+	assert.Equal(t, nil, err)
+	exit := make(chan bool)
+	csvIn, err := datasource.NewCsvSourceOptions("access_log", 0, r, exit, &datasource.CsvOptions{
+		Follow: true,
+		Infer:  true,
+		Types:  map[string]value.ValueType{"status": value.IntType},
+	})
+	assert.Equal(t, nil, err)
+	defer csvIn.Close()
+
+	msgs := make(chan []driver.Value)
+	go func() {
+		for msg := csvIn.Next(); msg != nil; msg = csvIn.Next() {
+			msgs <- msg.(*datasource.SqlDriverMessageMap).Values()
+		}
+		close(msgs)
+	}()
+	assert.Equal(t, []driver.Value{"/a", int64(200)}, <-msgs)
+
+	// appended after reaching EOF
+	_, err = f.WriteString("/b,500\n")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []driver.Value{"/b", int64(500)}, <-msgs)
+
+	close(exit)
+	_, open := <-msgs
+	assert.False(t, open)
+	f.Close()
+}
@@ -0,0 +1,159 @@
+package datasource
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	// CsvTimeLayouts are the time layouts tried (in order) when inferring
+	// the type of csv columns.  A column is only a time if every sampled
+	// value parses with the same layout.
+	CsvTimeLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		"2006/01/02 15:04:05",
+		"2006/01/02",
+		"01/02/2006 15:04:05",
+		"01/02/2006",
+		time.RFC1123Z,
+		time.RFC1123,
+	}
+)
+
+// csvColumnType the inferred type of a column as well as time layout
+// used to parse it
+type csvColumnType struct {
+	vt     value.ValueType
+	layout string
+}
+
+// csvTypeSample accumulates which types all values of a column satisfy
+type csvTypeSample struct {
+	ct      int
+	isInt   bool
+	isNum   bool
+	isBool  bool
+	isJson  bool
+	layouts []string
+}
+
+func newCsvTypeSample() *csvTypeSample {
+	return &csvTypeSample{isInt: true, isNum: true, isBool: true, isJson: true, layouts: CsvTimeLayouts}
+}
+
+func (m *csvTypeSample) add(val string) {
+	m.ct++
+	if m.isInt {
+		if _, err := strconv.ParseInt(val, 10, 64); err != nil {
+			m.isInt = false
+		}
+	}
+	if m.isNum {
+		if _, err := strconv.ParseFloat(val, 64); err != nil {
+			m.isNum = false
+		}
+	}
+	if m.isBool {
+		m.isBool = strings.EqualFold(val, "true") || strings.EqualFold(val, "false")
+	}
+	if m.isJson {
+		m.isJson = (strings.HasPrefix(val, "{") || strings.HasPrefix(val, "[")) && json.Valid([]byte(val))
+	}
+	if len(m.layouts) > 0 {
+		layouts := make([]string, 0, len(m.layouts))
+		for _, layout := range m.layouts {
+			if _, err := time.Parse(layout, val); err == nil {
+				layouts = append(layouts, layout)
+			}
+		}
+		m.layouts = layouts
+	}
+}
+
+func (m *csvTypeSample) colType() csvColumnType {
+	switch {
+	case m.ct == 0:
+		return csvColumnType{vt: value.StringType}
+	case m.isInt:
+		return csvColumnType{vt: value.IntType}
+	case m.isNum:
+		return csvColumnType{vt: value.NumberType}
+	case m.isBool:
+		return csvColumnType{vt: value.BoolType}
+	case len(m.layouts) > 0:
+		return csvColumnType{vt: value.TimeType, layout: m.layouts[0]}
+	case m.isJson:
+		return csvColumnType{vt: value.JsonType}
+	}
+	return csvColumnType{vt: value.StringType}
+}
+
+// inferCsvTypes infer the type of each of @cols columns from sample @rows,
+// a type is only chosen if all (non-null) values of the column satisfy it.
+func inferCsvTypes(cols int, rows [][]driver.Value) []csvColumnType {
+	samples := make([]*csvTypeSample, cols)
+	for i := range samples {
+		samples[i] = newCsvTypeSample()
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			if s, ok := v.(string); ok && i < cols {
+				samples[i].add(s)
+			}
+		}
+	}
+	types := make([]csvColumnType, cols)
+	for i, s := range samples {
+		types[i] = s.colType()
+	}
+	return types
+}
+
+// convert a csv string to the go type for this column type, ok is
+// false if it could not be converted.
+func (m csvColumnType) convert(val string) (driver.Value, bool) {
+	switch m.vt {
+	case value.IntType:
+		if iv, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return iv, true
+		}
+	case value.NumberType:
+		if fv, err := strconv.ParseFloat(val, 64); err == nil {
+			return fv, true
+		}
+	case value.BoolType:
+		if bv, err := strconv.ParseBool(val); err == nil {
+			return bv, true
+		}
+	case value.TimeType:
+		if m.layout != "" {
+			if t, err := time.Parse(m.layout, val); err == nil {
+				return t, true
+			}
+		}
+		if t, err := dateparse.ParseAny(val); err == nil {
+			return t, true
+		}
+	case value.JsonType:
+		var jv interface{}
+		if err := json.Unmarshal([]byte(val), &jv); err == nil {
+			return jv, true
+		}
+	default:
+		return val, true
+	}
+	u.LogThrottle(u.WARN, 10, "could not convert csv value %q to %s", val, m.vt)
+	return nil, false
+}
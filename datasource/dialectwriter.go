@@ -0,0 +1,400 @@
+package datasource
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	// Ensure our writers implement schema.DialectWriter
+	_ schema.DialectWriter = (*mysqlWriter)(nil)
+	_ schema.DialectWriter = (*postgresWriter)(nil)
+	_ schema.DialectWriter = (*bigqueryWriter)(nil)
+	_ schema.DialectWriter = (*cassandraWriter)(nil)
+)
+
+func init() {
+	schema.RegisterDialectWriter(&mysqlWriter{})
+	schema.RegisterDialectWriter(&postgresWriter{})
+	schema.RegisterDialectWriter(&bigqueryWriter{})
+	schema.RegisterDialectWriter(&cassandraWriter{})
+	DialectWriters = schema.DialectWriters()
+	for _, writer := range DialectWriters {
+		DialectWriterCols = append(DialectWriterCols, writer.Dialect())
+	}
+}
+
+// dialectWriters the registered writers, and those appended to the
+// deprecated DialectWriters that are not registered.
+func dialectWriters() []schema.DialectWriter {
+	writers := schema.DialectWriters()
+	for _, writer := range DialectWriters {
+		if _, ok := schema.DialectWriterGet(writer.Dialect()); !ok {
+			writers = append(writers, writer)
+		}
+	}
+	return writers
+}
+
+type postgresWriter struct {
+}
+
+func (m *postgresWriter) Dialect() string {
+	return "postgres"
+}
+func (m *postgresWriter) FieldType(t value.ValueType) string {
+	return PostgresValueString(t)
+}
+
+// Table output a CREATE TABLE statement using postgres dialect.
+func (m *postgresWriter) Table(tbl *schema.Table) string {
+
+	if tbl.View != nil {
+		keyword := "VIEW"
+		if tbl.View.Materialized {
+			keyword = "MATERIALIZED VIEW"
+		}
+		return fmt.Sprintf("CREATE %s %s AS %s;", keyword, pgIdentity(tbl.Name), tbl.View.Sql)
+	}
+
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "CREATE TABLE %s (", pgIdentity(tbl.Name))
+	for i, fld := range tbl.Fields {
+		if i != 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, "\n    %s ", pgIdentity(fld.Name))
+		if fld.ValueType() == value.StringType && fld.Length > 0 {
+			fmt.Fprintf(w, "varchar(%d)", fld.Length)
+		} else {
+			fmt.Fprint(w, m.FieldType(fld.ValueType()))
+		}
+		if fld.NoNulls {
+			fmt.Fprint(w, " NOT NULL")
+		}
+		if def := fld.DefaultValue(); def != nil {
+			fmt.Fprintf(w, " DEFAULT %s", pgLiteral(def))
+		}
+	}
+	// plain indexes are not constraints, they are created after the table
+	var indexes []string
+	for _, idx := range tbl.Indexes {
+		cols := make([]string, len(idx.Fields))
+		for i, f := range idx.Fields {
+			cols[i] = pgIdentity(f)
+		}
+		switch {
+		case idx.PrimaryKey:
+			fmt.Fprintf(w, ",\n    PRIMARY KEY (%s)", strings.Join(cols, ", "))
+		case idx.Unique:
+			fmt.Fprintf(w, ",\n    CONSTRAINT %s UNIQUE (%s)", pgIdentity(idx.Name), strings.Join(cols, ", "))
+		default:
+			indexes = append(indexes, fmt.Sprintf("\nCREATE INDEX %s ON %s (%s);", pgIdentity(idx.Name),
+				pgIdentity(tbl.Name), strings.Join(cols, ", ")))
+		}
+	}
+	fmt.Fprint(w, "\n);")
+	for _, idx := range indexes {
+		w.WriteString(idx)
+	}
+	// postgres has no inline column comments
+	for _, fld := range tbl.Fields {
+		if len(fld.Description) > 0 {
+			fmt.Fprintf(w, "\nCOMMENT ON COLUMN %s.%s IS %s;", pgIdentity(tbl.Name),
+				pgIdentity(fld.Name), pgLiteral(fld.Description))
+		}
+	}
+	return w.String()
+}
+
+// PostgresValueString convert a value.ValueType into a postgres type descriptor
+func PostgresValueString(t value.ValueType) string {
+	switch t {
+	case value.NumberType:
+		return "double precision"
+	case value.IntType:
+		return "bigint"
+	case value.BoolType:
+		return "boolean"
+	case value.TimeType:
+		return "timestamp"
+	case value.ByteSliceType:
+		return "bytea"
+	case value.StringType:
+		return "text"
+	case value.StringsType:
+		return "text[]"
+	case value.MapValueType, value.MapIntType, value.MapStringType, value.MapNumberType,
+		value.MapBoolType, value.MapTimeType, value.SliceValueType, value.StructType,
+		value.JsonType:
+		return "jsonb"
+	default:
+		// NilType, ErrorType, UnknownType, ValueInterfaceType
+		return "text"
+	}
+}
+
+// pgIdentity a double-quoted postgres identity.
+func pgIdentity(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// pgLiteral a value as a single-quoted postgres literal.
+func pgLiteral(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05") + "'"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+type bigqueryWriter struct {
+}
+
+func (m *bigqueryWriter) Dialect() string {
+	return "bigquery"
+}
+func (m *bigqueryWriter) FieldType(t value.ValueType) string {
+	return BigQueryValueString(t)
+}
+
+// Table output a CREATE TABLE statement using bigquery standard sql dialect.
+func (m *bigqueryWriter) Table(tbl *schema.Table) string {
+
+	if tbl.View != nil {
+		keyword := "VIEW"
+		if tbl.View.Materialized {
+			keyword = "MATERIALIZED VIEW"
+		}
+		return fmt.Sprintf("CREATE %s `%s` AS %s;", keyword, tbl.Name, tbl.View.Sql)
+	}
+
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "CREATE TABLE `%s` (", tbl.Name)
+	for i, fld := range tbl.Fields {
+		if i != 0 {
+			w.WriteByte(',')
+		}
+		fieldType := m.FieldType(fld.ValueType())
+		fmt.Fprintf(w, "\n    `%s` %s", fld.Name, fieldType)
+		// ARRAY columns can't be NULL, so can't be declared NOT NULL
+		if fld.NoNulls && !strings.HasPrefix(fieldType, "ARRAY") {
+			fmt.Fprint(w, " NOT NULL")
+		}
+		if def := fld.DefaultValue(); def != nil {
+			fmt.Fprintf(w, " DEFAULT %s", bigqueryLiteral(def))
+		}
+		if len(fld.Description) > 0 {
+			fmt.Fprintf(w, " OPTIONS(description=%s)", bigqueryQuote(fld.Description))
+		}
+	}
+	// bigquery has no unique constraints, and does not enforce primary keys
+	for _, idx := range tbl.Indexes {
+		if idx.PrimaryKey && len(idx.Fields) > 0 {
+			fmt.Fprintf(w, ",\n    PRIMARY KEY (`%s`) NOT ENFORCED", strings.Join(idx.Fields, "`, `"))
+		}
+	}
+	fmt.Fprint(w, "\n);")
+	return w.String()
+}
+
+// BigQueryValueString convert a value.ValueType into a bigquery type descriptor.
+// BigQuery has no map type, typed maps are the repeated key/value records
+// bigquery loads avro and parquet maps as.
+func BigQueryValueString(t value.ValueType) string {
+	switch t {
+	case value.NumberType:
+		return "FLOAT64"
+	case value.IntType:
+		return "INT64"
+	case value.BoolType:
+		return "BOOL"
+	case value.TimeType:
+		return "TIMESTAMP"
+	case value.ByteSliceType:
+		return "BYTES"
+	case value.StringType:
+		return "STRING"
+	case value.StringsType:
+		return "ARRAY<STRING>"
+	case value.MapIntType:
+		return "ARRAY<STRUCT<key STRING, value INT64>>"
+	case value.MapStringType:
+		return "ARRAY<STRUCT<key STRING, value STRING>>"
+	case value.MapNumberType:
+		return "ARRAY<STRUCT<key STRING, value FLOAT64>>"
+	case value.MapBoolType:
+		return "ARRAY<STRUCT<key STRING, value BOOL>>"
+	case value.MapTimeType:
+		return "ARRAY<STRUCT<key STRING, value TIMESTAMP>>"
+	case value.MapValueType, value.SliceValueType, value.StructType, value.JsonType:
+		return "JSON"
+	default:
+		// NilType, ErrorType, UnknownType, ValueInterfaceType
+		return "STRING"
+	}
+}
+
+// bigqueryLiteral a value as a bigquery literal.
+func bigqueryLiteral(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return bigqueryQuote(v)
+	case time.Time:
+		return "TIMESTAMP " + bigqueryQuote(v.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// bigqueryQuote @s as a double-quoted bigquery string literal.  Quotes,
+// backslashes and control characters are escaped, bigquery strings are
+// utf8 so invalid bytes are replaced by U+FFFD.
+func bigqueryQuote(s string) string {
+	w := &bytes.Buffer{}
+	w.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			w.WriteString(`\"`)
+		case '\\':
+			w.WriteString(`\\`)
+		case '\a':
+			w.WriteString(`\a`)
+		case '\b':
+			w.WriteString(`\b`)
+		case '\f':
+			w.WriteString(`\f`)
+		case '\n':
+			w.WriteString(`\n`)
+		case '\r':
+			w.WriteString(`\r`)
+		case '\t':
+			w.WriteString(`\t`)
+		case '\v':
+			w.WriteString(`\v`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(w, `\x%02x`, r)
+			} else {
+				w.WriteRune(r)
+			}
+		}
+	}
+	w.WriteByte('"')
+	return w.String()
+}
+
+type cassandraWriter struct {
+}
+
+func (m *cassandraWriter) Dialect() string {
+	return "cassandra"
+}
+func (m *cassandraWriter) FieldType(t value.ValueType) string {
+	return CassandraValueString(t)
+}
+
+// Table output a CREATE TABLE statement using cassandra cql dialect.
+//
+// CQL has no NOT NULL, DEFAULT or unique constraints and requires a primary
+// key, for tables without one the first column is used.  The HashPartition
+// fields of the primary key are the partition key, the rest are clustering
+// columns.
+func (m *cassandraWriter) Table(tbl *schema.Table) string {
+
+	if tbl.View != nil {
+		// cql materialized views are only a re-keyed copy of one table
+		return fmt.Sprintf("-- cassandra does not support %s %s AS %s", tbl.View.Keyword(),
+			pgIdentity(tbl.Name), tbl.View.Sql)
+	}
+
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "CREATE TABLE %s (", pgIdentity(tbl.Name))
+	for _, fld := range tbl.Fields {
+		fmt.Fprintf(w, "\n    %s %s,", pgIdentity(fld.Name), m.FieldType(fld.ValueType()))
+	}
+	var pk *schema.Index
+	for _, idx := range tbl.Indexes {
+		if idx.PrimaryKey && len(idx.Fields) > 0 {
+			pk = idx
+			break
+		}
+	}
+	switch {
+	case pk != nil && len(pk.HashPartition) > 0:
+		partition := make([]string, len(pk.HashPartition))
+		for i, f := range pk.HashPartition {
+			partition[i] = pgIdentity(f)
+		}
+		keys := []string{"(" + strings.Join(partition, ", ") + ")"}
+		for _, f := range pk.Fields {
+			if !stringIn(pk.HashPartition, f) {
+				keys = append(keys, pgIdentity(f))
+			}
+		}
+		fmt.Fprintf(w, "\n    PRIMARY KEY (%s)", strings.Join(keys, ", "))
+	case pk != nil:
+		keys := make([]string, len(pk.Fields))
+		for i, f := range pk.Fields {
+			keys[i] = pgIdentity(f)
+		}
+		fmt.Fprintf(w, "\n    PRIMARY KEY (%s)", strings.Join(keys, ", "))
+	case len(tbl.Fields) > 0:
+		fmt.Fprintf(w, "\n    PRIMARY KEY (%s)", pgIdentity(tbl.Fields[0].Name))
+	}
+	fmt.Fprint(w, "\n);")
+	return w.String()
+}
+
+// CassandraValueString convert a value.ValueType into a cassandra cql type descriptor
+func CassandraValueString(t value.ValueType) string {
+	switch t {
+	case value.NumberType:
+		return "double"
+	case value.IntType:
+		return "bigint"
+	case value.BoolType:
+		return "boolean"
+	case value.TimeType:
+		return "timestamp"
+	case value.ByteSliceType:
+		return "blob"
+	case value.StringType:
+		return "text"
+	case value.StringsType:
+		return "list<text>"
+	case value.MapValueType, value.MapStringType:
+		return "map<text, text>"
+	case value.MapIntType:
+		return "map<text, bigint>"
+	case value.MapNumberType:
+		return "map<text, double>"
+	case value.MapBoolType:
+		return "map<text, boolean>"
+	case value.MapTimeType:
+		return "map<text, timestamp>"
+	case value.SliceValueType:
+		return "list<text>"
+	default:
+		// NilType, ErrorType, UnknownType, ValueInterfaceType,
+		// StructType and JsonType are stored as text
+		return "text"
+	}
+}
+
+func stringIn(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package datasource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+func dialectWriter(t *testing.T, dialect string) schema.DialectWriter {
+	w, ok := schema.DialectWriterGet(dialect)
+	assert.True(t, ok, "has dialect writer %q", dialect)
+	return w
+}
+
+func TestDialectWriterFieldTypes(t *testing.T) {
+	pg := dialectWriter(t, "Postgres")
+	bq := dialectWriter(t, "bigquery")
+	cql := dialectWriter(t, "cassandra")
+
+	tests := []struct {
+		vt          value.ValueType
+		pg, bq, cql string
+	}{
+		{value.NilType, "text", "STRING", "text"},
+		{value.UnknownType, "text", "STRING", "text"},
+		{value.NumberType, "double precision", "FLOAT64", "double"},
+		{value.IntType, "bigint", "INT64", "bigint"},
+		{value.BoolType, "boolean", "BOOL", "boolean"},
+		{value.TimeType, "timestamp", "TIMESTAMP", "timestamp"},
+		{value.ByteSliceType, "bytea", "BYTES", "blob"},
+		{value.StringType, "text", "STRING", "text"},
+		{value.StringsType, "text[]", "ARRAY<STRING>", "list<text>"},
+		{value.MapValueType, "jsonb", "JSON", "map<text, text>"},
+		{value.MapIntType, "jsonb", "ARRAY<STRUCT<key STRING, value INT64>>", "map<text, bigint>"},
+		{value.MapStringType, "jsonb", "ARRAY<STRUCT<key STRING, value STRING>>", "map<text, text>"},
+		{value.MapNumberType, "jsonb", "ARRAY<STRUCT<key STRING, value FLOAT64>>", "map<text, double>"},
+		{value.MapBoolType, "jsonb", "ARRAY<STRUCT<key STRING, value BOOL>>", "map<text, boolean>"},
+		{value.MapTimeType, "jsonb", "ARRAY<STRUCT<key STRING, value TIMESTAMP>>", "map<text, timestamp>"},
+		{value.SliceValueType, "jsonb", "JSON", "list<text>"},
+		{value.StructType, "jsonb", "JSON", "text"},
+		{value.JsonType, "jsonb", "JSON", "text"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.pg, pg.FieldType(tt.vt), "postgres %s", tt.vt)
+		assert.Equal(t, tt.bq, bq.FieldType(tt.vt), "bigquery %s", tt.vt)
+		assert.Equal(t, tt.cql, cql.FieldType(tt.vt), "cassandra %s", tt.vt)
+	}
+
+	_, ok := schema.DialectWriterGet("oracle")
+	assert.False(t, ok)
+	var dialects []string
+	for _, w := range schema.DialectWriters() {
+		dialects = append(dialects, w.Dialect())
+	}
+	assert.Equal(t, []string{"mysql", "postgres", "bigquery", "cassandra"}, dialects)
+	// the deprecated lists still describe the registered writers
+	assert.Equal(t, dialects, datasource.DialectWriterCols)
+	assert.Equal(t, len(dialects), len(datasource.DialectWriters))
+}
+
+func TestDialectWriterTable(t *testing.T) {
+	tbl := schema.NewTable("events")
+	tbl.AddField(schema.NewField("tenant", value.StringType, 32, false, nil, "", "", ""))
+	tbl.AddField(schema.NewField("id", value.IntType, 8, false, nil, "", "", ""))
+	tbl.AddField(schema.NewField("kind", value.StringType, 0, true, "it's", "", "", "event kind"))
+	tbl.AddField(schema.NewField("tags", value.StringsType, 0, false, nil, "", "", ""))
+	tbl.SetColumnsFromFields()
+	tbl.Indexes = []*schema.Index{
+		{Name: "PRIMARY", Fields: []string{"tenant", "id"}, PrimaryKey: true, HashPartition: []string{"tenant"}},
+		{Name: "uniq_kind", Fields: []string{"kind"}, Unique: true},
+		{Name: "idx_id", Fields: []string{"id"}},
+	}
+
+	assert.Equal(t, "CREATE TABLE \"events\" (\n"+
+		"    \"tenant\" varchar(32) NOT NULL,\n"+
+		"    \"id\" bigint NOT NULL,\n"+
+		"    \"kind\" text DEFAULT 'it''s',\n"+
+		"    \"tags\" text[] NOT NULL,\n"+
+		"    PRIMARY KEY (\"tenant\", \"id\"),\n"+
+		"    CONSTRAINT \"uniq_kind\" UNIQUE (\"kind\")\n"+
+		");\n"+
+		"CREATE INDEX \"idx_id\" ON \"events\" (\"id\");\n"+
+		"COMMENT ON COLUMN \"events\".\"kind\" IS 'event kind';", dialectWriter(t, "postgres").Table(tbl))
+
+	assert.Equal(t, "CREATE TABLE `events` (\n"+
+		"    `tenant` STRING NOT NULL,\n"+
+		"    `id` INT64 NOT NULL,\n"+
+		"    `kind` STRING DEFAULT \"it's\" OPTIONS(description=\"event kind\"),\n"+
+		"    `tags` ARRAY<STRING>,\n"+
+		"    PRIMARY KEY (`tenant`, `id`) NOT ENFORCED\n"+
+		");", dialectWriter(t, "bigquery").Table(tbl))
+
+	assert.Equal(t, "CREATE TABLE \"events\" (\n"+
+		"    \"tenant\" text,\n"+
+		"    \"id\" bigint,\n"+
+		"    \"kind\" text,\n"+
+		"    \"tags\" list<text>,\n"+
+		"    PRIMARY KEY ((\"tenant\"), \"id\")\n"+
+		");", dialectWriter(t, "cassandra").Table(tbl))
+
+	// bigquery string literals escape quotes, backslashes and control
+	// characters, invalid utf8 is replaced
+	notes := schema.NewTable("notes")
+	notes.AddField(schema.NewField("body", value.StringType, 0, true, "a\"b\\c\nd\x01é\xff", "", "", `say "hi"`))
+	notes.SetColumnsFromFields()
+	assert.Equal(t, "CREATE TABLE `notes` (\n"+
+		"    `body` STRING DEFAULT \"a\\\"b\\\\c\\nd\\x01é\uFFFD\" OPTIONS(description=\"say \\\"hi\\\"\")\n"+
+		");", dialectWriter(t, "bigquery").Table(notes))
+
+	view := schema.NewTable("recent")
+	view.View = &schema.View{Sql: "SELECT id FROM events", Materialized: true}
+	assert.Equal(t, "CREATE MATERIALIZED VIEW \"recent\" AS SELECT id FROM events;",
+		dialectWriter(t, "postgres").Table(view))
+	assert.Equal(t, "CREATE MATERIALIZED VIEW `recent` AS SELECT id FROM events;",
+		dialectWriter(t, "bigquery").Table(view))
+}
@@ -0,0 +1,145 @@
+package files
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionAuto choose decompression by file extension (default)
+	CompressionAuto = "auto"
+	// CompressionNone files are read as-is
+	CompressionNone = "none"
+)
+
+var (
+	// the global compression registry mutex
+	compressionMu sync.Mutex
+	compressions  = make(map[string]*compression)
+)
+
+// Decompressor wraps a reader of compressed content, returning reader
+// of the uncompressed content.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+type compression struct {
+	name string
+	exts []string
+	d    Decompressor
+}
+
+func init() {
+	RegisterCompression("gzip", []string{".gz", ".gzip"}, func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+	RegisterCompression("zstd", []string{".zst", ".zstd"}, func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	})
+	// snappy framed (stream) format, not raw block format
+	RegisterCompression("snappy", []string{".sz", ".snappy"}, func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(snappy.NewReader(r)), nil
+	})
+	RegisterCompression("bzip2", []string{".bz2", ".bzip2"}, func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+	})
+}
+
+// RegisterCompression Register a Decompressor available by the provided
+// @name (for use in "compression" setting) and file extensions.
+func RegisterCompression(name string, exts []string, d Decompressor) {
+	if d == nil {
+		panic("Decompressor must not be nil")
+	}
+	name = strings.ToLower(name)
+	compressionMu.Lock()
+	defer compressionMu.Unlock()
+	if _, dupe := compressions[name]; dupe {
+		panic("Register called twice for compression " + name)
+	}
+	for i, ext := range exts {
+		exts[i] = strings.ToLower(ext)
+	}
+	compressions[name] = &compression{name: name, exts: exts, d: d}
+}
+
+// compressionGet find compression by name, or if @name is "auto" (or empty)
+// by the extension of @fileName.  Returns nil if file is not compressed.
+func compressionGet(name, fileName string) (*compression, error) {
+	compressionMu.Lock()
+	defer compressionMu.Unlock()
+	name = strings.ToLower(name)
+	switch name {
+	case CompressionNone:
+		return nil, nil
+	case "", CompressionAuto:
+		fileName = strings.ToLower(fileName)
+		for _, c := range compressions {
+			for _, ext := range c.exts {
+				if strings.HasSuffix(fileName, ext) {
+					return c, nil
+				}
+			}
+		}
+		return nil, nil
+	}
+	c, ok := compressions[name]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized compression %q", name)
+	}
+	return c, nil
+}
+
+// trimCompressionExt remove any known compression extension from file name
+// so users.csv.gz is treated as users.csv
+func trimCompressionExt(fileName string) string {
+	c, _ := compressionGet(CompressionAuto, fileName)
+	if c == nil {
+		return fileName
+	}
+	lower := strings.ToLower(fileName)
+	for _, ext := range c.exts {
+		if strings.HasSuffix(lower, ext) {
+			return fileName[:len(fileName)-len(ext)]
+		}
+	}
+	return fileName
+}
+
+// decompressReader closes both the decompressor and the underlying file
+type decompressReader struct {
+	io.ReadCloser
+	f io.Closer
+}
+
+func (m *decompressReader) Close() error {
+	err := m.ReadCloser.Close()
+	if ferr := m.f.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// decompress wrap @f in decompressor for given compression setting and file name.
+func decompress(name, fileName string, f io.ReadCloser) (io.ReadCloser, error) {
+	c, err := compressionGet(name, fileName)
+	if err != nil || c == nil {
+		return f, err
+	}
+	rc, err := c.d(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s reader for %q: %v", c.name, fileName, err)
+	}
+	return &decompressReader{ReadCloser: rc, f: f}, nil
+}
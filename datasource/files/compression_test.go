@@ -0,0 +1,129 @@
+package files
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+const compressTestCsv = "id,name\n1,bob\n2,alice\n"
+
+// bzip2 of compressTestCsv, there is no bzip2 writer in stdlib
+var compressTestBzip2 = []byte("\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\xea\x76\x2a\xae\x00\x00\x08\xd9\x00\x00\x10\x00\x04\x30\x00\x3e\x27\xa0\x00\x21\xa9\xa3\x65\x34\x36\xa1\x00\x00\x29\x41\x34\x42\xb7\x9f\x07\x6f\x6c\x27\xf1\x77\x24\x53\x85\x09\x0e\xa7\x62\xaa\xe0")
+
+func compressTestData(t *testing.T, name string) []byte {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch name {
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(buf)
+		assert.Equal(t, nil, err)
+		w = zw
+	case "snappy":
+		w = snappy.NewBufferedWriter(buf)
+	case "bzip2":
+		return compressTestBzip2
+	default:
+		return []byte(compressTestCsv)
+	}
+	_, err := io.WriteString(w, compressTestCsv)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, w.Close())
+	return buf.Bytes()
+}
+
+type closeCounter struct {
+	io.Reader
+	closed int
+}
+
+func (m *closeCounter) Close() error {
+	m.closed++
+	return nil
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		setting string
+		file    string
+		data    string
+	}{
+		{"", "users.csv", "none"},
+		{"auto", "users.csv.gz", "gzip"},
+		{"auto", "users.csv.GZ", "gzip"},
+		{"auto", "users.json.zst", "zstd"},
+		{"auto", "users.csv.sz", "snappy"},
+		{"auto", "users.csv.bz2", "bzip2"},
+		{"gzip", "users.csv", "gzip"},
+		{"none", "users.csv.gz", "none"},
+	}
+	for _, tt := range tests {
+		f := &closeCounter{Reader: bytes.NewReader(compressTestData(t, tt.data))}
+		rc, err := decompress(tt.setting, tt.file, f)
+		assert.Equal(t, nil, err, tt.file)
+		by, err := ioutil.ReadAll(rc)
+		assert.Equal(t, nil, err, tt.file)
+		assert.Equal(t, compressTestCsv, string(by), tt.file)
+		assert.Equal(t, nil, rc.Close())
+		assert.Equal(t, 1, f.closed, tt.file)
+	}
+
+	_, err := decompress("lzma", "users.csv", &closeCounter{Reader: &bytes.Buffer{}})
+	assert.NotEqual(t, nil, err)
+
+	assert.Equal(t, "users.csv", trimCompressionExt("users.csv.gz"))
+	assert.Equal(t, "users.csv", trimCompressionExt("users.csv"))
+	assert.Equal(t, "users", TableFromFileAndPath("", "tables/users.csv.zst"))
+}
+
+type compressTestSource struct {
+	*FileSource
+	dir string
+}
+
+func (m *compressTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       "testcompress",
+		SourceType: "testcompress",
+		Settings: u.JsonHelper(map[string]interface{}{
+			"path":      "",
+			"format":    "csv",
+			"type":      "localfs",
+			"localpath": m.dir,
+		}),
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func TestCompressedCsvSql(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_compress")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, "users"), 0755))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "users", "users1.csv.zst"), compressTestData(t, "zstd"), 0644))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "users", "users2.csv.bz2"), compressTestData(t, "bzip2"), 0644))
+
+	schema.RegisterSourceAsSchema("testcompress", &compressTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testcompress")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	var ct int64
+	assert.Equal(t, nil, db.QueryRow(`SELECT count(*) AS ct FROM users WHERE name = "bob"`).Scan(&ct))
+	assert.Equal(t, int64(2), ct)
+}
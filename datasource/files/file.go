@@ -9,6 +9,9 @@ import (
 
 	u "github.com/araddon/gou"
 	"github.com/lytics/cloudstorage"
+
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
 )
 
 var (
@@ -44,13 +47,22 @@ type FileInfo struct {
 	Partition   int            // which partition
 	Size        int            // Content-Length size in bytes
 	AppendCols  []driver.Value // Additional Column info extracted from file name/folder path
+	// HivePartitions key=value folders in path ie tables/events/dt=2024-01-01/events.csv
+	HivePartitions []HivePartition
 }
 
 // FileReader file info and access to file to supply to ScannerMakers
 type FileReader struct {
 	*FileInfo
-	F    io.ReadCloser // Actual file reader
-	Exit chan bool     // exit channel to shutdown reader
+	F    io.ReadCloser  // Actual file reader
+	Exit chan bool      // exit channel to shutdown reader
+	Sql  *rel.SqlSelect // Optional sql statement for this source, scanners may use for projection/filtering
+	// Settings of the source, scanners may use for format options
+	Settings u.JsonHelper
+	// Follow F is followed for appended data (SUBSCRIBE), Tbl is the
+	// table schema so scanners needn't read ahead to learn column types
+	Follow bool
+	Tbl    *schema.Table
 }
 
 func (m *FileInfo) String() string {
@@ -97,6 +109,7 @@ func FileInfoFromCloudObject(path string, obj cloudstorage.Object) *FileInfo {
 	if len(parts) > 1 {
 		fi.PartialPath = strings.Join(parts[0:len(parts)-1], "/")
 	}
+	fi.HivePartitions = hivePartitions(fi.PartialPath)
 	//u.Debugf("Fi: name=%q table=%q  partial:%q partial2:%q", fi.Name, fi.Table, fi.PartialPath, partialPath)
 	return fi
 }
@@ -113,6 +126,7 @@ func FileInfoFromCloudObject(path string, obj cloudstorage.Object) *FileInfo {
 // 2) Suport Table as name of file inside folder
 //     rootpath/users.csv
 //     rootpath/accounts.csv
+//     rootpath/users.part-1700000000-a1b2c3d4.csv  (part file from INSERT)
 //
 func TableFromFileAndPath(path, fileIn string) string {
 
@@ -133,10 +147,27 @@ func TableFromFileAndPath(path, fileIn string) string {
 
 	parts := strings.Split(fileWithPath, "/")
 
+	// hive style key=value partition folders are not part of table name
+	//     events/dt=2024-01-01/region=us/events.csv
+	if len(parts) > 2 {
+		nonPartition := make([]string, 0, len(parts))
+		for i, part := range parts {
+			if i < len(parts)-1 && isHiveSegment(part) {
+				continue
+			}
+			nonPartition = append(nonPartition, part)
+		}
+		parts = nonPartition
+		fileWithPath = strings.Join(parts, "/")
+	}
+
 	switch len(parts) {
 	case 1:
-		parts = strings.Split(fileWithPath, ".")
-		if len(parts) == 2 {
+		parts = strings.Split(trimCompressionExt(fileWithPath), ".")
+		switch {
+		case len(parts) == 2:
+			return strings.ToLower(parts[0])
+		case len(parts) == 3 && strings.HasPrefix(parts[1], partFilePrefix):
 			return strings.ToLower(parts[0])
 		}
 	case 2:
@@ -17,6 +17,9 @@ func TestFileTableNames(t *testing.T) {
 	assert.Equal(t, "players", TableFromFileAndPath("baseball", "baseball/tables/players.csv"))
 	assert.Equal(t, "players", TableFromFileAndPath("baseball", "baseball/tables/players/2017.csv"))
 
+	assert.Equal(t, "players", TableFromFileAndPath("baseball", "baseball/players.part-1700000000-a1b2c3d4.csv"))
+	assert.Equal(t, "", TableFromFileAndPath("baseball", "baseball/players.2017.csv"))
+
 	// Cannot interpret this
 	assert.Equal(t, "", TableFromFileAndPath("baseball", "baseball/tables/players/partition1/2017.csv"))
 }
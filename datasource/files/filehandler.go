@@ -62,6 +62,22 @@ type FileHandlerSchema interface {
 	schema.SourceTableSchema
 }
 
+// FileHandlerFollow - file handlers may optionally read files still being
+// appended to, which SUBSCRIBE requires.  Line based formats can, as a
+// followed FileReader only returns EOF once the subscription is done.
+type FileHandlerFollow interface {
+	FileHandler
+	Follow() bool
+}
+
+// FileScannerSchema - file scanners may optionally provide the table schema
+// when the file itself describes it (ie, parquet footer) instead of the
+// schema being introspected from the first rows.
+type FileScannerSchema interface {
+	schema.ConnScanner
+	SchemaTable() *schema.Table
+}
+
 // RegisterFileHandler Register a FileHandler available by the provided @scannerType
 func RegisterFileHandler(scannerType string, fh FileHandler) {
 	if fh == nil {
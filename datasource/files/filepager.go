@@ -1,7 +1,11 @@
 package files
 
 import (
+	"fmt"
+	"io"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	u "github.com/araddon/gou"
@@ -9,6 +13,7 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/api/iterator"
 
+	"github.com/fuhongbo/qlbridge/datasource"
 	"github.com/fuhongbo/qlbridge/exec"
 	"github.com/fuhongbo/qlbridge/plan"
 	"github.com/fuhongbo/qlbridge/schema"
@@ -33,7 +38,7 @@ type FilePager struct {
 	table           string
 	exit            chan bool
 	err             error
-	closed          bool
+	closed          int32 // atomic, Close may be called while scanning
 	fs              *FileSource
 	readers         chan (*FileReader)
 	partition       *schema.Partition
@@ -42,6 +47,12 @@ type FilePager struct {
 	tbl             *schema.Table
 	p               *plan.Source
 	usePartitioning bool
+	fetchOnce       sync.Once
+	fi              *FileInfo          // current file
+	appender        *partitionAppender // appends hive partition values to rows
+	sink            *fileSink          // rows Put but not yet flushed
+	follow          chan bool          // closed to stop following the last file of a SUBSCRIBE
+	followOnce      sync.Once
 
 	schema.ConnScanner
 }
@@ -53,6 +64,7 @@ func NewFilePager(tableName string, fs *FileSource) *FilePager {
 		table:   tableName,
 		exit:    make(chan bool),
 		readers: make(chan *FileReader, FileBufferSize),
+		follow:  make(chan bool),
 		partid:  -1,
 	}
 	return fp
@@ -61,6 +73,11 @@ func NewFilePager(tableName string, fs *FileSource) *FilePager {
 // WalkExecSource Provide ability to implement a source plan for execution
 func (m *FilePager) WalkExecSource(p *plan.Source) (exec.Task, error) {
 
+	if p.Subscribe() {
+		if fh, ok := m.fs.handler(m.table).(FileHandlerFollow); !ok || !fh.Follow() {
+			return nil, fmt.Errorf("format %q of table %q can not be followed by SUBSCRIBE", m.fs.tableFormat(m.table), m.table)
+		}
+	}
 	if m.p == nil {
 		m.p = p
 		if partitionId, ok := p.Custom.IntSafe("partition"); ok {
@@ -95,12 +112,20 @@ func (m *FilePager) NextScanner() (schema.ConnScanner, error) {
 		u.Warnf("NextFile Error %v", err)
 		return nil, err
 	}
+	if m.p != nil && m.p.Stmt != nil {
+		fr.Sql = m.p.Stmt.Source
+	}
 
-	scanner, err := m.fs.fh.Scanner(m.fs.store, fr)
+	if fr.Follow {
+		fr.Tbl, _ = m.fs.Table(m.table)
+	}
+	scanner, err := m.fs.handler(m.table).Scanner(m.fs.store, fr)
 	if err != nil {
 		u.Errorf("Could not open file scanner %v err=%v", m.fs.fileType, err)
 		return nil, err
 	}
+	m.fi = fr.FileInfo
+	m.appender = newPartitionAppender(fr.FileInfo)
 	m.ConnScanner = scanner
 	return scanner, err
 }
@@ -108,6 +133,10 @@ func (m *FilePager) NextScanner() (schema.ConnScanner, error) {
 // NextFile gets next file
 func (m *FilePager) NextFile() (*FileReader, error) {
 
+	// the fetcher is started on first use, so that the sql
+	// statement is known and can be used to skip files
+	m.RunFetcher()
+
 	select {
 	case <-m.exit:
 		// See if exit was called
@@ -120,13 +149,17 @@ func (m *FilePager) NextFile() (*FileReader, error) {
 	}
 }
 
+// RunFetcher start the go-routine fetching files, only the first
+// call has any effect.
 func (m *FilePager) RunFetcher() {
-	defer func() {
-		if r := recover(); r != nil {
-			u.Errorf("panic in fetcher %v", r)
-		}
-	}()
-	go m.fetcher()
+	m.fetchOnce.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				u.Errorf("panic in fetcher %v", r)
+			}
+		}()
+		go m.fetcher()
+	})
 }
 
 // fetcher process run in a go-routine to pre-fetch files
@@ -152,6 +185,17 @@ func (m *FilePager) fetcher() {
 		m.usePartitioning = true
 	}
 	printTiming := false
+	fh := m.fs.handler(m.table)
+	var pf *partitionFilter
+	if m.p != nil && m.p.Stmt != nil {
+		pf = newPartitionFilter(m.p.Stmt.Source)
+	}
+	// SUBSCRIBE queries follow the last file for appended rows
+	var pending *FileInfo
+	follow := m.p != nil && m.p.Subscribe()
+	if follow {
+		go m.followUntilDone()
+	}
 	u.Infof("starting fetcher table=%q fs.path=%q  path=%q partCt:%d limit=%d", m.table, m.fs.path, path, m.fs.partitionCt, m.Limit)
 
 	for {
@@ -165,6 +209,13 @@ func (m *FilePager) fetcher() {
 		default:
 			o, err := iter.Next()
 			if err == iterator.Done {
+				if pending != nil {
+					if fr, err := m.followFile(ctx, pending); err != nil {
+						u.Errorf("could not follow %q table %v", pending.Name, err)
+					} else {
+						m.readers <- fr
+					}
+				}
 				m.readers <- nil
 				return
 			} else if err == context.Canceled || err == context.DeadlineExceeded {
@@ -173,7 +224,7 @@ func (m *FilePager) fetcher() {
 			}
 			m.rowct++
 
-			fi := m.fs.File(o)
+			fi := m.fs.fileFor(fh, o)
 			if fi == nil || fi.Name == "" {
 				// this is expected, not all files are of file type
 				// we are looking for
@@ -193,6 +244,18 @@ func (m *FilePager) fetcher() {
 				}
 			}
 
+			if !pf.Match(fi) {
+				continue
+			}
+
+			if follow {
+				// hold back each file until we know it isn't the last
+				fi, pending = pending, fi
+				if fi == nil {
+					continue
+				}
+			}
+
 			obj, err := m.fs.store.Get(ctx, fi.Name)
 			if err != nil {
 				u.Debugf("could not open: path=%q fi.Name:%q", m.fs.path, fi.Name)
@@ -220,13 +283,15 @@ func (m *FilePager) fetcher() {
 			if printTiming {
 				u.Debugf("found file: %s   took:%vms", obj.Name(), time.Now().Sub(start).Nanoseconds()/1e6)
 			}
-
-			fr := &FileReader{
-				F:        f,
-				Exit:     make(chan bool),
-				FileInfo: fi,
+			rc, err := decompress(m.fs.compression, fi.Name, f)
+			if err != nil {
+				f.Close()
+				u.Errorf("could not read %q table %v", m.table, err)
+				return
 			}
 
+			fr := m.newFileReader(fi, rc)
+
 			// This will back-pressure after we reach our queue size
 			m.readers <- fr
 
@@ -239,26 +304,86 @@ func (m *FilePager) fetcher() {
 
 }
 
+func (m *FilePager) newFileReader(fi *FileInfo, rc io.ReadCloser) *FileReader {
+	fr := &FileReader{
+		F:        rc,
+		Exit:     make(chan bool),
+		FileInfo: fi,
+	}
+	if m.fs.ss != nil && m.fs.ss.Conf != nil {
+		fr.Settings = m.fs.ss.Conf.Settings
+	}
+	return fr
+}
+
+// followFile open the last file of a SUBSCRIBE to read data appended
+// to it until the query is done.
+func (m *FilePager) followFile(ctx context.Context, fi *FileInfo) (*FileReader, error) {
+	var f io.ReadCloser
+	if store, ok := m.fs.store.(FileStoreFollower); ok {
+		rc, err := store.Follow(fi.Name)
+		if err != nil {
+			return nil, err
+		}
+		f = rc
+	} else {
+		obj, err := m.fs.store.Get(ctx, fi.Name)
+		if err != nil {
+			return nil, err
+		}
+		of, err := obj.Open(cloudstorage.ReadOnly)
+		if err != nil {
+			return nil, err
+		}
+		f = of
+	}
+	rc, err := decompress(m.fs.compression, fi.Name, datasource.NewFollowReader(f, m.follow))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	fr := m.newFileReader(fi, rc)
+	fr.Follow = true
+	return fr, nil
+}
+
+// followUntilDone stop following once the query context is done
+func (m *FilePager) followUntilDone() {
+	ctx := m.p.Context()
+	if ctx == nil || ctx.Context == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		m.stopFollow()
+	case <-m.follow:
+	}
+}
+
+func (m *FilePager) stopFollow() {
+	m.followOnce.Do(func() { close(m.follow) })
+}
+
 // Next iterator for next message, wraps the file Scanner, Next file abstractions
 func (m *FilePager) Next() schema.Message {
 	if m.ConnScanner == nil {
 		m.NextScanner()
 	}
 	for {
-		if m.closed {
+		if atomic.LoadInt32(&m.closed) == 1 {
 			return nil
 		} else if m.ConnScanner == nil {
-			m.closed = true
+			atomic.StoreInt32(&m.closed, 1)
 			return nil
 		}
-		msg := m.ConnScanner.Next()
+		msg := m.appender.Append(m.ConnScanner.Next())
 		if msg == nil {
 			// Kind of crap api, side-effect method? uck
 			_, err := m.NextScanner()
 			if err != nil {
 				if err == iterator.Done {
 					// Truly was last file in partition
-					m.closed = true
+					atomic.StoreInt32(&m.closed, 1)
 					return nil
 				} else {
 					u.Errorf("unexpected end of scan %v", err)
@@ -268,7 +393,7 @@ func (m *FilePager) Next() schema.Message {
 
 			// now that we have a new scanner, lets try again
 			if m.ConnScanner != nil {
-				msg = m.ConnScanner.Next()
+				msg = m.appender.Append(m.ConnScanner.Next())
 			}
 		}
 
@@ -279,7 +404,13 @@ func (m *FilePager) Next() schema.Message {
 
 // Close this connection/pager
 func (m *FilePager) Close() error {
-	m.closed = true
+	atomic.StoreInt32(&m.closed, 1)
+	m.stopFollow()
+	if m.sink != nil {
+		// rows never flushed are discarded
+		m.sink.discard()
+		m.sink = nil
+	}
 	//close(m.exit)
 	return nil
 }
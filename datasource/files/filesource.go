@@ -74,8 +74,11 @@ type FileSource struct {
 	tables         map[string]*FileTable
 	path           string
 	tablePerFolder bool
-	fileType       string // csv, json, proto, customname
-	Partitioner    string // random, ??  (date, keyed?)
+	fileType       string                 // csv, json, proto, customname
+	tableFormats   map[string]string      // per table fileType overrides
+	handlers       map[string]FileHandler // fileType -> handler for tableFormats
+	compression    string                 // auto, none, gzip, zstd, snappy, bzip2
+	Partitioner    string                 // random, ??  (date, keyed?)
 	partitionFunc  Partitioner
 	partitionCt    uint64
 }
@@ -143,6 +146,13 @@ func (m *FileSource) init() error {
 		} else {
 			m.fileType = "csv"
 		}
+		m.compression = CompressionAuto
+		if compression := conf.String("compression"); compression != "" {
+			if _, err := compressionGet(compression, ""); err != nil {
+				return err
+			}
+			m.compression = compression
+		}
 		if partitioner := conf.String("partitioner"); partitioner != "" {
 			m.Partitioner = partitioner
 		}
@@ -165,6 +175,28 @@ func (m *FileSource) init() error {
 		m.fh = fileHandler
 		// u.Debugf("got fh: %T", m.fh)
 
+		// tables may be stored in a different format than the source default
+		//   "formats": {"events": "parquet"}
+		if formats := conf.Helper("formats"); formats != nil {
+			m.tableFormats = make(map[string]string, len(formats))
+			m.handlers = make(map[string]FileHandler)
+			for table := range formats {
+				format := strings.ToLower(formats.String(table))
+				m.tableFormats[strings.ToLower(table)] = format
+				if _, exists := m.handlers[format]; exists || format == m.fileType {
+					continue
+				}
+				fh, exists := scannerGet(format)
+				if !exists || fh == nil {
+					return fmt.Errorf("Could not find scanner for filetype %q of table %q", format, table)
+				}
+				if err := fh.Init(store, m.ss); err != nil {
+					return err
+				}
+				m.handlers[format] = fh
+			}
+		}
+
 		// ensure any additional columns are added
 		m.fdbcols = append(FileColumns, m.fh.FileAppendColumns()...)
 
@@ -190,8 +222,29 @@ func (m *FileSource) init() error {
 	return nil
 }
 
+// tableFormat the file format of table
+func (m *FileSource) tableFormat(table string) string {
+	if format, ok := m.tableFormats[table]; ok {
+		return format
+	}
+	return m.fileType
+}
+
+// handler the FileHandler for format of table
+func (m *FileSource) handler(table string) FileHandler {
+	if fh, ok := m.handlers[m.tableFormat(table)]; ok {
+		return fh
+	}
+	return m.fh
+}
+
+// File converts a store object into FileInfo using the default file handler
 func (m *FileSource) File(o cloudstorage.Object) *FileInfo {
-	fi := m.fh.File(m.path, o)
+	return m.fileFor(m.fh, o)
+}
+
+func (m *FileSource) fileFor(fh FileHandler, o cloudstorage.Object) *FileInfo {
+	fi := fh.File(m.path, o)
 	if fi == nil {
 		// u.Debugf("ignoring file, path:%v  %q  is nil", m.path, o.Name())
 		return nil
@@ -302,7 +355,7 @@ func (m *FileSource) Table(tableName string) (*schema.Table, error) {
 
 	var err error
 	// Its possible that the file handle implements schema handling
-	if schemaSource, hasSchema := m.fh.(schema.SourceTableSchema); hasSchema {
+	if schemaSource, hasSchema := m.handler(tableName).(schema.SourceTableSchema); hasSchema {
 		t, err = schemaSource.Table(tableName)
 		if err != nil {
 			u.Errorf("could not get %T table %q %v", schemaSource, tableName, err)
@@ -345,6 +398,13 @@ func (m *FileSource) buildTable(tableName string) (*schema.Table, error) {
 		return nil, err
 	}
 
+	// Some file types describe their own schema
+	if schemaScanner, hasSchema := scanner.(FileScannerSchema); hasSchema {
+		t := schemaScanner.SchemaTable()
+		addHivePartitionFields(t, pager.fi)
+		return t, nil
+	}
+
 	colScanner, hasColumns := scanner.(schema.ConnColumns)
 	if !hasColumns {
 		return nil, fmt.Errorf("Must have Columns to Introspect Tables")
@@ -358,6 +418,7 @@ func (m *FileSource) buildTable(tableName string) (*schema.Table, error) {
 		u.Errorf("Could not introspect schema %v", err)
 		return nil, err
 	}
+	addHivePartitionFields(t, pager.fi)
 	//u.Infof("built table %v %v", tableName, t.Columns())
 	return t, nil
 }
@@ -366,6 +427,5 @@ func (m *FileSource) createPager(tableName string, partition, limit int) (*FileP
 
 	pg := NewFilePager(tableName, m)
 	pg.Limit = limit
-	pg.RunFetcher()
 	return pg, nil
 }
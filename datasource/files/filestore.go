@@ -2,6 +2,9 @@ package files
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -82,6 +85,14 @@ type FileStore interface {
 	cloudstorage.StoreReader
 }
 
+// FileStoreFollower a FileStore which can open a file in place to follow
+// data appended to it, for SUBSCRIBE queries.  Stores which can't follow
+// are read from their normal (possibly cached) copy.
+type FileStoreFollower interface {
+	FileStore
+	Follow(o string) (io.ReadCloser, error)
+}
+
 // RegisterFileStore global registry for Registering
 // implementations of FileStore factories of the provided @storeType
 func RegisterFileStore(storeType string, fs FileStoreCreator) {
@@ -137,5 +148,21 @@ func createLocalFileStore(ss *schema.Schema) (FileStore, error) {
 	if c.LocalFS == "" {
 		return nil, fmt.Errorf(`"localfs" filestore requires a {"settings":{"localpath":"/path/to/files"}} to local files`)
 	}
-	return cloudstorage.NewStore(&c)
+	store, err := cloudstorage.NewStore(&c)
+	if err != nil {
+		return nil, err
+	}
+	return &localFileStore{Store: store, path: localPath}, nil
+}
+
+// localFileStore localfs store whose files can be followed, localfs reads
+// a copy of the file into its cache so never sees appended data.
+type localFileStore struct {
+	cloudstorage.Store
+	path string
+}
+
+// Follow open the file in place
+func (m *localFileStore) Follow(o string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(m.path, o))
 }
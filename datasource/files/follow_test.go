@@ -0,0 +1,156 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+type followTestSource struct {
+	*FileSource
+	dir string
+}
+
+func (m *followTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       ss.Name,
+		SourceType: ss.Name,
+		Settings: u.JsonHelper(map[string]interface{}{
+			"path":      "",
+			"format":    "csv",
+			"type":      "localfs",
+			"localpath": m.dir,
+			"formats": map[string]interface{}{
+				"app_json": "json",
+				"app_log":  "log",
+				"events":   "parquet",
+			},
+			"log": map[string]interface{}{
+				"regex": "^(?P<level>\\w+) (?P<msg>.*)$",
+			},
+		}),
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func TestSubscribeSql(t *testing.T) {
+	datasource.FollowPollInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "qlb_follow")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, "access_log"), 0755))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "access_log", "a.csv"),
+		[]byte("path,status\n/old,500\n/ok,200\n"), 0644))
+	logFile := filepath.Join(dir, "access_log", "b.csv")
+	assert.Equal(t, nil, ioutil.WriteFile(logFile, []byte("path,status\n/a,200\n/b,503\n"), 0644))
+
+	schema.RegisterSourceAsSchema("testfollow", &followTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testfollow")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows, err := db.QueryContext(ctx, "SUBSCRIBE SELECT path, status FROM access_log WHERE status >= 500")
+	assert.Equal(t, nil, err)
+	defer rows.Close()
+
+	next := func() string {
+		assert.True(t, rows.Next())
+		var path string
+		var status int64
+		assert.Equal(t, nil, rows.Scan(&path, &status))
+		return path
+	}
+	assert.Equal(t, "/old", next())
+	assert.Equal(t, "/b", next())
+
+	// rows appended to last file keep arriving, partial lines wait
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Equal(t, nil, err)
+	_, err = f.WriteString("/c,404\n/d,50")
+	assert.Equal(t, nil, err)
+	time.Sleep(30 * time.Millisecond)
+	_, err = f.WriteString("2\n")
+	assert.Equal(t, nil, err)
+	f.Close()
+	assert.Equal(t, "/d", next())
+
+	done := make(chan bool)
+	go func() {
+		assert.False(t, rows.Next())
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("subscription was not cancelled")
+	}
+}
+
+func TestSubscribeJsonLogSql(t *testing.T) {
+	datasource.FollowPollInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "qlb_follow_fmt")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	for _, table := range []string{"app_json", "app_log", "events"} {
+		assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, table), 0755))
+	}
+	jsonFile := filepath.Join(dir, "app_json", "a.json")
+	assert.Equal(t, nil, ioutil.WriteFile(jsonFile, []byte(`{"level":"error","msg":"a"}`+"\n"), 0644))
+	logFile := filepath.Join(dir, "app_log", "a.log")
+	assert.Equal(t, nil, ioutil.WriteFile(logFile, []byte("error a\ninfo b\n"), 0644))
+	writeParquetEvents(t, filepath.Join(dir, "events", "events.parquet"))
+
+	schema.RegisterSourceAsSchema("testfollowfmt", &followTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testfollowfmt")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	for _, tt := range []struct {
+		table, file, line string
+	}{
+		{"app_json", jsonFile, `{"level":"error","msg":"c"}`},
+		{"app_log", logFile, "error c"},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		rows, err := db.QueryContext(ctx, "SUBSCRIBE SELECT msg FROM "+tt.table+" WHERE level = 'error'")
+		assert.Equal(t, nil, err, tt.table)
+		next := func() string {
+			assert.True(t, rows.Next(), tt.table)
+			var msg string
+			assert.Equal(t, nil, rows.Scan(&msg), tt.table)
+			return msg
+		}
+		assert.Equal(t, "a", next())
+
+		f, err := os.OpenFile(tt.file, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.Equal(t, nil, err)
+		_, err = f.WriteString(tt.line + "\n")
+		assert.Equal(t, nil, err)
+		f.Close()
+		assert.Equal(t, "c", next())
+
+		cancel()
+		rows.Close()
+	}
+
+	// parquet files are written whole, they can't be followed
+	_, err = db.Query("SUBSCRIBE SELECT id FROM events")
+	assert.NotEqual(t, nil, err)
+}
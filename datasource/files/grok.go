@@ -0,0 +1,154 @@
+package files
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	// the global grok pattern registry mutex
+	grokMu       sync.Mutex
+	grokPatterns = make(map[string]string)
+
+	// %{NAME}, %{NAME:field}, %{NAME:field:type}
+	grokRef = regexp.MustCompile(`%\{(\w+)(?::(\w+))?(?::(\w+))?\}`)
+
+	// max depth of patterns referencing other patterns
+	grokMaxDepth = 20
+)
+
+func init() {
+	// A subset of the logstash grok pattern library, written for go
+	// regexp (RE2) which has no look-around or atomic groups.
+	for name, pattern := range map[string]string{
+		"USERNAME":          `[a-zA-Z0-9._-]+`,
+		"USER":              `%{USERNAME}`,
+		"INT":               `[+-]?[0-9]+`,
+		"POSINT":            `\b[1-9][0-9]*\b`,
+		"NONNEGINT":         `\b[0-9]+\b`,
+		"NUMBER":            `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+		"BASE10NUM":         `%{NUMBER}`,
+		"WORD":              `\b\w+\b`,
+		"NOTSPACE":          `\S+`,
+		"SPACE":             `\s*`,
+		"DATA":              `.*?`,
+		"GREEDYDATA":        `.*`,
+		"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+		"QS":                `%{QUOTEDSTRING}`,
+		"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+		"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])`,
+		"IPV6":              `[0-9A-Fa-f]*:[0-9A-Fa-f:.]*[0-9A-Fa-f]`,
+		"IP":                `%{IPV6}|%{IPV4}`,
+		"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+		"IPORHOST":          `%{IP}|%{HOSTNAME}`,
+		"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+		"PATH":              `(?:/[^/\s]*)+`,
+		"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+		"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+		"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+		"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]un(?:e)?|[Jj]ul(?:y)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+		"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+		"MONTHDAY":          `(?:0[1-9]|[12][0-9]|3[01]|[1-9])`,
+		"YEAR":              `[0-9]{2,4}`,
+		"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+		"MINUTE":            `(?:[0-5][0-9])`,
+		"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?)`,
+		"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})`,
+		"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+		"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+		"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+		"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+		"PROG":              `[\x21-\x5a\x5c\x5e-\x7e]+`,
+		"SYSLOGPROG":        `%{PROG:program}(?:\[%{POSINT:pid}\])?`,
+		"SYSLOGHOST":        `%{IPORHOST}`,
+		"SYSLOGBASE":        `%{SYSLOGTIMESTAMP:timestamp} %{SYSLOGHOST:logsource} %{SYSLOGPROG}:`,
+		"SYSLOGLINE":        `%{SYSLOGBASE} %{GREEDYDATA:message}`,
+		"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+		"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+		"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+	} {
+		RegisterGrokPattern(name, pattern)
+	}
+}
+
+// RegisterGrokPattern Register a named grok pattern for use as %{NAME}
+// in "log" file handler patterns, and in other patterns.
+func RegisterGrokPattern(name, pattern string) {
+	if name == "" || pattern == "" {
+		panic("grok pattern name and pattern must not be empty")
+	}
+	grokMu.Lock()
+	defer grokMu.Unlock()
+	if _, dupe := grokPatterns[name]; dupe {
+		panic("Register called twice for grok pattern " + name)
+	}
+	grokPatterns[name] = pattern
+}
+
+func grokPattern(name string, custom map[string]string) (string, bool) {
+	if pattern, ok := custom[name]; ok {
+		return pattern, true
+	}
+	grokMu.Lock()
+	defer grokMu.Unlock()
+	pattern, ok := grokPatterns[name]
+	return pattern, ok
+}
+
+// grokCompile expand the %{NAME:field:type} references of a grok pattern
+// into a regexp, with @custom patterns winning over registered ones.
+// Fields with a type suffix (int, float, bool, time, string) are
+// returned in types.
+func grokCompile(pattern string, custom map[string]string) (*regexp.Regexp, map[string]value.ValueType, error) {
+	types := make(map[string]value.ValueType)
+	expanded, err := grokExpand(pattern, custom, types, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid grok pattern %q: %v", pattern, err)
+	}
+	return re, types, nil
+}
+
+func grokExpand(pattern string, custom map[string]string, types map[string]value.ValueType, depth int) (string, error) {
+	if depth > grokMaxDepth {
+		return "", fmt.Errorf("grok patterns nested too deep, recursive? %q", pattern)
+	}
+	var err error
+	expanded := grokRef.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		parts := grokRef.FindStringSubmatch(ref)
+		name, field, typ := parts[1], parts[2], parts[3]
+		def, ok := grokPattern(name, custom)
+		if !ok {
+			err = fmt.Errorf("grok pattern %%{%s} not found", name)
+			return ""
+		}
+		inner, innerErr := grokExpand(def, custom, types, depth+1)
+		if innerErr != nil {
+			err = innerErr
+			return ""
+		}
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		if typ != "" {
+			vt, typeErr := logValueType(typ)
+			if typeErr != nil {
+				err = typeErr
+				return ""
+			}
+			types[strings.ToLower(field)] = vt
+		}
+		return "(?P<" + field + ">" + inner + ")"
+	})
+	return expanded, err
+}
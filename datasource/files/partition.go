@@ -0,0 +1,185 @@
+package files
+
+import (
+	"database/sql/driver"
+	"net/url"
+	"reflect"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+	"github.com/fuhongbo/qlbridge/vm"
+)
+
+// HivePartition is a hive style key=value folder in a file path
+// such as the dt and region in:
+//
+//	tables/events/dt=2024-01-01/region=us/part-0000.csv
+//
+// these are exposed as (string) columns on the table, and where clause
+// predicates on them are used to skip files without opening them.
+type HivePartition struct {
+	Key   string
+	Value string
+}
+
+// hivePartitions find the key=value segments of a folder path
+func hivePartitions(partialPath string) []HivePartition {
+	var parts []HivePartition
+	for _, seg := range strings.Split(partialPath, "/") {
+		if !isHiveSegment(seg) {
+			continue
+		}
+		idx := strings.Index(seg, "=")
+		val := seg[idx+1:]
+		if unescaped, err := url.PathUnescape(val); err == nil {
+			val = unescaped
+		}
+		parts = append(parts, HivePartition{Key: strings.ToLower(seg[:idx]), Value: val})
+	}
+	return parts
+}
+
+func isHiveSegment(seg string) bool {
+	return strings.Index(seg, "=") > 0
+}
+
+// addHivePartitionFields add the partition columns of @fi to the table
+func addHivePartitionFields(t *schema.Table, fi *FileInfo) {
+	if fi == nil || len(fi.HivePartitions) == 0 {
+		return
+	}
+	cols := append([]string{}, t.Columns()...)
+	for _, hp := range fi.HivePartitions {
+		if t.HasField(hp.Key) {
+			continue
+		}
+		t.AddField(schema.NewFieldBase(hp.Key, value.StringType, 64, "partition"))
+		cols = append(cols, hp.Key)
+	}
+	t.SetColumns(cols)
+}
+
+// partitionFilter the AND'd predicates of a where clause, those which only
+// reference partition columns of a file can be evaluated before opening it.
+type partitionFilter struct {
+	filters []partitionPredicate
+}
+
+type partitionPredicate struct {
+	n      expr.Node
+	idents []string
+}
+
+// newPartitionFilter create filter from the where clause of @sel,
+// returns nil if there is no where clause.
+func newPartitionFilter(sel *rel.SqlSelect) *partitionFilter {
+	if sel == nil || sel.Where == nil || sel.Where.Expr == nil {
+		return nil
+	}
+	pf := &partitionFilter{}
+	for _, n := range expr.FindConjuncts(sel.Where.Expr) {
+		pp := partitionPredicate{n: n}
+		for _, ident := range expr.FindAllIdentities(n) {
+			_, right, _ := ident.LeftRight()
+			pp.idents = append(pp.idents, strings.ToLower(right))
+		}
+		if len(pp.idents) > 0 {
+			pf.filters = append(pf.filters, pp)
+		}
+	}
+	return pf
+}
+
+// Match may this file contain rows matching the filter?  Files are only
+// excluded when a predicate on only its partition columns evaluates to
+// false, anything else is assumed to match.
+func (m *partitionFilter) Match(fi *FileInfo) bool {
+	if m == nil || len(fi.HivePartitions) == 0 {
+		return true
+	}
+	row := make(map[string]interface{}, len(fi.HivePartitions))
+	for _, hp := range fi.HivePartitions {
+		row[hp.Key] = hp.Value
+	}
+	var ctx expr.ContextReader
+	for _, pp := range m.filters {
+		onlyPartitions := true
+		for _, ident := range pp.idents {
+			if _, ok := row[ident]; !ok {
+				onlyPartitions = false
+				break
+			}
+		}
+		if !onlyPartitions {
+			continue
+		}
+		if ctx == nil {
+			ctx = datasource.NewContextSimpleNative(row)
+		}
+		v, ok := vm.Eval(ctx, pp.n)
+		if !ok || v == nil {
+			continue
+		}
+		if bv, isBool := v.(value.BoolValue); isBool && !bv.Val() {
+			u.Debugf("partition filter excluded file %q  %s", fi.Name, pp.n)
+			return false
+		}
+	}
+	return true
+}
+
+// partitionAppender appends hive partition values to rows of a file
+type partitionAppender struct {
+	parts    []HivePartition
+	base     uintptr // pointer of the scanners colindex we extended
+	baseLen  int     // row length of the scanner rows
+	colindex map[string]int
+	vals     []driver.Value
+}
+
+func newPartitionAppender(fi *FileInfo) *partitionAppender {
+	if fi == nil || len(fi.HivePartitions) == 0 {
+		return nil
+	}
+	return &partitionAppender{parts: fi.HivePartitions}
+}
+
+// Append the partition values to message, only SqlDriverMessageMap
+// are supported others are returned as-is.
+func (m *partitionAppender) Append(msg schema.Message) schema.Message {
+	mm, ok := msg.(*datasource.SqlDriverMessageMap)
+	if m == nil || !ok {
+		return msg
+	}
+	// scanners generally share one colindex for all rows of a file
+	if ptr := reflect.ValueOf(mm.ColIndex).Pointer(); ptr != m.base || len(mm.Vals) != m.baseLen || m.colindex == nil {
+		m.base = ptr
+		m.baseLen = len(mm.Vals)
+		m.colindex = make(map[string]int, len(mm.ColIndex)+len(m.parts))
+		for k, v := range mm.ColIndex {
+			m.colindex[k] = v
+		}
+		m.vals = m.vals[:0]
+		for _, hp := range m.parts {
+			if _, exists := m.colindex[hp.Key]; exists {
+				// the file contents win over the path
+				continue
+			}
+			m.colindex[hp.Key] = len(mm.Vals) + len(m.vals)
+			m.vals = append(m.vals, hp.Value)
+		}
+	}
+	if len(m.vals) == 0 {
+		return msg
+	}
+	vals := make([]driver.Value, len(mm.Vals), len(mm.Vals)+len(m.vals))
+	copy(vals, mm.Vals)
+	vals = append(vals, m.vals...)
+	return datasource.NewSqlDriverMessageMap(mm.Id(), vals, m.colindex)
+}
@@ -0,0 +1,120 @@
+package files
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+func TestHivePartitions(t *testing.T) {
+	assert.Equal(t, "events", TableFromFileAndPath("", "events/dt=2024-01-01/region=us/part-0.csv"))
+	assert.Equal(t, "events", TableFromFileAndPath("tables/", "tables/events/dt=2024-01-01/part-0.csv.gz"))
+
+	parts := hivePartitions("tables/events/DT=2024-01-01/region=us%2Fwest")
+	assert.Equal(t, []HivePartition{{"dt", "2024-01-01"}, {"region", "us/west"}}, parts)
+	assert.Equal(t, 0, len(hivePartitions("tables/events")))
+
+	fi := &FileInfo{Name: "events/dt=2024-01-01/region=us/part-0.csv", HivePartitions: parts[:1]}
+	fi.HivePartitions = append(fi.HivePartitions, HivePartition{"region", "us"})
+
+	tests := []struct {
+		sql   string
+		match bool
+	}{
+		{`SELECT * FROM events`, true},
+		{`SELECT * FROM events WHERE dt = "2024-01-01"`, true},
+		{`SELECT * FROM events WHERE dt = "2024-01-02"`, false},
+		{`SELECT * FROM events WHERE dt != "2024-01-01"`, false},
+		{`SELECT * FROM events WHERE region IN ("eu", "us") AND dt = "2024-01-01"`, true},
+		{`SELECT * FROM events WHERE region IN ("eu", "ap")`, false},
+		{`SELECT * FROM events WHERE name = "bob" AND region = "eu"`, false},
+		// can't evaluate without file contents
+		{`SELECT * FROM events WHERE name = "bob" OR region = "eu"`, true},
+		{`SELECT * FROM events WHERE name = "bob"`, true},
+	}
+	for _, tt := range tests {
+		sel, err := rel.ParseSqlSelect(tt.sql)
+		assert.Equal(t, nil, err, tt.sql)
+		assert.Equal(t, tt.match, newPartitionFilter(sel).Match(fi), tt.sql)
+	}
+
+	pa := newPartitionAppender(fi)
+	msg := datasource.NewSqlDriverMessageMap(1, []driver.Value{"bob", "ap"}, map[string]int{"name": 0, "region": 1})
+	out := pa.Append(msg).(*datasource.SqlDriverMessageMap)
+	assert.Equal(t, "2024-01-01", out.Vals[out.ColIndex["dt"]])
+	assert.Equal(t, "ap", out.Vals[out.ColIndex["region"]])
+	assert.Equal(t, 3, len(out.Vals))
+}
+
+type partitionTestSource struct {
+	*FileSource
+	dir string
+}
+
+func (m *partitionTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       "testpartition",
+		SourceType: "testpartition",
+		Settings: u.JsonHelper(map[string]interface{}{
+			"path":      "",
+			"format":    "csv",
+			"type":      "localfs",
+			"localpath": m.dir,
+		}),
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func TestHivePartitionSql(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_partition")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	writeFile := func(folder, name, data string) {
+		assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, "events", folder), 0755))
+		assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "events", folder, name), []byte(data), 0644))
+	}
+	writeFile("dt=2024-01-01/region=eu", "part-0.csv", "id,name\n1,bob\n2,alice\n")
+	writeFile("dt=2024-01-01/region=us", "part-0.csv", "id,name\n3,bob\n")
+	writeFile("dt=2024-01-02/region=us", "part-0.csv", "id,name\n4,bob\n5,eve\n")
+	// not valid gzip, the query fails if this file is opened
+	writeFile("dt=2024-01-02/region=zz", "part-0.csv.gz", "not gzip")
+
+	schema.RegisterSourceAsSchema("testpartition", &partitionTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testpartition")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT id, name, dt FROM events WHERE region = "us" AND name = "bob"`)
+	assert.Equal(t, nil, err)
+	defer rows.Close()
+	got := make([]string, 0)
+	for rows.Next() {
+		var id, name, dt string
+		assert.Equal(t, nil, rows.Scan(&id, &name, &dt))
+		got = append(got, id+":"+dt)
+	}
+	assert.Equal(t, nil, rows.Err())
+	assert.Equal(t, []string{"3:2024-01-01", "4:2024-01-02"}, got)
+
+	var ct int64
+	assert.Equal(t, nil, db.QueryRow(`SELECT count(*) AS ct FROM events WHERE dt = "2024-01-01"`).Scan(&ct))
+	assert.Equal(t, int64(3), ct)
+
+	ss, ok := schema.DefaultRegistry().Schema("testpartition")
+	assert.True(t, ok)
+	et, err := ss.Table("events")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "name", "dt", "region"}, et.Columns())
+}
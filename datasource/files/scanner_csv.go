@@ -1,16 +1,23 @@
 package files
 
 import (
+	"fmt"
+	"unicode/utf8"
+
 	u "github.com/araddon/gou"
 	"github.com/lytics/cloudstorage"
 
 	"github.com/fuhongbo/qlbridge/datasource"
 	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
 )
 
 var (
 	// ensuure our csv handler implements FileHandler interface
-	_ FileHandler = (*csvFiles)(nil)
+	_ FileHandler       = (*csvFiles)(nil)
+	_ FileHandlerFollow = (*csvFiles)(nil)
+	// csv scanners with types provide their schema
+	_ FileScannerSchema = (*csvTypedScanner)(nil)
 )
 
 func init() {
@@ -24,14 +31,157 @@ type csvFiles struct {
 
 func (m *csvFiles) Init(store FileStore, ss *schema.Schema) error { return nil }
 func (m *csvFiles) FileAppendColumns() []string                   { return m.appendcols }
+func (m *csvFiles) Follow() bool                                  { return true }
 func (m *csvFiles) File(path string, obj cloudstorage.Object) *FileInfo {
 	return FileInfoFromCloudObject(path, obj)
 }
 func (m *csvFiles) Scanner(store cloudstorage.StoreReader, fr *FileReader) (schema.ConnScanner, error) {
-	csv, err := datasource.NewCsvSource(fr.Table, 0, fr.F, fr.Exit)
+	opts, err := csvOptions(fr.Settings, fr.Table)
+	if err != nil {
+		u.Errorf("Invalid csv settings for %q %v", fr.Table, err)
+		return nil, err
+	}
+	if fr.Follow {
+		opts.Follow = true
+		if opts.Infer && fr.Tbl != nil {
+			// can't sample rows not yet appended, use types the table
+			// schema was inferred with
+			opts.Types = csvTableTypes(fr.Tbl, fr.FileInfo, opts.Types)
+		}
+	}
+	csv, err := datasource.NewCsvSourceOptions(fr.Table, 0, fr.F, fr.Exit, opts)
 	if err != nil {
 		u.Errorf("Could not open file for csv reading %v", err)
 		return nil, err
 	}
+	if opts.Infer || len(opts.Types) > 0 {
+		return &csvTypedScanner{csv}, nil
+	}
 	return csv, nil
 }
+
+// csvTableTypes column types of table, except hive partition columns
+// which are not in the file, explicit @types win.
+func csvTableTypes(tbl *schema.Table, fi *FileInfo, types map[string]value.ValueType) map[string]value.ValueType {
+	colTypes := make(map[string]value.ValueType, len(tbl.Fields))
+	for _, f := range tbl.Fields {
+		colTypes[f.Name] = f.ValueType()
+	}
+	for _, hp := range fi.HivePartitions {
+		delete(colTypes, hp.Key)
+	}
+	for col, vt := range types {
+		colTypes[col] = vt
+	}
+	return colTypes
+}
+
+// csvTypedScanner the csv columns are typed (inferred or declared) so
+// the table schema comes from scanner instead of introspecting the
+// values.
+type csvTypedScanner struct {
+	*datasource.CsvDataSource
+}
+
+func (m *csvTypedScanner) SchemaTable() *schema.Table {
+	t, _ := m.Table("")
+	return t
+}
+
+// csvOptions read the csv dialect options from source settings, options
+// for a table under "tables" are merged over the defaults.  Type inference
+// is opt-in with "infer", otherwise columns are strings unless declared.
+//
+//	"settings": {
+//	   "csv": {
+//	      "delimiter": "|",
+//	      "quote": "'",
+//	      "null": ["NULL", ""],
+//	      "header": false,
+//	      "columns": ["id", "name"],
+//	      "infer": true,
+//	      "sample": 100,
+//	      "types": {"id": "int"},
+//	      "tables": {
+//	         "users": {"delimiter": "\t"}
+//	      }
+//	   }
+//	}
+func csvOptions(settings u.JsonHelper, table string) (*datasource.CsvOptions, error) {
+	opts := &datasource.CsvOptions{}
+	conf := settings.Helper("csv")
+	if conf == nil {
+		return opts, nil
+	}
+	if err := csvApplyOptions(opts, conf); err != nil {
+		return nil, err
+	}
+	if tables := conf.Helper("tables"); tables != nil {
+		if tconf := tables.Helper(table); tconf != nil {
+			if err := csvApplyOptions(opts, tconf); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return opts, nil
+}
+
+func csvApplyOptions(opts *datasource.CsvOptions, conf u.JsonHelper) error {
+	char := func(key string) (rune, error) {
+		s, ok := conf.StringSafe(key)
+		if !ok {
+			return 0, nil
+		}
+		if s == "\\t" {
+			return '\t', nil
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		if size == 0 || size != len(s) {
+			return 0, fmt.Errorf("csv %s must be single character got %q", key, s)
+		}
+		return r, nil
+	}
+	if r, err := char("delimiter"); err != nil {
+		return err
+	} else if r != 0 {
+		opts.Delimiter = r
+	}
+	if r, err := char("quote"); err != nil {
+		return err
+	} else if r != 0 {
+		opts.Quote = r
+	}
+	if conf.HasKey("null") {
+		opts.Nulls = conf.Strings("null")
+		if len(opts.Nulls) == 0 {
+			opts.Nulls = []string{""}
+		}
+	}
+	if header, ok := conf.BoolSafe("header"); ok {
+		opts.NoHeader = !header
+	}
+	if cols := conf.Strings("columns"); len(cols) > 0 {
+		opts.Columns = cols
+	}
+	if infer, ok := conf.BoolSafe("infer"); ok {
+		opts.Infer = infer
+	}
+	if sample, ok := conf.IntSafe("sample"); ok {
+		opts.SampleRows = sample
+	}
+	if types := conf.Helper("types"); types != nil {
+		if opts.Types == nil {
+			opts.Types = make(map[string]value.ValueType, len(types))
+		}
+		for col := range types {
+			vt := value.ValueFromString(types.String(col))
+			switch vt {
+			case value.StringType, value.IntType, value.NumberType, value.BoolType, value.TimeType, value.JsonType:
+			default:
+				return fmt.Errorf("unsupported csv type %q for column %q", types.String(col), col)
+			}
+			opts.Types[col] = vt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package files
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+func TestCsvOptionsSettings(t *testing.T) {
+	settings := u.JsonHelper{
+		"csv": map[string]interface{}{
+			"delimiter": "\\t",
+			"null":      "",
+			"infer":     true,
+			"types":     map[string]interface{}{"id": "int"},
+			"tables": map[string]interface{}{
+				"users": map[string]interface{}{
+					"delimiter": "|",
+					"header":    false,
+					"columns":   []interface{}{"id", "name"},
+					"infer":     false,
+				},
+			},
+		},
+	}
+	opts, err := csvOptions(settings, "events")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, '\t', opts.Delimiter)
+	assert.Equal(t, []string{""}, opts.Nulls)
+	assert.Equal(t, true, opts.Infer)
+	assert.Equal(t, false, opts.NoHeader)
+	assert.Equal(t, value.IntType, opts.Types["id"])
+
+	opts, err = csvOptions(settings, "users")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, '|', opts.Delimiter)
+	assert.Equal(t, false, opts.Infer)
+	assert.Equal(t, true, opts.NoHeader)
+	assert.Equal(t, []string{"id", "name"}, opts.Columns)
+
+	// inference is opt-in so existing tables keep their types
+	opts, err = csvOptions(nil, "users")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, opts.Infer)
+
+	_, err = csvOptions(u.JsonHelper{"csv": map[string]interface{}{"delimiter": "||"}}, "users")
+	assert.NotEqual(t, nil, err)
+	_, err = csvOptions(u.JsonHelper{"csv": map[string]interface{}{"types": map[string]interface{}{"id": "[]byte"}}}, "users")
+	assert.NotEqual(t, nil, err)
+}
+
+type csvTestSource struct {
+	*FileSource
+	dir string
+}
+
+func (m *csvTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       "testcsvopts",
+		SourceType: "testcsvopts",
+		Settings: u.JsonHelper(map[string]interface{}{
+			"path":      "",
+			"format":    "csv",
+			"type":      "localfs",
+			"localpath": m.dir,
+			"csv": map[string]interface{}{
+				"null": "NA",
+				"tables": map[string]interface{}{
+					"scores": map[string]interface{}{
+						"delimiter": ";",
+						"header":    false,
+						"columns":   []interface{}{"name", "score", "code"},
+						"infer":     true,
+						"types":     map[string]interface{}{"code": "string"},
+					},
+				},
+			},
+		}),
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func TestCsvTypedSql(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_csvopts")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, "scores"), 0755))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "scores", "scores.csv"),
+		[]byte("bob;9;007\nalice;10;010\neve;NA;100\ntim;100;011\n"), 0644))
+
+	schema.RegisterSourceAsSchema("testcsvopts", &csvTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testcsvopts")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	// "10" > "9" is false as strings
+	rows, err := db.Query("SELECT name, code FROM scores WHERE score > 9")
+	assert.Equal(t, nil, err)
+	defer rows.Close()
+	got := make([]string, 0)
+	for rows.Next() {
+		var name, code string
+		assert.Equal(t, nil, rows.Scan(&name, &code))
+		got = append(got, name+":"+code)
+	}
+	assert.Equal(t, []string{"alice:010", "tim:011"}, got)
+
+	var ct int64
+	assert.Equal(t, nil, db.QueryRow("SELECT count(*) AS ct FROM scores WHERE NOT EXISTS score").Scan(&ct))
+	assert.Equal(t, int64(1), ct)
+
+	ss, ok := schema.DefaultRegistry().Schema("testcsvopts")
+	assert.True(t, ok)
+	st, err := ss.Table("scores")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"name", "score", "code"}, st.Columns())
+	assert.Equal(t, value.IntType, st.FieldMap["score"].ValueType())
+	assert.Equal(t, value.StringType, st.FieldMap["code"].ValueType())
+}
@@ -10,7 +10,9 @@ import (
 
 var (
 	// ensuure our json handler implements FileHandler interface
-	_ FileHandler = (*jsonHandler)(nil)
+	_ FileHandler       = (*jsonHandler)(nil)
+	_ FileHandlerFollow = (*jsonHandler)(nil)
+	_ FileHandlerFollow = (*jsonHandlerTables)(nil)
 )
 
 func init() {
@@ -45,6 +47,7 @@ func NewJsonHandlerTables(lh datasource.FileLineHandler, tables []string) FileHa
 
 func (m *jsonHandler) Init(store FileStore, ss *schema.Schema) error { return nil }
 func (m *jsonHandler) FileAppendColumns() []string                   { return nil }
+func (m *jsonHandler) Follow() bool                                  { return true }
 func (m *jsonHandler) File(path string, obj cloudstorage.Object) *FileInfo {
 	return FileInfoFromCloudObject(path, obj)
 }
@@ -59,3 +62,7 @@ func (m *jsonHandler) Scanner(store cloudstorage.StoreReader, fr *FileReader) (s
 func (m *jsonHandlerTables) Tables() []string {
 	return m.tables
 }
+func (m *jsonHandlerTables) Follow() bool {
+	fh, ok := m.FileHandler.(FileHandlerFollow)
+	return ok && fh.Follow()
+}
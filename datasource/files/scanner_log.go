@@ -0,0 +1,302 @@
+package files
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+	u "github.com/araddon/gou"
+	"github.com/lytics/cloudstorage"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	// ensure our log handler implements FileHandler interface
+	_ FileHandler       = (*logFiles)(nil)
+	_ FileHandlerFollow = (*logFiles)(nil)
+
+	_ schema.ConnScanner = (*logScanner)(nil)
+	_ schema.ConnColumns = (*logScanner)(nil)
+	_ FileScannerSchema  = (*logScanner)(nil)
+
+	// LogTimeLayouts are the time layouts tried (in order) for "time"
+	// columns of log files, before falling back to dateparse.
+	LogTimeLayouts = []string{
+		"02/Jan/2006:15:04:05 -0700", // apache, nginx %{HTTPDATE}
+		"Jan _2 15:04:05",            // syslog %{SYSLOGTIMESTAMP}
+		time.RFC3339Nano,
+	}
+)
+
+func init() {
+	RegisterFileHandler("log", &logFiles{})
+}
+
+// the built in plain text log filehandler, each line is parsed with a
+// regex of named groups or a grok pattern.
+type logFiles struct{}
+
+func (m *logFiles) Init(store FileStore, ss *schema.Schema) error { return nil }
+func (m *logFiles) FileAppendColumns() []string                   { return nil }
+func (m *logFiles) Follow() bool                                  { return true }
+func (m *logFiles) File(path string, obj cloudstorage.Object) *FileInfo {
+	return FileInfoFromCloudObject(path, obj)
+}
+func (m *logFiles) Scanner(store cloudstorage.StoreReader, fr *FileReader) (schema.ConnScanner, error) {
+	lf, err := logFormatFor(fr.Settings, fr.Table)
+	if err != nil {
+		u.Errorf("Invalid log settings for %q %v", fr.Table, err)
+		return nil, err
+	}
+	return newLogScanner(fr, lf), nil
+}
+
+// logFormat the compiled line pattern of a log table
+type logFormat struct {
+	re       *regexp.Regexp
+	cols     []string          // lower-cased column names, in pattern order
+	groups   []int             // regex sub-match index of each column
+	types    []value.ValueType // type of each column
+	colindex map[string]int
+}
+
+// logFormatFor read the log line format from source settings, options
+// for a table under "tables" are merged over the defaults.
+//
+//	"settings": {
+//	   "log": {
+//	      "pattern": "%{COMBINEDAPACHELOG}",
+//	      "regex": "^(?P<level>\\w+) (?P<msg>.*)$",
+//	      "patterns": {"MYTIME": "%{HOUR}:%{MINUTE}"},
+//	      "types": {"response": "int", "timestamp": "time"},
+//	      "tables": {
+//	         "syslog": {"pattern": "%{SYSLOGLINE}"}
+//	      }
+//	   }
+//	}
+//
+// "pattern" is grok, "regex" a go regexp, one of them is required.  A
+// table with its own pattern doesn't inherit the default types.
+func logFormatFor(settings u.JsonHelper, table string) (*logFormat, error) {
+	conf := settings.Helper("log")
+	if conf == nil {
+		return nil, fmt.Errorf(`"log" format requires {"log":{"pattern":"%%{...}"}} or "regex" settings`)
+	}
+	var pattern, regex string
+	patterns := make(map[string]string)
+	types := make(map[string]string)
+	apply := func(conf u.JsonHelper) {
+		if p, r := conf.String("pattern"), conf.String("regex"); p != "" || r != "" {
+			// types describe the columns of a pattern, so a new
+			// pattern starts with no types
+			pattern, regex = p, r
+			types = make(map[string]string)
+		}
+		if pc := conf.Helper("patterns"); pc != nil {
+			for name := range pc {
+				patterns[name] = pc.String(name)
+			}
+		}
+		if tc := conf.Helper("types"); tc != nil {
+			for col := range tc {
+				types[strings.ToLower(col)] = tc.String(col)
+			}
+		}
+	}
+	apply(conf)
+	if tables := conf.Helper("tables"); tables != nil {
+		if tconf := tables.Helper(table); tconf != nil {
+			apply(tconf)
+		}
+	}
+
+	var re *regexp.Regexp
+	colTypes := make(map[string]value.ValueType)
+	var err error
+	switch {
+	case regex != "":
+		re, err = regexp.Compile(regex)
+	case pattern != "":
+		re, colTypes, err = grokCompile(pattern, patterns)
+	default:
+		err = fmt.Errorf("log table %q requires a pattern or regex", table)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for col, typ := range types {
+		vt, err := logValueType(typ)
+		if err != nil {
+			return nil, err
+		}
+		colTypes[col] = vt
+	}
+	return newLogFormat(re, colTypes)
+}
+
+func newLogFormat(re *regexp.Regexp, colTypes map[string]value.ValueType) (*logFormat, error) {
+	lf := &logFormat{re: re, colindex: make(map[string]int)}
+	for i, name := range re.SubexpNames() {
+		name = strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+		if _, dupe := lf.colindex[name]; dupe {
+			// first group of a name wins
+			continue
+		}
+		vt, ok := colTypes[name]
+		if !ok {
+			vt = value.StringType
+		}
+		lf.colindex[name] = len(lf.cols)
+		lf.cols = append(lf.cols, name)
+		lf.groups = append(lf.groups, i)
+		lf.types = append(lf.types, vt)
+	}
+	if len(lf.cols) == 0 {
+		return nil, fmt.Errorf("log pattern %q has no named groups to use as columns", re.String())
+	}
+	for col := range colTypes {
+		if _, ok := lf.colindex[col]; !ok {
+			return nil, fmt.Errorf("log type for unknown column %q", col)
+		}
+	}
+	return lf, nil
+}
+
+func logValueType(typ string) (value.ValueType, error) {
+	switch strings.ToLower(typ) {
+	case "", "string":
+		return value.StringType, nil
+	case "int", "integer", "long":
+		return value.IntType, nil
+	case "float", "number", "double":
+		return value.NumberType, nil
+	case "bool", "boolean":
+		return value.BoolType, nil
+	case "time", "date", "datetime":
+		return value.TimeType, nil
+	}
+	return value.UnknownType, fmt.Errorf("unsupported log column type %q", typ)
+}
+
+// convert the matched text of column i to its type, nil if it can't be
+func (m *logFormat) convert(i int, val string) driver.Value {
+	switch m.types[i] {
+	case value.StringType:
+		return val
+	case value.IntType:
+		if iv, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return iv
+		}
+	case value.NumberType:
+		if fv, err := strconv.ParseFloat(val, 64); err == nil {
+			return fv
+		}
+	case value.BoolType:
+		if bv, err := strconv.ParseBool(val); err == nil {
+			return bv
+		}
+	case value.TimeType:
+		for _, layout := range LogTimeLayouts {
+			if t, err := time.Parse(layout, val); err == nil {
+				return t
+			}
+		}
+		if t, err := dateparse.ParseAny(val); err == nil {
+			return t
+		}
+	}
+	if val != "" && val != "-" {
+		u.LogThrottle(u.WARN, 10, "could not convert log value %q to %s", val, m.types[i])
+	}
+	return nil
+}
+
+func (m *logFormat) table(name string) *schema.Table {
+	tbl := schema.NewTable(strings.ToLower(name))
+	for i, col := range m.cols {
+		vt := m.types[i]
+		tbl.AddField(schema.NewFieldBase(col, vt, 64, vt.String()))
+	}
+	tbl.SetColumns(append([]string(nil), m.cols...))
+	return tbl
+}
+
+// logScanner scans the lines of a log file, lines which don't match
+// the pattern are skipped.
+type logScanner struct {
+	*logFormat
+	table string
+	tbl   *schema.Table
+	rc    io.ReadCloser
+	r     *bufio.Reader
+	exit  <-chan bool
+	rowct uint64
+}
+
+func newLogScanner(fr *FileReader, lf *logFormat) *logScanner {
+	return &logScanner{
+		logFormat: lf,
+		table:     fr.Table,
+		tbl:       lf.table(fr.Table),
+		rc:        fr.F,
+		r:         bufio.NewReader(fr.F),
+		exit:      fr.Exit,
+	}
+}
+
+func (m *logScanner) Columns() []string          { return m.cols }
+func (m *logScanner) SchemaTable() *schema.Table { return m.tbl }
+func (m *logScanner) Close() error {
+	if m.rc != nil {
+		return m.rc.Close()
+	}
+	return nil
+}
+
+func (m *logScanner) Next() schema.Message {
+	for {
+		select {
+		case <-m.exit:
+			return nil
+		default:
+		}
+		line, err := m.r.ReadString('\n')
+		if len(line) == 0 && err != nil {
+			if err != io.EOF {
+				u.Warnf("could not read log %q %v", m.table, err)
+			}
+			return nil
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		loc := m.re.FindStringSubmatchIndex(line)
+		if loc == nil {
+			u.LogThrottle(u.WARN, 10, "line does not match log pattern of %q: %q", m.table, line)
+			continue
+		}
+		vals := make([]driver.Value, len(m.cols))
+		for i, g := range m.groups {
+			if loc[2*g] < 0 {
+				// optional group did not participate
+				continue
+			}
+			vals[i] = m.convert(i, line[loc[2*g]:loc[2*g+1]])
+		}
+		m.rowct++
+		return datasource.NewSqlDriverMessageMap(m.rowct, vals, m.colindex)
+	}
+}
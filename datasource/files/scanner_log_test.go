@@ -0,0 +1,149 @@
+package files
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+func TestGrokCompile(t *testing.T) {
+	re, types, err := grokCompile(`%{IPORHOST:client} %{INT:ms:int} %{LOGLEVEL:level} %{MYWORD:w}`,
+		map[string]string{"MYWORD": `%{WORD}`})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]value.ValueType{"ms": value.IntType}, types)
+	m := re.FindStringSubmatch("10.0.0.1 25 WARN hello")
+	assert.Equal(t, []string{"10.0.0.1 25 WARN hello", "10.0.0.1", "25", "WARN", "hello"}, m)
+
+	_, _, err = grokCompile(`%{NOPE:x}`, nil)
+	assert.NotEqual(t, nil, err)
+	_, _, err = grokCompile(`%{A}`, map[string]string{"A": "%{B}", "B": "%{A}"})
+	assert.NotEqual(t, nil, err)
+	_, _, err = grokCompile(`%{INT:x:money}`, nil)
+	assert.NotEqual(t, nil, err)
+
+	for _, pattern := range []string{"COMBINEDAPACHELOG", "SYSLOGLINE", "TIMESTAMP_ISO8601", "URIPATHPARAM", "UUID", "HOSTPORT"} {
+		_, _, err = grokCompile("%{"+pattern+"}", nil)
+		assert.Equal(t, nil, err, pattern)
+	}
+
+	lf, err := logFormatFor(u.JsonHelper{"log": map[string]interface{}{
+		"regex": `^(?P<Level>\w+) (?P<msg>.*)$`,
+		"tables": map[string]interface{}{
+			"syslog": map[string]interface{}{"pattern": "%{SYSLOGLINE}"},
+		},
+	}}, "app")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"level", "msg"}, lf.cols)
+
+	lf, err = logFormatFor(u.JsonHelper{"log": map[string]interface{}{
+		"regex":  `^(?P<level>\w+)`,
+		"tables": map[string]interface{}{"syslog": map[string]interface{}{"pattern": "%{SYSLOGLINE}"}},
+	}}, "syslog")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"timestamp", "logsource", "program", "pid", "message"}, lf.cols)
+
+	_, err = logFormatFor(u.JsonHelper{"log": map[string]interface{}{"regex": `^\w+$`}}, "app")
+	assert.NotEqual(t, nil, err)
+	_, err = logFormatFor(u.JsonHelper{"log": map[string]interface{}{
+		"regex": `^(?P<level>\w+)`, "types": map[string]interface{}{"nope": "int"}}}, "app")
+	assert.NotEqual(t, nil, err)
+	_, err = logFormatFor(nil, "app")
+	assert.NotEqual(t, nil, err)
+}
+
+type logTestSource struct {
+	*FileSource
+	dir string
+}
+
+func (m *logTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       "testlogs",
+		SourceType: "testlogs",
+		Settings: u.JsonHelper(map[string]interface{}{
+			"path":      "",
+			"format":    "log",
+			"type":      "localfs",
+			"localpath": m.dir,
+			"log": map[string]interface{}{
+				"pattern": "%{COMBINEDAPACHELOG}",
+				"types":   map[string]interface{}{"response": "int", "bytes": "int", "timestamp": "time"},
+				"tables": map[string]interface{}{
+					"syslog": map[string]interface{}{"pattern": "%{SYSLOGLINE}", "types": map[string]interface{}{"pid": "int"}},
+				},
+			},
+		}),
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func TestLogFileSql(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_logs")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, "access_log"), 0755))
+	assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, "syslog"), 0755))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "access_log", "access.log"), []byte(
+		`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"
+10.1.1.2 - - [10/Oct/2000:13:56:01 -0700] "POST /login HTTP/1.1" 503 - "-" "curl/7.1"
+not a log line
+10.1.1.3 - - [10/Oct/2000:14:01:00 -0700] "GET /missing?a=1 HTTP/1.1" 404 12 "-" "curl/7.1"
+`), 0644))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "syslog", "messages.log"), []byte(
+		`Oct 11 22:14:15 web1 sshd[4321]: Failed password for root
+Oct  1 08:00:00 web1 cron: job started
+`), 0644))
+
+	schema.RegisterSourceAsSchema("testlogs", &logTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testlogs")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT clientip, verb, request, bytes FROM access_log WHERE response >= 400")
+	assert.Equal(t, nil, err)
+	got := make([]string, 0)
+	for rows.Next() {
+		var ip, verb, req string
+		var bytes sql.NullInt64
+		assert.Equal(t, nil, rows.Scan(&ip, &verb, &req, &bytes))
+		got = append(got, ip+" "+verb+" "+req)
+		if req == "/login" {
+			assert.False(t, bytes.Valid)
+		} else {
+			assert.Equal(t, int64(12), bytes.Int64)
+		}
+	}
+	rows.Close()
+	assert.Equal(t, []string{"10.1.1.2 POST /login", "10.1.1.3 GET /missing?a=1"}, got)
+
+	var ct int64
+	assert.Equal(t, nil, db.QueryRow("SELECT count(*) AS ct FROM access_log").Scan(&ct))
+	assert.Equal(t, int64(3), ct)
+
+	var program, message string
+	assert.Equal(t, nil, db.QueryRow("SELECT program, message FROM syslog WHERE pid = 4321").Scan(&program, &message))
+	assert.Equal(t, "sshd", program)
+	assert.Equal(t, "Failed password for root", message)
+
+	ss, ok := schema.DefaultRegistry().Schema("testlogs")
+	assert.True(t, ok)
+	tbl, err := ss.Table("access_log")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, value.IntType, tbl.FieldMap["response"].ValueType())
+	assert.Equal(t, value.TimeType, tbl.FieldMap["timestamp"].ValueType())
+	assert.Equal(t, value.StringType, tbl.FieldMap["agent"].ValueType())
+
+	var ts time.Time
+	assert.Equal(t, nil, db.QueryRow("SELECT timestamp FROM access_log WHERE auth = \"frank\"").Scan(&ts))
+	assert.Equal(t, int64(971211336), ts.Unix())
+}
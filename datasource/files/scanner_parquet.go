@@ -0,0 +1,739 @@
+package files
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	u "github.com/araddon/gou"
+	"github.com/lytics/cloudstorage"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/lex"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	// ensure our parquet handler implements FileHandler interface
+	_ FileHandler = (*parquetFiles)(nil)
+
+	_ schema.ConnScanner = (*parquetScanner)(nil)
+	_ schema.ConnColumns = (*parquetScanner)(nil)
+	_ FileScannerSchema  = (*parquetScanner)(nil)
+
+	_ source.ParquetFile = (*parquetFile)(nil)
+
+	errParquetReadOnly = fmt.Errorf("parquet file is read only")
+)
+
+func init() {
+	RegisterFileHandler("parquet", &parquetFiles{})
+}
+
+// the built in parquet filehandler
+type parquetFiles struct{}
+
+func (m *parquetFiles) Init(store FileStore, ss *schema.Schema) error { return nil }
+func (m *parquetFiles) FileAppendColumns() []string                   { return nil }
+func (m *parquetFiles) File(path string, obj cloudstorage.Object) *FileInfo {
+	// hidden/metadata files written alongside parquet such as
+	// _SUCCESS, _metadata, .part-0000.crc are not data files
+	name := obj.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	if strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+		return nil
+	}
+	fi := FileInfoFromCloudObject(path, obj)
+	fi.FileType = "parquet"
+	return fi
+}
+func (m *parquetFiles) Scanner(store cloudstorage.StoreReader, fr *FileReader) (schema.ConnScanner, error) {
+	ps, err := newParquetScanner(fr)
+	if err != nil {
+		u.Errorf("Could not open file for parquet reading %v", err)
+		return nil, err
+	}
+	return ps, nil
+}
+
+// parquetColumn a single top-level (non-nested) column of a parquet file.
+type parquetColumn struct {
+	name   string // lower-cased column name as exposed to sql
+	path   string // parquet-go internal path used for reading
+	pt     parquet.Type
+	ct     *parquet.ConvertedType
+	vt     value.ValueType
+	scale  int32
+	isUTF8 bool
+}
+
+// parquetScanner scans a single parquet file.  Only the columns needed by
+// the sql statement are decoded, and row-groups whose column statistics
+// show they cannot match the where clause are skipped entirely.
+type parquetScanner struct {
+	table    string
+	tbl      *schema.Table
+	exit     <-chan bool
+	rc       io.Closer
+	pf       *parquetFile
+	pr       *reader.ParquetReader
+	cols     []*parquetColumn
+	pathMap  map[string]*parquetColumn
+	read     []*parquetColumn // the projected columns we decode
+	colindex map[string]int
+	filter   expr.Node
+	rg       int              // index of next row-group
+	rows     [][]driver.Value // decoded rows of current row-group
+	pos      int
+	rowct    uint64
+	skipped  int // count of row-groups pruned by statistics
+}
+
+func newParquetScanner(fr *FileReader) (*parquetScanner, error) {
+
+	pf, err := newParquetFileFromReader(fr.F)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := reader.NewParquetColumnReader(pf, 1)
+	if err != nil {
+		return nil, err
+	}
+	m := &parquetScanner{
+		table:   fr.Table,
+		exit:    fr.Exit,
+		rc:      fr.F,
+		pf:      pf,
+		pr:      pr,
+		pathMap: make(map[string]*parquetColumn),
+	}
+	if err = m.loadColumns(); err != nil {
+		return nil, err
+	}
+	m.project(fr.Sql)
+	return m, nil
+}
+
+// loadColumns read the column descriptions from the footer.
+func (m *parquetScanner) loadColumns() error {
+	sh := m.pr.SchemaHandler
+	root := sh.GetRootInName()
+	tbl := schema.NewTable(strings.ToLower(m.table))
+	cols := make([]string, 0)
+	for i, se := range sh.SchemaElements {
+		if i == 0 || se.GetNumChildren() > 0 || se.Type == nil {
+			continue
+		}
+		inPath := sh.IndexMap[int32(i)]
+		if strings.Count(strings.TrimPrefix(inPath, root), ".") != 1 {
+			// nested/repeated structures are not supported
+			u.Debugf("skipping nested parquet column %q", inPath)
+			continue
+		}
+		col := &parquetColumn{
+			name:  strings.ToLower(sh.Infos[i].ExName),
+			path:  inPath,
+			pt:    se.GetType(),
+			ct:    se.ConvertedType,
+			scale: se.GetScale(),
+		}
+		if se.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+			u.Debugf("skipping repeated parquet column %q", inPath)
+			continue
+		}
+		col.isUTF8 = col.ct != nil && (*col.ct == parquet.ConvertedType_UTF8 ||
+			*col.ct == parquet.ConvertedType_ENUM || *col.ct == parquet.ConvertedType_JSON)
+		col.vt = parquetValueType(col)
+		m.cols = append(m.cols, col)
+		m.pathMap[col.path] = col
+		cols = append(cols, col.name)
+		tbl.AddField(schema.NewFieldBase(col.name, col.vt, 64, parquetTypeName(col)))
+	}
+	if len(m.cols) == 0 {
+		return fmt.Errorf("no readable columns found in parquet file for table %q", m.table)
+	}
+	tbl.SetColumns(cols)
+	m.tbl = tbl
+	return nil
+}
+
+// project determine which columns need to be decoded, and which where
+// clause may be used to prune row-groups for given sql statement.
+func (m *parquetScanner) project(sel *rel.SqlSelect) {
+
+	m.read = m.cols
+	if sel == nil {
+		m.setColIndex()
+		return
+	}
+	if sel.Where != nil && sel.Where.Expr != nil {
+		m.filter = sel.Where.Expr
+	}
+	if sel.Star {
+		m.setColIndex()
+		return
+	}
+
+	needed := make(map[string]struct{})
+	addIdents := func(n expr.Node) {
+		if n == nil {
+			return
+		}
+		for _, ident := range expr.FindAllIdentities(n) {
+			_, right, _ := ident.LeftRight()
+			needed[strings.ToLower(right)] = struct{}{}
+		}
+	}
+	addCols := func(cols rel.Columns) {
+		for _, col := range cols {
+			if col.Star {
+				needed["*"] = struct{}{}
+			}
+			addIdents(col.Expr)
+			addIdents(col.Guard)
+		}
+	}
+	addCols(sel.Columns)
+	addCols(sel.GroupBy)
+	addCols(sel.OrderBy)
+	addIdents(sel.Having)
+	if sel.Where != nil {
+		addIdents(sel.Where.Expr)
+	}
+	if _, hasStar := needed["*"]; hasStar {
+		m.setColIndex()
+		return
+	}
+
+	read := make([]*parquetColumn, 0, len(needed))
+	for _, col := range m.cols {
+		if _, ok := needed[col.name]; ok {
+			read = append(read, col)
+		}
+	}
+	if len(read) == 0 {
+		// count(*) etc still need rows, decode the first column
+		read = append(read, m.cols[0])
+	}
+	m.read = read
+	m.setColIndex()
+}
+
+func (m *parquetScanner) setColIndex() {
+	m.colindex = make(map[string]int, len(m.read))
+	for i, col := range m.read {
+		m.colindex[col.name] = i
+	}
+}
+
+// Columns list of all column names in file
+func (m *parquetScanner) Columns() []string { return m.tbl.Columns() }
+
+// SchemaTable the table schema as described in parquet file footer.
+func (m *parquetScanner) SchemaTable() *schema.Table { return m.tbl }
+
+// Close the underlying file
+func (m *parquetScanner) Close() error {
+	if m.rc != nil {
+		err := m.rc.Close()
+		m.rc = nil
+		return err
+	}
+	return nil
+}
+
+// Next returns the next row, nil when complete
+func (m *parquetScanner) Next() schema.Message {
+	for {
+		select {
+		case <-m.exit:
+			return nil
+		default:
+		}
+		if m.pos < len(m.rows) {
+			vals := m.rows[m.pos]
+			m.rows[m.pos] = nil
+			m.pos++
+			m.rowct++
+			return datasource.NewSqlDriverMessageMap(m.rowct, vals, m.colindex)
+		}
+		if !m.nextRowGroup() {
+			m.Close()
+			return nil
+		}
+	}
+}
+
+// nextRowGroup decode the next row-group that may match the filter.
+func (m *parquetScanner) nextRowGroup() bool {
+	rowGroups := m.pr.Footer.GetRowGroups()
+	for m.rg < len(rowGroups) {
+		rg := rowGroups[m.rg]
+		m.rg++
+		if m.filter != nil && !parquetMayMatch(m.filter, m.rowGroupStats(rg)) {
+			m.skipped++
+			continue
+		}
+		rows, err := m.readRowGroup(rg)
+		if err != nil {
+			u.Errorf("could not read parquet row-group table=%q err=%v", m.table, err)
+			return false
+		}
+		m.rows = rows
+		m.pos = 0
+		return true
+	}
+	return false
+}
+
+// readRowGroup decodes the projected columns of a single row-group.  We use
+// a copy of the footer containing only this row-group so the column buffers
+// can neither read into, nor require reading through, other row-groups.
+func (m *parquetScanner) readRowGroup(rg *parquet.RowGroup) ([][]driver.Value, error) {
+	footer := *m.pr.Footer
+	footer.RowGroups = []*parquet.RowGroup{rg}
+	footer.NumRows = rg.NumRows
+
+	rowCt := int(rg.NumRows)
+	rows := make([][]driver.Value, rowCt)
+	for i := range rows {
+		rows[i] = make([]driver.Value, len(m.read))
+	}
+	for ci, col := range m.read {
+		cb, err := reader.NewColumnBuffer(m.pf, &footer, m.pr.SchemaHandler, col.path)
+		if err != nil {
+			return nil, err
+		}
+		tbl, _ := cb.ReadRows(rg.NumRows)
+		if len(tbl.Values) != rowCt {
+			return nil, fmt.Errorf("parquet column %q expected %d values got %d", col.name, rowCt, len(tbl.Values))
+		}
+		for ri, v := range tbl.Values {
+			rows[ri][ci] = parquetValue(col, v)
+		}
+	}
+	return rows, nil
+}
+
+// rowGroupStats the min/max statistics for the columns of a row-group.
+func (m *parquetScanner) rowGroupStats(rg *parquet.RowGroup) map[string]*parquetStats {
+	root := m.pr.SchemaHandler.GetRootInName()
+	stats := make(map[string]*parquetStats)
+	for _, chunk := range rg.GetColumns() {
+		md := chunk.GetMetaData()
+		if md == nil || md.Statistics == nil {
+			continue
+		}
+		col, ok := m.colByPath(root + "." + strings.Join(md.GetPathInSchema(), "."))
+		if !ok {
+			continue
+		}
+		min, max := md.Statistics.MinValue, md.Statistics.MaxValue
+		if min == nil || max == nil {
+			// Deprecated, but still what many writers (including parquet-go) write
+			min, max = md.Statistics.Min, md.Statistics.Max
+		}
+		if min == nil || max == nil {
+			continue
+		}
+		minV, maxV := parquetStatValue(col, min), parquetStatValue(col, max)
+		if minV == nil || maxV == nil {
+			continue
+		}
+		stats[col.name] = &parquetStats{min: minV, max: maxV}
+	}
+	return stats
+}
+
+func (m *parquetScanner) colByPath(inPath string) (*parquetColumn, bool) {
+	col, ok := m.pathMap[inPath]
+	return col, ok
+}
+
+func parquetValueType(col *parquetColumn) value.ValueType {
+	if col.ct != nil {
+		switch *col.ct {
+		case parquet.ConvertedType_TIMESTAMP_MILLIS, parquet.ConvertedType_TIMESTAMP_MICROS,
+			parquet.ConvertedType_DATE:
+			return value.TimeType
+		case parquet.ConvertedType_DECIMAL:
+			return value.NumberType
+		case parquet.ConvertedType_JSON:
+			return value.JsonType
+		}
+	}
+	switch col.pt {
+	case parquet.Type_BOOLEAN:
+		return value.BoolType
+	case parquet.Type_INT32, parquet.Type_INT64:
+		return value.IntType
+	case parquet.Type_FLOAT, parquet.Type_DOUBLE:
+		return value.NumberType
+	case parquet.Type_INT96:
+		return value.TimeType
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		if col.isUTF8 {
+			return value.StringType
+		}
+		return value.ByteSliceType
+	}
+	return value.UnknownType
+}
+
+func parquetTypeName(col *parquetColumn) string {
+	if col.ct != nil {
+		return strings.ToLower(col.ct.String())
+	}
+	return strings.ToLower(col.pt.String())
+}
+
+// parquetValue convert the physical value read by parquet-go into the
+// go type for the column's sql value type.
+func parquetValue(col *parquetColumn, v interface{}) driver.Value {
+	if v == nil {
+		return nil
+	}
+	if col.ct != nil {
+		switch *col.ct {
+		case parquet.ConvertedType_TIMESTAMP_MILLIS:
+			if iv, ok := v.(int64); ok {
+				return time.Unix(0, iv*int64(time.Millisecond)).UTC()
+			}
+		case parquet.ConvertedType_TIMESTAMP_MICROS:
+			if iv, ok := v.(int64); ok {
+				return time.Unix(0, iv*int64(time.Microsecond)).UTC()
+			}
+		case parquet.ConvertedType_DATE:
+			if iv, ok := v.(int32); ok {
+				return time.Unix(int64(iv)*86400, 0).UTC()
+			}
+		case parquet.ConvertedType_DECIMAL:
+			scale := math.Pow10(int(col.scale))
+			switch vt := v.(type) {
+			case int32:
+				return float64(vt) / scale
+			case int64:
+				return float64(vt) / scale
+			}
+		}
+	}
+	switch vt := v.(type) {
+	case int32:
+		return int64(vt)
+	case float32:
+		return float64(vt)
+	case string:
+		if col.pt == parquet.Type_INT96 {
+			return parquetInt96Time([]byte(vt))
+		}
+		if !col.isUTF8 {
+			return []byte(vt)
+		}
+	}
+	return v
+}
+
+// parquetInt96Time convert legacy (impala, spark) int96 timestamps
+// which are nanoseconds-of-day followed by the julian day.
+func parquetInt96Time(by []byte) driver.Value {
+	if len(by) != 12 {
+		return nil
+	}
+	nanos := int64(binary.LittleEndian.Uint64(by[:8]))
+	days := int64(binary.LittleEndian.Uint32(by[8:]))
+	const julianUnixEpoch = 2440588
+	return time.Unix((days-julianUnixEpoch)*86400, nanos).UTC()
+}
+
+// parquetStats min/max statistics for a single column of a row-group.
+type parquetStats struct {
+	min, max value.Value
+}
+
+// parquetStatValue decode a plain encoded statistic value.  Only types
+// whose ordering is the same as the sql value ordering are decoded,
+// others return nil meaning no pruning is done on that column.
+func parquetStatValue(col *parquetColumn, by []byte) value.Value {
+	if col.ct != nil && !col.isUTF8 {
+		switch *col.ct {
+		case parquet.ConvertedType_INT_8, parquet.ConvertedType_INT_16,
+			parquet.ConvertedType_INT_32, parquet.ConvertedType_INT_64:
+		default:
+			return nil
+		}
+	}
+	switch col.pt {
+	case parquet.Type_INT32:
+		if len(by) == 4 {
+			return value.NewIntValue(int64(int32(binary.LittleEndian.Uint32(by))))
+		}
+	case parquet.Type_INT64:
+		if len(by) == 8 {
+			return value.NewIntValue(int64(binary.LittleEndian.Uint64(by)))
+		}
+	case parquet.Type_FLOAT:
+		if len(by) == 4 {
+			return value.NewNumberValue(float64(math.Float32frombits(binary.LittleEndian.Uint32(by))))
+		}
+	case parquet.Type_DOUBLE:
+		if len(by) == 8 {
+			return value.NewNumberValue(math.Float64frombits(binary.LittleEndian.Uint64(by)))
+		}
+	case parquet.Type_BYTE_ARRAY:
+		if col.isUTF8 {
+			return value.NewStringValue(string(by))
+		}
+	}
+	return nil
+}
+
+// parquetMayMatch evaluate whether a row-group with given column statistics
+// may contain rows matching the filter expression.  This is conservative,
+// anything it doesn't understand is assumed to possibly match.
+func parquetMayMatch(node expr.Node, stats map[string]*parquetStats) bool {
+	switch n := node.(type) {
+	case *expr.BinaryNode:
+		if len(n.Args) != 2 {
+			return true
+		}
+		switch n.Operator.T {
+		case lex.TokenLogicAnd:
+			return parquetMayMatch(n.Args[0], stats) && parquetMayMatch(n.Args[1], stats)
+		case lex.TokenLogicOr:
+			return parquetMayMatch(n.Args[0], stats) || parquetMayMatch(n.Args[1], stats)
+		case lex.TokenIN:
+			st, ok := parquetIdentStats(n.Args[0], stats)
+			arr, isArr := n.Args[1].(*expr.ArrayNode)
+			if !ok || !isArr {
+				return true
+			}
+			for _, arg := range arr.Args {
+				lit := parquetLiteral(arg)
+				if lit == nil || parquetInRange(st, lit, lit) {
+					return true
+				}
+			}
+			return false
+		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenLT, lex.TokenLE, lex.TokenGT, lex.TokenGE:
+			op := n.Operator.T
+			st, ok := parquetIdentStats(n.Args[0], stats)
+			lit := parquetLiteral(n.Args[1])
+			if !ok {
+				// literal on left side, 5 < x  is same as x > 5
+				st, ok = parquetIdentStats(n.Args[1], stats)
+				lit = parquetLiteral(n.Args[0])
+				op = parquetFlipOp(op)
+			}
+			if !ok || lit == nil {
+				return true
+			}
+			switch op {
+			case lex.TokenEqual, lex.TokenEqualEqual:
+				return parquetInRange(st, lit, lit)
+			case lex.TokenLT:
+				c, ok := parquetCompare(st.min, lit)
+				return !ok || c < 0
+			case lex.TokenLE:
+				c, ok := parquetCompare(st.min, lit)
+				return !ok || c <= 0
+			case lex.TokenGT:
+				c, ok := parquetCompare(st.max, lit)
+				return !ok || c > 0
+			case lex.TokenGE:
+				c, ok := parquetCompare(st.max, lit)
+				return !ok || c >= 0
+			}
+		}
+	case *expr.BooleanNode:
+		if n.Negated() {
+			return true
+		}
+		switch n.Operator.T {
+		case lex.TokenLogicAnd, lex.TokenAnd:
+			for _, arg := range n.Args {
+				if !parquetMayMatch(arg, stats) {
+					return false
+				}
+			}
+			return true
+		case lex.TokenLogicOr, lex.TokenOr:
+			for _, arg := range n.Args {
+				if parquetMayMatch(arg, stats) {
+					return true
+				}
+			}
+			return false
+		}
+	case *expr.TriNode:
+		if n.Negated() || n.Operator.T != lex.TokenBetween || len(n.Args) != 3 {
+			return true
+		}
+		st, ok := parquetIdentStats(n.Args[0], stats)
+		lower, upper := parquetLiteral(n.Args[1]), parquetLiteral(n.Args[2])
+		if !ok || lower == nil || upper == nil {
+			return true
+		}
+		return parquetInRange(st, lower, upper)
+	}
+	return true
+}
+
+func parquetFlipOp(op lex.TokenType) lex.TokenType {
+	switch op {
+	case lex.TokenLT:
+		return lex.TokenGT
+	case lex.TokenLE:
+		return lex.TokenGE
+	case lex.TokenGT:
+		return lex.TokenLT
+	case lex.TokenGE:
+		return lex.TokenLE
+	}
+	return op
+}
+
+func parquetIdentStats(n expr.Node, stats map[string]*parquetStats) (*parquetStats, bool) {
+	in, ok := n.(*expr.IdentityNode)
+	if !ok {
+		return nil, false
+	}
+	_, right, _ := in.LeftRight()
+	st, ok := stats[strings.ToLower(right)]
+	return st, ok
+}
+
+func parquetLiteral(n expr.Node) value.Value {
+	switch nt := n.(type) {
+	case *expr.NumberNode:
+		if nt.IsInt {
+			return value.NewIntValue(nt.Int64)
+		}
+		return value.NewNumberValue(nt.Float64)
+	case *expr.StringNode:
+		return value.NewStringValue(nt.Text)
+	case *expr.ValueNode:
+		return nt.Value
+	}
+	return nil
+}
+
+// parquetInRange may any value in [lower, upper] fall within the stats min/max
+func parquetInRange(st *parquetStats, lower, upper value.Value) bool {
+	cmax, ok := parquetCompare(st.max, lower)
+	if !ok {
+		return true
+	}
+	cmin, ok := parquetCompare(st.min, upper)
+	if !ok {
+		return true
+	}
+	return cmax >= 0 && cmin <= 0
+}
+
+// parquetCompare compare a statistic to a literal, returns -1, 0, 1 and
+// false if the types are not comparable, callers must never prune a
+// row-group on a failed comparison.
+func parquetCompare(stat, lit value.Value) (int, bool) {
+	switch sv := stat.(type) {
+	case value.IntValue:
+		switch lv := lit.(type) {
+		case value.IntValue:
+			return compareInt64(sv.Val(), lv.Val()), true
+		case value.NumberValue:
+			return compareFloat64(sv.Float(), lv.Val()), true
+		case value.StringValue:
+			if fv, ok := value.StringToFloat64(lv.Val()); ok {
+				return compareFloat64(sv.Float(), fv), true
+			}
+		}
+	case value.NumberValue:
+		switch lv := lit.(type) {
+		case value.IntValue:
+			return compareFloat64(sv.Val(), lv.Float()), true
+		case value.NumberValue:
+			return compareFloat64(sv.Val(), lv.Val()), true
+		case value.StringValue:
+			if fv, ok := value.StringToFloat64(lv.Val()); ok {
+				return compareFloat64(sv.Val(), fv), true
+			}
+		}
+	case value.StringValue:
+		if lv, ok := lit.(value.StringValue); ok {
+			return strings.Compare(sv.Val(), lv.Val()), true
+		}
+	}
+	return 0, false
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// parquetFile adapts a file we have opened for reading to the parquet-go
+// ParquetFile interface.  parquet-go opens a separate handle per column so
+// each Open shares the underlying io.ReaderAt with its own offset.
+type parquetFile struct {
+	ra   io.ReaderAt
+	size int64
+	*io.SectionReader
+}
+
+func newParquetFile(ra io.ReaderAt, size int64) *parquetFile {
+	return &parquetFile{ra: ra, size: size, SectionReader: io.NewSectionReader(ra, 0, size)}
+}
+
+// newParquetFileFromReader parquet requires random access (the footer is at
+// end of file), local files (cloudstorage caches remote files locally) are
+// used directly, anything else is read into memory.
+func newParquetFileFromReader(r io.Reader) (*parquetFile, error) {
+	if f, ok := r.(*os.File); ok {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return newParquetFile(f, fi.Size()), nil
+	}
+	by, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return newParquetFile(bytes.NewReader(by), int64(len(by))), nil
+}
+
+func (m *parquetFile) Open(name string) (source.ParquetFile, error) {
+	return newParquetFile(m.ra, m.size), nil
+}
+func (m *parquetFile) Create(name string) (source.ParquetFile, error) {
+	return nil, errParquetReadOnly
+}
+func (m *parquetFile) Write(p []byte) (int, error) { return 0, errParquetReadOnly }
+func (m *parquetFile) Close() error                { return nil }
@@ -0,0 +1,213 @@
+package files
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+type parquetEvent struct {
+	Id     int64   `parquet:"name=id, type=INT64"`
+	Name   string  `parquet:"name=name, type=UTF8"`
+	Score  float64 `parquet:"name=score, type=DOUBLE"`
+	Active bool    `parquet:"name=active, type=BOOLEAN"`
+}
+
+// parquetWriteFile adapts os.File for parquet-go writer
+type parquetWriteFile struct {
+	*os.File
+}
+
+func (m *parquetWriteFile) Open(name string) (source.ParquetFile, error) {
+	f, err := os.Open(m.Name())
+	return &parquetWriteFile{f}, err
+}
+func (m *parquetWriteFile) Create(name string) (source.ParquetFile, error) {
+	f, err := os.Create(name)
+	return &parquetWriteFile{f}, err
+}
+
+// writeParquetEvents writes 3 row-groups of 10 rows each, ids 0-29
+func writeParquetEvents(t *testing.T, fileName string) {
+	f, err := os.Create(fileName)
+	assert.Equal(t, nil, err)
+	pw, err := writer.NewParquetWriter(&parquetWriteFile{f}, new(parquetEvent), 1)
+	assert.Equal(t, nil, err)
+	for i := 0; i < 30; i++ {
+		ev := parquetEvent{Id: int64(i), Name: string(rune('a' + i%26)), Score: float64(i) / 2, Active: i%2 == 0}
+		assert.Equal(t, nil, pw.Write(ev))
+		if i%10 == 9 {
+			assert.Equal(t, nil, pw.Flush(true))
+		}
+	}
+	assert.Equal(t, nil, pw.WriteStop())
+	assert.Equal(t, nil, f.Close())
+}
+
+func openParquetScanner(t *testing.T, fileName, sqlText string) *parquetScanner {
+	f, err := os.Open(fileName)
+	assert.Equal(t, nil, err)
+	fr := &FileReader{F: f, Exit: make(chan bool), FileInfo: &FileInfo{Table: "events"}}
+	if sqlText != "" {
+		stmt, err := rel.ParseSqlSelect(sqlText)
+		assert.Equal(t, nil, err)
+		fr.Sql = stmt
+	}
+	ps, err := newParquetScanner(fr)
+	assert.Equal(t, nil, err)
+	return ps
+}
+
+func scanAll(ps *parquetScanner) [][]driver.Value {
+	rows := make([][]driver.Value, 0)
+	for msg := ps.Next(); msg != nil; msg = ps.Next() {
+		rows = append(rows, msg.(*datasource.SqlDriverMessageMap).Values())
+	}
+	return rows
+}
+
+func TestParquetScanner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_parquet")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "events.parquet")
+	writeParquetEvents(t, fileName)
+
+	// schema comes from footer
+	ps := openParquetScanner(t, fileName, "")
+	assert.Equal(t, []string{"id", "name", "score", "active"}, ps.Columns())
+	assert.Equal(t, 3, len(ps.pr.Footer.RowGroups))
+	rows := scanAll(ps)
+	assert.Equal(t, 30, len(rows))
+	assert.Equal(t, []driver.Value{int64(3), "d", float64(1.5), false}, rows[3])
+
+	// only needed columns are decoded, row-groups pruned by stats
+	ps = openParquetScanner(t, fileName, "SELECT name FROM events WHERE id >= 12 AND id < 15")
+	assert.Equal(t, 2, len(ps.read))
+	rows = scanAll(ps)
+	assert.Equal(t, 10, len(rows)) // where is applied downstream, only row-group 2 scanned
+	assert.Equal(t, 2, ps.skipped)
+	assert.Equal(t, []driver.Value{"k", int64(10)}, []driver.Value{rows[0][ps.colindex["name"]], rows[0][ps.colindex["id"]]})
+
+	tests := []struct {
+		sql     string
+		skipped int
+	}{
+		{"SELECT id FROM events WHERE id = 25", 2},
+		{"SELECT id FROM events WHERE 5 > id", 2},
+		{"SELECT id FROM events WHERE id = 5 OR id = 25", 1},
+		{"SELECT id FROM events WHERE id BETWEEN 8 AND 11", 1},
+		{"SELECT id FROM events WHERE id IN (1, 29)", 1},
+		{"SELECT id FROM events WHERE score > 100", 3},
+		{"SELECT id FROM events WHERE name = \"zz\"", 3},
+		{"SELECT id FROM events WHERE id > 100 OR name = \"a\"", 1},
+		{"SELECT id FROM events WHERE NOT (id = 25)", 0},
+		{"SELECT id FROM events WHERE active = true", 0},
+		{"SELECT count(*) FROM events", 0},
+	}
+	for _, tt := range tests {
+		ps = openParquetScanner(t, fileName, tt.sql)
+		scanAll(ps)
+		assert.Equal(t, tt.skipped, ps.skipped, tt.sql)
+	}
+}
+
+type parquetTestSource struct {
+	*FileSource
+	dir string
+}
+
+func (m *parquetTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       "testparquet",
+		SourceType: "testparquet",
+		Settings: u.JsonHelper(map[string]interface{}{
+			"path":      "",
+			"format":    "parquet",
+			"type":      "localfs",
+			"localpath": m.dir,
+		}),
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func TestParquetSql(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_parquet_sql")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, "events"), 0755))
+	writeParquetEvents(t, filepath.Join(dir, "events", "events.parquet"))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "events", "_SUCCESS"), nil, 0644))
+
+	schema.RegisterSourceAsSchema("testparquet", &parquetTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testparquet")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, name FROM events WHERE id >= 12 AND id < 15 AND active = true")
+	assert.Equal(t, nil, err)
+	defer rows.Close()
+	got := make([]string, 0)
+	for rows.Next() {
+		var id int64
+		var name string
+		assert.Equal(t, nil, rows.Scan(&id, &name))
+		got = append(got, name)
+	}
+	assert.Equal(t, []string{"m", "o"}, got)
+
+	var ct int64
+	assert.Equal(t, nil, db.QueryRow("SELECT count(*) AS ct FROM events WHERE score > 10").Scan(&ct))
+	assert.Equal(t, int64(9), ct)
+
+	ss, ok := schema.DefaultRegistry().Schema("testparquet")
+	assert.True(t, ok)
+	et, err := ss.Table("events")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "name", "score", "active"}, et.Columns())
+	assert.Equal(t, "int", et.FieldMap["id"].ValueType().String())
+}
+
+func TestParquetMayMatch(t *testing.T) {
+	stats := map[string]*parquetStats{
+		"id":   {min: value.NewIntValue(10), max: value.NewIntValue(20)},
+		"name": {min: value.NewStringValue("bob"), max: value.NewStringValue("sue")},
+	}
+	tests := []struct {
+		qry   string
+		match bool
+	}{
+		{"id > 20", false},
+		{"id >= 20", true},
+		{"id < 10", false},
+		{"id = 15", true},
+		{"id BETWEEN 21 AND 30", false},
+		{`name = "zed"`, false},
+		// not comparable, can't be pruned
+		{`id < "abc"`, true},
+		{`id > "abc"`, true},
+		{"name < 5", true},
+		{"name >= 5", true},
+		{`id = "abc"`, true},
+	}
+	for _, tt := range tests {
+		node, err := expr.ParseExpression(tt.qry)
+		assert.Equal(t, nil, err, tt.qry)
+		assert.Equal(t, tt.match, parquetMayMatch(node, stats), tt.qry)
+	}
+}
@@ -0,0 +1,334 @@
+package files
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	u "github.com/araddon/gou"
+	"github.com/lytics/cloudstorage"
+	"google.golang.org/api/iterator"
+
+	"github.com/fuhongbo/qlbridge/lex"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+const (
+	// partFilePrefix prefix of the name of files written by inserts
+	partFilePrefix = "part-"
+)
+
+var (
+	// ensure our pager accepts writes
+	_ schema.ConnUpsert  = (*FilePager)(nil)
+	_ schema.ConnFlusher = (*FilePager)(nil)
+	// ensure our source can create, alter, drop tables
+	_ schema.SourceDDL = (*FileSource)(nil)
+)
+
+// FileStoreWriter a FileStore which can also create new files,
+// cloudstorage.Store implementations (gcs, localfs) satisfy this.
+type FileStoreWriter interface {
+	FileStore
+	NewWriter(o string, metadata map[string]string) (io.WriteCloser, error)
+}
+
+// FileRowWriter writes rows of a table into a single new file
+type FileRowWriter interface {
+	// Write a row, values are in order of table columns
+	Write(row []driver.Value) error
+	// Close finishes file (footers etc) but does not close underlying writer
+	Close() error
+}
+
+// FileHandlerWriter file handlers may optionally write new files
+// for INSERT and SELECT ... INTO
+type FileHandlerWriter interface {
+	FileHandler
+	// FileExt the file extension for new files ie ".csv"
+	FileExt() string
+	// Writer create a row writer for a table writing to @w
+	Writer(w io.Writer, tbl *schema.Table, settings u.JsonHelper) (FileRowWriter, error)
+}
+
+// fileSink buffers rows written to a table in a local temp file,
+// which is copied into the store as a new part file on commit so
+// readers never see a partial file.
+type fileSink struct {
+	fs    *FileSource
+	table string
+	tbl   *schema.Table
+	name  string // store object name of the new file
+	tmp   *os.File
+	w     FileRowWriter
+	rowct int
+}
+
+func newFileSink(fs *FileSource, table string) (*fileSink, error) {
+	if _, ok := fs.store.(FileStoreWriter); !ok {
+		return nil, fmt.Errorf("file store %T for %q is read only", fs.store, table)
+	}
+	fh, ok := fs.handler(table).(FileHandlerWriter)
+	if !ok {
+		return nil, fmt.Errorf("format %q of table %q does not support writes", fs.tableFormat(table), table)
+	}
+	tbl, err := fs.Table(table)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempFile("", "qlb_sink")
+	if err != nil {
+		return nil, err
+	}
+	w, err := fh.Writer(tmp, tbl, fs.ss.Conf.Settings)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &fileSink{
+		fs:    fs,
+		table: table,
+		tbl:   tbl,
+		name:  fs.newFileName(table, fh.FileExt()),
+		tmp:   tmp,
+		w:     w,
+	}, nil
+}
+
+// newFileName for a new part file of table
+func (m *FileSource) newFileName(table, ext string) string {
+	id := make([]byte, 4)
+	rand.Read(id)
+	part := fmt.Sprintf("%s%d-%s%s", partFilePrefix, time.Now().UnixNano(), hex.EncodeToString(id), ext)
+	if ft, exists := m.tables[table]; exists && ft.PartialPath != "" {
+		return path.Join(m.path, ft.PartialPath, part)
+	}
+	// tables from file names, ie users.csv, table is the name up to first "."
+	// and TableFromFileAndPath recognizes the part name
+	return path.Join(m.path, table+"."+part)
+}
+
+// row converts a Put value to table column ordered row
+func (m *fileSink) row(v interface{}) ([]driver.Value, error) {
+	cols := m.tbl.Columns()
+	switch val := v.(type) {
+	case []driver.Value:
+		if len(val) != len(cols) {
+			return nil, fmt.Errorf("expected %d values for %q but got %d", len(cols), m.table, len(val))
+		}
+		return val, nil
+	case map[string]driver.Value:
+		row := make([]driver.Value, len(cols))
+		for k, colVal := range val {
+			idx, ok := m.tbl.FieldPositions[strings.ToLower(k)]
+			if !ok {
+				return nil, fmt.Errorf("column %q not found in table %q", k, m.table)
+			}
+			row[idx] = colVal
+		}
+		return row, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T for %q", v, m.table)
+}
+
+func (m *fileSink) put(v interface{}) error {
+	row, err := m.row(v)
+	if err != nil {
+		return err
+	}
+	m.rowct++
+	return m.w.Write(row)
+}
+
+// commit copies the temp file to store as new file
+func (m *fileSink) commit(ctx context.Context) error {
+	defer m.discard()
+	if err := m.w.Close(); err != nil {
+		return err
+	}
+	if m.rowct == 0 {
+		return nil
+	}
+	if _, err := m.tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	store := m.fs.store.(FileStoreWriter)
+	w, err := store.NewWriter(m.name, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, m.tmp); err != nil {
+		w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	u.Debugf("wrote %d rows to %q", m.rowct, m.name)
+	return nil
+}
+
+// discard the temp file, nothing is written to store
+func (m *fileSink) discard() {
+	if m.tmp != nil {
+		m.tmp.Close()
+		os.Remove(m.tmp.Name())
+		m.tmp = nil
+	}
+}
+
+// Put a row into this table, the row is buffered until Flush()
+// writes a new part file.  Values are either []driver.Value in order
+// of table columns or map[string]driver.Value of column names.
+func (m *FilePager) Put(ctx context.Context, key schema.Key, value interface{}) (schema.Key, error) {
+	if m.sink == nil {
+		sink, err := newFileSink(m.fs, m.table)
+		if err != nil {
+			return nil, err
+		}
+		m.sink = sink
+	}
+	if err := m.sink.put(value); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// PutMulti put many rows, see Put
+func (m *FilePager) PutMulti(ctx context.Context, keys []schema.Key, src interface{}) ([]schema.Key, error) {
+	var rows []interface{}
+	switch val := src.(type) {
+	case [][]driver.Value:
+		for _, row := range val {
+			rows = append(rows, row)
+		}
+	case []map[string]driver.Value:
+		for _, row := range val {
+			rows = append(rows, row)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported value type %T for %q", src, m.table)
+	}
+	for _, row := range rows {
+		if _, err := m.Put(ctx, nil, row); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// Flush write the rows Put so far as a new file to store
+func (m *FilePager) Flush(ctx context.Context) error {
+	if m.sink == nil {
+		return nil
+	}
+	sink := m.sink
+	m.sink = nil
+	return sink.commit(ctx)
+}
+
+// FileStoreDeleter a FileStore which can delete files, needed to
+// DROP TABLE.  cloudstorage.Store implementations satisfy this.
+type FileStoreDeleter interface {
+	FileStore
+	Delete(ctx context.Context, o string) error
+}
+
+// CreateTable declare a new table, stored in a folder of its name.  The
+// table only exists in this source's schema until rows are written to it.
+func (m *FileSource) CreateTable(tbl *schema.Table) error {
+	if _, exists := m.tables[tbl.Name]; exists {
+		return fmt.Errorf("table %q already exists", tbl.Name)
+	}
+	if _, ok := m.store.(FileStoreWriter); !ok {
+		return fmt.Errorf("file store %T for %q is read only", m.store, tbl.Name)
+	}
+	if _, ok := m.handler(tbl.Name).(FileHandlerWriter); !ok {
+		return fmt.Errorf("format %q of table %q does not support writes", m.tableFormat(tbl.Name), tbl.Name)
+	}
+	if len(tbl.Columns()) != len(tbl.Fields) {
+		tbl.SetColumnsFromFields()
+	}
+	m.tables[tbl.Name] = &FileTable{Table: tbl.Name, PartialPath: tbl.Name}
+	m.tableSchemas[tbl.Name] = tbl
+	m.tablenames = append(m.tablenames, tbl.Name)
+	return nil
+}
+
+// DropTable delete all of the files of table.
+func (m *FileSource) DropTable(table string) error {
+	ft, exists := m.tables[table]
+	if !exists {
+		return schema.ErrNotFound
+	}
+	store, ok := m.store.(FileStoreDeleter)
+	if !ok {
+		return fmt.Errorf("file store %T for %q is read only", m.store, table)
+	}
+
+	ctx := context.Background()
+	q := cloudstorage.Query{Delimiter: "", Prefix: path.Join(m.path, ft.PartialPath)}
+	iter, err := m.store.Objects(ctx, q)
+	if err != nil {
+		return err
+	}
+	fh := m.handler(table)
+	for {
+		o, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return err
+		}
+		if fi := m.fileFor(fh, o); fi == nil || fi.Table != table {
+			continue
+		}
+		if err = store.Delete(ctx, o.Name()); err != nil {
+			return err
+		}
+	}
+
+	delete(m.tables, table)
+	delete(m.tableSchemas, table)
+	names := make([]string, 0, len(m.tablenames))
+	for _, tn := range m.tablenames {
+		if tn != table {
+			names = append(names, tn)
+		}
+	}
+	m.tablenames = names
+	return nil
+}
+
+// AlterTable change the columns of table for files written from now on,
+// existing files are not re-written.  Renaming a column is not supported
+// as rows of existing files would no longer be found by name.
+func (m *FileSource) AlterTable(table string, changes []*schema.ColumnChange) error {
+	if _, exists := m.tables[table]; !exists {
+		return schema.ErrNotFound
+	}
+	for _, cc := range changes {
+		if cc.Op == lex.TokenChange && cc.Field != nil && cc.Field.Name != cc.Name {
+			return fmt.Errorf("cannot rename column %q of file table %q", cc.Name, table)
+		}
+	}
+	tbl, err := m.Table(table)
+	if err != nil {
+		return err
+	}
+	nt, _, err := schema.ApplyColumnChanges(tbl, changes)
+	if err != nil {
+		return err
+	}
+	m.tableSchemas[table] = nt
+	return nil
+}
@@ -0,0 +1,137 @@
+package files
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+var (
+	// ensure our csv handler can write
+	_ FileHandlerWriter = (*csvFiles)(nil)
+)
+
+func (m *csvFiles) FileExt() string { return ".csv" }
+
+// Writer create a csv writer using the csv dialect options of table
+func (m *csvFiles) Writer(w io.Writer, tbl *schema.Table, settings u.JsonHelper) (FileRowWriter, error) {
+	opts, err := csvOptions(settings, tbl.Name)
+	if err != nil {
+		return nil, err
+	}
+	cw := &csvRowWriter{quote: '"'}
+	if opts.Quote != 0 && opts.Quote != '"' {
+		if opts.Quote > 127 {
+			return nil, fmt.Errorf("csv quote %q must be ascii", opts.Quote)
+		}
+		cw.quote = opts.Quote
+		w = &quoteSwapWriter{w: w, quote: byte(opts.Quote)}
+	}
+	cw.w = csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		cw.w.Comma = opts.Delimiter
+	}
+	if len(opts.Nulls) > 0 {
+		cw.null = opts.Nulls[0]
+	}
+	cw.vals = make([]string, len(tbl.Columns()))
+	if !opts.NoHeader {
+		if err := cw.w.Write(tbl.Columns()); err != nil {
+			return nil, err
+		}
+	}
+	return cw, nil
+}
+
+type csvRowWriter struct {
+	w     *csv.Writer
+	quote rune
+	null  string
+	vals  []string
+}
+
+func (m *csvRowWriter) Write(row []driver.Value) error {
+	for i, v := range row {
+		if v == nil {
+			m.vals[i] = m.null
+			continue
+		}
+		m.vals[i] = m.swap(csvString(v))
+	}
+	return m.w.Write(m.vals)
+}
+
+func (m *csvRowWriter) Close() error {
+	m.w.Flush()
+	return m.w.Error()
+}
+
+// swap the custom quote char and '"', swapped back by quoteSwapWriter
+func (m *csvRowWriter) swap(s string) string {
+	if m.quote == '"' {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case m.quote:
+			return '"'
+		case '"':
+			return m.quote
+		}
+		return r
+	}, s)
+}
+
+// quoteSwapWriter encoding/csv only writes '"' quotes, swap them
+// for the custom quote char.
+type quoteSwapWriter struct {
+	w     io.Writer
+	quote byte
+	buf   []byte
+}
+
+func (m *quoteSwapWriter) Write(p []byte) (int, error) {
+	m.buf = append(m.buf[:0], p...)
+	for i, b := range m.buf {
+		switch b {
+		case m.quote:
+			m.buf[i] = '"'
+		case '"':
+			m.buf[i] = m.quote
+		}
+	}
+	return m.w.Write(m.buf)
+}
+
+// csvString format value for csv
+func csvString(v driver.Value) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		by, err := json.Marshal(val)
+		if err == nil {
+			return string(by)
+		}
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,40 @@
+package files
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+var (
+	// ensure our json handler can write
+	_ FileHandlerWriter = (*jsonHandler)(nil)
+)
+
+func (m *jsonHandler) FileExt() string { return ".json" }
+
+// Writer create a new-line delimited json writer
+func (m *jsonHandler) Writer(w io.Writer, tbl *schema.Table, settings u.JsonHelper) (FileRowWriter, error) {
+	return &jsonRowWriter{enc: json.NewEncoder(w), cols: tbl.Columns()}, nil
+}
+
+type jsonRowWriter struct {
+	enc  *json.Encoder
+	cols []string
+}
+
+func (m *jsonRowWriter) Write(row []driver.Value) error {
+	doc := make(map[string]driver.Value, len(row))
+	for i, v := range row {
+		if v != nil {
+			doc[m.cols[i]] = v
+		}
+	}
+	return m.enc.Encode(doc)
+}
+
+func (m *jsonRowWriter) Close() error { return nil }
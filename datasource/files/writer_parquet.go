@@ -0,0 +1,133 @@
+package files
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	u "github.com/araddon/gou"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	// ensure our parquet handler can write
+	_ FileHandlerWriter = (*parquetFiles)(nil)
+)
+
+func (m *parquetFiles) FileExt() string { return ".parquet" }
+
+// Writer create a parquet writer, all columns are flat optional columns
+// typed from the table schema.
+func (m *parquetFiles) Writer(w io.Writer, tbl *schema.Table, settings u.JsonHelper) (FileRowWriter, error) {
+	cols := tbl.Columns()
+	md := make([]string, len(cols))
+	types := make([]value.ValueType, len(cols))
+	for i, col := range cols {
+		vt := value.StringType
+		if f, ok := tbl.FieldMap[col]; ok {
+			vt = f.ValueType()
+		}
+		switch vt {
+		case value.IntType:
+			md[i] = fmt.Sprintf("name=%s, type=INT64", col)
+		case value.NumberType:
+			md[i] = fmt.Sprintf("name=%s, type=DOUBLE", col)
+		case value.BoolType:
+			md[i] = fmt.Sprintf("name=%s, type=BOOLEAN", col)
+		case value.TimeType:
+			md[i] = fmt.Sprintf("name=%s, type=INT64, convertedtype=TIMESTAMP_MILLIS", col)
+		default:
+			vt = value.StringType
+			md[i] = fmt.Sprintf("name=%s, type=UTF8", col)
+		}
+		types[i] = vt
+	}
+	pw, err := writer.NewCSVWriter(md, &parquetWriter{w: w}, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetRowWriter{pw: pw, types: types}, nil
+}
+
+type parquetRowWriter struct {
+	pw    *writer.CSVWriter
+	types []value.ValueType
+}
+
+func (m *parquetRowWriter) Write(row []driver.Value) error {
+	rec := make([]interface{}, len(row))
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+		pv, err := parquetWriteValue(m.types[i], v)
+		if err != nil {
+			return err
+		}
+		rec[i] = pv
+	}
+	return m.pw.Write(rec)
+}
+
+func (m *parquetRowWriter) Close() error { return m.pw.WriteStop() }
+
+// parquetWriteValue convert value to the go type parquet-go expects for
+// the column type.
+func parquetWriteValue(vt value.ValueType, v driver.Value) (interface{}, error) {
+	switch vt {
+	case value.IntType:
+		if iv, ok := value.ValueToInt64(value.NewValue(v)); ok {
+			return iv, nil
+		}
+	case value.NumberType:
+		if fv, ok := value.ValueToFloat64(value.NewValue(v)); ok {
+			return fv, nil
+		}
+	case value.BoolType:
+		if bv, ok := v.(bool); ok {
+			return bv, nil
+		}
+		if bv, ok := value.ValueToBool(value.NewValue(v)); ok {
+			return bv, nil
+		}
+	case value.TimeType:
+		if t, ok := v.(time.Time); ok {
+			return t.UnixNano() / int64(time.Millisecond), nil
+		}
+		if t, ok := value.ValueToTime(value.NewValue(v)); ok {
+			return t.UnixNano() / int64(time.Millisecond), nil
+		}
+	default:
+		switch val := v.(type) {
+		case map[string]interface{}, []interface{}:
+			by, err := json.Marshal(val)
+			return string(by), err
+		}
+		return csvString(v), nil
+	}
+	return nil, fmt.Errorf("could not convert %v to parquet %s", v, vt)
+}
+
+// parquetWriter adapts io.Writer for parquet-go writer which only writes
+type parquetWriter struct {
+	w io.Writer
+}
+
+func (m *parquetWriter) Write(p []byte) (int, error) { return m.w.Write(p) }
+func (m *parquetWriter) Read(p []byte) (int, error)  { return 0, io.EOF }
+func (m *parquetWriter) Seek(offset int64, whence int) (int64, error) {
+	return 0, fmt.Errorf("parquet writer does not seek")
+}
+func (m *parquetWriter) Close() error { return nil }
+func (m *parquetWriter) Open(name string) (source.ParquetFile, error) {
+	return nil, fmt.Errorf("parquet writer does not open")
+}
+func (m *parquetWriter) Create(name string) (source.ParquetFile, error) {
+	return nil, fmt.Errorf("parquet writer does not create")
+}
@@ -0,0 +1,244 @@
+package files
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+type writerTestSource struct {
+	*FileSource
+	dir string
+}
+
+func (m *writerTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       "testwriter",
+		SourceType: "testwriter",
+		Settings: u.JsonHelper(map[string]interface{}{
+			"path":      "",
+			"format":    "csv",
+			"type":      "localfs",
+			"localpath": m.dir,
+			"formats": map[string]interface{}{
+				"events": "parquet",
+				"logs":   "json",
+			},
+			"csv": map[string]interface{}{"null": "NULL"},
+		}),
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func tableFiles(t *testing.T, dir, table string) []string {
+	files, err := ioutil.ReadDir(filepath.Join(dir, table))
+	assert.Equal(t, nil, err)
+	names := make([]string, 0)
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".metadata") {
+			names = append(names, f.Name())
+		}
+	}
+	return names
+}
+
+func TestFileWriterSql(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_writer")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	for _, table := range []string{"users", "events", "logs"} {
+		assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, table), 0755))
+	}
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "users", "users.csv"),
+		[]byte("id,name,score,active\n1,bob,9.5,true\n2,alice,10,false\n3,eve,NULL,true\n"), 0644))
+	writeParquetEvents(t, filepath.Join(dir, "events", "events.parquet"))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "logs", "logs.json"),
+		[]byte(`{"id":1,"msg":"hello"}`+"\n"), 0644))
+
+	schema.RegisterSourceAsSchema("testwriter", &writerTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testwriter")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	count := func(sqlText string) int64 {
+		var ct int64
+		assert.Equal(t, nil, db.QueryRow(sqlText).Scan(&ct), sqlText)
+		return ct
+	}
+
+	// csv insert, new part file
+	res, err := db.Exec(`INSERT INTO users (id, name, score, active) VALUES (4, "zed", 11.5, true), (5, 'amy, "a"', NULL, false)`)
+	assert.Equal(t, nil, err)
+	affected, _ := res.RowsAffected()
+	assert.Equal(t, int64(2), affected)
+	assert.Equal(t, 2, len(tableFiles(t, dir, "users")))
+	assert.Equal(t, int64(2), count(`SELECT count(*) AS ct FROM users WHERE score > 9.9`))
+	assert.Equal(t, int64(1), count(`SELECT count(*) AS ct FROM users WHERE name = 'amy, "a"'`))
+
+	// unknown column, nothing written
+	_, err = db.Exec(`INSERT INTO users (id, nickname) VALUES (6, "bad")`)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, 2, len(tableFiles(t, dir, "users")))
+
+	// select into a parquet table
+	res, err = db.Exec(`SELECT id, name, score, active INTO events FROM users WHERE active = true`)
+	assert.Equal(t, nil, err)
+	affected, _ = res.RowsAffected()
+	assert.Equal(t, int64(3), affected)
+	assert.Equal(t, 2, len(tableFiles(t, dir, "events")))
+	assert.Equal(t, int64(33), count(`SELECT count(*) AS ct FROM events`))
+	var score float64
+	assert.Equal(t, nil, db.QueryRow(`SELECT score FROM events WHERE name = "zed"`).Scan(&score))
+	assert.Equal(t, 11.5, score)
+
+	// select into new-line delimited json
+	_, err = db.Exec(`SELECT id, name AS msg INTO logs FROM users WHERE id > 3`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(3), count(`SELECT count(*) AS ct FROM logs`))
+	files := tableFiles(t, dir, "logs")
+	assert.Equal(t, 2, len(files))
+
+	// unknown column of target
+	_, err = db.Exec(`SELECT id, name INTO logs FROM users`)
+	assert.NotEqual(t, nil, err)
+
+	// new files show up in the files table
+	assert.Equal(t, int64(6), count(`SELECT count(*) AS ct FROM testwriter_files`))
+
+	// omitted columns are written as NULL
+	_, err = db.Exec(`INSERT INTO users (id, name) VALUES (7, "kim")`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), count(`SELECT count(*) AS ct FROM users WHERE name = "kim" AND NOT EXISTS score`))
+}
+
+type rootWriterTestSource struct {
+	*FileSource
+	dir string
+}
+
+func (m *rootWriterTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       "testrootwriter",
+		SourceType: "testrootwriter",
+		Settings: u.JsonHelper(map[string]interface{}{
+			"path":      "",
+			"format":    "csv",
+			"type":      "localfs",
+			"localpath": m.dir,
+		}),
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func TestFileWriterRootLayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_rootwriter")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	// tables are file names in the root, no table folders
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "users.csv"),
+		[]byte("id,name\n1,bob\n2,alice\n"), 0644))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "orders.csv"),
+		[]byte("id,user_id\n1,1\n"), 0644))
+
+	schema.RegisterSourceAsSchema("testrootwriter", &rootWriterTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testrootwriter")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO users (id, name) VALUES (3, "eve")`)
+	assert.Equal(t, nil, err)
+
+	files, err := ioutil.ReadDir(dir)
+	assert.Equal(t, nil, err)
+	names := make([]string, 0)
+	for _, f := range files {
+		if !f.IsDir() && !strings.HasSuffix(f.Name(), ".metadata") {
+			names = append(names, f.Name())
+		}
+	}
+	assert.Equal(t, 3, len(names), "new part file written in root %v", names)
+
+	var ct int64
+	assert.Equal(t, nil, db.QueryRow(`SELECT count(*) AS ct FROM users`).Scan(&ct))
+	assert.Equal(t, int64(3), ct)
+	assert.Equal(t, nil, db.QueryRow(`SELECT count(*) AS ct FROM orders`).Scan(&ct))
+	assert.Equal(t, int64(1), ct)
+}
+
+func TestFileTableDDL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlb_ddl")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	assert.Equal(t, nil, os.MkdirAll(filepath.Join(dir, "users"), 0755))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "users", "users.csv"),
+		[]byte("id,name\n1,bob\n"), 0644))
+
+	schema.RegisterSourceAsSchema("testddl", &writerTestSource{FileSource: NewFileSource(), dir: dir})
+
+	db, err := sql.Open("qlbridge", "testddl")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE notes (id int NOT NULL, body varchar(100))`)
+	assert.Equal(t, nil, err)
+	_, err = db.Exec(`INSERT INTO notes (id, body) VALUES (1, "hello"), (2, "world")`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(tableFiles(t, dir, "notes")))
+	var ct int64
+	assert.Equal(t, nil, db.QueryRow(`SELECT count(*) AS ct FROM notes`).Scan(&ct))
+	assert.Equal(t, int64(2), ct)
+
+	_, err = db.Exec(`ALTER TABLE notes ADD COLUMN tag varchar(10)`)
+	assert.Equal(t, nil, err)
+	s, _ := schema.DefaultRegistry().Schema("testddl")
+	tbl, err := s.Table("notes")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "body", "tag"}, tbl.Columns())
+	_, err = db.Exec(`ALTER TABLE notes CHANGE body text varchar(100)`)
+	assert.NotEqual(t, nil, err)
+
+	_, err = db.Exec(`DROP TABLE notes`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(tableFiles(t, dir, "notes")))
+	_, err = s.Table("notes")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestCsvRowWriter(t *testing.T) {
+	tbl := schema.NewTable("users")
+	tbl.AddField(schema.NewFieldBase("id", value.IntType, 64, "int"))
+	tbl.AddField(schema.NewFieldBase("name", value.StringType, 64, "string"))
+	tbl.SetColumns([]string{"id", "name"})
+
+	settings := u.JsonHelper{"csv": map[string]interface{}{"delimiter": "|", "quote": "'", "null": "NULL", "infer": true}}
+	buf := &bytes.Buffer{}
+	w, err := (&csvFiles{}).Writer(buf, tbl, settings)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, w.Write([]driver.Value{int64(1), `it's "x"|y`}))
+	assert.Equal(t, nil, w.Write([]driver.Value{int64(2), nil}))
+	assert.Equal(t, nil, w.Close())
+	assert.Equal(t, "id|name\n1|'it''s \"x\"|y'\n2|NULL\n", buf.String())
+
+	opts, err := csvOptions(settings, "users")
+	assert.Equal(t, nil, err)
+	csvIn, err := datasource.NewCsvSourceOptions("users", 0, buf, make(<-chan bool), opts)
+	assert.Equal(t, nil, err)
+	msg := csvIn.Next().(*datasource.SqlDriverMessageMap)
+	assert.Equal(t, []driver.Value{int64(1), `it's "x"|y`}, msg.Values())
+	msg = csvIn.Next().(*datasource.SqlDriverMessageMap)
+	assert.Equal(t, []driver.Value{int64(2), nil}, msg.Values())
+}
@@ -0,0 +1,63 @@
+package datasource
+
+import (
+	"io"
+	"time"
+)
+
+var (
+	_ io.ReadCloser = (*FollowReader)(nil)
+
+	// FollowPollInterval how long a FollowReader waits at end of
+	// file before trying to read appended data again
+	FollowPollInterval = 250 * time.Millisecond
+)
+
+// FollowReader reads a file (or stdin) which is still being appended to
+// like tail -f.  At EOF it waits for more data instead of returning EOF,
+// only returning EOF once exit is closed.
+//   - partial lines are not a problem as line readers keep waiting for
+//     the rest of the line
+//   - a blocking reader (stdin, pipes) can not be interrupted by exit
+//     until its Read returns
+type FollowReader struct {
+	r    io.Reader
+	exit <-chan bool
+	Poll time.Duration
+}
+
+// NewFollowReader follow @r until @exit is closed
+func NewFollowReader(r io.Reader, exit <-chan bool) *FollowReader {
+	return &FollowReader{r: r, exit: exit, Poll: FollowPollInterval}
+}
+
+// Read implements io.Reader, see FollowReader
+func (m *FollowReader) Read(p []byte) (int, error) {
+	for {
+		select {
+		case <-m.exit:
+			return 0, io.EOF
+		default:
+		}
+		n, err := m.r.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+		select {
+		case <-m.exit:
+			return 0, io.EOF
+		case <-time.After(m.Poll):
+		}
+	}
+}
+
+// Close the underlying reader if it is an io.Closer
+func (m *FollowReader) Close() error {
+	if c, ok := m.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,389 @@
+package datasource
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	// infoSchemaTables the mysql shaped information_schema tables, see
+	// schema.InformationSchema.
+	infoSchemaTables = []string{"schemata", "tables", "columns", "views", "statistics",
+		"table_constraints", "key_column_usage", "routines"}
+
+	// started is the time the Uptime status is counted from
+	started = time.Now()
+)
+
+func init() {
+	for _, name := range infoSchemaTables {
+		defaultSchemaTables = append(defaultSchemaTables, schema.InformationSchema+"."+name)
+	}
+}
+
+// rowsTable a schema table, and the func to build its rows for each
+// query of it.
+type rowsTable func(m *SchemaDb) (*schema.Table, func() [][]driver.Value)
+
+// computedTables are the info-schema tables whose rows are built from
+// the registry each time they are opened.
+var computedTables = map[string]rowsTable{
+	"status":                                 (*SchemaDb).tableForStatus,
+	"functions":                              (*SchemaDb).tableForFunctions,
+	"indexes":                                (*SchemaDb).tableForIndexRows,
+	"keys":                                   (*SchemaDb).tableForIndexRows,
+	"views":                                  (*SchemaDb).tableForViewRows,
+	schema.InformationSchema + ".schemata":   (*SchemaDb).tableForSchemata,
+	schema.InformationSchema + ".tables":     (*SchemaDb).tableForInfoTables,
+	schema.InformationSchema + ".columns":    (*SchemaDb).tableForInfoColumns,
+	schema.InformationSchema + ".views":      (*SchemaDb).tableForInfoViews,
+	schema.InformationSchema + ".statistics": (*SchemaDb).tableForStatistics,
+	schema.InformationSchema + ".table_constraints": (*SchemaDb).tableForConstraints,
+	schema.InformationSchema + ".key_column_usage":  (*SchemaDb).tableForKeyColumnUsage,
+	schema.InformationSchema + ".routines":          (*SchemaDb).tableForRoutines,
+}
+
+// newInfoTable a table of string columns @cols, other than those typed
+// in @types.
+func newInfoTable(name string, cols []string, types map[string]value.ValueType) *schema.Table {
+	t := schema.NewTable(name)
+	for _, col := range cols {
+		vt, ok := types[col]
+		if !ok {
+			vt = value.StringType
+		}
+		t.AddField(schema.NewFieldBase(col, vt, 64, vt.String()))
+	}
+	t.SetColumns(cols)
+	return t
+}
+
+// schemas the schemas described by information_schema, all of those in
+// the registry as well as this one.
+func (m *SchemaDb) schemas() []*schema.Schema {
+	names := registry.Schemas()
+	sort.Strings(names)
+	schemas := make([]*schema.Schema, 0, len(names)+1)
+	hasOwn := false
+	for _, name := range names {
+		if s, ok := registry.Schema(name); ok && s != nil {
+			schemas = append(schemas, s)
+			hasOwn = hasOwn || s == m.s
+		}
+	}
+	if !hasOwn && m.s != nil {
+		schemas = append(schemas, m.s)
+	}
+	return schemas
+}
+
+// eachTable calls @fn for each table of each schema, in order.
+func (m *SchemaDb) eachTable(fn func(s *schema.Schema, tbl *schema.Table)) {
+	for _, s := range m.schemas() {
+		names := append([]string(nil), s.Tables()...)
+		sort.Strings(names)
+		for _, name := range names {
+			tbl, _ := s.Table(name)
+			if tbl == nil {
+				continue
+			}
+			if s == m.s && len(tbl.Columns()) > 0 && len(tbl.Fields) == 0 {
+				m.inspect(tbl.Name)
+			}
+			fn(s, tbl)
+		}
+	}
+}
+
+// tableFields the fields of @tbl, or untyped fields of its columns if
+// it has not been described.
+func tableFields(tbl *schema.Table) []*schema.Field {
+	if len(tbl.Fields) > 0 {
+		return tbl.Fields
+	}
+	fields := make([]*schema.Field, len(tbl.Columns()))
+	for i, col := range tbl.Columns() {
+		fields[i] = schema.NewFieldBase(col, value.UnknownType, 0, "")
+	}
+	return fields
+}
+
+// indexName the mysql name of @idx.
+func indexName(idx *schema.Index) string {
+	if idx.PrimaryKey {
+		return "PRIMARY"
+	}
+	return idx.Name
+}
+
+// uniqueIndex is @idx a PRIMARY KEY or UNIQUE constraint.
+func uniqueIndex(idx *schema.Index) bool {
+	return idx.PrimaryKey || idx.Unique
+}
+
+func (m *SchemaDb) tableForStatus() (*schema.Table, func() [][]driver.Value) {
+	t, _ := m.tableForVariables("status")
+	return t, func() [][]driver.Value {
+		var tableCt int64
+		m.eachTable(func(*schema.Schema, *schema.Table) { tableCt++ })
+		uptime := int64(time.Since(started) / time.Second)
+		rows := [][]driver.Value{
+			{"Open_tables", tableCt},
+			{"Uptime", uptime},
+			{"Uptime_since_flush_status", uptime},
+		}
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForFunctions() (*schema.Table, func() [][]driver.Value) {
+	t, _ := m.tableForProcedures("functions")
+	return t, func() [][]driver.Value {
+		funcs := expr.Funcs()
+		rows := make([][]driver.Value, len(funcs))
+		for i, fn := range funcs {
+			comment := ""
+			if fn.Aggregate {
+				comment = "aggregate"
+			}
+			rows[i] = []driver.Value{m.s.Name, fn.Name, "FUNCTION", "", nil, nil, "DEFINER",
+				comment, "utf8", "utf8_general_ci", "utf8_general_ci"}
+		}
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForIndexRows() (*schema.Table, func() [][]driver.Value) {
+	t, _ := m.tableForIndexes()
+	return t, func() [][]driver.Value {
+		rows := make([][]driver.Value, 0)
+		for _, name := range m.s.Tables() {
+			tbl, _ := m.s.Table(name)
+			if tbl == nil {
+				continue
+			}
+			for _, idx := range tbl.Indexes {
+				for i, col := range idx.Fields {
+					null := "YES"
+					if f, ok := tbl.FieldMap[col]; ok && f.NoNulls {
+						null = ""
+					}
+					// Table, Non_unique, Key_name, Seq_in_index, Column_name, Collation, Cardinality,
+					// Sub_part, Packed, Null, Index_type, Index_comment
+					rows = append(rows, []driver.Value{tbl.Name, !uniqueIndex(idx), indexName(idx), int64(i + 1), col, "A",
+						nil, nil, nil, null, "BTREE", ""})
+				}
+			}
+		}
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForViewRows() (*schema.Table, func() [][]driver.Value) {
+	t := schema.NewTable("views")
+	t.AddField(schema.NewFieldBase("Table", value.StringType, 64, "string"))
+	t.AddField(schema.NewFieldBase("Table_type", value.StringType, 64, "string"))
+	t.AddField(schema.NewFieldBase("View_definition", value.StringType, 1024, "string"))
+	t.AddField(schema.NewFieldBase("Refresh_interval", value.StringType, 64, "string"))
+	t.SetColumns(schema.ShowViewColumns)
+	return t, func() [][]driver.Value {
+		rows := make([][]driver.Value, 0)
+		for _, tableName := range m.s.Tables() {
+			tbl, _ := m.s.Table(tableName)
+			if tbl == nil || tbl.View == nil {
+				continue
+			}
+			interval := ""
+			if tbl.View.RefreshInterval > 0 {
+				interval = tbl.View.RefreshInterval.String()
+			}
+			rows = append(rows, []driver.Value{tableName, tbl.TableType(), tbl.View.Sql, interval})
+		}
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForSchemata() (*schema.Table, func() [][]driver.Value) {
+	t := newInfoTable(schema.InformationSchema+".schemata", []string{"CATALOG_NAME", "SCHEMA_NAME",
+		"DEFAULT_CHARACTER_SET_NAME", "DEFAULT_COLLATION_NAME", "SQL_PATH"}, nil)
+	return t, func() [][]driver.Value {
+		schemas := m.schemas()
+		rows := make([][]driver.Value, 0, len(schemas)+1)
+		rows = append(rows, []driver.Value{"def", schema.InformationSchema, "utf8", "utf8_general_ci", nil})
+		for _, s := range schemas {
+			rows = append(rows, []driver.Value{"def", s.Name, "utf8", "utf8_general_ci", nil})
+		}
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForInfoTables() (*schema.Table, func() [][]driver.Value) {
+	t := newInfoTable(schema.InformationSchema+".tables", []string{"TABLE_CATALOG", "TABLE_SCHEMA",
+		"TABLE_NAME", "TABLE_TYPE", "ENGINE", "TABLE_ROWS", "TABLE_COLLATION", "TABLE_COMMENT"},
+		map[string]value.ValueType{"TABLE_ROWS": value.IntType})
+	return t, func() [][]driver.Value {
+		rows := make([][]driver.Value, 0)
+		m.eachTable(func(s *schema.Schema, tbl *schema.Table) {
+			if tbl.View != nil {
+				rows = append(rows, []driver.Value{"def", s.Name, tbl.Name, "VIEW", nil, nil, nil, tbl.TableType()})
+				return
+			}
+			engine := ""
+			if ss, err := s.SchemaForTable(tbl.Name); err == nil && ss.Conf != nil {
+				engine = ss.Conf.SourceType
+			}
+			rows = append(rows, []driver.Value{"def", s.Name, tbl.Name, "BASE TABLE", engine, nil, "utf8_general_ci", ""})
+		})
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForInfoColumns() (*schema.Table, func() [][]driver.Value) {
+	t := newInfoTable(schema.InformationSchema+".columns", []string{"TABLE_CATALOG", "TABLE_SCHEMA",
+		"TABLE_NAME", "COLUMN_NAME", "ORDINAL_POSITION", "COLUMN_DEFAULT", "IS_NULLABLE", "DATA_TYPE",
+		"CHARACTER_MAXIMUM_LENGTH", "COLLATION_NAME", "COLUMN_TYPE", "COLUMN_KEY", "EXTRA", "COLUMN_COMMENT"},
+		map[string]value.ValueType{"ORDINAL_POSITION": value.IntType, "CHARACTER_MAXIMUM_LENGTH": value.IntType})
+	return t, func() [][]driver.Value {
+		rows := make([][]driver.Value, 0)
+		m.eachTable(func(s *schema.Schema, tbl *schema.Table) {
+			for i, f := range tableFields(tbl) {
+				dataType, colType := mysqlColumnType(f)
+				nullable := "YES"
+				if f.NoNulls {
+					nullable = "NO"
+				}
+				var def, maxLen, collation driver.Value
+				if dv := f.DefaultValue(); dv != nil {
+					def = fmt.Sprintf("%v", dv)
+				}
+				if f.ValueType() == value.StringType {
+					maxLen, collation = int64(mysqlVarcharLen(f)), "utf8_general_ci"
+				}
+				rows = append(rows, []driver.Value{"def", s.Name, tbl.Name, f.Name, int64(i + 1), def, nullable,
+					dataType, maxLen, collation, colType, f.Key, strings.ToLower(f.Extra), f.Description})
+			}
+		})
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForInfoViews() (*schema.Table, func() [][]driver.Value) {
+	t := newInfoTable(schema.InformationSchema+".views", []string{"TABLE_CATALOG", "TABLE_SCHEMA",
+		"TABLE_NAME", "VIEW_DEFINITION", "CHECK_OPTION", "IS_UPDATABLE", "DEFINER", "SECURITY_TYPE",
+		"CHARACTER_SET_CLIENT", "COLLATION_CONNECTION"}, nil)
+	return t, func() [][]driver.Value {
+		rows := make([][]driver.Value, 0)
+		m.eachTable(func(s *schema.Schema, tbl *schema.Table) {
+			if tbl.View == nil {
+				return
+			}
+			rows = append(rows, []driver.Value{"def", s.Name, tbl.Name, tbl.View.Sql, "NONE", "NO", "",
+				"DEFINER", "utf8", "utf8_general_ci"})
+		})
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForStatistics() (*schema.Table, func() [][]driver.Value) {
+	t := newInfoTable(schema.InformationSchema+".statistics", []string{"TABLE_CATALOG", "TABLE_SCHEMA",
+		"TABLE_NAME", "NON_UNIQUE", "INDEX_SCHEMA", "INDEX_NAME", "SEQ_IN_INDEX", "COLUMN_NAME",
+		"COLLATION", "CARDINALITY", "SUB_PART", "PACKED", "NULLABLE", "INDEX_TYPE", "COMMENT", "INDEX_COMMENT"},
+		map[string]value.ValueType{"NON_UNIQUE": value.IntType, "SEQ_IN_INDEX": value.IntType,
+			"CARDINALITY": value.IntType, "SUB_PART": value.IntType})
+	return t, func() [][]driver.Value {
+		rows := make([][]driver.Value, 0)
+		m.eachTable(func(s *schema.Schema, tbl *schema.Table) {
+			for _, idx := range tbl.Indexes {
+				nonUnique := int64(1)
+				if uniqueIndex(idx) {
+					nonUnique = 0
+				}
+				for i, col := range idx.Fields {
+					nullable := "YES"
+					if f, ok := tbl.FieldMap[col]; ok && f.NoNulls {
+						nullable = ""
+					}
+					rows = append(rows, []driver.Value{"def", s.Name, tbl.Name, nonUnique, s.Name, indexName(idx), int64(i + 1),
+						col, "A", nil, nil, nil, nullable, "BTREE", "", ""})
+				}
+			}
+		})
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForConstraints() (*schema.Table, func() [][]driver.Value) {
+	t := newInfoTable(schema.InformationSchema+".table_constraints", []string{"CONSTRAINT_CATALOG",
+		"CONSTRAINT_SCHEMA", "CONSTRAINT_NAME", "TABLE_SCHEMA", "TABLE_NAME", "CONSTRAINT_TYPE"}, nil)
+	return t, func() [][]driver.Value {
+		rows := make([][]driver.Value, 0)
+		m.eachTable(func(s *schema.Schema, tbl *schema.Table) {
+			for _, idx := range tbl.Indexes {
+				if !uniqueIndex(idx) {
+					// a plain index is not a constraint
+					continue
+				}
+				kind := "UNIQUE"
+				if idx.PrimaryKey {
+					kind = "PRIMARY KEY"
+				}
+				rows = append(rows, []driver.Value{"def", s.Name, indexName(idx), s.Name, tbl.Name, kind})
+			}
+		})
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForKeyColumnUsage() (*schema.Table, func() [][]driver.Value) {
+	t := newInfoTable(schema.InformationSchema+".key_column_usage", []string{"CONSTRAINT_CATALOG",
+		"CONSTRAINT_SCHEMA", "CONSTRAINT_NAME", "TABLE_CATALOG", "TABLE_SCHEMA", "TABLE_NAME",
+		"COLUMN_NAME", "ORDINAL_POSITION", "POSITION_IN_UNIQUE_CONSTRAINT", "REFERENCED_TABLE_SCHEMA",
+		"REFERENCED_TABLE_NAME", "REFERENCED_COLUMN_NAME"},
+		map[string]value.ValueType{"ORDINAL_POSITION": value.IntType, "POSITION_IN_UNIQUE_CONSTRAINT": value.IntType})
+	return t, func() [][]driver.Value {
+		rows := make([][]driver.Value, 0)
+		m.eachTable(func(s *schema.Schema, tbl *schema.Table) {
+			for _, idx := range tbl.Indexes {
+				if !uniqueIndex(idx) {
+					continue
+				}
+				for i, col := range idx.Fields {
+					// there are no foreign keys, so nothing is referenced
+					rows = append(rows, []driver.Value{"def", s.Name, indexName(idx), "def", s.Name, tbl.Name,
+						col, int64(i + 1), nil, nil, nil, nil})
+				}
+			}
+		})
+		return rows
+	}
+}
+
+func (m *SchemaDb) tableForRoutines() (*schema.Table, func() [][]driver.Value) {
+	t := newInfoTable(schema.InformationSchema+".routines", []string{"SPECIFIC_NAME", "ROUTINE_CATALOG",
+		"ROUTINE_SCHEMA", "ROUTINE_NAME", "ROUTINE_TYPE", "DATA_TYPE", "ROUTINE_BODY", "ROUTINE_DEFINITION",
+		"IS_DETERMINISTIC", "SQL_DATA_ACCESS", "SECURITY_TYPE", "ROUTINE_COMMENT"}, nil)
+	return t, func() [][]driver.Value {
+		funcs := expr.Funcs()
+		rows := make([][]driver.Value, len(funcs))
+		for i, fn := range funcs {
+			dataType := "text"
+			if fn.CustomFunc != nil {
+				dataType = MysqlValueString(fn.Type())
+			}
+			comment := ""
+			if fn.Aggregate {
+				comment = "aggregate"
+			}
+			rows[i] = []driver.Value{fn.Name, "def", m.s.Name, fn.Name, "FUNCTION", dataType, "EXTERNAL", nil,
+				"NO", "NO SQL", "DEFINER", comment}
+		}
+		return rows
+	}
+}
@@ -3,6 +3,7 @@
 package membtree
 
 import (
+	"bytes"
 	"database/sql/driver"
 	"fmt"
 
@@ -25,28 +26,34 @@ const (
 
 var (
 	// Different Features of this Static Data Source
-	_ schema.Source       = (*StaticDataSource)(nil)
-	_ schema.Conn         = (*StaticDataSource)(nil)
-	_ schema.ConnColumns  = (*StaticDataSource)(nil)
-	_ schema.ConnScanner  = (*StaticDataSource)(nil)
-	_ schema.ConnSeeker   = (*StaticDataSource)(nil)
-	_ schema.ConnUpsert   = (*StaticDataSource)(nil)
-	_ schema.ConnDeletion = (*StaticDataSource)(nil)
+	_ schema.Source        = (*StaticDataSource)(nil)
+	_ schema.Conn          = (*StaticDataSource)(nil)
+	_ schema.ConnColumns   = (*StaticDataSource)(nil)
+	_ schema.ConnScanner   = (*StaticDataSource)(nil)
+	_ schema.ConnSeeker    = (*StaticDataSource)(nil)
+	_ schema.ConnSeekerKey = (*StaticDataSource)(nil)
+	_ schema.ConnLength    = (*StaticDataSource)(nil)
+	_ schema.ConnUpsert    = (*StaticDataSource)(nil)
+	_ schema.ConnDeletion  = (*StaticDataSource)(nil)
+
+	_ schema.ConnRangeScanner = (*StaticDataSource)(nil)
 )
 
-// Key implements Key and Sort interfaces.
+// Key implements Key and Sort interfaces.  The tree is sorted by the
+// ordered encoding of the indexed column value, Id is its hash.
 type Key struct {
-	Id uint64
+	Id  uint64
+	key []byte // ordered key, see orderedKey()
 }
 
-func NewKey(key uint64) *Key     { return &Key{key} }
+func NewKey(key uint64) *Key     { return &Key{Id: key} }
 func (m *Key) Key() driver.Value { return driver.Value(m.Id) }
 func (m *Key) Less(than btree.Item) bool {
 	switch it := than.(type) {
 	case *DriverItem:
-		return m.Id < it.IdVal
+		return bytes.Compare(m.key, it.key) < 0
 	case *Key:
-		return m.Id < it.Id
+		return bytes.Compare(m.key, it.key) < 0
 	default:
 		u.Warnf("what type? %T", than)
 	}
@@ -55,16 +62,16 @@ func (m *Key) Less(than btree.Item) bool {
 
 type DriverItem struct {
 	*datasource.SqlDriverMessageMap
+	key []byte // ordered key of the indexed column
 }
 
 func (m *DriverItem) Less(than btree.Item) bool {
 
 	switch it := than.(type) {
 	case *DriverItem:
-		//u.Infof("Less? %p:%p less?%v gt?%v  %v vs %v   thanT:%T", m, than, m.IdVal < it.IdVal, m.IdVal > it.IdVal, m.IdVal, it.IdVal, than)
-		return m.IdVal < it.IdVal
+		return bytes.Compare(m.key, it.key) < 0
 	case *Key:
-		return m.IdVal < it.Id
+		return bytes.Compare(m.key, it.key) < 0
 	default:
 		u.Warnf("what type? %T", than)
 	}
@@ -111,6 +118,7 @@ func makeId(dv driver.Value) uint64 {
 //
 // Features
 // - only a single column may (and must) be identified as the "Indexed" column
+// - rows are ordered by the indexed column, allowing range scans on it
 // - NOT threadsafe
 // - each StaticDataSource = a single Table
 type StaticDataSource struct {
@@ -119,7 +127,10 @@ type StaticDataSource struct {
 	tbl      *schema.Table
 	indexCol int        // Which column position is indexed?  ie primary key
 	cursor   btree.Item // cursor position for paging
+	scan     *keyScan   // range of the current scan, nil is all rows ascending
 	bt       *btree.BTree
+	kinds    map[byte]int              // count of keys by kind, see orderedKey()
+	wal      *datasource.WriteAheadLog // optional log of writes
 	max      int
 }
 
@@ -155,7 +166,7 @@ func NewStaticData(name string) *StaticDataSource {
 
 func (m *StaticDataSource) Init()                                     {}
 func (m *StaticDataSource) Setup(*schema.Schema) error                { return nil }
-func (m *StaticDataSource) Open(connInfo string) (schema.Conn, error) { return m, nil }
+func (m *StaticDataSource) Open(connInfo string) (schema.Conn, error) { m.scan = nil; return m, nil }
 func (m *StaticDataSource) Table(table string) (*schema.Table, error) { return m.tbl, nil }
 func (m *StaticDataSource) Close() error                              { return nil }
 func (m *StaticDataSource) CreateIterator() schema.Iterator           { return m }
@@ -164,6 +175,147 @@ func (m *StaticDataSource) Columns() []string                         { return m
 func (m *StaticDataSource) Length() int                               { return m.bt.Len() }
 func (m *StaticDataSource) SetColumns(cols []string)                  { m.tbl.SetColumns(cols) }
 
+// SeekColumn the indexed column Get() seeks on
+func (m *StaticDataSource) SeekColumn() string { return m.tbl.Columns()[m.indexCol] }
+
+// KeyColumn the indexed column rows are ordered by
+func (m *StaticDataSource) KeyColumn() string { return m.tbl.Columns()[m.indexCol] }
+
+// RangeScan narrow the next scan to the range @r of the indexed column.
+// The keys are encoded from the values stored, which may not be of the
+// declared type of the column (such as the strings of a csv int column),
+// or of mixed kinds.  Those don't sort as the column does, so the scan is
+// left unchanged and schema.ErrNotImplemented returned.
+func (m *StaticDataSource) RangeScan(r *schema.KeyRange) error {
+	m.scan = nil
+	m.cursor = nil
+	vt, ok := m.keyType()
+	if !ok {
+		return schema.ErrNotImplemented
+	}
+	s := &keyScan{desc: r.Desc}
+	if vt == value.StringType && !(r.StartInclusive && r.EndInclusive && r.Start != nil && r.Start == r.End) {
+		// strings are not compared in order by where clauses, so only
+		// equality and prefixes narrow the scan
+		r = &schema.KeyRange{Prefix: r.Prefix, Desc: r.Desc}
+	}
+	if r.Start != nil {
+		var rounded bool
+		if s.lower, rounded = boundKey(vt, r.Start, true); s.lower != nil {
+			s.lowerInc = r.StartInclusive || rounded
+		}
+	}
+	if r.End != nil {
+		var rounded bool
+		if s.upper, rounded = boundKey(vt, r.End, false); s.upper != nil {
+			s.upperInc = r.EndInclusive || rounded
+		}
+	}
+	if r.Prefix != "" && (vt == value.StringType || vt == value.UnknownType) {
+		s.prefix = orderedKey(r.Prefix)
+	}
+	m.scan = s
+	return nil
+}
+
+// keyType the type the stored keys sort as, false if they are of more
+// than one kind or not of the declared type of the key column.
+func (m *StaticDataSource) keyType() (value.ValueType, bool) {
+	declared, _ := m.tbl.Column(m.KeyColumn())
+	vt := value.UnknownType
+	for kind, ct := range m.kinds {
+		if ct <= 0 || kind == keyNil {
+			continue
+		}
+		var kt value.ValueType
+		switch kind {
+		case keyBool:
+			kt = value.BoolType
+		case keyInt, keyUint:
+			kt = value.IntType
+		case keyFloat:
+			kt = value.NumberType
+		case keyTime:
+			kt = value.TimeType
+		case keyString:
+			kt = value.StringType
+		default:
+			return value.UnknownType, false
+		}
+		if vt != value.UnknownType && vt != kt {
+			return value.UnknownType, false
+		}
+		vt = kt
+	}
+	switch {
+	case vt == value.UnknownType:
+		// no rows, any bounds are fine
+		return declared, true
+	case declared != value.UnknownType && declared != vt:
+		return value.UnknownType, false
+	}
+	return vt, true
+}
+
+// insert @item replacing any item of the same key.
+func (m *StaticDataSource) insert(item *DriverItem) {
+	if prev := m.bt.ReplaceOrInsert(item); prev != nil {
+		m.countKind(prev.(*DriverItem).key, -1)
+	}
+	m.countKind(item.key, 1)
+}
+
+func (m *StaticDataSource) countKind(key []byte, n int) {
+	if len(key) == 0 {
+		return
+	}
+	if m.kinds == nil {
+		m.kinds = make(map[byte]int)
+	}
+	m.kinds[key[0]] += n
+}
+
+// nextItem the item after the cursor in scan order, nil when there are
+// no more in the scan range.
+func (m *StaticDataSource) nextItem() btree.Item {
+	s := m.scan
+	if s == nil {
+		s = &keyScan{}
+	}
+	var item *DriverItem
+	visit := func(a btree.Item) bool {
+		if m.cursor == a {
+			return true
+		}
+		di := a.(*DriverItem)
+		if (!s.desc && s.below(di.key)) || (s.desc && s.above(di.key)) {
+			return true
+		}
+		item = di
+		return false // stop after this
+	}
+	switch {
+	case !s.desc && m.cursor != nil:
+		m.bt.AscendGreaterOrEqual(m.cursor, visit)
+	case !s.desc && s.lower != nil:
+		m.bt.AscendGreaterOrEqual(&Key{key: s.lower}, visit)
+	case !s.desc && s.prefix != nil:
+		m.bt.AscendGreaterOrEqual(&Key{key: s.prefix}, visit)
+	case !s.desc:
+		m.bt.Ascend(visit)
+	case m.cursor != nil:
+		m.bt.DescendLessOrEqual(m.cursor, visit)
+	case s.upper != nil:
+		m.bt.DescendLessOrEqual(&Key{key: s.upper}, visit)
+	default:
+		m.bt.Descend(visit)
+	}
+	if item == nil || (!s.desc && s.above(item.key)) || (s.desc && s.below(item.key)) {
+		return nil
+	}
+	return item
+}
+
 func (m *StaticDataSource) Next() schema.Message {
 	//u.Infof("Next()")
 	select {
@@ -171,36 +323,16 @@ func (m *StaticDataSource) Next() schema.Message {
 		return nil
 	default:
 		for {
-			var item btree.Item
-
 			if m.cursor == nil {
-				//u.Infof("create new Ascend len=%d", m.Length())
 				m.max = 0
-				m.bt.Ascend(func(a btree.Item) bool {
-					item = a
-					//u.Debugf("first  item btreeP:%p itemP:%p cursorP:%p  %#v", m, item, m.cursor, item)
-					return false // stop after this
-				})
-			} else {
-				m.bt.AscendGreaterOrEqual(m.cursor, func(a btree.Item) bool {
-					if m.cursor == a {
-						//u.Debugf("equal, return true ie continue")
-						item = nil
-						return true
-					}
-					item = a
-					//u.Debugf("found  item btreeP:%p itemP:%p cursorP:%p  %#v", m, item, m.cursor, item)
-					return false // stop after this
-				})
 			}
+			item := m.nextItem()
 			m.max++
-			// if m.max > 20 {
-			// 	return nil
-			// }
 
 			if item == nil {
 				//u.Debugf("reset cursor to nil  %#v", item)
 				m.cursor = nil
+				m.scan = nil
 				return nil
 			}
 			m.cursor = item
@@ -225,12 +357,13 @@ func (m *StaticDataSource) Put(ctx context.Context, key schema.Key, row interfac
 		}
 		id := makeId(rowVals[m.indexCol])
 		sdm := datasource.NewSqlDriverMessageMap(id, rowVals, m.tbl.FieldPositions)
-		item := DriverItem{sdm}
-		itemResult := m.bt.ReplaceOrInsert(&item)
-		if itemResult != nil {
-			//u.Errorf("could not insert? %#v", itemResult)
-		}
+		m.insert(&DriverItem{sdm, orderedKey(rowVals[m.indexCol])})
 		//u.Debugf("%p  PUT: id:%v IdVal:%v  Id():%v vals:%#v", m, id, sdm.IdVal, sdm.Id(), rowVals)
+		if m.wal != nil {
+			if err := m.wal.Append(datasource.WalPut, rowVals...); err != nil {
+				return nil, err
+			}
+		}
 		return NewKey(id), nil
 	case map[string]driver.Value:
 		// We need to convert the key:value to []driver.Value so
@@ -251,6 +384,7 @@ func (m *StaticDataSource) Put(ctx context.Context, key schema.Key, row interfac
 			}
 		}
 		id := uint64(0)
+		var ord []byte
 		if key == nil {
 			if row[m.indexCol] == nil {
 				// Since we do not have an indexed column to work off of,
@@ -261,8 +395,14 @@ func (m *StaticDataSource) Put(ctx context.Context, key schema.Key, row interfac
 				return nil, fmt.Errorf("cannot update on non index column ")
 			}
 			id = makeId(row[m.indexCol])
+			ord = orderedKey(row[m.indexCol])
 		} else {
 			id = makeId(key)
+			if k := m.itemKey(key); k != nil {
+				ord = k.key
+			} else {
+				ord = orderedKey(row[m.indexCol])
+			}
 			sdm, _ := m.Get(key)
 			//u.Debugf("sdm: %#v  err%v", sdm, err)
 			if sdm != nil {
@@ -278,8 +418,12 @@ func (m *StaticDataSource) Put(ctx context.Context, key schema.Key, row interfac
 		//u.Debugf("PUT: %#v", row)
 		//u.Infof("PUT: %v  key:%v  row:%v", id, key, row)
 		sdm := datasource.NewSqlDriverMessageMap(id, row, m.tbl.FieldPositions)
-		item := DriverItem{sdm}
-		m.bt.ReplaceOrInsert(&item)
+		m.insert(&DriverItem{sdm, ord})
+		if m.wal != nil {
+			if err := m.wal.Append(datasource.WalPut, row...); err != nil {
+				return nil, err
+			}
+		}
 		return NewKey(id), nil
 	default:
 		u.Warnf("not implemented %T", row)
@@ -287,14 +431,55 @@ func (m *StaticDataSource) Put(ctx context.Context, key schema.Key, row interfac
 	}
 }
 
+// PutMulti put each row of @src ([][]driver.Value) in turn
 func (m *StaticDataSource) PutMulti(ctx context.Context, keys []schema.Key, src interface{}) ([]schema.Key, error) {
-	return nil, fmt.Errorf("not implemented")
+	rows, ok := src.([][]driver.Value)
+	if !ok {
+		return nil, fmt.Errorf("Expected [][]driver.Value but got %T", src)
+	}
+	out := make([]schema.Key, len(rows))
+	for i, row := range rows {
+		var key schema.Key
+		if i < len(keys) {
+			key = keys[i]
+		}
+		k, err := m.Put(ctx, key, row)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = k
+	}
+	return out, nil
+}
+
+// itemKey the tree key for @key, a value of the indexed column or a Key.
+// A Key of only the hashed Id is found by scanning, nil if not found.
+func (m *StaticDataSource) itemKey(key driver.Value) *Key {
+	switch kt := key.(type) {
+	case *Key:
+		if kt.key != nil {
+			return kt
+		}
+		var found *Key
+		m.bt.Ascend(func(a btree.Item) bool {
+			if di := a.(*DriverItem); di.IdVal == kt.Id {
+				found = &Key{Id: kt.Id, key: di.key}
+				return false
+			}
+			return true
+		})
+		return found
+	case datasource.KeyCol:
+		return m.itemKey(kt.Val)
+	}
+	return &Key{Id: makeId(key), key: orderedKey(key)}
 }
 
 func (m *StaticDataSource) Get(key driver.Value) (schema.Message, error) {
-	item := m.bt.Get(NewKey(makeId(key)))
-	if item != nil {
-		return item.(*DriverItem).SqlDriverMessageMap, nil
+	if k := m.itemKey(key); k != nil {
+		if item := m.bt.Get(k); item != nil {
+			return item.(*DriverItem).SqlDriverMessageMap, nil
+		}
 	}
 	return nil, schema.ErrNotFound // Should not found be an error?
 }
@@ -302,22 +487,32 @@ func (m *StaticDataSource) Get(key driver.Value) (schema.Message, error) {
 func (m *StaticDataSource) MultiGet(keys []driver.Value) ([]schema.Message, error) {
 	rows := make([]schema.Message, len(keys))
 	for i, key := range keys {
-		item := m.bt.Get(NewKey(makeId(key)))
-		if item == nil {
-			return nil, schema.ErrNotFound
+		msg, err := m.Get(key)
+		if err != nil {
+			return nil, err
 		}
-		rows[i] = item.(*DriverItem).SqlDriverMessageMap
+		rows[i] = msg
 	}
 	return rows, nil
 }
 
 // Interface for Deletion
 func (m *StaticDataSource) Delete(key driver.Value) (int, error) {
-	item := m.bt.Delete(NewKey(makeId(key)))
+	k := m.itemKey(key)
+	if k == nil {
+		return 0, schema.ErrNotFound
+	}
+	item := m.bt.Delete(k)
 	if item == nil {
 		//u.Warnf("could not delete: %v", key)
 		return 0, schema.ErrNotFound
 	}
+	m.countKind(item.(*DriverItem).key, -1)
+	if m.wal != nil {
+		if err := m.wal.Append(datasource.WalDelete, item.(*DriverItem).Values()[m.indexCol]); err != nil {
+			return 0, err
+		}
+	}
 	return 1, nil
 }
 
@@ -349,8 +544,7 @@ func (m *StaticDataSource) DeleteExpression(p interface{}, where expr.Node) (int
 				//this means do NOT delete
 			} else {
 				// Delete!
-				indexVal := msgCtx.Values()[m.indexCol]
-				deletedKeys = append(deletedKeys, NewKey(makeId(indexVal)))
+				deletedKeys = append(deletedKeys, &Key{Id: di.IdVal, key: di.key})
 			}
 		case nil:
 			// ??
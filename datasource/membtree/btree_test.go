@@ -1,8 +1,12 @@
 package membtree_test
 
 import (
+	"database/sql"
 	"database/sql/driver"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
@@ -12,10 +16,12 @@ import (
 
 	"github.com/fuhongbo/qlbridge/datasource"
 	"github.com/fuhongbo/qlbridge/datasource/membtree"
+	"github.com/fuhongbo/qlbridge/exec"
 	"github.com/fuhongbo/qlbridge/plan"
 	"github.com/fuhongbo/qlbridge/rel"
 	"github.com/fuhongbo/qlbridge/schema"
 	"github.com/fuhongbo/qlbridge/testutil"
+	"github.com/fuhongbo/qlbridge/value"
 )
 
 const (
@@ -155,3 +161,217 @@ func TestStaticDataSource(t *testing.T) {
 	assert.Equal(t, nil, err)
 	assert.Equal(t, curSize, delCt, "Should have deleted all records")
 }
+
+func scanKeys(static *membtree.StaticDataSource, r *schema.KeyRange) []driver.Value {
+	var keys []driver.Value
+	static.Open("")
+	if r != nil {
+		static.RangeScan(r)
+	}
+	for msg := static.Next(); msg != nil; msg = static.Next() {
+		keys = append(keys, msg.(*datasource.SqlDriverMessageMap).Values()[0])
+	}
+	return keys
+}
+
+func TestRangeScan(t *testing.T) {
+	ints := membtree.NewStaticDataSource("ints", 0, [][]driver.Value{
+		{int64(300), "c"}, {int64(-5), "a"}, {int64(20), "b"}, {int64(4000), "d"}, {int64(1), "e"},
+	}, []string{"id", "name"})
+
+	// ordered by value, not the hash of it
+	assert.Equal(t, []driver.Value{int64(-5), int64(1), int64(20), int64(300), int64(4000)}, scanKeys(ints, nil))
+	assert.Equal(t, []driver.Value{int64(20), int64(300)},
+		scanKeys(ints, &schema.KeyRange{Start: int64(1), End: int64(300), EndInclusive: true}))
+	assert.Equal(t, []driver.Value{int64(1), int64(20)},
+		scanKeys(ints, &schema.KeyRange{Start: int64(1), StartInclusive: true, End: 299.5}))
+	assert.Equal(t, []driver.Value{int64(20), int64(300)},
+		scanKeys(ints, &schema.KeyRange{Start: 1.5, End: 300.0, EndInclusive: true}))
+	assert.Equal(t, []driver.Value{int64(4000), int64(300), int64(20)},
+		scanKeys(ints, &schema.KeyRange{Start: int64(20), StartInclusive: true, Desc: true}))
+	assert.Equal(t, []driver.Value{int64(1), int64(-5)},
+		scanKeys(ints, &schema.KeyRange{End: int64(20), Desc: true}))
+	assert.Equal(t, []driver.Value(nil), scanKeys(ints, &schema.KeyRange{Start: int64(5000)}))
+
+	// the range is only for the one scan
+	assert.Equal(t, 5, len(scanKeys(ints, nil)))
+
+	row, err := ints.Get(20)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "b", row.(*datasource.SqlDriverMessageMap).Values()[1])
+
+	strs := membtree.NewStaticDataSource("strs", 0, [][]driver.Value{
+		{"cat", 1}, {"apple", 2}, {"apricot", 3}, {"b", 4}, {"ap", 5},
+	}, []string{"name", "ct"})
+	assert.Equal(t, []driver.Value{"ap", "apple", "apricot", "b", "cat"}, scanKeys(strs, nil))
+	assert.Equal(t, []driver.Value{"ap", "apple", "apricot"}, scanKeys(strs, &schema.KeyRange{Prefix: "ap"}))
+	assert.Equal(t, []driver.Value{"apricot", "apple", "ap"}, scanKeys(strs, &schema.KeyRange{Prefix: "ap", Desc: true}))
+	// where clauses don't compare strings in order, so only equality narrows
+	assert.Equal(t, 5, len(scanKeys(strs, &schema.KeyRange{Start: "apple", End: "b", EndInclusive: true})))
+	assert.Equal(t, []driver.Value{"apple"},
+		scanKeys(strs, &schema.KeyRange{Start: "apple", StartInclusive: true, End: "apple", EndInclusive: true}))
+
+	// keys of other than the column type, or of mixed kinds, don't sort
+	// as the column so are not range scanned
+	mixed := membtree.NewStaticDataSource("mixed", 0, [][]driver.Value{
+		{int64(3), "a"}, {"2", "b"}, {int64(1), "c"},
+	}, []string{"id", "name"})
+	assert.Equal(t, schema.ErrNotImplemented, mixed.RangeScan(&schema.KeyRange{Start: int64(2)}))
+	assert.Equal(t, 3, len(scanKeys(mixed, nil)))
+}
+
+func TestRangeScanSql(t *testing.T) {
+	created := func(s string) time.Time { return dateparse.MustParse(s).In(time.UTC) }
+	events := membtree.NewStaticDataSource("events", 0, [][]driver.Value{
+		{int64(10), "signup", created("2017-01-01")},
+		{int64(2), "login", created("2017-01-02")},
+		{int64(33), "login", created("2017-01-03")},
+		{int64(7), "logout", created("2017-01-04")},
+		{int64(100), "login", created("2017-01-05")},
+	}, []string{"id", "kind", "created"})
+	assert.Equal(t, nil, schema.RegisterSourceAsSchema("btree_events", events))
+	exec.RegisterSqlDriver()
+
+	db, err := sql.Open("qlbridge", "btree_events")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	ids := func(q string) []int64 {
+		rows, err := db.Query(q)
+		assert.Equal(t, nil, err, q)
+		if err != nil {
+			return nil
+		}
+		defer rows.Close()
+		var out []int64
+		for rows.Next() {
+			var id int64
+			assert.Equal(t, nil, rows.Scan(&id))
+			out = append(out, id)
+		}
+		return out
+	}
+	assert.Equal(t, []int64{7, 10, 33}, ids(`SELECT id FROM events WHERE id > 2 AND id <= 33`))
+	assert.Equal(t, []int64{2, 7}, ids(`SELECT id FROM events WHERE id BETWEEN 1 AND 10`))
+	assert.Equal(t, []int64{2, 33}, ids(`SELECT id FROM events WHERE 40 > id AND kind = "login"`))
+	assert.Equal(t, []int64{100, 33, 10, 7, 2}, ids(`SELECT id FROM events ORDER BY id DESC`))
+	assert.Equal(t, []int64{2, 7, 10, 33, 100}, ids(`SELECT id FROM events ORDER BY id`))
+	assert.Equal(t, []int64{100, 33, 2}, ids(`SELECT id FROM events WHERE kind = "login" ORDER BY id DESC`))
+	assert.Equal(t, []int64{33, 10}, ids(`SELECT id FROM events WHERE id > 7 AND id < 100 ORDER BY id DESC`))
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "membtreesnap")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	snapPath, walPath := filepath.Join(dir, "names.snap"), filepath.Join(dir, "names.wal")
+
+	// keyed on the 2nd column
+	static := membtree.NewStaticDataSource("names", 1, [][]driver.Value{
+		{int64(1), "cat"}, {int64(2), "apple"},
+	}, []string{"ct", "name"})
+	// described as a number, the restored rows would introspect as int
+	tbl, _ := static.Table("names")
+	tbl.FieldMap["ct"].Type = uint32(value.NumberType)
+	assert.Equal(t, nil, static.OpenWriteAheadLog(walPath))
+	_, err = static.Put(nil, nil, []driver.Value{int64(3), "bob"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, static.Snapshot(snapPath))
+	_, err = static.Put(nil, nil, []driver.Value{int64(4), "dan"})
+	assert.Equal(t, nil, err)
+	_, err = static.Delete("cat")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, static.CloseWriteAheadLog())
+
+	restored, err := membtree.NewStaticDataSourceFromSnapshot(snapPath)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "name", restored.KeyColumn())
+	tbl, _ = restored.Table("names")
+	assert.Equal(t, value.NumberType, tbl.FieldMap["ct"].ValueType())
+	assert.Equal(t, value.StringType, tbl.FieldMap["name"].ValueType())
+	assert.Equal(t, 3, restored.Length())
+
+	assert.Equal(t, nil, restored.OpenWriteAheadLog(walPath))
+	defer restored.CloseWriteAheadLog()
+	assert.Equal(t, 3, restored.Length())
+	_, err = restored.Get("cat")
+	assert.Equal(t, schema.ErrNotFound, err)
+	row, err := restored.Get("dan")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(4), row.(*datasource.SqlDriverMessageMap).Values()[0])
+}
+
+func TestWriteAheadLogSql(t *testing.T) {
+	dir, err := ioutil.TempDir("", "membtreewal")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	walPath := filepath.Join(dir, "notes.wal")
+
+	notes := membtree.NewStaticDataSource("notes", 0, [][]driver.Value{
+		{int64(1), "first"},
+	}, []string{"id", "body"})
+	assert.Equal(t, nil, notes.OpenWriteAheadLog(walPath))
+	assert.Equal(t, nil, schema.RegisterSourceAsSchema("btree_notes", notes))
+	exec.RegisterSqlDriver()
+
+	db, err := sql.Open("qlbridge", "btree_notes")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	// each statement opens and closes a conn, the log must stay open
+	for _, q := range []string{
+		`INSERT INTO notes (id, body) VALUES (2, "second")`,
+		`SELECT id FROM notes`,
+		`INSERT INTO notes (id, body) VALUES (3, "third")`,
+	} {
+		_, err = db.Exec(q)
+		assert.Equal(t, nil, err, q)
+	}
+	assert.Equal(t, nil, notes.CloseWriteAheadLog())
+
+	replayed := membtree.NewStaticDataSource("notes", 0, nil, []string{"id", "body"})
+	assert.Equal(t, nil, replayed.OpenWriteAheadLog(walPath))
+	defer replayed.CloseWriteAheadLog()
+	assert.Equal(t, 2, replayed.Length())
+	row, err := replayed.Get(int64(3))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "third", row.(*datasource.SqlDriverMessageMap).Values()[1])
+}
+
+func TestRangeScanMockCsv(t *testing.T) {
+	// mockcsv values are strings of the int and string columns
+	exec.RegisterSqlDriver()
+	db, err := sql.Open("qlbridge", "mockcsv")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	strs := func(q string) []string {
+		rows, err := db.Query(q)
+		assert.Equal(t, nil, err, q)
+		if err != nil {
+			return nil
+		}
+		defer rows.Close()
+		out := make([]string, 0)
+		for rows.Next() {
+			var v string
+			assert.Equal(t, nil, rows.Scan(&v))
+			out = append(out, v)
+		}
+		return out
+	}
+	sorted := func(q string) []string {
+		out := strs(q)
+		sort.Strings(out)
+		return out
+	}
+	// the keys of the int order_id are strings, so are not range scanned
+	assert.Equal(t, []string{"2"}, strs(`SELECT order_id FROM orders WHERE order_id = 2`))
+	assert.Equal(t, []string{"1", "2"}, sorted(`SELECT order_id FROM orders WHERE order_id < 3`))
+	assert.Equal(t, []string{"2", "3"}, sorted(`SELECT order_id FROM orders WHERE order_id >= 2`))
+	assert.Equal(t, []string{"3", "2", "1"}, strs(`SELECT order_id FROM orders ORDER BY order_id DESC`))
+	// strings are scanned by equality, not by range
+	assert.Equal(t, []string{"9Ip1aKbeZe2njCDM", "hT2impsOPUREcVPc", "hT2impsabc345c"},
+		sorted(`SELECT user_id FROM users WHERE user_id > "a"`))
+	assert.Equal(t, []string{"hT2impsOPUREcVPc"}, strs(`SELECT user_id FROM users WHERE user_id = "hT2impsOPUREcVPc"`))
+}
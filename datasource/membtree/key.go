@@ -0,0 +1,194 @@
+package membtree
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/araddon/dateparse"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+// Ordered key encoding, the first byte is the kind of value so keys of
+// different kinds sort by kind, the rest sorts as the value does.
+const (
+	keyNil byte = iota
+	keyBool
+	keyInt
+	keyUint // uints too large for int64, sort after all ints
+	keyFloat
+	keyTime
+	keyString
+	keyOther
+)
+
+// orderedKey encode @dv to bytes that sort in the same order as the value,
+// for ints, floats, strings and times.
+func orderedKey(dv driver.Value) []byte {
+	switch vt := dv.(type) {
+	case nil:
+		return []byte{keyNil}
+	case bool:
+		if vt {
+			return []byte{keyBool, 1}
+		}
+		return []byte{keyBool, 0}
+	case int:
+		return intKey(keyInt, int64(vt))
+	case int8:
+		return intKey(keyInt, int64(vt))
+	case int16:
+		return intKey(keyInt, int64(vt))
+	case int32:
+		return intKey(keyInt, int64(vt))
+	case int64:
+		return intKey(keyInt, vt)
+	case uint:
+		return uintKey(uint64(vt))
+	case uint8:
+		return uintKey(uint64(vt))
+	case uint16:
+		return uintKey(uint64(vt))
+	case uint32:
+		return uintKey(uint64(vt))
+	case uint64:
+		return uintKey(vt)
+	case float32:
+		return floatKey(float64(vt))
+	case float64:
+		return floatKey(vt)
+	case time.Time:
+		return intKey(keyTime, vt.UnixNano())
+	case string:
+		return append([]byte{keyString}, vt...)
+	case []byte:
+		return append([]byte{keyString}, vt...)
+	case datasource.KeyCol:
+		return orderedKey(vt.Val)
+	}
+	return append([]byte{keyOther}, fmt.Sprintf("%v", dv)...)
+}
+
+func intKey(kind byte, v int64) []byte {
+	by := make([]byte, 9)
+	by[0] = kind
+	// flip the sign bit so negatives sort before positives
+	binary.BigEndian.PutUint64(by[1:], uint64(v)^(1<<63))
+	return by
+}
+
+func uintKey(v uint64) []byte {
+	if v <= math.MaxInt64 {
+		return intKey(keyInt, int64(v))
+	}
+	by := make([]byte, 9)
+	by[0] = keyUint
+	binary.BigEndian.PutUint64(by[1:], v)
+	return by
+}
+
+func floatKey(f float64) []byte {
+	bits := math.Float64bits(f)
+	if f >= 0 {
+		bits ^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	by := make([]byte, 9)
+	by[0] = keyFloat
+	binary.BigEndian.PutUint64(by[1:], bits)
+	return by
+}
+
+// boundKey encode a range bound @v as the type @vt of the key column, so a
+// literal compares to the keys stored.  Rounding a fractional number to an
+// int column makes the bound inclusive.  Returns nil if @v can't be
+// converted, ie the bound is dropped and the scan is wider.
+func boundKey(vt value.ValueType, v driver.Value, lower bool) ([]byte, bool) {
+	switch vt {
+	case value.IntType:
+		var f float64
+		switch bv := v.(type) {
+		case int64:
+			return orderedKey(bv), false
+		case float64:
+			f = bv
+		case string:
+			pf, err := strconv.ParseFloat(bv, 64)
+			if err != nil {
+				return nil, false
+			}
+			f = pf
+		default:
+			return nil, false
+		}
+		if f == math.Trunc(f) {
+			return orderedKey(int64(f)), false
+		}
+		// x > 1.5 is x >= 2 and x < 1.5 is x <= 1 for ints
+		if lower {
+			return orderedKey(int64(math.Ceil(f))), true
+		}
+		return orderedKey(int64(math.Floor(f))), true
+	case value.NumberType:
+		switch bv := v.(type) {
+		case int64:
+			return orderedKey(float64(bv)), false
+		case float64:
+			return orderedKey(bv), false
+		case string:
+			pf, err := strconv.ParseFloat(bv, 64)
+			if err != nil {
+				return nil, false
+			}
+			return orderedKey(pf), false
+		}
+		return nil, false
+	case value.TimeType:
+		if s, ok := v.(string); ok {
+			t, err := dateparse.ParseAny(s)
+			if err != nil {
+				return nil, false
+			}
+			return orderedKey(t), false
+		}
+		return nil, false
+	case value.StringType:
+		return orderedKey(fmt.Sprintf("%v", v)), false
+	}
+	return orderedKey(v), false
+}
+
+// keyScan is a range of encoded keys to read, in ascending or descending order.
+type keyScan struct {
+	lower, upper       []byte // nil is unbounded
+	lowerInc, upperInc bool
+	prefix             []byte
+	desc               bool
+}
+
+// below is key @k before the start of the range
+func (s *keyScan) below(k []byte) bool {
+	if s.lower != nil {
+		if c := bytes.Compare(k, s.lower); c < 0 || (c == 0 && !s.lowerInc) {
+			return true
+		}
+	}
+	return s.prefix != nil && bytes.Compare(k, s.prefix) < 0
+}
+
+// above is key @k past the end of the range
+func (s *keyScan) above(k []byte) bool {
+	if s.upper != nil {
+		if c := bytes.Compare(k, s.upper); c > 0 || (c == 0 && !s.upperInc) {
+			return true
+		}
+	}
+	return s.prefix != nil && !bytes.HasPrefix(k, s.prefix) && bytes.Compare(k, s.prefix) > 0
+}
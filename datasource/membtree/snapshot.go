@@ -0,0 +1,68 @@
+package membtree
+
+import (
+	"database/sql/driver"
+
+	"github.com/google/btree"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+// Snapshot writes the columns and rows of this table to file @path
+// atomically.  If a write-ahead log is open it is emptied, as its writes
+// are now in the snapshot.
+func (m *StaticDataSource) Snapshot(path string) error {
+	s := datasource.NewSnapshot(m.tbl, []*schema.Index{{Name: "id", Fields: []string{m.KeyColumn()}, PrimaryKey: true}})
+	m.bt.Ascend(func(a btree.Item) bool {
+		s.Rows = append(s.Rows, a.(*DriverItem).Values())
+		return true
+	})
+	return datasource.SaveSnapshot(path, s, m.wal)
+}
+
+// NewStaticDataSourceFromSnapshot creates a StaticDataSource from the
+// snapshot file @path written by Snapshot().
+func NewStaticDataSourceFromSnapshot(path string) (*StaticDataSource, error) {
+	var m *StaticDataSource
+	err := datasource.RestoreSnapshot(path, func(s *datasource.Snapshot) (*schema.Table, error) {
+		m = NewStaticDataSource(s.Table, s.KeyColumn(), s.Rows, s.Columns)
+		return m.tbl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OpenWriteAheadLog logs each Put and Delete to file @path so writes made
+// since the last Snapshot() survive a restart.  The writes already in the
+// log are replayed first.  The log stays open until CloseWriteAheadLog().
+func (m *StaticDataSource) OpenWriteAheadLog(path string) error {
+	if err := m.CloseWriteAheadLog(); err != nil {
+		return err
+	}
+	wal, err := datasource.OpenWriteAheadLog(path, datasource.WalReplay(
+		func(row []driver.Value) error {
+			_, err := m.Put(nil, nil, row)
+			return err
+		},
+		func(key driver.Value) { m.Delete(key) },
+		nil,
+	))
+	if err != nil {
+		return err
+	}
+	m.wal = wal
+	return nil
+}
+
+// CloseWriteAheadLog stop logging writes and close the log file.
+func (m *StaticDataSource) CloseWriteAheadLog() error {
+	if m.wal == nil {
+		return nil
+	}
+	err := m.wal.Close()
+	m.wal = nil
+	return err
+}
@@ -6,6 +6,8 @@ package memdb
 import (
 	"database/sql/driver"
 	"fmt"
+	"strings"
+	"sync"
 
 	u "github.com/araddon/gou"
 	"github.com/hashicorp/go-memdb"
@@ -24,15 +26,19 @@ const (
 
 var (
 	// Ensure our MemDB implements schema.Source
-	_ schema.Source = (*MemDb)(nil)
+	_ schema.Source             = (*MemDb)(nil)
+	_ schema.SourceIndexCreator = (*MemDb)(nil)
 
 	// Ensure our dbConn implements variety of Connection interfaces.
-	_ schema.Conn         = (*dbConn)(nil)
-	_ schema.ConnColumns  = (*dbConn)(nil)
-	_ schema.ConnScanner  = (*dbConn)(nil)
-	_ schema.ConnUpsert   = (*dbConn)(nil)
-	_ schema.ConnDeletion = (*dbConn)(nil)
-	_ schema.ConnSeeker   = (*dbConn)(nil)
+	_ schema.Conn          = (*dbConn)(nil)
+	_ schema.ConnColumns   = (*dbConn)(nil)
+	_ schema.ConnScanner   = (*dbConn)(nil)
+	_ schema.ConnUpsert    = (*dbConn)(nil)
+	_ schema.ConnDeletion  = (*dbConn)(nil)
+	_ schema.ConnSeeker    = (*dbConn)(nil)
+	_ schema.ConnSeekerKey = (*dbConn)(nil)
+
+	_ schema.ConnIndexScanner = (*dbConn)(nil)
 )
 
 // MemDb implements qlbridge `Source` to allow in-memory native go data
@@ -41,16 +47,19 @@ type MemDb struct {
 	exit           chan bool
 	*schema.Schema                 // schema
 	tbl            *schema.Table   // schema table
+	mu             sync.RWMutex    // protects indexes, db which CreateIndex replaces
 	indexes        []*schema.Index // index descriptions
 	primaryIndex   string
 	db             *memdb.MemDB
+	wal            *datasource.WriteAheadLog // optional log of writes
 	max            int
 }
 type dbConn struct {
-	md     *MemDb
-	db     *memdb.MemDB
-	txn    *memdb.Txn
-	result memdb.ResultIterator
+	md      *MemDb
+	txn     *memdb.Txn
+	result  memdb.ResultIterator
+	scan    *schema.IndexScan // index lookup narrowing Next()
+	scanPos int               // next of scan.Values to look up
 }
 
 // NewMemDbData creates a MemDb with given indexes, columns, and values
@@ -112,6 +121,12 @@ func (m *MemDb) Table(table string) (*schema.Table, error) { return m.tbl, nil }
 // Close this source
 func (m *MemDb) Close() error {
 	defer func() { recover() }()
+	m.mu.Lock()
+	if m.wal != nil {
+		m.wal.Close()
+		m.wal = nil
+	}
+	m.mu.Unlock()
 	close(m.exit)
 	return nil
 }
@@ -119,6 +134,108 @@ func (m *MemDb) Close() error {
 // Tables list, should be single table
 func (m *MemDb) Tables() []string { return []string{m.tbl.Name} }
 
+// CreateIndex adds a secondary index on the fields of @idx, unique if
+// idx.Unique.  The go-memdb schema is fixed once created, so a new db is
+// built with the index and the rows copied over.
+func (m *MemDb) CreateIndex(table string, idx *schema.Index) error {
+	if !strings.EqualFold(table, m.tbl.Name) {
+		return fmt.Errorf("could not find table %q", table)
+	}
+	if idx.Name == "" || len(idx.Fields) == 0 {
+		return fmt.Errorf("index must have a name and fields")
+	}
+	ni := &schema.Index{Name: idx.Name, Unique: idx.Unique}
+	for _, f := range idx.Fields {
+		col := ""
+		for _, c := range m.tbl.Columns() {
+			if strings.EqualFold(c, f) {
+				col = c
+				break
+			}
+		}
+		if col == "" {
+			return fmt.Errorf("could not find column %q for index %q", f, idx.Name)
+		}
+		ni.Fields = append(ni.Fields, col)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.indexes {
+		if strings.EqualFold(existing.Name, ni.Name) {
+			return fmt.Errorf("index %q already exists", ni.Name)
+		}
+	}
+
+	prev := m.indexes
+	m.indexes = append(prev[:len(prev):len(prev)], ni)
+	db, err := memdb.NewMemDB(makeMemDbSchema(m))
+	if err != nil {
+		m.indexes = prev
+		return err
+	}
+	iter, err := m.db.Txn(false).Get(m.tbl.Name, m.primaryIndex)
+	if err != nil {
+		m.indexes = prev
+		return err
+	}
+	txn := db.Txn(true)
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		msg := raw.(*datasource.SqlDriverMessage)
+		if ni.Unique {
+			if err = m.checkUnique(txn, ni, msg.Vals); err != nil {
+				txn.Abort()
+				m.indexes = prev
+				return err
+			}
+		}
+		if err = txn.Insert(m.tbl.Name, msg); err != nil {
+			txn.Abort()
+			m.indexes = prev
+			return err
+		}
+	}
+	txn.Commit()
+	m.db = db
+	// the table describes its indexes, such as for SHOW INDEX
+	for _, existing := range m.tbl.Indexes {
+		if strings.EqualFold(existing.Name, ni.Name) {
+			return nil
+		}
+	}
+	m.tbl.Indexes = append(m.tbl.Indexes, ni)
+	return nil
+}
+
+// checkUnique ensure no other row than @row has the same values for
+// the fields of unique index @idx.
+func (m *MemDb) checkUnique(txn *memdb.Txn, idx *schema.Index, row []driver.Value) error {
+	args := make([]interface{}, 0, len(idx.Fields))
+	for _, f := range idx.Fields {
+		v := row[m.tbl.FieldPositions[f]]
+		if v == nil {
+			return nil
+		}
+		args = append(args, v)
+	}
+	raw, err := txn.First(m.tbl.Name, idx.Name, args...)
+	if err != nil {
+		return err
+	}
+	if existing, ok := raw.(*datasource.SqlDriverMessage); ok &&
+		fmt.Sprintf("%v", existing.Vals[0]) != fmt.Sprintf("%v", row[0]) {
+		return fmt.Errorf("duplicate value %v for unique index %q", args, idx.Name)
+	}
+	return nil
+}
+
+// memDb the current go-memdb, it is replaced when an index is created.
+func (m *MemDb) memDb() *memdb.MemDB {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.db
+}
+
 func (m *MemDb) buildDefaultIndexes() {
 	if len(m.indexes) == 0 {
 		//u.Debugf("no index provided creating on %q", m.tbl.Columns()[0])
@@ -146,15 +263,73 @@ func (m *MemDb) buildDefaultIndexes() {
 //func (m *MemDb) SetColumns(cols []string)                  { m.tbl.SetColumns(cols) }
 
 func newDbConn(mdb *MemDb) *dbConn {
-	c := &dbConn{md: mdb, db: mdb.db}
+	c := &dbConn{md: mdb}
 	return c
 }
 func (m *dbConn) Columns() []string { return m.md.tbl.Columns() }
 func (m *dbConn) Close() error      { return nil }
+
+// SeekColumn the column of the unique primary index Get() seeks on
+func (m *dbConn) SeekColumn() string { return m.md.tbl.Columns()[0] }
+
+// Indexes the primary and secondary indexes of this table
+func (m *dbConn) Indexes() []*schema.Index {
+	m.md.mu.RLock()
+	defer m.md.mu.RUnlock()
+	return append([]*schema.Index(nil), m.md.indexes...)
+}
+
+// IndexScan narrow Next() to the rows found by looking up @scan
+func (m *dbConn) IndexScan(scan *schema.IndexScan) error {
+	// drop repeated values, ie IN (1, 1) so rows are only read once
+	seen := make(map[string]bool, len(scan.Values))
+	vals := make([][]driver.Value, 0, len(scan.Values))
+	for _, row := range scan.Values {
+		if len(row) != len(scan.Index.Fields) {
+			return fmt.Errorf("index %q has %d fields but got %v", scan.Index.Name, len(scan.Index.Fields), row)
+		}
+		key := fmt.Sprintf("%v", row)
+		if !seen[key] {
+			seen[key] = true
+			vals = append(vals, row)
+		}
+	}
+	m.scan = &schema.IndexScan{Index: scan.Index, Values: vals, Prefix: scan.Prefix}
+	m.scanPos = 0
+	m.result = nil
+	return nil
+}
+
+// nextResult the iterator of rows to read, the whole table or the next
+// lookup of an index scan, nil when there are no more.
+func (m *dbConn) nextResult() (memdb.ResultIterator, error) {
+	if m.scan == nil {
+		if m.scanPos > 0 {
+			return nil, nil
+		}
+		m.scanPos++
+		return m.txn.Get(m.md.tbl.Name, m.md.primaryIndex)
+	}
+	if m.scanPos >= len(m.scan.Values) {
+		return nil, nil
+	}
+	row := m.scan.Values[m.scanPos]
+	m.scanPos++
+	args := make([]interface{}, len(row))
+	for i, v := range row {
+		args[i] = v
+	}
+	index := m.scan.Index.Name
+	if m.scan.Prefix {
+		index += "_prefix"
+	}
+	return m.txn.Get(m.md.tbl.Name, index, args...)
+}
+
 func (m *dbConn) Next() schema.Message {
 
 	if m.txn == nil {
-		m.txn = m.db.Txn(false)
+		m.txn = m.md.memDb().Txn(false)
 	}
 	select {
 	case <-m.md.exit:
@@ -162,16 +337,20 @@ func (m *dbConn) Next() schema.Message {
 	default:
 		for {
 			if m.result == nil {
-				result, err := m.txn.Get(m.md.tbl.Name, m.md.primaryIndex)
+				result, err := m.nextResult()
 				if err != nil {
 					u.Errorf("error %v", err)
 					return nil
 				}
+				if result == nil {
+					return nil
+				}
 				m.result = result
 			}
 			raw := m.result.Next()
 			if raw == nil {
-				return nil
+				m.result = nil
+				continue
 			}
 			if msg, ok := raw.(*datasource.SqlDriverMessage); ok {
 				return msg.ToMsgMap(m.md.tbl.FieldPositions)
@@ -187,13 +366,20 @@ func (m *dbConn) Put(ctx context.Context, key schema.Key, row interface{}) (sche
 
 	switch rowVals := row.(type) {
 	case []driver.Value:
-		txn := m.db.Txn(true)
+		m.md.mu.RLock()
+		defer m.md.mu.RUnlock()
+		txn := m.md.db.Txn(true)
 		key, err := m.putValues(txn, rowVals)
 		if err != nil {
 			txn.Abort()
 			return nil, err
 		}
 		txn.Commit()
+		if m.md.wal != nil {
+			if err = m.md.wal.Append(datasource.WalPut, rowVals...); err != nil {
+				return nil, err
+			}
+		}
 		return key, nil
 	default:
 		return nil, fmt.Errorf("Expected []driver.Value but got %T", row)
@@ -205,6 +391,15 @@ func (m *dbConn) putValues(txn *memdb.Txn, row []driver.Value) (schema.Key, erro
 		u.Warnf("wrong column ct expected %d got %d for %v", len(m.Columns()), len(row), row)
 		return nil, fmt.Errorf("Wrong number of columns, expected %v got %v", len(m.Columns()), len(row))
 	}
+	for _, idx := range m.md.indexes {
+		// the primary index is unique in go-memdb itself
+		if !idx.Unique || idx.PrimaryKey {
+			continue
+		}
+		if err := m.md.checkUnique(txn, idx, row); err != nil {
+			return nil, err
+		}
+	}
 	id := makeId(row[0])
 	msg := &datasource.SqlDriverMessage{Vals: row, IdVal: id}
 	if err := txn.Insert(m.md.tbl.Name, msg); err != nil {
@@ -214,7 +409,9 @@ func (m *dbConn) putValues(txn *memdb.Txn, row []driver.Value) (schema.Key, erro
 }
 
 func (m *dbConn) PutMulti(ctx context.Context, keys []schema.Key, objs interface{}) ([]schema.Key, error) {
-	txn := m.db.Txn(true)
+	m.md.mu.RLock()
+	defer m.md.mu.RUnlock()
+	txn := m.md.db.Txn(true)
 
 	switch rows := objs.(type) {
 	case [][]driver.Value:
@@ -228,13 +425,20 @@ func (m *dbConn) PutMulti(ctx context.Context, keys []schema.Key, objs interface
 			keys = append(keys, key)
 		}
 		txn.Commit()
+		if m.md.wal != nil {
+			for _, row := range rows {
+				if err := m.md.wal.Append(datasource.WalPut, row...); err != nil {
+					return nil, err
+				}
+			}
+		}
 		return keys, nil
 	}
 	return nil, fmt.Errorf("unrecognized put object type: %T", objs)
 }
 
 func (m *dbConn) Get(key driver.Value) (schema.Message, error) {
-	txn := m.db.Txn(false)
+	txn := m.md.memDb().Txn(false)
 	iter, err := txn.Get(m.md.tbl.Name, m.md.primaryIndex, fmt.Sprintf("%v", key))
 	if err != nil {
 		txn.Abort()
@@ -254,7 +458,9 @@ func (m *dbConn) Get(key driver.Value) (schema.Message, error) {
 
 // Interface for Deletion
 func (m *dbConn) Delete(key driver.Value) (int, error) {
-	txn := m.db.Txn(true)
+	m.md.mu.RLock()
+	defer m.md.mu.RUnlock()
+	txn := m.md.db.Txn(true)
 	err := txn.Delete(m.md.tbl.Name, key)
 	if err != nil {
 		txn.Abort()
@@ -262,6 +468,11 @@ func (m *dbConn) Delete(key driver.Value) (int, error) {
 		return 0, err
 	}
 	txn.Commit()
+	if m.md.wal != nil {
+		if err = m.md.wal.Append(datasource.WalDelete, key); err != nil {
+			return 0, err
+		}
+	}
 	return 1, nil
 }
 
@@ -269,7 +480,10 @@ func (m *dbConn) Delete(key driver.Value) (int, error) {
 func (m *dbConn) DeleteExpression(p interface{}, where expr.Node) (int, error) {
 
 	var deletedKeys []schema.Key
-	txn := m.db.Txn(true)
+	var deletedVals []driver.Value
+	m.md.mu.RLock()
+	defer m.md.mu.RUnlock()
+	txn := m.md.db.Txn(true)
 	iter, err := txn.Get(m.md.tbl.Name, m.md.primaryIndex)
 	if err != nil {
 		txn.Abort()
@@ -305,6 +519,7 @@ deleteLoop:
 				}
 				indexVal := msg.Vals[0]
 				deletedKeys = append(deletedKeys, schema.NewKeyUint(makeId(indexVal)))
+				deletedVals = append(deletedVals, indexVal)
 			}
 		case nil:
 			// ??
@@ -322,5 +537,12 @@ deleteLoop:
 		return 0, err
 	}
 	txn.Commit()
+	if m.md.wal != nil {
+		for _, key := range deletedVals {
+			if err = m.md.wal.Append(datasource.WalDelete, key); err != nil {
+				return 0, err
+			}
+		}
+	}
 	return len(deletedKeys), nil
 }
@@ -15,7 +15,8 @@ import (
 var (
 	_ = u.EMPTY
 	// Indexes
-	_ memdb.Indexer = (*indexWrapper)(nil)
+	_ memdb.Indexer       = (*indexWrapper)(nil)
+	_ memdb.PrefixIndexer = (*indexWrapper)(nil)
 )
 
 func makeId(dv driver.Value) uint64 {
@@ -35,22 +36,39 @@ func makeId(dv driver.Value) uint64 {
 	return 0
 }
 
-// Wrap the index so we can operate on rows
+// Wrap the index so we can operate on rows, the index value is the
+// null terminated values of its fields in order.
 type indexWrapper struct {
 	t *schema.Table
 	*schema.Index
+	pos []int // positions of the index fields in row
+}
+
+func newIndexWrapper(t *schema.Table, idx *schema.Index) *indexWrapper {
+	iw := &indexWrapper{t: t, Index: idx}
+	for _, f := range idx.Fields {
+		iw.pos = append(iw.pos, t.FieldPositions[f])
+	}
+	return iw
 }
 
 func (s *indexWrapper) FromObject(obj interface{}) (bool, []byte, error) {
 	switch row := obj.(type) {
 	case *datasource.SqlDriverMessage:
-		if len(row.Vals) < 0 {
+		if len(row.Vals) == 0 {
 			return false, nil, u.LogErrorf("No values in row?")
 		}
-		// Add the null character as a terminator
-		val := fmt.Sprintf("%v", row.Vals[0])
-		val += "\x00"
-		return true, []byte(val), nil
+		val := make([]byte, 0, 16*len(s.pos))
+		for _, pos := range s.pos {
+			if pos >= len(row.Vals) || row.Vals[pos] == nil {
+				// secondary indexes allow missing, rows without a value
+				// are not in the index
+				return false, nil, nil
+			}
+			// Add the null character as a terminator
+			val = append(val, fmt.Sprintf("%v\x00", row.Vals[pos])...)
+		}
+		return true, val, nil
 	case int, uint64, int64, string:
 		// Add the null character as a terminator
 		val := fmt.Sprintf("%v\x00", row)
@@ -61,13 +79,25 @@ func (s *indexWrapper) FromObject(obj interface{}) (bool, []byte, error) {
 }
 
 func (s *indexWrapper) FromArgs(args ...interface{}) ([]byte, error) {
-	if len(args) != 1 {
-		return nil, fmt.Errorf("must provide only a single argument")
+	if len(args) != len(s.Fields) {
+		return nil, fmt.Errorf("must provide %d arguments for index %q", len(s.Fields), s.Name)
+	}
+	val := make([]byte, 0, 16*len(args))
+	for _, arg := range args {
+		// Add the null character as a terminator
+		val = append(val, fmt.Sprintf("%v\x00", arg)...)
+	}
+	return val, nil
+}
+
+// PrefixFromArgs allows the "<index>_prefix" lookup of rows whose last
+// field starts with the last arg.
+func (s *indexWrapper) PrefixFromArgs(args ...interface{}) ([]byte, error) {
+	val, err := s.FromArgs(args...)
+	if err != nil {
+		return nil, err
 	}
-	arg := fmt.Sprintf("%v", args[0])
-	// Add the null character as a terminator
-	arg += "\x00"
-	return []byte(arg), nil
+	return val[:len(val)-1], nil
 }
 
 func makeMemDbSchema(m *MemDb) *memdb.DBSchema {
@@ -77,10 +107,12 @@ func makeMemDbSchema(m *MemDb) *memdb.DBSchema {
 	for _, idx := range m.indexes {
 		sidx := &memdb.IndexSchema{
 			Name:    idx.Name,
-			Indexer: &indexWrapper{Index: idx},
+			Indexer: newIndexWrapper(m.tbl, idx),
 		}
 		if idx.PrimaryKey {
 			sidx.Unique = true
+		} else {
+			sidx.AllowMissing = true
 		}
 		sindexes[idx.Name] = sidx
 	}
@@ -1,12 +1,16 @@
 package memdb
 
 import (
+	"database/sql"
 	"database/sql/driver"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/exec"
+	"github.com/fuhongbo/qlbridge/schema"
 )
 
 func TestIndex(t *testing.T) {
@@ -22,3 +26,106 @@ func TestIndex(t *testing.T) {
 
 	assert.Equal(t, uint64(264), makeId(v))
 }
+
+func scanIds(t *testing.T, c schema.Conn, scan *schema.IndexScan) []string {
+	is := c.(schema.ConnIndexScanner)
+	assert.Equal(t, nil, is.IndexScan(scan))
+	var ids []string
+	for msg := c.(schema.ConnScanner).Next(); msg != nil; msg = c.(schema.ConnScanner).Next() {
+		ids = append(ids, msg.(*datasource.SqlDriverMessageMap).Vals[0].(string))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestCreateIndex(t *testing.T) {
+	db, err := NewMemDbData("people", [][]driver.Value{
+		{"p1", "aaron", "portland"},
+		{"p2", "bob", "seattle"},
+		{"p3", "bobby", "portland"},
+		{"p4", "cat", nil},
+	}, []string{"id", "Name", "city"})
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, nil, db.CreateIndex("people", &schema.Index{Name: "idx_city", Fields: []string{"city"}}))
+	assert.Equal(t, nil, db.CreateIndex("people", &schema.Index{Name: "idx_name", Fields: []string{"name"}, Unique: true}))
+	assert.NotEqual(t, nil, db.CreateIndex("people", &schema.Index{Name: "idx_city", Fields: []string{"city"}}))
+	assert.NotEqual(t, nil, db.CreateIndex("people", &schema.Index{Name: "idx_x", Fields: []string{"nope"}}))
+	assert.NotEqual(t, nil, db.CreateIndex("people", &schema.Index{Name: "idx_dupe", Fields: []string{"city"}, Unique: true}))
+
+	c, _ := db.Open("people")
+	indexes := c.(schema.ConnIndexScanner).Indexes()
+	assert.Equal(t, 3, len(indexes))
+	city, name := indexes[1], indexes[2]
+	assert.Equal(t, []string{"Name"}, name.Fields)
+
+	assert.Equal(t, []string{"p1", "p3"}, scanIds(t, c, &schema.IndexScan{Index: city, Values: [][]driver.Value{{"portland"}}}))
+	c, _ = db.Open("people")
+	assert.Equal(t, []string{"p1", "p2", "p3"}, scanIds(t, c, &schema.IndexScan{Index: city,
+		Values: [][]driver.Value{{"portland"}, {"seattle"}, {"portland"}}}))
+	c, _ = db.Open("people")
+	assert.Equal(t, []string{"p2", "p3"}, scanIds(t, c, &schema.IndexScan{Index: name,
+		Values: [][]driver.Value{{"bob"}}, Prefix: true}))
+	c, _ = db.Open("people")
+	assert.Equal(t, []string(nil), scanIds(t, c, &schema.IndexScan{Index: city, Values: [][]driver.Value{{"port"}}}))
+
+	// rows written after the index was created are in it, unique is enforced
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"p5", "dan", "seattle"})
+	assert.Equal(t, nil, err)
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"p6", "dan", "boise"})
+	assert.NotEqual(t, nil, err)
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"p5", "dan", "boise"})
+	assert.Equal(t, nil, err)
+	c, _ = db.Open("people")
+	assert.Equal(t, []string{"p2"}, scanIds(t, c, &schema.IndexScan{Index: city, Values: [][]driver.Value{{"seattle"}}}))
+}
+
+func TestIndexScanSql(t *testing.T) {
+	db, err := NewMemDbData("products", [][]driver.Value{
+		{int64(1), "apple", "fruit", 1.5},
+		{int64(2), "apricot", "fruit", 3.0},
+		{int64(3), "carrot", "vegetable", 0.5},
+		{int64(4), "banana", "fruit", 0.25},
+	}, []string{"id", "name", "kind", "price"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, schema.RegisterSourceAsSchema("memdb_products", db))
+	exec.RegisterSqlDriver()
+
+	sdb, err := sql.Open("qlbridge", "memdb_products")
+	assert.Equal(t, nil, err)
+	defer sdb.Close()
+
+	_, err = sdb.Exec("CREATE INDEX idx_kind ON products (kind)")
+	assert.Equal(t, nil, err)
+	_, err = sdb.Exec("CREATE INDEX idx_name ON products (name)")
+	assert.Equal(t, nil, err)
+
+	names := func(q string) []string {
+		rows, err := sdb.Query(q)
+		assert.Equal(t, nil, err, q)
+		if err != nil {
+			return nil
+		}
+		defer rows.Close()
+		var out []string
+		for rows.Next() {
+			var name string
+			assert.Equal(t, nil, rows.Scan(&name))
+			out = append(out, name)
+		}
+		sort.Strings(out)
+		return out
+	}
+	assert.Equal(t, []string{"apple", "apricot"},
+		names(`SELECT name FROM products WHERE kind = "fruit" AND price > 1`))
+	assert.Equal(t, []string{"apple", "banana", "carrot"},
+		names(`SELECT name FROM products WHERE name IN ("apple", "banana", "carrot", "kiwi")`))
+	assert.Equal(t, []string{"apple", "apricot"},
+		names(`SELECT name FROM products WHERE name LIKE "ap%"`))
+	assert.Equal(t, []string{"carrot"},
+		names(`SELECT name FROM products WHERE id = 3`))
+	assert.Equal(t, []string{"banana"},
+		names(`SELECT name FROM products WHERE kind = "fruit" AND name LIKE "b%"`))
+	assert.Equal(t, []string{"banana"},
+		names(`SELECT name FROM products WHERE AND (kind = "fruit", name LIKE "b%")`))
+}
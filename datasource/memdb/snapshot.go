@@ -0,0 +1,80 @@
+package memdb
+
+import (
+	"database/sql/driver"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+// Snapshot writes the schema, indexes and rows of this table to file @path
+// atomically.  If a write-ahead log is open it is emptied, as its writes
+// are now in the snapshot.
+func (m *MemDb) Snapshot(path string) error {
+	// writers hold the read lock, so no write is half way in or logged
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := datasource.NewSnapshot(m.tbl, m.indexes)
+	iter, err := m.db.Txn(false).Get(m.tbl.Name, m.primaryIndex)
+	if err != nil {
+		return err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		s.Rows = append(s.Rows, raw.(*datasource.SqlDriverMessage).Vals)
+	}
+	return datasource.SaveSnapshot(path, s, m.wal)
+}
+
+// NewMemDbFromSnapshot creates a MemDb from the snapshot file @path
+// written by Snapshot().
+func NewMemDbFromSnapshot(path string) (*MemDb, error) {
+	var m *MemDb
+	err := datasource.RestoreSnapshot(path, func(s *datasource.Snapshot) (*schema.Table, error) {
+		var err error
+		if m, err = NewMemDbData(s.Table, s.Rows, s.Columns); err != nil {
+			return nil, err
+		}
+		for _, idx := range s.Indexes {
+			if idx.PrimaryKey {
+				continue
+			}
+			if err = m.CreateIndex(s.Table, idx); err != nil {
+				return nil, err
+			}
+		}
+		return m.tbl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OpenWriteAheadLog logs each Put, Delete and AlterTable to file @path so
+// writes made since the last Snapshot() survive a restart.  The writes
+// already in the log are replayed first.
+func (m *MemDb) OpenWriteAheadLog(path string) error {
+	conn := newDbConn(m)
+	defer conn.Close()
+	wal, err := datasource.OpenWriteAheadLog(path, datasource.WalReplay(
+		func(row []driver.Value) error {
+			_, err := conn.Put(nil, nil, row)
+			return err
+		},
+		func(key driver.Value) { conn.Delete(key) },
+		func(changes []*schema.ColumnChange) error {
+			return m.AlterTable(m.tbl.Name, changes)
+		},
+	))
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	if m.wal != nil {
+		m.wal.Close()
+	}
+	m.wal = wal
+	m.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,129 @@
+package memdb
+
+import (
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/lex"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memdbsnap")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	snapPath, walPath := filepath.Join(dir, "people.snap"), filepath.Join(dir, "people.wal")
+
+	db, err := NewMemDbData("people", [][]driver.Value{
+		{"p1", "aaron", "portland"},
+		{"p2", "bob", "seattle"},
+	}, []string{"id", "name", "city"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, db.CreateIndex("people", &schema.Index{Name: "idx_name", Fields: []string{"name"}, Unique: true}))
+	assert.Equal(t, nil, db.OpenWriteAheadLog(walPath))
+
+	c, _ := db.Open("people")
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"p3", "cat", "boise"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, db.Snapshot(snapPath))
+
+	// written after the snapshot, so only in the log
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"p4", "dan", "seattle"})
+	assert.Equal(t, nil, err)
+	_, err = c.(schema.ConnDeletion).Delete("p1")
+	assert.Equal(t, nil, err)
+	db.Close()
+
+	db2, err := NewMemDbFromSnapshot(snapPath)
+	assert.Equal(t, nil, err)
+	c, _ = db2.Open("people")
+	assert.Equal(t, 2, len(c.(schema.ConnIndexScanner).Indexes()))
+	_, err = c.(schema.ConnSeeker).Get("p1")
+	assert.Equal(t, nil, err)
+	_, err = c.(schema.ConnSeeker).Get("p4")
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	assert.Equal(t, nil, db2.OpenWriteAheadLog(walPath))
+	defer db2.Close()
+	c, _ = db2.Open("people")
+	_, err = c.(schema.ConnSeeker).Get("p1")
+	assert.Equal(t, schema.ErrNotFound, err)
+	msg, err := c.(schema.ConnSeeker).Get("p4")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "dan", msg.Body().([]driver.Value)[1])
+
+	// the unique index is restored
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"p5", "cat", "boise"})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSnapshotTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memdbsnaptypes")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	snapPath := filepath.Join(dir, "zips.snap")
+
+	// declared varchar, the rows alone would introspect as int
+	tbl := schema.NewTable("zips")
+	tbl.AddField(schema.NewFieldBase("id", value.StringType, 16, "varchar"))
+	tbl.AddField(schema.NewFieldBase("zip", value.StringType, 5, "varchar"))
+	db, err := NewMemDbForTable(tbl)
+	assert.Equal(t, nil, err)
+	c, _ := db.Open("zips")
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"z1", "02134"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, db.Snapshot(snapPath))
+
+	db2, err := NewMemDbFromSnapshot(snapPath)
+	assert.Equal(t, nil, err)
+	rt, _ := db2.Table("zips")
+	vt, _ := rt.Column("zip")
+	assert.Equal(t, value.StringType, vt)
+}
+
+func TestWriteAheadLogAlter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memdbwalalter")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	walPath := filepath.Join(dir, "people.wal")
+
+	peopleTable := func() *schema.Table {
+		tbl := schema.NewTable("people")
+		tbl.AddField(schema.NewField("id", value.StringType, 16, false, nil, "", "", ""))
+		tbl.AddField(schema.NewField("name", value.StringType, 50, true, nil, "", "", ""))
+		return tbl
+	}
+	db, err := NewMemDbForTable(peopleTable())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, db.OpenWriteAheadLog(walPath))
+	c, _ := db.Open("people")
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"p1", "aaron"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, db.AlterTable("people", []*schema.ColumnChange{
+		{Op: lex.TokenAdd, Name: "age", Field: schema.NewField("age", value.IntType, 0, true, int64(21), "", "", "")},
+	}))
+	_, err = c.(schema.ConnUpsert).Put(nil, nil, []driver.Value{"p2", "bob", int64(30)})
+	assert.Equal(t, nil, err)
+	db.Close()
+
+	// the alter replays before the puts made after it
+	db2, err := NewMemDbForTable(peopleTable())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, db2.OpenWriteAheadLog(walPath))
+	defer db2.Close()
+	tbl, _ := db2.Table("people")
+	assert.Equal(t, []string{"id", "name", "age"}, tbl.Columns())
+	c, _ = db2.Open("people")
+	msg, err := c.(schema.ConnSeeker).Get("p1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(21), msg.Body().([]driver.Value)[2])
+	msg, err = c.(schema.ConnSeeker).Get("p2")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(30), msg.Body().([]driver.Value)[2])
+}
@@ -0,0 +1,269 @@
+package memdb
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-memdb"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+var (
+	// Ensure our Source implements schema.Source and DDL interfaces
+	_ schema.Source             = (*Source)(nil)
+	_ schema.SourceDDL          = (*Source)(nil)
+	_ schema.SourceIndexCreator = (*Source)(nil)
+)
+
+// Source is a schema.Source of many in-memory MemDb tables, which are
+// created, altered and dropped through CREATE/ALTER/DROP TABLE.
+type Source struct {
+	mu     sync.RWMutex
+	tables map[string]*MemDb
+	names  []string
+}
+
+// NewSource creates an empty memdb source, tables are added with CreateTable.
+func NewSource() *Source {
+	return &Source{tables: make(map[string]*MemDb)}
+}
+
+// Init initilize this source
+func (m *Source) Init() {}
+
+// Setup this source with parent schema.
+func (m *Source) Setup(*schema.Schema) error { return nil }
+
+// Open a Conn for this source @table name
+func (m *Source) Open(table string) (schema.Conn, error) {
+	db, err := m.table(table)
+	if err != nil {
+		return nil, err
+	}
+	return db.Open(table)
+}
+
+// Table by name
+func (m *Source) Table(table string) (*schema.Table, error) {
+	db, err := m.table(table)
+	if err != nil {
+		return nil, err
+	}
+	return db.Table(table)
+}
+
+// Tables list of table names
+func (m *Source) Tables() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.names...)
+}
+
+// Close this source and all of its tables
+func (m *Source) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, db := range m.tables {
+		db.Close()
+	}
+	m.tables = make(map[string]*MemDb)
+	m.names = nil
+	return nil
+}
+
+// CreateTable creates a new empty table described by @tbl.
+func (m *Source) CreateTable(tbl *schema.Table) error {
+	db, err := NewMemDbForTable(tbl)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.tables[tbl.Name]; exists {
+		db.Close()
+		return fmt.Errorf("table %q already exists", tbl.Name)
+	}
+	m.tables[tbl.Name] = db
+	m.names = append(m.names, tbl.Name)
+	sort.Strings(m.names)
+	return nil
+}
+
+// DropTable drops the table and all of its rows.
+func (m *Source) DropTable(table string) error {
+	table = strings.ToLower(table)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	db, ok := m.tables[table]
+	if !ok {
+		return schema.ErrNotFound
+	}
+	delete(m.tables, table)
+	names := make([]string, 0, len(m.names))
+	for _, n := range m.names {
+		if n != table {
+			names = append(names, n)
+		}
+	}
+	m.names = names
+	return db.Close()
+}
+
+// AlterTable applies the column @changes to table.
+func (m *Source) AlterTable(table string, changes []*schema.ColumnChange) error {
+	db, err := m.table(table)
+	if err != nil {
+		return err
+	}
+	return db.AlterTable(table, changes)
+}
+
+// CreateIndex adds a secondary index to table.
+func (m *Source) CreateIndex(table string, idx *schema.Index) error {
+	db, err := m.table(table)
+	if err != nil {
+		return err
+	}
+	return db.CreateIndex(table, idx)
+}
+
+func (m *Source) table(table string) (*MemDb, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	db, ok := m.tables[strings.ToLower(table)]
+	if !ok {
+		return nil, schema.ErrNotFound
+	}
+	return db, nil
+}
+
+// NewMemDbForTable creates an empty MemDb for the fields and indexes
+// of @tbl.  The key of a MemDb is its first column, so a primary key if
+// declared must be that single column.  The other indexes are created
+// as secondary indexes, unique if declared so.
+func NewMemDbForTable(tbl *schema.Table) (*MemDb, error) {
+	if len(tbl.Fields) < 1 {
+		return nil, fmt.Errorf("must have columns provided")
+	}
+	if len(tbl.Columns()) != len(tbl.Fields) {
+		tbl.SetColumnsFromFields()
+	}
+	first := tbl.Columns()[0]
+
+	m := &MemDb{}
+	m.exit = make(chan bool, 1)
+	m.tbl = tbl
+	var secondary []*schema.Index
+	for _, idx := range tbl.Indexes {
+		if !idx.PrimaryKey {
+			secondary = append(secondary, idx)
+			continue
+		}
+		if len(idx.Fields) != 1 || idx.Fields[0] != first {
+			return nil, fmt.Errorf("memdb primary key of %q must be its first column %q", tbl.Name, first)
+		}
+	}
+	// the go-memdb "id" index on the first column is the primary key
+	m.buildDefaultIndexes()
+	var err error
+	if m.db, err = memdb.NewMemDB(makeMemDbSchema(m)); err != nil {
+		return nil, err
+	}
+	for _, idx := range secondary {
+		if err = m.CreateIndex(tbl.Name, idx); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// AlterTable applies the column @changes, building a new go-memdb and
+// migrating the rows, new columns get the column default.  The first
+// (key) column may not be dropped or moved.  The alter is logged to an
+// open write-ahead log so the puts after it replay onto the new columns.
+func (m *MemDb) AlterTable(table string, changes []*schema.ColumnChange) error {
+	if !strings.EqualFold(table, m.tbl.Name) {
+		return fmt.Errorf("could not find table %q", table)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.tbl.Fields) != len(m.tbl.Columns()) {
+		return fmt.Errorf("table %q has no field schema to alter", table)
+	}
+	nt, srcs, err := schema.ApplyColumnChanges(m.tbl, changes)
+	if err != nil {
+		return err
+	}
+	if len(srcs) == 0 || srcs[0] != 0 {
+		return fmt.Errorf("memdb cannot drop or move the key column %q of %q", m.tbl.Columns()[0], table)
+	}
+
+	// old column name -> new column name, dropped columns are missing
+	renames := make(map[string]string, len(srcs))
+	for i, src := range srcs {
+		if src >= 0 {
+			renames[m.tbl.Columns()[src]] = nt.Columns()[i]
+		}
+	}
+	indexes := make([]*schema.Index, 0, len(m.indexes))
+	for _, idx := range m.indexes {
+		ni := &schema.Index{Name: idx.Name, PrimaryKey: idx.PrimaryKey, Unique: idx.Unique}
+		for _, f := range idx.Fields {
+			if to, ok := renames[f]; ok {
+				ni.Fields = append(ni.Fields, to)
+			}
+		}
+		if len(ni.Fields) != len(idx.Fields) {
+			// an index on a dropped column goes with it
+			continue
+		}
+		indexes = append(indexes, ni)
+	}
+
+	prevTbl, prevIndexes := m.tbl, m.indexes
+	m.tbl, m.indexes = nt, indexes
+	db, err := memdb.NewMemDB(makeMemDbSchema(m))
+	if err != nil {
+		m.tbl, m.indexes = prevTbl, prevIndexes
+		return err
+	}
+	iter, err := m.db.Txn(false).Get(prevTbl.Name, m.primaryIndex)
+	if err != nil {
+		m.tbl, m.indexes = prevTbl, prevIndexes
+		return err
+	}
+	txn := db.Txn(true)
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		msg := raw.(*datasource.SqlDriverMessage)
+		row := make([]driver.Value, len(srcs))
+		for i, src := range srcs {
+			if src >= 0 {
+				row[i] = msg.Vals[src]
+			} else {
+				row[i] = nt.Fields[i].DefaultValue()
+			}
+		}
+		if err = txn.Insert(nt.Name, &datasource.SqlDriverMessage{Vals: row, IdVal: msg.IdVal}); err != nil {
+			txn.Abort()
+			m.tbl, m.indexes = prevTbl, prevIndexes
+			return err
+		}
+	}
+	txn.Commit()
+	m.db = db
+	if m.wal != nil {
+		// later puts in the log have the altered columns
+		vals, err := datasource.ColumnChangesRow(changes)
+		if err != nil {
+			return err
+		}
+		return m.wal.Append(datasource.WalAlter, vals...)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package memdb
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/exec"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+func TestSourceDDL(t *testing.T) {
+	src := NewSource()
+	assert.Equal(t, nil, schema.RegisterSourceAsSchema("memdb_ddl", src))
+	exec.RegisterSqlDriver()
+
+	sdb, err := sql.Open("qlbridge", "memdb_ddl")
+	assert.Equal(t, nil, err)
+	defer sdb.Close()
+
+	_, err = sdb.Exec(`CREATE TABLE accounts (
+		id int NOT NULL,
+		email varchar(255) NOT NULL,
+		plan varchar(20) DEFAULT "free",
+		PRIMARY KEY (id),
+		CONSTRAINT uniq_email UNIQUE (email)
+	)`)
+	assert.Equal(t, nil, err)
+	_, err = sdb.Exec(`CREATE TABLE IF NOT EXISTS accounts (id int)`)
+	assert.Equal(t, nil, err)
+	_, err = sdb.Exec(`CREATE TABLE accounts (id int)`)
+	assert.NotEqual(t, nil, err)
+	// memdb keys rows by their first column
+	_, err = sdb.Exec(`CREATE TABLE bad (a int, b int, PRIMARY KEY (b))`)
+	assert.NotEqual(t, nil, err)
+
+	s, ok := schema.DefaultRegistry().Schema("memdb_ddl")
+	assert.True(t, ok)
+	tbl, err := s.Table("accounts")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "email", "plan"}, tbl.Columns())
+	assert.Equal(t, "PRI", tbl.FieldMap["id"].Key)
+	assert.Equal(t, "UNI", tbl.FieldMap["email"].Key)
+	assert.True(t, tbl.FieldMap["email"].NoNulls)
+	assert.Equal(t, "free", tbl.FieldMap["plan"].DefaultValue())
+
+	_, err = sdb.Exec(`INSERT INTO accounts (id, email, plan) VALUES (1, "a@x.com", "pro"), (2, "b@x.com", "free")`)
+	assert.Equal(t, nil, err)
+	_, err = sdb.Exec(`INSERT INTO accounts (id, email, plan) VALUES (3, "a@x.com", "free")`)
+	assert.NotEqual(t, nil, err, "unique email")
+
+	_, err = sdb.Exec(`ALTER TABLE accounts ADD COLUMN age int NOT NULL DEFAULT 21 AFTER email, DROP COLUMN plan`)
+	assert.Equal(t, nil, err)
+	tbl, err = s.Table("accounts")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "email", "age"}, tbl.Columns())
+	assert.Equal(t, value.IntType, tbl.FieldMap["age"].ValueType())
+
+	rows, err := sdb.Query(`SELECT email, age FROM accounts WHERE id = 2`)
+	assert.Equal(t, nil, err)
+	var email string
+	var age int64
+	assert.True(t, rows.Next())
+	assert.Equal(t, nil, rows.Scan(&email, &age))
+	assert.Equal(t, "b@x.com", email)
+	assert.Equal(t, int64(21), age)
+	rows.Close()
+
+	_, err = sdb.Exec(`ALTER TABLE accounts CHANGE email mail varchar(100) NOT NULL`)
+	assert.Equal(t, nil, err)
+	tbl, _ = s.Table("accounts")
+	assert.Equal(t, []string{"id", "mail", "age"}, tbl.Columns())
+	assert.Equal(t, "UNI", tbl.FieldMap["mail"].Key)
+	_, err = sdb.Exec(`ALTER TABLE accounts DROP COLUMN id`)
+	assert.NotEqual(t, nil, err)
+
+	_, err = sdb.Exec(`DROP TABLE accounts`)
+	assert.Equal(t, nil, err)
+	_, err = s.Table("accounts")
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, []string(nil), src.Tables())
+	_, err = sdb.Exec(`DROP TABLE IF EXISTS accounts`)
+	assert.Equal(t, nil, err)
+	_, err = sdb.Exec(`DROP TABLE accounts`)
+	assert.NotEqual(t, nil, err)
+}
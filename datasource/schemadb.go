@@ -5,6 +5,8 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	u "github.com/araddon/gou"
 
@@ -28,11 +30,17 @@ var (
 
 	// normal tables
 	defaultSchemaTables = []string{"tables", "databases", "columns", "global_variables", "session_variables",
-		"functions", "procedures", "engines", "status", "indexes"}
+		"functions", "procedures", "engines", "status", "indexes", "views"}
 	// DialectWriterCols list of columns for dialectwriter.
-	DialectWriterCols = []string{"mysql"}
+	//
+	// Deprecated: use schema.DialectWriters(), the <dialect>_create
+	// columns are named from the registered writers.
+	DialectWriterCols []string
 	// DialectWriters list of differnt writers.
-	DialectWriters = []schema.DialectWriter{&mysqlWriter{}}
+	//
+	// Deprecated: use schema.RegisterDialectWriter and schema.DialectWriters(),
+	// writers appended here are still listed after the registered ones.
+	DialectWriters []schema.DialectWriter
 
 	// privates
 	_        = u.EMPTY
@@ -98,6 +106,10 @@ func (m *SchemaDb) Tables() []string { return m.tbls }
 // Table get schema Table
 func (m *SchemaDb) Table(table string) (*schema.Table, error) {
 
+	if rt, ok := computedTables[table]; ok {
+		t, _ := rt(m)
+		return t, nil
+	}
 	switch table {
 	case "tables":
 		return m.tableForTables()
@@ -109,10 +121,6 @@ func (m *SchemaDb) Table(table string) (*schema.Table, error) {
 		return m.tableForProcedures(table)
 	case "engines":
 		return m.tableForEngines()
-	case "indexes", "keys":
-		return m.tableForIndexes()
-	case "status":
-		return m.tableForVariables(table)
 	case "columns":
 		return m.tableForTable(table)
 	default:
@@ -123,13 +131,17 @@ func (m *SchemaDb) Table(table string) (*schema.Table, error) {
 // Open Create a SchemaSource specific to schema object (table, database)
 func (m *SchemaDb) Open(schemaObjectName string) (schema.Conn, error) {
 
+	if rt, ok := computedTables[schemaObjectName]; ok {
+		tbl, rows := rt(m)
+		return &SchemaSource{db: m, tbl: tbl, rows: rows()}, nil
+	}
 	tbl, err := m.Table(schemaObjectName)
 	if err == nil && tbl != nil {
 
 		switch schemaObjectName {
 		case "session_variables", "global_variables":
 			return &SchemaSource{db: m, tbl: tbl, session: true}, nil
-		case "engines", "procedures", "functions", "indexes":
+		case "engines", "procedures":
 			return &SchemaSource{db: m, tbl: tbl, rows: nil}, nil
 		default:
 			return &SchemaSource{db: m, tbl: tbl, rows: tbl.AsRows()}, nil
@@ -305,21 +317,26 @@ func (m *SchemaDb) tableForTables() (*schema.Table, error) {
 	t.AddField(schema.NewFieldBase("Table", value.StringType, 64, "string"))
 	t.AddField(schema.NewFieldBase("Table_type", value.StringType, 64, "string"))
 
+	writers := dialectWriters()
 	cols := schema.ShowTableColumns
-	for _, col := range DialectWriterCols {
-		cols = append(cols, fmt.Sprintf("%s_create", col))
+	for _, writer := range writers {
+		cols = append(cols, fmt.Sprintf("%s_create", writer.Dialect()))
 	}
 	t.SetColumns(cols)
 
 	rows := make([][]driver.Value, len(m.s.Tables()))
 	for i, tableName := range m.s.Tables() {
-		rows[i] = []driver.Value{tableName, "BASE TABLE"}
 		tbl, err := m.s.Table(tableName)
+		if tbl != nil {
+			rows[i] = []driver.Value{tableName, tbl.TableType()}
+		} else {
+			rows[i] = []driver.Value{tableName, "BASE TABLE"}
+		}
 		if tbl != nil && len(tbl.Columns()) > 0 && len(tbl.Fields) == 0 {
 			// I really don't like where this is, needs to be in schema somewhere
 			m.inspect(tbl.Name)
 		}
-		for _, writer := range DialectWriters {
+		for _, writer := range writers {
 			if err != nil {
 				rows[i] = append(rows[i], "error")
 			} else {
@@ -405,6 +422,24 @@ func (m *mysqlWriter) FieldType(t value.ValueType) string {
 // Table output a CREATE TABLE statement using mysql dialect.
 func (m *mysqlWriter) Table(tbl *schema.Table) string {
 
+	if tbl.View != nil {
+		create := fmt.Sprintf("CREATE %s `%s` AS %s", tbl.View.Keyword(), tbl.Name, tbl.View.Sql)
+		var with []string
+		if tbl.View.WatermarkDelay > 0 {
+			with = append(with, fmt.Sprintf("watermark = %q", tbl.View.WatermarkDelay.String()))
+		}
+		if tbl.View.Retention > 0 {
+			with = append(with, fmt.Sprintf("retention = %q", tbl.View.Retention.String()))
+		}
+		if tbl.View.RetainWindows > 0 {
+			with = append(with, fmt.Sprintf("retain_windows = %d", tbl.View.RetainWindows))
+		}
+		if len(with) > 0 {
+			create += " WITH " + strings.Join(with, ", ")
+		}
+		return create
+	}
+
 	w := &bytes.Buffer{}
 	//u.Infof("%s tbl=%p fields? %#v fields?%v", tbl.Name, tbl, tbl.FieldMap, len(tbl.Fields))
 	fmt.Fprintf(w, "CREATE TABLE `%s` (", tbl.Name)
@@ -415,6 +450,20 @@ func (m *mysqlWriter) Table(tbl *schema.Table) string {
 		fmt.Fprint(w, "\n    ")
 		mysqlWriteField(w, fld)
 	}
+	for _, idx := range tbl.Indexes {
+		cols := make([]string, len(idx.Fields))
+		for i, f := range idx.Fields {
+			cols[i] = fmt.Sprintf("`%s`", f)
+		}
+		switch {
+		case idx.PrimaryKey:
+			fmt.Fprintf(w, ",\n    PRIMARY KEY (%s)", strings.Join(cols, ", "))
+		case idx.Unique:
+			fmt.Fprintf(w, ",\n    UNIQUE KEY `%s` (%s)", idx.Name, strings.Join(cols, ", "))
+		default:
+			fmt.Fprintf(w, ",\n    KEY `%s` (%s)", idx.Name, strings.Join(cols, ", "))
+		}
+	}
 	fmt.Fprint(w, "\n) ENGINE=InnoDB DEFAULT CHARSET=utf8;")
 	//tblStr := fmt.Sprintf("CREATE TABLE `%s` (\n\n);", tbl.Name, strings.Join(cols, ","))
 	//return tblStr, nil
@@ -422,29 +471,67 @@ func (m *mysqlWriter) Table(tbl *schema.Table) string {
 }
 func mysqlWriteField(w *bytes.Buffer, fld *schema.Field) {
 	fmt.Fprintf(w, "`%s` ", fld.Name)
-	deflen := fld.Length
+	dataType, colType := mysqlColumnType(fld)
+	if dataType == "json" {
+		fmt.Fprintf(w, "JSON")
+	} else {
+		fmt.Fprint(w, colType)
+		if fld.NoNulls {
+			fmt.Fprint(w, " NOT NULL")
+		}
+		switch def := fld.DefaultValue(); {
+		case def != nil:
+			fmt.Fprintf(w, " DEFAULT %s", mysqlLiteral(def))
+		case !fld.NoNulls:
+			fmt.Fprint(w, " DEFAULT NULL")
+		}
+	}
+	if len(fld.Description) > 0 {
+		fmt.Fprintf(w, " COMMENT %q", fld.Description)
+	}
+}
+
+// mysqlColumnType the mysql DATA_TYPE and COLUMN_TYPE of @fld.
+func mysqlColumnType(fld *schema.Field) (string, string) {
 	switch fld.ValueType() {
 	case value.BoolType:
-		fmt.Fprint(w, "tinyint(1) DEFAULT NULL")
+		return "tinyint", "tinyint(1)"
 	case value.IntType:
-		fmt.Fprint(w, "bigint DEFAULT NULL")
+		return "bigint", "bigint"
 	case value.StringType:
-		if deflen == 0 {
-			deflen = 255
-		}
-		fmt.Fprintf(w, "varchar(%d) DEFAULT NULL", deflen)
+		return "varchar", fmt.Sprintf("varchar(%d)", mysqlVarcharLen(fld))
 	case value.NumberType:
-		fmt.Fprint(w, "float DEFAULT NULL")
+		return "float", "float"
 	case value.TimeType:
-		fmt.Fprint(w, "datetime DEFAULT NULL")
+		return "datetime", "datetime"
 	case value.JsonType:
-		fmt.Fprintf(w, "JSON")
-	default:
-		fmt.Fprint(w, "text DEFAULT NULL")
+		return "json", "json"
 	}
-	if len(fld.Description) > 0 {
-		fmt.Fprintf(w, " COMMENT %q", fld.Description)
+	return "text", "text"
+}
+
+// mysqlVarcharLen the declared length of a string field, 255 if none.
+func mysqlVarcharLen(fld *schema.Field) uint32 {
+	if fld.Length == 0 {
+		return 255
+	}
+	return fld.Length
+}
+
+// mysqlLiteral a column DEFAULT value as a mysql literal.
+func mysqlLiteral(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return fmt.Sprintf("%q", v.Format("2006-01-02 15:04:05"))
 	}
+	return fmt.Sprintf("%v", v)
 }
 func MysqlValueString(t value.ValueType) string {
 	switch t {
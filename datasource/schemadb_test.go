@@ -2,6 +2,7 @@ package datasource_test
 
 import (
 	"database/sql/driver"
+	"fmt"
 	"testing"
 
 	"github.com/fuhongbo/qlbridge/datasource"
@@ -40,6 +41,39 @@ func TestSchemaShowStatements(t *testing.T) {
 	testutil.TestSelect(t, `show create table users;`,
 		[][]driver.Value{{"users", createStmt}},
 	)
+	testutil.TestSelect(t, `show create table users WITH dialect = "postgres";`,
+		[][]driver.Value{{"users", "CREATE TABLE \"users\" (\n" +
+			"    \"user_id\" text,\n" +
+			"    \"email\" text,\n" +
+			"    \"interests\" text,\n" +
+			"    \"reg_date\" timestamp,\n" +
+			"    \"referral_count\" bigint,\n" +
+			"    \"json_data\" jsonb\n" +
+			");"}},
+	)
+	testutil.TestSelect(t, `show create table users WITH dialect = "bigquery";`,
+		[][]driver.Value{{"users", "CREATE TABLE `users` (\n" +
+			"    `user_id` STRING,\n" +
+			"    `email` STRING,\n" +
+			"    `interests` STRING,\n" +
+			"    `reg_date` TIMESTAMP,\n" +
+			"    `referral_count` INT64,\n" +
+			"    `json_data` JSON\n" +
+			");"}},
+	)
+	// cassandra requires a primary key, the first column is used
+	testutil.TestSelect(t, `show create table users WITH dialect = "cassandra";`,
+		[][]driver.Value{{"users", "CREATE TABLE \"users\" (\n" +
+			"    \"user_id\" text,\n" +
+			"    \"email\" text,\n" +
+			"    \"interests\" text,\n" +
+			"    \"reg_date\" timestamp,\n" +
+			"    \"referral_count\" bigint,\n" +
+			"    \"json_data\" text,\n" +
+			"    PRIMARY KEY (\"user_id\")\n" +
+			");"}},
+	)
+	testutil.TestSelectErr(t, `show create table users WITH dialect = "oracle";`, nil)
 
 	// - rewrite show tables -> "use schema; select Table, Table_Type from schema.tables;"
 	testutil.TestSelect(t, `show full tables;`,
@@ -98,6 +132,45 @@ func TestSchemaShowStatements(t *testing.T) {
 		},
 	)
 
+	// STATUS, FUNCTIONS
+	testutil.TestSelect(t, `show status like 'Open_tables';`,
+		[][]driver.Value{{"Open_tables", int64(2)}},
+	)
+	testutil.TestSelect(t, `show global status like 'Open_tables';`,
+		[][]driver.Value{{"Open_tables", int64(2)}},
+	)
+	testutil.TestSelect(t, `select Name, Type, Comment from context.functions where Name = "count";`,
+		[][]driver.Value{{"count", "FUNCTION", "aggregate"}},
+	)
+	testutil.TestSelect(t, `show function status like 'contains';`,
+		[][]driver.Value{{"mockcsv", "contains", "FUNCTION", "", nil, nil, "DEFINER", "", "utf8", "utf8_general_ci", "utf8_general_ci"}},
+	)
+	testutil.TestSelect(t, `show functions like 'tolower';`,
+		[][]driver.Value{{"mockcsv", "tolower", "FUNCTION", "", nil, nil, "DEFINER", "", "utf8", "utf8_general_ci", "utf8_general_ci"}},
+	)
+	// mockcsv tables have no keys
+	testutil.TestSelect(t, `show index from users;`,
+		[][]driver.Value{},
+	)
+
+	// information_schema
+	testutil.TestSelect(t, `select SCHEMA_NAME from information_schema.schemata;`,
+		[][]driver.Value{{"information_schema"}, {"mockcsv"}},
+	)
+	testutil.TestSelect(t, `select TABLE_NAME, TABLE_TYPE, ENGINE from information_schema.tables where TABLE_SCHEMA = "mockcsv";`,
+		[][]driver.Value{{"orders", "BASE TABLE", ""}, {"users", "BASE TABLE", ""}},
+	)
+	testutil.TestSelect(t, `SELECT COLUMN_NAME, ORDINAL_POSITION, IS_NULLABLE, DATA_TYPE, COLUMN_TYPE
+		FROM information_schema.columns WHERE TABLE_NAME = "users" AND ORDINAL_POSITION < 3;`,
+		[][]driver.Value{{"user_id", int64(1), "YES", "varchar", "varchar(255)"}, {"email", int64(2), "YES", "varchar", "varchar(255)"}},
+	)
+	testutil.TestSelect(t, `select ROUTINE_NAME, ROUTINE_TYPE from information_schema.routines where ROUTINE_NAME = "count";`,
+		[][]driver.Value{{"count", "FUNCTION"}},
+	)
+	for _, tbl := range []string{"views", "statistics", "table_constraints", "key_column_usage"} {
+		testutil.TestSelect(t, fmt.Sprintf(`select * from information_schema.%s;`, tbl), [][]driver.Value{})
+	}
+
 	// DESCRIBE
 	testutil.TestSelect(t, `describe users;`,
 		[][]driver.Value{
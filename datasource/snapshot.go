@@ -0,0 +1,755 @@
+package datasource
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/fuhongbo/qlbridge/lex"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+const (
+	snapshotMagic   = "QLBS"
+	snapshotVersion = 1
+)
+
+// value type tags of the binary encoding
+const (
+	tagNil byte = iota
+	tagFalse
+	tagTrue
+	tagInt
+	tagInt64
+	tagUint64
+	tagFloat64
+	tagString
+	tagBytes
+	tagTime
+	tagStrings
+	tagSlice
+	tagMap
+	tagStringMap
+)
+
+// WAL operations
+const (
+	WalPut    byte = 'P'
+	WalDelete byte = 'D'
+	WalAlter  byte = 'A'
+)
+
+var (
+	// ErrBadSnapshot is returned reading a snapshot that is truncated or corrupt.
+	ErrBadSnapshot = fmt.Errorf("invalid or corrupt snapshot")
+)
+
+// Snapshot is the schema and rows of an in-memory table, written to disk
+// so it can be restored without rebuilding it from upstream.
+type Snapshot struct {
+	Table   string
+	Columns []string
+	Types   []value.ValueType // type of each of Columns
+	Indexes []*schema.Index
+	Rows    [][]driver.Value
+}
+
+// NewSnapshot the snapshot of the columns, types and @indexes of @tbl,
+// for the source to append its rows to.
+func NewSnapshot(tbl *schema.Table, indexes []*schema.Index) *Snapshot {
+	s := &Snapshot{Table: tbl.Name, Columns: tbl.Columns(), Indexes: indexes}
+	s.TypesFrom(tbl)
+	return s
+}
+
+// WriteSnapshot writes @s to file @path atomically:  it is written to a
+// temp file in the same directory, synced, then renamed over @path.
+func WriteSnapshot(path string, s *Snapshot) error {
+
+	buf := []byte(snapshotMagic)
+	buf = append(buf, snapshotVersion)
+	buf = appendString(buf, s.Table)
+	buf = appendStrings(buf, s.Columns)
+	buf = appendUvarint(buf, uint64(len(s.Types)))
+	for _, vt := range s.Types {
+		buf = appendUvarint(buf, uint64(vt))
+	}
+	buf = appendUvarint(buf, uint64(len(s.Indexes)))
+	for _, idx := range s.Indexes {
+		buf = appendString(buf, idx.Name)
+		buf = appendStrings(buf, idx.Fields)
+		buf = appendBool(buf, idx.PrimaryKey)
+		buf = appendBool(buf, idx.Unique)
+	}
+	buf = appendUvarint(buf, uint64(len(s.Rows)))
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	crc := crc32.NewIEEE()
+	w := bufio.NewWriter(io.MultiWriter(f, crc))
+	if _, err = w.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	for _, row := range s.Rows {
+		if buf, err = AppendRow(buf[:0], row); err != nil {
+			f.Close()
+			return err
+		}
+		if _, err = w.Write(buf); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = binary.Write(f, binary.BigEndian, crc.Sum32()); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SaveSnapshot writes @s to file @path, then empties the write-ahead log
+// @wal of the source if it has one open, as its writes are now in the
+// snapshot.
+func SaveSnapshot(path string, s *Snapshot, wal *WriteAheadLog) error {
+	if err := WriteSnapshot(path, s); err != nil {
+		return err
+	}
+	if wal != nil {
+		return wal.Truncate()
+	}
+	return nil
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot from file @path.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	by, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(by) < len(snapshotMagic)+5 || string(by[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, ErrBadSnapshot
+	}
+	body := by[:len(by)-4]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(by[len(by)-4:]) {
+		return nil, ErrBadSnapshot
+	}
+	if body[len(snapshotMagic)] != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", body[len(snapshotMagic)])
+	}
+	r := bytes.NewReader(body[len(snapshotMagic)+1:])
+
+	s := &Snapshot{}
+	if s.Table, err = readString(r); err != nil {
+		return nil, err
+	}
+	if s.Columns, err = readStrings(r); err != nil {
+		return nil, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrBadSnapshot
+	}
+	for i := uint64(0); i < n; i++ {
+		vt, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, ErrBadSnapshot
+		}
+		s.Types = append(s.Types, value.ValueType(vt))
+	}
+	if n, err = binary.ReadUvarint(r); err != nil {
+		return nil, ErrBadSnapshot
+	}
+	for i := uint64(0); i < n; i++ {
+		idx := &schema.Index{}
+		if idx.Name, err = readString(r); err != nil {
+			return nil, err
+		}
+		if idx.Fields, err = readStrings(r); err != nil {
+			return nil, err
+		}
+		if idx.PrimaryKey, err = readBool(r); err != nil {
+			return nil, err
+		}
+		if idx.Unique, err = readBool(r); err != nil {
+			return nil, err
+		}
+		s.Indexes = append(s.Indexes, idx)
+	}
+	if n, err = binary.ReadUvarint(r); err != nil {
+		return nil, ErrBadSnapshot
+	}
+	s.Rows = make([][]driver.Value, 0, n)
+	for i := uint64(0); i < n; i++ {
+		row, err := ReadRow(r)
+		if err != nil {
+			return nil, err
+		}
+		s.Rows = append(s.Rows, row)
+	}
+	return s, nil
+}
+
+// RestoreSnapshot reads the snapshot file @path and calls @load to build
+// a source of it.  The table @load returns is given the snapshot column
+// types.
+func RestoreSnapshot(path string, load func(s *Snapshot) (*schema.Table, error)) error {
+	s, err := ReadSnapshot(path)
+	if err != nil {
+		return err
+	}
+	tbl, err := load(s)
+	if err != nil {
+		return err
+	}
+	s.ApplyTypes(tbl)
+	return nil
+}
+
+// TypesFrom set the snapshot column types from the fields of @tbl.
+func (m *Snapshot) TypesFrom(tbl *schema.Table) {
+	m.Types = make([]value.ValueType, len(m.Columns))
+	for i, col := range m.Columns {
+		m.Types[i], _ = tbl.Column(col)
+	}
+}
+
+// ApplyTypes set the types of the fields of @tbl to the snapshot column
+// types, so a restored table has the types it was snapshot with instead
+// of the ones introspected from its rows.
+func (m *Snapshot) ApplyTypes(tbl *schema.Table) {
+	for i, vt := range m.Types {
+		if i >= len(m.Columns) {
+			break
+		} else if vt == value.UnknownType {
+			continue
+		}
+		f, ok := tbl.FieldMap[m.Columns[i]]
+		if !ok {
+			tbl.AddField(schema.NewFieldBase(m.Columns[i], vt, 64, vt.String()))
+			continue
+		}
+		f.Type = uint32(vt)
+		f.NativeType = uint32(vt)
+	}
+}
+
+// KeyColumn the position in Columns of the single column primary key,
+// 0 if there is none.
+func (m *Snapshot) KeyColumn() int {
+	for _, idx := range m.Indexes {
+		if !idx.PrimaryKey || len(idx.Fields) != 1 {
+			continue
+		}
+		for i, col := range m.Columns {
+			if col == idx.Fields[0] {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// ColumnChangesRow encodes the ALTER TABLE @changes as the values of a
+// WalAlter log entry, 5 values per change.
+func ColumnChangesRow(changes []*schema.ColumnChange) ([]driver.Value, error) {
+	vals := make([]driver.Value, 0, len(changes)*5)
+	for _, cc := range changes {
+		var fby []byte
+		if cc.Field != nil {
+			by, err := proto.Marshal(&cc.Field.FieldPb)
+			if err != nil {
+				return nil, err
+			}
+			fby = by
+		}
+		vals = append(vals, int64(cc.Op), cc.Name, cc.First, cc.After, fby)
+	}
+	return vals, nil
+}
+
+// ColumnChangesFromRow decodes the changes of a WalAlter log entry
+// written by ColumnChangesRow.
+func ColumnChangesFromRow(vals []driver.Value) ([]*schema.ColumnChange, error) {
+	if len(vals)%5 != 0 {
+		return nil, ErrBadSnapshot
+	}
+	changes := make([]*schema.ColumnChange, 0, len(vals)/5)
+	for i := 0; i < len(vals); i += 5 {
+		op, ok1 := vals[i].(int64)
+		name, ok2 := vals[i+1].(string)
+		first, ok3 := vals[i+2].(bool)
+		after, ok4 := vals[i+3].(string)
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return nil, ErrBadSnapshot
+		}
+		cc := &schema.ColumnChange{Op: lex.TokenType(op), Name: name, First: first, After: after}
+		if fby, ok := vals[i+4].([]byte); ok && len(fby) > 0 {
+			cc.Field = &schema.Field{}
+			if err := proto.Unmarshal(fby, &cc.Field.FieldPb); err != nil {
+				return nil, ErrBadSnapshot
+			}
+		}
+		changes = append(changes, cc)
+	}
+	return changes, nil
+}
+
+// WriteAheadLog is an append only log of the Put, Delete and ALTER calls
+// made on an in-memory table, replayed on restore to recover the writes
+// made since its last snapshot.  Each entry is length prefixed and checksummed,
+// a partially written last entry is discarded on open.
+type WriteAheadLog struct {
+	// Sync the file after each entry, surviving os crash not just process exit
+	Sync bool
+	mu   sync.Mutex
+	f    *os.File
+	buf  []byte
+}
+
+// OpenWriteAheadLog opens (creating if needed) the log at @path, calling
+// @replay with each entry already in it in order.
+func OpenWriteAheadLog(path string, replay func(op byte, vals []driver.Value) error) (*WriteAheadLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	by, err := ioutil.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	good := 0
+	for good < len(by) {
+		op, vals, n, ok := readWalEntry(by[good:])
+		if !ok {
+			break
+		}
+		if replay != nil {
+			if err = replay(op, vals); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+		good += n
+	}
+	// drop a torn entry from a crash mid write so appends follow good ones
+	if err = f.Truncate(int64(good)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err = f.Seek(int64(good), io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &WriteAheadLog{f: f}, nil
+}
+
+// WalReplay the replay func for OpenWriteAheadLog of a source, which
+// applies the logged writes with @put, @del and @alter.  @alter is nil
+// for sources which don't log ALTER TABLE.
+func WalReplay(put func(row []driver.Value) error, del func(key driver.Value),
+	alter func(changes []*schema.ColumnChange) error) func(op byte, vals []driver.Value) error {
+
+	return func(op byte, vals []driver.Value) error {
+		switch op {
+		case WalPut:
+			return put(vals)
+		case WalDelete:
+			// the row may since be gone from a snapshot taken after
+			if len(vals) == 1 {
+				del(vals[0])
+			}
+			return nil
+		case WalAlter:
+			if alter == nil {
+				break
+			}
+			changes, err := ColumnChangesFromRow(vals)
+			if err != nil {
+				return err
+			}
+			return alter(changes)
+		}
+		return fmt.Errorf("unknown write-ahead log entry %q", op)
+	}
+}
+
+func readWalEntry(by []byte) (byte, []driver.Value, int, bool) {
+	size, n := binary.Uvarint(by)
+	if n <= 0 || uint64(len(by)-n) < size+4 || size == 0 {
+		return 0, nil, 0, false
+	}
+	payload := by[n : n+int(size)]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(by[n+int(size):]) {
+		return 0, nil, 0, false
+	}
+	vals, err := ReadRow(bytes.NewReader(payload[1:]))
+	if err != nil {
+		return 0, nil, 0, false
+	}
+	return payload[0], vals, n + int(size) + 4, true
+}
+
+// Append an entry of @op with @vals, the row for a put, or key for a delete.
+func (m *WriteAheadLog) Append(op byte, vals ...driver.Value) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	payload, err := AppendRow([]byte{op}, vals)
+	if err != nil {
+		return err
+	}
+	m.buf = appendUvarint(m.buf[:0], uint64(len(payload)))
+	m.buf = append(m.buf, payload...)
+	m.buf = appendUint32(m.buf, crc32.ChecksumIEEE(payload))
+	if _, err = m.f.Write(m.buf); err != nil {
+		return err
+	}
+	if m.Sync {
+		return m.f.Sync()
+	}
+	return nil
+}
+
+// Truncate empties the log, after a snapshot has been written.
+func (m *WriteAheadLog) Truncate() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := m.f.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close the log file.
+func (m *WriteAheadLog) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.f.Close()
+}
+
+// AppendRow appends the binary encoding of @row to @buf.
+func AppendRow(buf []byte, row []driver.Value) ([]byte, error) {
+	buf = appendUvarint(buf, uint64(len(row)))
+	var err error
+	for _, v := range row {
+		if buf, err = appendValue(buf, v); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// ReadRow reads a row encoded by AppendRow.
+func ReadRow(r *bytes.Reader) ([]driver.Value, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return nil, ErrBadSnapshot
+	}
+	row := make([]driver.Value, n)
+	for i := range row {
+		if row[i], err = readValue(r); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+// appendValue appends a type tag and the value @v, smaller ints and
+// floats are widened to int64, float64.
+func appendValue(buf []byte, v interface{}) ([]byte, error) {
+	switch vt := v.(type) {
+	case nil:
+		return append(buf, tagNil), nil
+	case bool:
+		if vt {
+			return append(buf, tagTrue), nil
+		}
+		return append(buf, tagFalse), nil
+	case int:
+		return appendVarint(append(buf, tagInt), int64(vt)), nil
+	case int8:
+		return appendVarint(append(buf, tagInt64), int64(vt)), nil
+	case int16:
+		return appendVarint(append(buf, tagInt64), int64(vt)), nil
+	case int32:
+		return appendVarint(append(buf, tagInt64), int64(vt)), nil
+	case int64:
+		return appendVarint(append(buf, tagInt64), vt), nil
+	case uint:
+		return appendUvarint(append(buf, tagUint64), uint64(vt)), nil
+	case uint32:
+		return appendUvarint(append(buf, tagUint64), uint64(vt)), nil
+	case uint64:
+		return appendUvarint(append(buf, tagUint64), vt), nil
+	case float32:
+		return appendUint64(append(buf, tagFloat64), math.Float64bits(float64(vt))), nil
+	case float64:
+		return appendUint64(append(buf, tagFloat64), math.Float64bits(vt)), nil
+	case string:
+		return appendString(append(buf, tagString), vt), nil
+	case []byte:
+		buf = appendUvarint(append(buf, tagBytes), uint64(len(vt)))
+		return append(buf, vt...), nil
+	case time.Time:
+		by, err := vt.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = appendUvarint(append(buf, tagTime), uint64(len(by)))
+		return append(buf, by...), nil
+	case []string:
+		return appendStrings(append(buf, tagStrings), vt), nil
+	case []interface{}:
+		buf = appendUvarint(append(buf, tagSlice), uint64(len(vt)))
+		var err error
+		for _, iv := range vt {
+			if buf, err = appendValue(buf, iv); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]interface{}:
+		buf = appendUvarint(append(buf, tagMap), uint64(len(vt)))
+		var err error
+		for _, k := range sortedKeys(vt) {
+			buf = appendString(buf, k)
+			if buf, err = appendValue(buf, vt[k]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]string:
+		buf = appendUvarint(append(buf, tagStringMap), uint64(len(vt)))
+		keys := make([]string, 0, len(vt))
+		for k := range vt {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			buf = appendString(appendString(buf, k), vt[k])
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("cannot encode value of type %T", v)
+}
+
+func readValue(r *bytes.Reader) (interface{}, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, ErrBadSnapshot
+	}
+	switch tag {
+	case tagNil:
+		return nil, nil
+	case tagFalse:
+		return false, nil
+	case tagTrue:
+		return true, nil
+	case tagInt, tagInt64:
+		iv, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, ErrBadSnapshot
+		}
+		if tag == tagInt {
+			return int(iv), nil
+		}
+		return iv, nil
+	case tagUint64:
+		uv, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, ErrBadSnapshot
+		}
+		return uv, nil
+	case tagFloat64:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, ErrBadSnapshot
+		}
+		return math.Float64frombits(bits), nil
+	case tagString:
+		return readString(r)
+	case tagBytes:
+		return readBytes(r)
+	case tagTime:
+		by, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		var t time.Time
+		if err = t.UnmarshalBinary(by); err != nil {
+			return nil, ErrBadSnapshot
+		}
+		return t, nil
+	case tagStrings:
+		return readStrings(r)
+	case tagSlice:
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, ErrBadSnapshot
+		}
+		vals := make([]interface{}, n)
+		for i := range vals {
+			if vals[i], err = readValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return vals, nil
+	case tagMap:
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, ErrBadSnapshot
+		}
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := readString(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[k], err = readValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case tagStringMap:
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, ErrBadSnapshot
+		}
+		m := make(map[string]string, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := readString(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[k], err = readString(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+	return nil, ErrBadSnapshot
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutVarint(tmp[:], v)]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], v)
+	return append(buf, tmp[:]...)
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(buf, tmp[:]...)
+}
+
+func appendBool(buf []byte, b bool) []byte {
+	if b {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+func readBool(r *bytes.Reader) (bool, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return false, ErrBadSnapshot
+	}
+	return b == 1, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = appendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	by, err := readBytes(r)
+	return string(by), err
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return nil, ErrBadSnapshot
+	}
+	by := make([]byte, n)
+	if _, err = io.ReadFull(r, by); err != nil {
+		return nil, ErrBadSnapshot
+	}
+	return by, nil
+}
+
+func appendStrings(buf []byte, strs []string) []byte {
+	buf = appendUvarint(buf, uint64(len(strs)))
+	for _, s := range strs {
+		buf = appendString(buf, s)
+	}
+	return buf
+}
+
+func readStrings(r *bytes.Reader) ([]string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return nil, ErrBadSnapshot
+	}
+	strs := make([]string, 0, n)
+	for i := uint64(0); i < n; i++ {
+		s, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
@@ -0,0 +1,148 @@
+package datasource_test
+
+import (
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlbsnap")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "users.snap")
+
+	created := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := &datasource.Snapshot{
+		Table:   "users",
+		Columns: []string{"user_id", "name", "score", "created", "active", "tags", "attrs"},
+		Indexes: []*schema.Index{
+			{Name: "id", Fields: []string{"user_id"}, PrimaryKey: true},
+			{Name: "idx_name", Fields: []string{"name"}, Unique: true},
+			{Name: "idx_score", Fields: []string{"score"}},
+		},
+		Rows: [][]driver.Value{
+			{int64(1), "aaron", 9.5, created, true, []string{"a", "b"}, map[string]interface{}{"x": int64(1)}},
+			{int64(2), "bob", nil, created, false, nil, map[string]string{"y": "z"}},
+		},
+	}
+	assert.Equal(t, nil, datasource.WriteSnapshot(path, s))
+
+	s2, err := datasource.ReadSnapshot(path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, s.Table, s2.Table)
+	assert.Equal(t, s.Columns, s2.Columns)
+	assert.Equal(t, 3, len(s2.Indexes))
+	assert.Equal(t, true, s2.Indexes[0].PrimaryKey)
+	assert.Equal(t, []string{"name"}, s2.Indexes[1].Fields)
+	assert.Equal(t, true, s2.Indexes[1].Unique)
+	assert.Equal(t, false, s2.Indexes[2].Unique)
+	assert.Equal(t, 2, len(s2.Rows))
+	assert.Equal(t, "aaron", s2.Rows[0][1])
+	assert.Equal(t, 9.5, s2.Rows[0][2])
+	assert.True(t, created.Equal(s2.Rows[0][3].(time.Time)))
+	assert.Equal(t, []string{"a", "b"}, s2.Rows[0][5])
+	assert.Equal(t, map[string]interface{}{"x": int64(1)}, s2.Rows[0][6])
+	assert.Equal(t, nil, s2.Rows[1][2])
+	assert.Equal(t, map[string]string{"y": "z"}, s2.Rows[1][6])
+
+	// flip a byte, the checksum catches it
+	by, _ := ioutil.ReadFile(path)
+	by[len(by)/2] ^= 0xff
+	assert.Equal(t, nil, ioutil.WriteFile(path, by, 0644))
+	_, err = datasource.ReadSnapshot(path)
+	assert.Equal(t, datasource.ErrBadSnapshot, err)
+}
+
+func TestWriteAheadLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlbwal")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "users.wal")
+
+	wal, err := datasource.OpenWriteAheadLog(path, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, wal.Append(datasource.WalPut, int64(1), "aaron"))
+	assert.Equal(t, nil, wal.Append(datasource.WalDelete, int64(1)))
+	assert.Equal(t, nil, wal.Close())
+
+	// a crash part way through the last entry
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write([]byte{20, 'P', 2})
+	f.Close()
+
+	var ops []byte
+	var vals [][]driver.Value
+	replay := func(op byte, row []driver.Value) error {
+		ops = append(ops, op)
+		vals = append(vals, row)
+		return nil
+	}
+	wal, err = datasource.OpenWriteAheadLog(path, replay)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []byte{datasource.WalPut, datasource.WalDelete}, ops)
+	assert.Equal(t, []driver.Value{int64(1), "aaron"}, vals[0])
+
+	// appends follow the last good entry
+	assert.Equal(t, nil, wal.Append(datasource.WalPut, int64(2), "bob"))
+	assert.Equal(t, nil, wal.Close())
+	ops = nil
+	wal, err = datasource.OpenWriteAheadLog(path, replay)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(ops))
+
+	assert.Equal(t, nil, wal.Truncate())
+	assert.Equal(t, nil, wal.Close())
+	ops = nil
+	wal, err = datasource.OpenWriteAheadLog(path, replay)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(ops))
+	wal.Close()
+}
+
+func TestWalReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlbwal")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "users.wal")
+
+	wal, err := datasource.OpenWriteAheadLog(path, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, wal.Append(datasource.WalPut, int64(1), "aaron"))
+	assert.Equal(t, nil, wal.Append(datasource.WalDelete, int64(1)))
+	alter, err := datasource.ColumnChangesRow([]*schema.ColumnChange{{Name: "email"}})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, wal.Append(datasource.WalAlter, alter...))
+	assert.Equal(t, nil, wal.Close())
+
+	var puts [][]driver.Value
+	var deletes []driver.Value
+	var alters []string
+	put := func(row []driver.Value) error {
+		puts = append(puts, row)
+		return nil
+	}
+	del := func(key driver.Value) { deletes = append(deletes, key) }
+	wal, err = datasource.OpenWriteAheadLog(path, datasource.WalReplay(put, del,
+		func(changes []*schema.ColumnChange) error {
+			alters = append(alters, changes[0].Name)
+			return nil
+		}))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, [][]driver.Value{{int64(1), "aaron"}}, puts)
+	assert.Equal(t, []driver.Value{int64(1)}, deletes)
+	assert.Equal(t, []string{"email"}, alters)
+	wal.Close()
+
+	// a source which can't alter its table can't replay an alter
+	_, err = datasource.OpenWriteAheadLog(path, datasource.WalReplay(put, del, nil))
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,371 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/exec"
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+var (
+	// ensure our conn implements connection features
+	_ schema.ConnAll        = (*qryconn)(nil)
+	_ schema.ConnMutation   = (*qryconn)(nil)
+	_ schema.ConnPatchWhere = (*qryconn)(nil)
+	_ plan.SourcePlanner    = (*qryconn)(nil)
+)
+
+// qryconn is a single-query connection wrapping a sql rows object.
+type qryconn struct {
+	*exec.TaskBase
+	stmt   rel.SqlStatement
+	source *Source
+	tbl    *schema.Table
+	ps     *plan.Source
+	rows   *sql.Rows
+	ct     uint64
+	cols   []string
+	colidx map[string]int
+	err    error
+}
+
+func newQueryConn(tbl *schema.Table, source *Source) *qryconn {
+	return &qryconn{
+		tbl:    tbl,
+		cols:   tbl.Columns(),
+		colidx: tbl.FieldPositions,
+		source: source,
+	}
+}
+
+// Close the qryconn, closing the rows of the query if any.
+func (m *qryconn) Close() error {
+	if m.rows != nil {
+		if err := m.rows.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateIterator creates an interator to page through each row in this query resultset.
+func (m *qryconn) CreateIterator() schema.Iterator { return m }
+
+// Columns gets the columns used in this query.
+func (m *qryconn) Columns() []string { return m.cols }
+
+// CreateMutator part of Mutator interface to allow this connection to have access
+// to the full plan context to take original sql statement.
+func (m *qryconn) CreateMutator(pc interface{}) (schema.ConnMutator, error) {
+	if ctx, ok := pc.(*plan.Context); ok && ctx != nil {
+		m.TaskBase = exec.NewTaskBase(ctx)
+		m.stmt = ctx.Stmt
+		return m, nil
+	}
+	return nil, fmt.Errorf("Expected *plan.Context but got %T", pc)
+}
+
+func (m *qryconn) context() context.Context {
+	if m.TaskBase != nil && m.Ctx != nil && m.Ctx.Context != nil {
+		return m.Ctx.Context
+	}
+	return context.Background()
+}
+
+func (m *qryconn) table() string { return m.source.dialect.Identity(m.tbl.Name) }
+
+func (m *qryconn) columnList(cols []string) string {
+	quoted := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = m.source.dialect.Identity(col)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Next read the next row, if the select was not pushed down via
+// WalkSourceSelect (ie a join side) all rows of the table are scanned.
+func (m *qryconn) Next() schema.Message {
+	if m.rows == nil {
+		if m.err != nil {
+			return nil
+		}
+		qry := fmt.Sprintf("SELECT %s FROM %s", m.columnList(m.cols), m.table())
+		m.rows, m.err = m.source.db.QueryContext(m.context(), qry)
+		if m.err != nil {
+			u.Errorf("could not query %q err=%v", qry, m.err)
+			return nil
+		}
+	}
+	if m.TaskBase != nil {
+		select {
+		case <-m.SigChan():
+			return nil
+		default:
+		}
+	}
+	if !m.rows.Next() {
+		if err := m.rows.Err(); err != nil {
+			m.err = err
+			u.Warnf("err=%v", err)
+		}
+		return nil
+	}
+	vals, err := scanRow(m.rows, len(m.cols))
+	if err != nil {
+		m.err = err
+		u.Warnf("err=%v", err)
+		return nil
+	}
+	msg := datasource.NewSqlDriverMessageMap(m.ct, vals, m.colidx)
+	m.ct++
+	return msg
+}
+
+func scanRow(rows *sql.Rows, ct int) ([]driver.Value, error) {
+	readCols := make([]interface{}, ct)
+	writeCols := make([]driver.Value, ct)
+	for i := range writeCols {
+		readCols[i] = &writeCols[i]
+	}
+	if err := rows.Scan(readCols...); err != nil {
+		return nil, err
+	}
+	for i, col := range writeCols {
+		switch val := col.(type) {
+		case []uint8:
+			writeCols[i] = driver.Value(string(val))
+		}
+	}
+	return writeCols, nil
+}
+
+// WalkSourceSelect An interface implemented by this connection allowing the planner
+// to push down as much sql logic down to the database.
+func (m *qryconn) WalkSourceSelect(planner plan.Planner, p *plan.Source) (plan.Task, error) {
+
+	sqlSelect := p.Stmt.Source
+	if p.Final {
+		// single source, the whole statement is ours.  Join sides have
+		// already been rewritten to their own columns and predicates, with
+		// the column indexes into the joined row left intact.
+		p.Stmt.Source = nil
+		p.Stmt.Rewrite(sqlSelect)
+		sqlSelect = p.Stmt.Source
+		sqlSelect.RewriteAsRawSelect()
+	}
+
+	m.cols = sqlSelect.Columns.UnAliasedFieldNames()
+	m.colidx = sqlSelect.ColIndexes()
+	sqlString, err := NewRewriter(sqlSelect, m.source.dialect).Rewrite()
+	if err != nil {
+		return nil, err
+	}
+	u.Debugf("pushdown %s sql: %s", m.source.dialect.Name, sqlString)
+
+	m.TaskBase = exec.NewTaskBase(p.Context())
+	rows, err := m.source.db.QueryContext(m.context(), sqlString)
+	if err != nil {
+		u.Errorf("could not query %q err=%v", sqlString, err)
+		return nil, err
+	}
+	m.rows = rows
+	p.SourceExec = true
+	m.ps = p
+	return nil, nil
+}
+
+// Put interface for Upsert.Put(), rows are an update of the row of @key
+// if given else an insert, of all columns for []driver.Value rows or of
+// the given columns for map[string]driver.Value rows.
+func (m *qryconn) Put(ctx context.Context, key schema.Key, row interface{}) (schema.Key, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var cols []string
+	var args []interface{}
+	switch rowVals := row.(type) {
+	case []driver.Value:
+		if len(rowVals) != len(m.cols) {
+			return nil, fmt.Errorf("Wrong number of columns, got %v expected %v", len(rowVals), len(m.cols))
+		}
+		cols = m.cols
+		for _, v := range rowVals {
+			args = append(args, v)
+		}
+	case map[string]driver.Value:
+		for col := range rowVals {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+		for _, col := range cols {
+			args = append(args, rowVals[col])
+		}
+	default:
+		return nil, fmt.Errorf("Expected []driver.Value but got %T", row)
+	}
+
+	if key != nil {
+		keyCol, keyVal := m.keyCol(key)
+		sets := make([]string, len(cols))
+		for i, col := range cols {
+			sets[i] = fmt.Sprintf("%s = %s", m.source.dialect.Identity(col), m.source.dialect.Placeholder(i+1))
+		}
+		qry := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", m.table(), strings.Join(sets, ", "),
+			m.source.dialect.Identity(keyCol), m.source.dialect.Placeholder(len(cols)+1))
+		if _, err := m.source.db.ExecContext(ctx, qry, append(args, keyVal)...); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+
+	qry := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", m.table(), m.columnList(cols), m.source.dialect.Placeholders(len(cols)))
+	if _, err := m.source.db.ExecContext(ctx, qry, args...); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// PutMulti put each row of @src ([][]driver.Value) in turn
+func (m *qryconn) PutMulti(ctx context.Context, keys []schema.Key, src interface{}) ([]schema.Key, error) {
+	rows, ok := src.([][]driver.Value)
+	if !ok {
+		return nil, fmt.Errorf("Expected [][]driver.Value but got %T", src)
+	}
+	out := make([]schema.Key, len(rows))
+	for i, row := range rows {
+		var key schema.Key
+		if i < len(keys) {
+			key = keys[i]
+		}
+		k, err := m.Put(ctx, key, row)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = k
+	}
+	return out, nil
+}
+
+// keyCol the column, value of @key, the first column of the table unless
+// it is a named key.
+func (m *qryconn) keyCol(key schema.Key) (string, driver.Value) {
+	switch kc := key.(type) {
+	case datasource.KeyCol:
+		return kc.Name, kc.Val
+	case *datasource.KeyCol:
+		return kc.Name, kc.Val
+	}
+	return m.tbl.Columns()[0], key.Key()
+}
+
+// Get a single row by key (the first column of the table).
+func (m *qryconn) Get(key driver.Value) (schema.Message, error) {
+	if kc, ok := key.(datasource.KeyCol); ok {
+		key = kc.Val
+	}
+	cols := m.tbl.Columns()
+	qry := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", m.columnList(cols), m.table(),
+		m.source.dialect.Identity(cols[0]), m.source.dialect.Placeholder(1))
+	rows, err := m.source.db.QueryContext(m.context(), qry, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, schema.ErrNotFound
+	}
+	vals, err := scanRow(rows, len(cols))
+	if err != nil {
+		return nil, err
+	}
+	return datasource.NewSqlDriverMessageMap(0, vals, m.tbl.FieldPositions), nil
+}
+
+// Delete deletes a single row by key (the first column of the table)
+func (m *qryconn) Delete(key driver.Value) (int, error) {
+	qry := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", m.table(),
+		m.source.dialect.Identity(m.tbl.Columns()[0]), m.source.dialect.Placeholder(1))
+	if kc, ok := key.(datasource.KeyCol); ok {
+		key = kc.Val
+	}
+	return m.exec(m.context(), qry, key)
+}
+
+// DeleteExpression Delete using a Where Expression, pushed down to the database
+func (m *qryconn) DeleteExpression(p interface{}, where expr.Node) (int, error) {
+	whereSql, err := NewRewriter(nil, m.source.dialect).Where(where)
+	if err != nil {
+		return 0, err
+	}
+	return m.exec(m.context(), fmt.Sprintf("DELETE FROM %s WHERE %s", m.table(), whereSql))
+}
+
+// PatchWhere update the rows matching @where with the columns of @patch
+// (map[string]driver.Value) pushed down to the database, expr.Node values
+// are rewritten into the dialect.
+func (m *qryconn) PatchWhere(ctx context.Context, where expr.Node, patch interface{}) (int64, error) {
+	vals, ok := patch.(map[string]driver.Value)
+	if !ok {
+		return 0, fmt.Errorf("Expected map[string]driver.Value but got %T", patch)
+	}
+	cols := make([]string, 0, len(vals))
+	for col := range vals {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	sets := make([]string, len(cols))
+	args := make([]interface{}, 0, len(cols))
+	for i, col := range cols {
+		if node, ok := vals[col].(expr.Node); ok {
+			// an expression of the columns of the row, SET n = n + 1
+			valSql, err := NewRewriter(nil, m.source.dialect).Where(node)
+			if err != nil {
+				return 0, err
+			}
+			sets[i] = fmt.Sprintf("%s = %s", m.source.dialect.Identity(col), valSql)
+			continue
+		}
+		args = append(args, vals[col])
+		sets[i] = fmt.Sprintf("%s = %s", m.source.dialect.Identity(col), m.source.dialect.Placeholder(len(args)))
+	}
+	qry := fmt.Sprintf("UPDATE %s SET %s", m.table(), strings.Join(sets, ", "))
+	if where != nil {
+		whereSql, err := NewRewriter(nil, m.source.dialect).Where(where)
+		if err != nil {
+			return 0, err
+		}
+		qry += " WHERE " + whereSql
+	}
+	ct, err := m.exec(ctx, qry, args...)
+	return int64(ct), err
+}
+
+func (m *qryconn) exec(ctx context.Context, qry string, args ...interface{}) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	res, err := m.source.db.ExecContext(ctx, qry, args...)
+	if err != nil {
+		u.Warnf("could not exec %q err=%v", qry, err)
+		return 0, err
+	}
+	ct, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(ct), nil
+}
@@ -0,0 +1,205 @@
+package sqldb
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	dialectMu sync.Mutex
+	dialects  = make(map[string]*Dialect)
+
+	// driverDialects the dialect of well known database/sql driver names,
+	// other drivers must set the "dialect" setting.
+	driverDialects = map[string]string{
+		"mysql":    "mysql",
+		"postgres": "postgres",
+		"pgx":      "postgres",
+		"sqlite3":  "sqlite",
+		"sqlite":   "sqlite",
+	}
+)
+
+func init() {
+	RegisterDialect(&Dialect{
+		Name:          "mysql",
+		LiteralQuote:  '\'',
+		IdentityQuote: '`',
+		Funcs: map[string]string{
+			"tolower": "lower",
+			"toupper": "upper",
+			"len":     "char_length",
+		},
+		Placeholder: questionPlaceholder,
+		Columns:     informationSchemaColumns("DATABASE()", questionPlaceholder),
+	})
+	RegisterDialect(&Dialect{
+		Name:          "postgres",
+		LiteralQuote:  '\'',
+		IdentityQuote: '"',
+		Funcs: map[string]string{
+			"tolower": "lower",
+			"toupper": "upper",
+			"len":     "length",
+		},
+		Placeholder: dollarPlaceholder,
+		Columns:     informationSchemaColumns("current_schema()", dollarPlaceholder),
+	})
+	RegisterDialect(&Dialect{
+		Name:          "sqlite",
+		LiteralQuote:  '\'',
+		IdentityQuote: '"',
+		Funcs: map[string]string{
+			"tolower": "lower",
+			"toupper": "upper",
+			"len":     "length",
+		},
+		Placeholder: questionPlaceholder,
+		Columns: func(string) (string, []interface{}) {
+			// sqlite has no information_schema, only one schema per file
+			return `SELECT m.name, p.name, p.type FROM sqlite_master AS m
+				JOIN pragma_table_info(m.name) AS p
+				WHERE m.type = 'table' ORDER BY m.name, p.cid`, nil
+		},
+	})
+}
+
+// Dialect describes the flavor of sql spoken by a database/sql driver,
+// used to write the sql pushed down to it.
+type Dialect struct {
+	Name          string
+	LiteralQuote  byte              // quote for string literals
+	IdentityQuote byte              // quote for table, column names
+	Funcs         map[string]string // qlbridge func name -> native func name
+	// Placeholder the bind parameter for arg @i (1 based)
+	Placeholder func(i int) string
+	// Columns the query (and args) listing (table, column, data type)
+	// of tables in @dbschema, the connections default schema if empty.
+	Columns func(dbschema string) (string, []interface{})
+}
+
+// RegisterDialect makes a sql dialect available by name for "sql"
+// sources, ie {"dialect":"mysql"}.
+func RegisterDialect(d *Dialect) {
+	if d == nil || d.Name == "" {
+		panic("sql dialect must have a name")
+	}
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	name := strings.ToLower(d.Name)
+	if _, dupe := dialects[name]; dupe {
+		panic("Register called twice for sql dialect " + name)
+	}
+	dialects[name] = d
+}
+
+// DialectByName get a registered dialect
+func DialectByName(name string) (*Dialect, bool) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	d, ok := dialects[strings.ToLower(name)]
+	return d, ok
+}
+
+// dialectFor find the dialect for @name if given, else the default
+// dialect of database/sql @driver.
+func dialectFor(name, driver string) (*Dialect, error) {
+	if name == "" {
+		name = driverDialects[driver]
+		if name == "" {
+			return nil, fmt.Errorf("unknown sql dialect for driver %q, use the \"dialect\" setting", driver)
+		}
+	}
+	d, ok := DialectByName(name)
+	if !ok {
+		return nil, fmt.Errorf("sql dialect %q not found", name)
+	}
+	return d, nil
+}
+
+// Writer a new expr.DialectWriter using this dialects quoting.
+func (d *Dialect) Writer() expr.DialectWriter {
+	return &dialectWriter{DialectWriter: expr.NewDialectWriter(d.LiteralQuote, d.IdentityQuote)}
+}
+
+// Func the native name of qlbridge function @name, name itself if it
+// has no mapping.
+func (d *Dialect) Func(name string) string {
+	if native, ok := d.Funcs[strings.ToLower(name)]; ok {
+		return native
+	}
+	return name
+}
+
+// Placeholders the comma separated bind parameters for @n args
+func (d *Dialect) Placeholders(n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = d.Placeholder(i + 1)
+	}
+	return strings.Join(ph, ", ")
+}
+
+// Identity quote identity @name if needed
+func (d *Dialect) Identity(name string) string {
+	w := d.Writer()
+	w.WriteIdentity(name)
+	return w.String()
+}
+
+// dialectWriter expr.NewDialectWriter writes null as empty
+type dialectWriter struct {
+	expr.DialectWriter
+}
+
+func (w *dialectWriter) WriteNull() { io.WriteString(w, "NULL") }
+func (w *dialectWriter) WriteValue(v value.Value) {
+	if v == nil || v.Nil() {
+		w.WriteNull()
+		return
+	}
+	w.DialectWriter.WriteValue(v)
+}
+
+func questionPlaceholder(int) string { return "?" }
+func dollarPlaceholder(i int) string { return "$" + strconv.Itoa(i) }
+
+func informationSchemaColumns(current string, placeholder func(int) string) func(string) (string, []interface{}) {
+	return func(dbschema string) (string, []interface{}) {
+		qry := `SELECT table_name, column_name, data_type FROM information_schema.columns
+			WHERE table_schema = %s ORDER BY table_name, ordinal_position`
+		if dbschema == "" {
+			return fmt.Sprintf(qry, current), nil
+		}
+		return fmt.Sprintf(qry, placeholder(1)), []interface{}{dbschema}
+	}
+}
+
+// TypeFromString the value type of a database column type name, ie
+// "varchar(255)", "bigint", "timestamp with time zone".
+func TypeFromString(t string) value.ValueType {
+	t = strings.ToLower(strings.TrimSpace(t))
+	if idx := strings.IndexAny(t, "( "); idx > 0 {
+		t = t[:idx]
+	}
+	switch t {
+	case "bool", "boolean":
+		return value.BoolType
+	case "int", "integer", "tinyint", "smallint", "mediumint", "bigint",
+		"int2", "int4", "int8", "serial", "bigserial", "smallserial":
+		return value.IntType
+	case "real", "float", "float4", "float8", "double", "decimal", "numeric":
+		return value.NumberType
+	case "date", "datetime", "time", "timestamp", "timestamptz":
+		return value.TimeType
+	case "json", "jsonb":
+		return value.JsonType
+	}
+	return value.StringType
+}
@@ -0,0 +1,301 @@
+package sqldb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	// Ensure we import sqlite driver as the backing database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/datasource/membtree"
+	"github.com/fuhongbo/qlbridge/datasource/memdb"
+	td "github.com/fuhongbo/qlbridge/datasource/mockcsvtestdata"
+	"github.com/fuhongbo/qlbridge/datasource/sqldb"
+	"github.com/fuhongbo/qlbridge/datasource/sqlite"
+	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/testutil"
+)
+
+var (
+	sch      *schema.Schema
+	loadData sync.Once
+)
+
+func exitIfErr(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func LoadTestDataOnce(t *testing.T) {
+	loadData.Do(func() {
+		// load our mock data sources "users", "orders"
+		td.LoadTestDataOnce()
+
+		dir, err := ioutil.TempDir("", "sqldb")
+		exitIfErr(err)
+		dbFile := filepath.Join(dir, "test.db")
+
+		db, err := sql.Open("sqlite3", dbFile)
+		exitIfErr(err)
+		for _, tablename := range td.MockSchema.Tables() {
+			tbl, _ := td.MockSchema.Table(tablename)
+			if tbl == nil {
+				panic("missing table " + tablename)
+			}
+			_, err := db.Exec(sqlite.TableToString(tbl))
+			exitIfErr(err)
+		}
+		db.Close()
+
+		by := []byte(`{
+			"name": "sqldb_test",
+			"type": "sql",
+			"settings" : {
+			  "driver" : "sqlite3",
+			  "dsn" : "` + dbFile + `"
+			}
+		}`)
+		conf := &schema.ConfigSource{}
+		err = json.Unmarshal(by, conf)
+		assert.Equal(t, nil, err)
+
+		reg := schema.DefaultRegistry()
+		err = reg.SchemaAddFromConfig(conf)
+		assert.Equal(t, nil, err)
+
+		s, ok := reg.Schema("sqldb_test")
+		assert.Equal(t, true, ok)
+		assert.NotEqual(t, nil, s)
+		sch = s
+
+		// Copy the mock data into the database through the sql source
+		for _, tablename := range td.MockSchema.Tables() {
+			baseConn, err := td.MockSchema.OpenConn(tablename)
+			exitIfErr(err)
+
+			sdbConn, err := s.OpenConn(tablename)
+			exitIfErr(err)
+			upsert := sdbConn.(schema.ConnUpsert)
+
+			conn := baseConn.(schema.ConnScanner)
+			for {
+				msg := conn.Next()
+				if msg == nil {
+					break
+				}
+				sm := msg.(*datasource.SqlDriverMessageMap)
+				if _, err := upsert.Put(context.Background(), nil, sm.Vals); err != nil {
+					u.Errorf("could not insert %v  %#v", err, sm.Vals)
+				}
+			}
+			assert.Equal(t, nil, sdbConn.Close())
+		}
+
+		td.TestContext = planContext
+	})
+}
+
+func TestMain(m *testing.M) {
+	testutil.Setup() // will call flag.Parse()
+
+	// Now run the actual Tests
+	os.Exit(m.Run())
+}
+
+func planContext(query string) *plan.Context {
+	ctx := plan.NewContext(query)
+	ctx.DisableRecover = true
+	ctx.Schema = sch
+	ctx.Session = datasource.NewMySqlSessionVars()
+	return ctx
+}
+
+func TestSuite(t *testing.T) {
+	defer func() {
+		td.SetContextToMockCsv()
+	}()
+	LoadTestDataOnce(t)
+
+	tbl, err := sch.Table("users")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"user_id", "email", "interests", "reg_date", "referral_count", "json_data"}, tbl.Columns())
+
+	testutil.RunSimpleSuite(t)
+}
+
+func TestMutations(t *testing.T) {
+	defer func() {
+		td.SetContextToMockCsv()
+	}()
+	LoadTestDataOnce(t)
+	td.TestContext = planContext
+
+	testutil.TestSelect(t, `SELECT COUNT(*) AS ct FROM orders WHERE user_id = "9Ip1aKbeZe2njCDM"`,
+		[][]driver.Value{{int64(2)}},
+	)
+	db, err := sql.Open("qlbridge", "sqldb_test")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+
+	res, err := db.Exec(`UPDATE orders SET item_count = 9 WHERE user_id = "9Ip1aKbeZe2njCDM"`)
+	assert.Equal(t, nil, err)
+	affected, err := res.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), affected)
+	testutil.TestSelect(t, `SELECT order_id, item_count FROM orders WHERE user_id = "9Ip1aKbeZe2njCDM"`,
+		[][]driver.Value{{int64(1), int64(9)}, {int64(2), int64(9)}},
+	)
+
+	// an expression of the column is evaluated by the database per row
+	res, err = db.Exec(`UPDATE orders SET item_count = item_count + 1, price = 3.5 WHERE order_id = 1`)
+	assert.Equal(t, nil, err)
+	affected, err = res.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), affected)
+	testutil.TestSelect(t, `SELECT order_id, item_count, price FROM orders WHERE user_id = "9Ip1aKbeZe2njCDM"`,
+		[][]driver.Value{{int64(1), int64(10), float64(3.5)}, {int64(2), int64(9), float64(37.5)}},
+	)
+
+	res, err = db.Exec(`DELETE FROM orders WHERE user_id = "9Ip1aKbeZe2njCDM"`)
+	assert.Equal(t, nil, err)
+	affected, err = res.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), affected)
+	testutil.TestSelect(t, `SELECT order_id FROM orders WHERE user_id = "9Ip1aKbeZe2njCDM"`,
+		[][]driver.Value{},
+	)
+
+	// the conflicting row is read then updated by its key
+	_, err = db.Exec(`INSERT INTO orders (order_id, user_id, item_id, price, order_date, item_count)
+		VALUES (1, "9Ip1aKbeZe2njCDM", 1, 2.5, "2014-01-01", 1)`)
+	assert.Equal(t, nil, err)
+	_, err = db.Exec(`INSERT INTO orders (order_id, user_id, item_id, price, order_date, item_count)
+		VALUES (1, "9Ip1aKbeZe2njCDM", 1, 2.5, "2014-01-01", 4)
+		ON CONFLICT (order_id) DO UPDATE SET item_count = item_count + excluded.item_count`)
+	assert.Equal(t, nil, err)
+	testutil.TestSelect(t, `SELECT order_id, item_count FROM orders WHERE user_id = "9Ip1aKbeZe2njCDM"`,
+		[][]driver.Value{{int64(1), int64(5)}},
+	)
+}
+
+func TestRewriteDialects(t *testing.T) {
+	tests := []struct {
+		dialect string
+		sql     string
+		expect  string
+	}{
+		{"postgres",
+			"SELECT tolower(email), count(*) FROM users WHERE `first name` = \"bob\" AND interests != NULL",
+			`SELECT lower(email), count(*) FROM users WHERE "first name" = 'bob' AND interests IS NOT NULL `,
+		},
+		{"mysql",
+			"SELECT toupper(email) AS e FROM users WHERE len(email) > 5 AND name = \"bob\" ORDER BY email",
+			"SELECT upper(email) AS e FROM users WHERE char_length(email) > 5 AND name = 'bob' ORDER BY email",
+		},
+		{"sqlite",
+			"SELECT email FROM users WHERE tolower(email) LIKE \"%it's%\"",
+			`SELECT email FROM users WHERE lower(email) LIKE '%it''s%'`,
+		},
+	}
+	for _, tt := range tests {
+		d, ok := sqldb.DialectByName(tt.dialect)
+		assert.True(t, ok, tt.dialect)
+		stmt, err := rel.ParseSqlSelect(tt.sql)
+		assert.Equal(t, nil, err)
+		out, err := sqldb.NewRewriter(stmt, d).Rewrite()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tt.expect, out, tt.dialect)
+	}
+
+	_, ok := sqldb.DialectByName("oracle")
+	assert.False(t, ok)
+}
+
+func TestFederatedJoin(t *testing.T) {
+	LoadTestDataOnce(t)
+
+	// child schema "fed_orders" a sqlite table, "fed_users" an in memory one
+	// both under the parent schema "fed"
+	dir, err := ioutil.TempDir("", "sqldbfed")
+	assert.Equal(t, nil, err)
+	dbFile := filepath.Join(dir, "fed.db")
+	db, err := sql.Open("sqlite3", dbFile)
+	assert.Equal(t, nil, err)
+	_, err = db.Exec(`CREATE TABLE orders (order_id integer, user_id text, price real)`)
+	assert.Equal(t, nil, err)
+	_, err = db.Exec(`INSERT INTO orders VALUES (1,'u1',10.5),(2,'u1',20.0),(3,'u2',5.0),(4,'u9',1.0)`)
+	assert.Equal(t, nil, err)
+	db.Close()
+
+	reg := schema.DefaultRegistry()
+	conf := &schema.ConfigSource{}
+	err = json.Unmarshal([]byte(`{"name":"fed_orders","schema":"fed","type":"sql",
+		"settings":{"driver":"sqlite3","dsn":"`+dbFile+`"}}`), conf)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, reg.SchemaAddFromConfig(conf))
+
+	users, err := memdb.NewMemDbData("users", [][]driver.Value{
+		{"u1", "aaron@email.com"}, {"u2", "bob@email.com"}, {"u3", "cat@email.com"},
+	}, []string{"user_id", "email"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, reg.SchemaAddChild("fed", schema.NewSchemaSource("fed_users", users)))
+
+	// small table of known size, so may be joined by seeking users
+	visits := membtree.NewStaticDataSource("visits", 0, [][]driver.Value{
+		{"v1", "u2", "home"}, {"v2", "u3", "cart"}, {"v3", "u7", "home"},
+	}, []string{"visit_id", "user_id", "page"})
+	assert.Equal(t, nil, reg.SchemaAddChild("fed", schema.NewSchemaSource("fed_visits", visits)))
+
+	qdb, err := sql.Open("qlbridge", "fed")
+	assert.Equal(t, nil, err)
+	defer qdb.Close()
+
+	query := func(sqlText string) [][]string {
+		rows, err := qdb.Query(sqlText)
+		assert.Equal(t, nil, err, sqlText)
+		if err != nil {
+			return nil
+		}
+		defer rows.Close()
+		var out [][]string
+		for rows.Next() {
+			var a, b, c string
+			assert.Equal(t, nil, rows.Scan(&a, &b, &c))
+			out = append(out, []string{a, b, c})
+		}
+		assert.Equal(t, nil, rows.Err())
+		sort.Slice(out, func(i, j int) bool { return strings.Join(out[i], ",") < strings.Join(out[j], ",") })
+		return out
+	}
+
+	// sqlite and memdb, each side has its own where pushed down
+	assert.Equal(t, [][]string{{"u1", "aaron@email.com", "10.5"}, {"u1", "aaron@email.com", "20"}},
+		query(`SELECT u.user_id, u.email, o.price FROM users AS u
+			INNER JOIN orders AS o ON u.user_id = o.user_id
+			WHERE o.price > 6 AND u.email LIKE "%@email.com"`))
+
+	// visits (3 rows) seeks users by its key column
+	assert.Equal(t, [][]string{{"v1", "bob@email.com", "home"}, {"v2", "cat@email.com", "cart"}},
+		query(`SELECT v.visit_id, u.email, v.page FROM visits AS v
+			INNER JOIN users AS u ON v.user_id = u.user_id`))
+	assert.Equal(t, [][]string{{"v2", "cat@email.com", "cart"}},
+		query(`SELECT v.visit_id, u.email, v.page FROM visits AS v
+			INNER JOIN users AS u ON v.user_id = u.user_id
+			WHERE u.email != "bob@email.com"`))
+}
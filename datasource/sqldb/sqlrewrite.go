@@ -0,0 +1,295 @@
+package sqldb
+
+import (
+	"fmt"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/lex"
+	"github.com/fuhongbo/qlbridge/rel"
+)
+
+// Rewriter rewrites a qlbridge select statement into the sql dialect of
+// the underlying database so the whole statement can be pushed down.
+type Rewriter struct {
+	sel           *rel.SqlSelect
+	result        *rel.SqlSelect
+	dialect       *Dialect
+	needsPolyFill bool // do we request that features be polyfilled?
+}
+
+// NewRewriter create a rewriter of @stmt into sql @dialect
+func NewRewriter(stmt *rel.SqlSelect, dialect *Dialect) *Rewriter {
+	m := &Rewriter{
+		sel:     stmt,
+		result:  rel.NewSqlSelect(),
+		dialect: dialect,
+	}
+	return m
+}
+
+// Rewrite the select statement, returning the sql string to send to the
+// underlying database.
+func (m *Rewriter) Rewrite() (string, error) {
+
+	var err error
+
+	if m.sel.Where != nil {
+		m.result.Where = m.sel.Where
+		m.result.Where.Expr, err = m.walkNode(m.sel.Where.Expr)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Evaluate the Select columns make sure we can pass them down or polyfill
+	err = m.walkSelectList()
+	if err != nil {
+		u.Warnf("Could Not evaluate Columns/Aggs %s %v", m.sel.Columns.String(), err)
+		return "", err
+	}
+	m.result.From = m.sel.From
+
+	if len(m.sel.GroupBy) > 0 {
+		err = m.walkGroupBy()
+		if err != nil {
+			u.Warnf("Could Not evaluate GroupBys %s %v", m.sel.GroupBy.String(), err)
+			return "", err
+		}
+	}
+
+	if len(m.sel.OrderBy) > 0 {
+		for _, col := range m.sel.OrderBy {
+			if col.Expr != nil {
+				col.Expr = m.dialectFuncs(col.Expr)
+			}
+		}
+		m.result.OrderBy = m.sel.OrderBy
+	}
+
+	w := m.dialect.Writer()
+	m.result.WriteDialect(w)
+	return w.String(), nil
+}
+
+// Where rewrite a where expression into the sql dialect, for use in
+// pushed down DELETE, UPDATE statements.
+func (m *Rewriter) Where(where expr.Node) (string, error) {
+	n, err := m.walkNode(where)
+	if err != nil {
+		return "", err
+	}
+	w := m.dialect.Writer()
+	n.WriteDialect(w)
+	return w.String(), nil
+}
+
+// Aggregations from the <select_list>
+//
+//	SELECT <select_list> FROM ... WHERE
+func (m *Rewriter) walkSelectList() error {
+
+	m.result.Columns = m.sel.Columns
+
+	for i := len(m.result.Columns) - 1; i >= 0; i-- {
+		col := m.result.Columns[i]
+		if col.Expr != nil {
+			switch curNode := col.Expr.(type) {
+			case *expr.FuncNode:
+				// All Func Nodes are Aggregates?
+				newNode, err := m.selectFunc(curNode)
+				if err != nil {
+					u.Error(err)
+					return err
+				}
+				col.Expr = newNode
+			case *expr.IdentityNode:
+				// likely a projection, not agg
+			default:
+				col.Expr = m.dialectFuncs(curNode)
+			}
+		}
+
+	}
+	return nil
+}
+
+// Group By Clause
+//
+//	operation(field) FROM x GROUP BY x,y,z
+func (m *Rewriter) walkGroupBy() error {
+
+	for _, col := range m.sel.GroupBy {
+		if col.Expr != nil {
+			switch col.Expr.(type) {
+			case *expr.IdentityNode, *expr.FuncNode:
+				newExpr, err := m.walkNode(col.Expr)
+				if err != nil {
+					u.Error(err)
+					return err
+				}
+				col.Expr = newExpr
+			}
+		}
+	}
+
+	return nil
+}
+
+// expressions when used ast part of <select_list>
+func (m *Rewriter) selectFunc(cur expr.Node) (expr.Node, error) {
+	switch curNode := cur.(type) {
+	case *expr.FuncNode:
+		return m.walkProjectionFunc(curNode)
+	default:
+		u.Warnf("likely ?? not agg T:%T  %v", cur, cur)
+	}
+	return cur, nil
+}
+
+// Walk() an expression, and its logic to create an equivalent
+// expression in the sql dialect.
+//
+// - if can't express logic we need to allow qlbridge to poly-fill
+func (m *Rewriter) walkNode(cur expr.Node) (expr.Node, error) {
+	switch curNode := cur.(type) {
+	case *expr.NumberNode, *expr.StringNode:
+		return curNode, nil
+	case *expr.BinaryNode:
+		return m.walkFilterBinary(curNode)
+	case *expr.BooleanNode:
+		nn := *curNode
+		nn.Args = make([]expr.Node, len(curNode.Args))
+		for i, arg := range curNode.Args {
+			n, err := m.walkNode(arg)
+			if err != nil {
+				return nil, err
+			}
+			nn.Args[i] = n
+		}
+		return &nn, nil
+	case *expr.TriNode: // Between
+		return m.walkFilterTri(curNode)
+	case *expr.UnaryNode:
+		u.Warnf("not implemented: %#v", curNode)
+		return nil, fmt.Errorf("Not implemented urnary function: %v", curNode.String())
+	case *expr.FuncNode:
+		return m.walkFilterFunc(curNode)
+	case *expr.IdentityNode:
+		return curNode, nil
+	case *expr.ArrayNode:
+		return m.walkArrayNode(curNode)
+	default:
+		u.Debugf("unrecognized T:%T  %v", cur, cur)
+	}
+
+	return cur, nil
+}
+
+// Tri Nodes expressions:
+//
+//	<expression> [NOT] BETWEEN <expression> AND <expression>
+func (m *Rewriter) walkFilterTri(node *expr.TriNode) (expr.Node, error) {
+	return m.dialectFuncs(node), nil
+}
+
+// Array Nodes expressions:
+//
+//	year IN (1990,1992)  =>
+func (m *Rewriter) walkArrayNode(node *expr.ArrayNode) (expr.Node, error) {
+	return node, nil
+}
+
+// Binary Node:   operations for >, >=, <, <=, =, !=, AND, OR, Like, IN
+//
+//	x = NULL          =>   x IS NULL
+//	x != NULL         =>   x IS NOT NULL
+func (m *Rewriter) walkFilterBinary(node *expr.BinaryNode) (expr.Node, error) {
+
+	switch node.Operator.T {
+	case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE:
+		if node.Args[1].String() == "NULL" {
+			nn := *node
+			nn.Args = []expr.Node{m.dialectFuncs(node.Args[0]), expr.NewIdentityNodeVal("")}
+			if node.Operator.T == lex.TokenNE {
+				nn.Operator.V = "IS NOT NULL"
+			} else {
+				nn.Operator.V = "IS NULL"
+			}
+			return &nn, nil
+		}
+	case lex.TokenLogicAnd, lex.TokenLogicOr, lex.TokenAnd, lex.TokenOr:
+		lh, err := m.walkNode(node.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		rh, err := m.walkNode(node.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		nn := *node
+		nn.Args = []expr.Node{lh, rh}
+		return &nn, nil
+	}
+
+	return m.dialectFuncs(node), nil
+}
+
+// Take an expression func, and map it to the function name of the dialect
+//
+//	tolower(fieldname)  =>  lower(fieldname)
+func (m *Rewriter) walkFilterFunc(node *expr.FuncNode) (expr.Node, error) {
+	return m.dialectFuncs(node), nil
+}
+
+// Take an expression func, and map that function to a native sql aggregate
+// or function.
+//
+// Single Value Aggregates:
+//
+//	min, max, avg, sum, count
+func (m *Rewriter) walkProjectionFunc(node *expr.FuncNode) (expr.Node, error) {
+	return m.dialectFuncs(node), nil
+}
+
+// dialectFuncs copy of expression @n with func names mapped to those of
+// the dialect, the original is left alone as it is shared with the
+// qlbridge side of the plan.
+func (m *Rewriter) dialectFuncs(n expr.Node) expr.Node {
+	args := func(nodes []expr.Node) []expr.Node {
+		out := make([]expr.Node, len(nodes))
+		for i, arg := range nodes {
+			out[i] = m.dialectFuncs(arg)
+		}
+		return out
+	}
+	switch cur := n.(type) {
+	case *expr.FuncNode:
+		nn := *cur
+		nn.Name = m.dialect.Func(cur.Name)
+		nn.Args = args(cur.Args)
+		return &nn
+	case *expr.BinaryNode:
+		nn := *cur
+		nn.Args = args(cur.Args)
+		return &nn
+	case *expr.BooleanNode:
+		nn := *cur
+		nn.Args = args(cur.Args)
+		return &nn
+	case *expr.TriNode:
+		nn := *cur
+		nn.Args = args(cur.Args)
+		return &nn
+	case *expr.UnaryNode:
+		nn := *cur
+		nn.Arg = m.dialectFuncs(cur.Arg)
+		return &nn
+	case *expr.ArrayNode:
+		nn := *cur
+		nn.Args = args(cur.Args)
+		return &nn
+	}
+	return n
+}
@@ -17,6 +17,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/datasource/sqldb"
 	"github.com/fuhongbo/qlbridge/exec"
 	"github.com/fuhongbo/qlbridge/expr"
 	"github.com/fuhongbo/qlbridge/plan"
@@ -34,6 +35,9 @@ var (
 	// 	WalkSourceSelect(pl Planner, s *Source) (Task, error)
 	// }
 	_ plan.SourcePlanner = (*qryconn)(nil)
+
+	// dialect of the sql pushed down to sqlite
+	dialect, _ = sqldb.DialectByName("sqlite")
 )
 
 type (
@@ -54,6 +58,9 @@ type (
 		err       error
 		sqlInsert string
 		sqlUpdate string
+		sqlExists string
+		sqlGet    string
+		sqlDelete string
 	}
 )
 
@@ -75,6 +82,17 @@ func (m *qryconn) init() {
 		vals[i] = "?"
 	}
 	m.sqlInsert = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", m.tbl.Name, strings.Join(cols, ", "), strings.Join(vals, ", "))
+	if len(cols) > 0 {
+		keyCol := cols[m.indexCol]
+		sets := make([]string, len(cols))
+		for i, col := range cols {
+			sets[i] = col + " = ?"
+		}
+		m.sqlUpdate = fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?;", m.tbl.Name, strings.Join(sets, ", "), keyCol)
+		m.sqlExists = fmt.Sprintf("SELECT count(*) FROM %s WHERE %s = ?;", m.tbl.Name, keyCol)
+		m.sqlGet = fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?;", strings.Join(cols, ", "), m.tbl.Name, keyCol)
+		m.sqlDelete = fmt.Sprintf("DELETE FROM %s WHERE %s = ?;", m.tbl.Name, keyCol)
+	}
 }
 
 // Close the qryconn.  Since sqlite is a NON-threadsafe db, this is very important
@@ -162,7 +180,8 @@ func (m *qryconn) Next() schema.Message {
 	}
 }
 
-// Put interface for Upsert.Put() to do single row insert based on key.
+// Put interface for Upsert.Put() to do single row insert or update of the
+// row of the same key.
 func (m *qryconn) Put(ctx context.Context, key schema.Key, row interface{}) (schema.Key, error) {
 
 	//u.Infof("%p Put(),  row:%#v", m, row)
@@ -173,60 +192,88 @@ func (m *qryconn) Put(ctx context.Context, key schema.Key, row interface{}) (sch
 			return nil, fmt.Errorf("Wrong number of columns, got %v expected %v", len(rowVals), len(m.Columns()))
 		}
 
-		id := MakeId(rowVals[m.indexCol])
-
-		row := m.source.db.QueryRow(fmt.Sprintf("SELECT * FROM %v WHERE %s = $1", m.tbl.Name, m.cols[0]), rowVals[m.indexCol])
-		vals := make([]driver.Value, len(m.cols))
-		if err := row.Scan(&vals); err != nil && err != sql.ErrNoRows {
+		keyVal := rowVals[m.indexCol]
+		var ct int64
+		if err := m.source.db.QueryRow(m.sqlExists, keyVal).Scan(&ct); err != nil {
 			u.Warnf("could not get current? %v", err)
 			return nil, err
-		} else if err == sql.ErrNoRows {
-			//u.Debugf("empty, now do insert")
-			//sdm := datasource.NewSqlDriverMessageMap(id, rowVals, m.tbl.FieldPositions)
-			ivals := make([]interface{}, len(rowVals))
-			for i, v := range rowVals {
-				ivals[i] = v
-			}
-			_, err = m.source.db.Exec(m.sqlInsert, ivals...)
-			if err != nil {
-				u.Warnf("wtf %v", err)
+		}
+		ivals := make([]interface{}, len(rowVals), len(rowVals)+1)
+		for i, v := range rowVals {
+			ivals[i] = v
+		}
+		if ct == 0 {
+			if _, err := m.source.db.Exec(m.sqlInsert, ivals...); err != nil {
+				return nil, err
 			}
-			//u.Debugf("%p  PUT: id:%v IdVal:%v  Id():%v vals:%#v", m, id, sdm.IdVal, sdm.Id(), rowVals)
 		} else {
-			u.Debugf("found current? %v", vals)
-			sdm := datasource.NewSqlDriverMessageMap(id, rowVals, m.tbl.FieldPositions)
-			_, err = m.source.db.Exec(m.stmt.String(), nil)
-			if err != nil {
-				u.Warnf("wtf %v", err)
+			if _, err := m.source.db.Exec(m.sqlUpdate, append(ivals, keyVal)...); err != nil {
+				return nil, err
 			}
-			u.Debugf("%p  PUT: id:%v IdVal:%v  Id():%v vals:%#v", m, id, sdm.IdVal, sdm.Id(), rowVals)
 		}
-
-		return NewKey(id), nil
+		return NewKey(MakeId(keyVal)), nil
 	default:
 		u.Warnf("not implemented %T", row)
 		return nil, fmt.Errorf("Expected []driver.Value but got %T", row)
 	}
 }
 
+// PutMulti put each row of @src ([][]driver.Value) in turn
 func (m *qryconn) PutMulti(ctx context.Context, keys []schema.Key, src interface{}) ([]schema.Key, error) {
-	return nil, fmt.Errorf("not implemented")
+	rows, ok := src.([][]driver.Value)
+	if !ok {
+		return nil, fmt.Errorf("Expected [][]driver.Value but got %T", src)
+	}
+	out := make([]schema.Key, len(rows))
+	for i, row := range rows {
+		var key schema.Key
+		if i < len(keys) {
+			key = keys[i]
+		}
+		k, err := m.Put(ctx, key, row)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = k
+	}
+	return out, nil
 }
 
 // Get a single row by key.
 func (m *qryconn) Get(key driver.Value) (schema.Message, error) {
 
-	row := m.source.db.QueryRow(fmt.Sprintf("SELECT * FROM %v WHERE %s = $1", m.tbl.Name, m.cols[0]), key)
 	vals := make([]driver.Value, len(m.cols))
-	if err := row.Scan(&vals); err != nil {
+	dest := make([]interface{}, len(m.cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	if err := m.source.db.QueryRow(m.sqlGet, key).Scan(dest...); err == sql.ErrNoRows {
+		return nil, schema.ErrNotFound
+	} else if err != nil {
 		return nil, err
 	}
-	return datasource.NewSqlDriverMessageMap(0, vals, m.colidx), nil
+	for i, val := range vals {
+		if by, ok := val.([]uint8); ok {
+			vals[i] = string(by)
+		}
+	}
+	return datasource.NewSqlDriverMessageMap(MakeId(key), vals, m.tbl.FieldPositions), nil
 }
 
 // Delete deletes a single row by key
 func (m *qryconn) Delete(key driver.Value) (int, error) {
-	return -1, schema.ErrNotImplemented
+	res, err := m.source.db.Exec(m.sqlDelete, key)
+	if err != nil {
+		return 0, err
+	}
+	ct, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if ct == 0 {
+		return 0, schema.ErrNotFound
+	}
+	return int(ct), nil
 }
 
 // WalkSourceSelect An interface implemented by this connection allowing the planner
@@ -235,15 +282,20 @@ func (m *qryconn) WalkSourceSelect(planner plan.Planner, p *plan.Source) (plan.T
 
 	sqlSelect := p.Stmt.Source
 	u.Infof("original %s", sqlSelect.String())
-	p.Stmt.Source = nil
-	p.Stmt.Rewrite(sqlSelect)
-	sqlSelect = p.Stmt.Source
-	u.Infof("original after From(source) rewrite %s", sqlSelect.String())
-	sqlSelect.RewriteAsRawSelect()
+	if p.Final {
+		p.Stmt.Source = nil
+		p.Stmt.Rewrite(sqlSelect)
+		sqlSelect = p.Stmt.Source
+		u.Infof("original after From(source) rewrite %s", sqlSelect.String())
+		sqlSelect.RewriteAsRawSelect()
+	}
 
 	m.cols = sqlSelect.Columns.UnAliasedFieldNames()
 	m.colidx = sqlSelect.ColIndexes()
-	sqlString, _ := newRewriter(sqlSelect).rewrite()
+	sqlString, err := sqldb.NewRewriter(sqlSelect, dialect).Rewrite()
+	if err != nil {
+		return nil, err
+	}
 
 	u.Infof("after sqlite-rewrite %s", sqlSelect.String())
 	u.Infof("pushdown sql: %s", sqlString)
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/fuhongbo/qlbridge/datasource"
 	"github.com/fuhongbo/qlbridge/schema"
 	"github.com/fuhongbo/qlbridge/value"
 )
@@ -17,8 +16,7 @@ var (
 )
 
 func init() {
-	datasource.DialectWriterCols = append(datasource.DialectWriterCols, "sqlite")
-	datasource.DialectWriters = append(datasource.DialectWriters, &sqliteWriter{})
+	schema.RegisterDialectWriter(&sqliteWriter{})
 }
 
 type sqliteWriter struct {
@@ -42,10 +40,16 @@ func (m *sqliteWriter) Table(tbl *schema.Table) string {
 
 // TableToString Table output a CREATE TABLE statement using mysql dialect.
 func TableToString(tbl *schema.Table) string {
+	return createTableSQL(tbl.Name, tbl)
+}
+
+// createTableSQL the CREATE TABLE statement for the fields, primary key
+// of @tbl as table @name.
+func createTableSQL(name string, tbl *schema.Table) string {
 
 	w := &bytes.Buffer{}
 	//u.Infof("%s tbl=%p fields? %#v fields?%v", tbl.Name, tbl, tbl.FieldMap, len(tbl.Fields))
-	fmt.Fprintf(w, "CREATE TABLE `%s` (", tbl.Name)
+	fmt.Fprintf(w, "CREATE TABLE `%s` (", name)
 	for i, fld := range tbl.Fields {
 		if i != 0 {
 			w.WriteByte(',')
@@ -53,6 +57,11 @@ func TableToString(tbl *schema.Table) string {
 		fmt.Fprint(w, "\n    ")
 		WriteField(w, fld)
 	}
+	for _, idx := range tbl.Indexes {
+		if idx.PrimaryKey && len(idx.Fields) > 0 {
+			fmt.Fprintf(w, ",\n    PRIMARY KEY (`%s`)", strings.Join(idx.Fields, "`, `"))
+		}
+	}
 	fmt.Fprint(w, "\n);")
 	//tblStr := fmt.Sprintf("CREATE TABLE `%s` (\n\n);", tbl.Name, strings.Join(cols, ","))
 	//return tblStr, nil
@@ -91,6 +100,23 @@ func WriteField(w *bytes.Buffer, fld *schema.Field) {
 	if len(fld.Description) > 0 {
 		fmt.Fprintf(w, " COMMENT %q", fld.Description)
 	}
+	if fld.NoNulls {
+		fmt.Fprint(w, " NOT NULL")
+	}
+	if def := fld.DefaultValue(); def != nil {
+		switch dv := def.(type) {
+		case string:
+			fmt.Fprintf(w, " DEFAULT '%s'", strings.Replace(dv, "'", "''", -1))
+		case bool:
+			if dv {
+				fmt.Fprint(w, " DEFAULT 1")
+			} else {
+				fmt.Fprint(w, " DEFAULT 0")
+			}
+		default:
+			fmt.Fprintf(w, " DEFAULT %v", dv)
+		}
+	}
 }
 
 // TypeFromString given a string, return data type
@@ -3,7 +3,9 @@ package sqlite
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -13,6 +15,7 @@ import (
 
 	"github.com/fuhongbo/qlbridge/expr"
 	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
 )
 
 const (
@@ -27,7 +30,8 @@ func init() {
 
 var (
 	// Ensure our source implements Source interface
-	_ schema.Source = (*Source)(nil)
+	_ schema.Source    = (*Source)(nil)
+	_ schema.SourceDDL = (*Source)(nil)
 	// ensure our Source implements connection features
 	_ schema.Conn = (*Source)(nil)
 )
@@ -163,17 +167,291 @@ func tableFromSQL(name, sqls string) *schema.Table {
 	//u.Debugf("%s  %v", name, sqls)
 	cols := strings.Split(sqls, "\n")
 	cols = cols[1 : len(cols)-1]
+	var pk []string
 	for _, cols := range cols {
 		parts := strings.Split(strings.Trim(cols, " \t,"), " ")
 		if len(parts) < 2 {
 			continue
 		}
+		switch strings.ToUpper(parts[0]) {
+		case "PRIMARY":
+			// PRIMARY KEY (`a`, `b`)
+			for _, p := range parts[2:] {
+				pk = append(pk, expr.IdentityTrim(strings.Trim(p, "(),")))
+			}
+			continue
+		case "UNIQUE", "CONSTRAINT", "FOREIGN", "CHECK":
+			continue
+		}
 		colName := expr.IdentityTrim(parts[0])
-		// NewFieldBase(name string, valType value.ValueType, size int, desc string)
-		t.AddField(schema.NewFieldBase(colName, TypeFromString(parts[1]), 255, ""))
+		vt := TypeFromString(parts[1])
+		allowNulls := true
+		var def driver.Value
+		for i := 2; i < len(parts); i++ {
+			switch strings.ToUpper(parts[i]) {
+			case "NOT":
+				if i+1 < len(parts) && strings.ToUpper(parts[i+1]) == "NULL" {
+					allowNulls = false
+				}
+			case "DEFAULT":
+				if i+1 < len(parts) {
+					def = defaultFromSQL(vt, parts[i+1])
+				}
+			}
+		}
+		// NewField(name, valType, size, allowNulls, defaultVal, key, collation, description)
+		t.AddField(schema.NewField(colName, vt, typeSize(parts[1]), allowNulls, def, "", "", ""))
 		// u.Debugf("%d  %v", i, parts)
 		// u.Debugf("%q", expr.IdentityTrim(parts[0]))
 	}
+	if len(pk) > 0 {
+		for _, fn := range pk {
+			if f, ok := t.FieldMap[fn]; ok {
+				f.Key = "PRI"
+			}
+		}
+		t.Indexes = append(t.Indexes, &schema.Index{Name: "primary", Fields: pk, PrimaryKey: true})
+	}
 	t.SetColumnsFromFields()
 	return t
 }
+
+// typeSize the declared size of a column type such as varchar(50), 0 if
+// none as sqlite types such as TEXT have no length.
+func typeSize(typ string) int {
+	start, end := strings.Index(typ, "("), strings.Index(typ, ")")
+	if start < 0 || end < start {
+		return 0
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(typ[start+1 : end]))
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// defaultFromSQL the value of a column DEFAULT clause in create statement.
+func defaultFromSQL(vt value.ValueType, s string) driver.Value {
+	if strings.EqualFold(s, "null") {
+		return nil
+	}
+	if len(s) > 1 && s[0] == '\'' {
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+	}
+	switch vt {
+	case value.IntType:
+		if iv, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return iv
+		}
+	case value.NumberType:
+		if fv, err := strconv.ParseFloat(s, 64); err == nil {
+			return fv
+		}
+	}
+	return s
+}
+
+// CreateTable create a new table in the sqlite db.
+func (m *Source) CreateTable(tbl *schema.Table) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.db == nil {
+		return fmt.Errorf("sqlite source is not open")
+	}
+	if _, err := m.db.Exec(TableToString(tbl)); err != nil {
+		return err
+	}
+	return m.loadTable(tbl.Name)
+}
+
+// DropTable drop the table from the sqlite db.
+func (m *Source) DropTable(table string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.db == nil {
+		return fmt.Errorf("sqlite source is not open")
+	}
+	table = strings.ToLower(table)
+	if _, err := m.db.Exec(fmt.Sprintf("DROP TABLE `%s`;", table)); err != nil {
+		return err
+	}
+	m.tblmu.Lock()
+	defer m.tblmu.Unlock()
+	delete(m.tables, table)
+	tl := make([]string, 0, len(m.tableList))
+	for _, tn := range m.tableList {
+		if tn != table {
+			tl = append(tl, tn)
+		}
+	}
+	m.tableList = tl
+	return nil
+}
+
+// AlterTable apply column changes to the table.  The bundled sqlite can
+// only add columns, so the table is rebuilt: a new table is created, rows
+// copied over, the old table dropped and the new one renamed.
+func (m *Source) AlterTable(table string, changes []*schema.ColumnChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.db == nil {
+		return fmt.Errorf("sqlite source is not open")
+	}
+	table = strings.ToLower(table)
+	m.tblmu.Lock()
+	tbl, ok := m.tables[table]
+	m.tblmu.Unlock()
+	if !ok {
+		return schema.ErrNotFound
+	}
+	nt, srcs, err := schema.ApplyColumnChanges(tbl, changes)
+	if err != nil {
+		return err
+	}
+
+	tmp := table + "__alter"
+	var to, from []string
+	renames := make(map[string]string, len(srcs))
+	for i, src := range srcs {
+		if src >= 0 {
+			to = append(to, "`"+nt.Fields[i].Name+"`")
+			from = append(from, "`"+tbl.Fields[src].Name+"`")
+			renames[tbl.Fields[src].Name] = nt.Fields[i].Name
+		}
+	}
+	// dropping the old table drops its indexes, so they are re-created
+	// on the new one after the copy
+	indexes, err := m.indexSQL(table, renames)
+	if err != nil {
+		return err
+	}
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmts := []string{createTableSQL(tmp, nt)}
+	if len(to) > 0 {
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO `%s` (%s) SELECT %s FROM `%s`;",
+			tmp, strings.Join(to, ", "), strings.Join(from, ", "), table))
+	}
+	stmts = append(stmts,
+		fmt.Sprintf("DROP TABLE `%s`;", table),
+		fmt.Sprintf("ALTER TABLE `%s` RENAME TO `%s`;", tmp, table),
+	)
+	stmts = append(stmts, indexes...)
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	return m.loadTable(table)
+}
+
+// indexSQL the CREATE INDEX statements for the secondary indexes (and
+// UNIQUE constraints) of table, with columns renamed per @renames.  An
+// index on a column which is no longer in @renames is dropped with it.
+func (m *Source) indexSQL(table string, renames map[string]string) ([]string, error) {
+	type index struct {
+		name   string
+		unique bool
+		origin string
+	}
+	rows, err := m.db.Query(fmt.Sprintf("PRAGMA index_list(`%s`);", table))
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	var indexes []index
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range vals {
+			dest[i] = &vals[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		idx := index{}
+		for i, col := range cols {
+			switch col {
+			case "name":
+				idx.name = fmt.Sprintf("%s", vals[i])
+			case "unique":
+				idx.unique = fmt.Sprintf("%v", vals[i]) == "1"
+			case "origin":
+				idx.origin = fmt.Sprintf("%s", vals[i])
+			}
+		}
+		if idx.origin != "pk" {
+			indexes = append(indexes, idx)
+		}
+	}
+	rows.Close()
+
+	stmts := make([]string, 0, len(indexes))
+	for _, idx := range indexes {
+		var fields []string
+		irows, err := m.db.Query(fmt.Sprintf("PRAGMA index_info(`%s`);", idx.name))
+		if err != nil {
+			return nil, err
+		}
+		for irows.Next() {
+			var seqno, cid int64
+			var name sql.NullString
+			if err = irows.Scan(&seqno, &cid, &name); err != nil {
+				irows.Close()
+				return nil, err
+			}
+			fields = append(fields, name.String)
+		}
+		irows.Close()
+
+		names := make([]string, 0, len(fields))
+		for _, f := range fields {
+			if to, ok := renames[f]; ok {
+				names = append(names, to)
+			}
+		}
+		if len(names) == 0 || len(names) != len(fields) {
+			continue
+		}
+		name := idx.name
+		if idx.origin == "u" {
+			// sqlite_autoindex_ names are reserved, name the constraint
+			name = fmt.Sprintf("%s_%s_key", table, strings.Join(names, "_"))
+		}
+		create := "CREATE INDEX"
+		if idx.unique {
+			create = "CREATE UNIQUE INDEX"
+		}
+		stmts = append(stmts, fmt.Sprintf("%s `%s` ON `%s` (`%s`);", create, name, table, strings.Join(names, "`, `")))
+	}
+	return stmts, nil
+}
+
+// loadTable (re)load the schema of table from sqlite_master.
+func (m *Source) loadTable(table string) error {
+	table = strings.ToLower(table)
+	var sqls string
+	row := m.db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND lower(tbl_name) = ?;", table)
+	if err := row.Scan(&sqls); err != nil {
+		return err
+	}
+	t := tableFromSQL(table, sqls)
+	m.tblmu.Lock()
+	defer m.tblmu.Unlock()
+	if _, exists := m.tables[table]; !exists {
+		m.tableList = append(m.tableList, table)
+	}
+	m.tables[table] = t
+	return nil
+}
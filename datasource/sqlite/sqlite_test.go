@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"os"
 	"sync"
 	"testing"
@@ -17,6 +18,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/exec"
 	"github.com/fuhongbo/qlbridge/plan"
 	"github.com/fuhongbo/qlbridge/schema"
 	"github.com/fuhongbo/qlbridge/testutil"
@@ -152,3 +154,164 @@ func TestSuite(t *testing.T) {
 	LoadTestDataOnce(t)
 	testutil.RunSimpleSuite(t)
 }
+
+func TestDDL(t *testing.T) {
+	LoadTestDataOnce(t)
+	exec.RegisterSqlDriver()
+
+	sdb, err := sql.Open("qlbridge", "sqlite_test")
+	assert.Equal(t, nil, err)
+	defer sdb.Close()
+
+	_, err = sdb.Exec(`CREATE TABLE ddltest (
+		id int NOT NULL,
+		name varchar(50) NOT NULL DEFAULT "none",
+		PRIMARY KEY (id)
+	)`)
+	assert.Equal(t, nil, err)
+	tbl, err := sch.Table("ddltest")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "name"}, tbl.Columns())
+	assert.Equal(t, "PRI", tbl.FieldMap["id"].Key)
+	assert.True(t, tbl.FieldMap["name"].NoNulls)
+	assert.Equal(t, "none", tbl.FieldMap["name"].DefaultValue())
+
+	_, err = sdb.Exec(`INSERT INTO ddltest (id, name) VALUES (1, "a"), (2, "b")`)
+	assert.Equal(t, nil, err)
+
+	// secondary indexes survive the table rebuild of ALTER
+	rawdb, err := sql.Open("sqlite3", testFile)
+	assert.Equal(t, nil, err)
+	defer rawdb.Close()
+	_, err = rawdb.Exec("CREATE UNIQUE INDEX ddltest_name ON ddltest (name);")
+	assert.Equal(t, nil, err)
+
+	_, err = sdb.Exec(`ALTER TABLE ddltest ADD COLUMN score int DEFAULT 7, CHANGE name title varchar(50)`)
+	assert.Equal(t, nil, err)
+	tbl, err = sch.Table("ddltest")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "title", "score"}, tbl.Columns())
+
+	var idxName, idxCol string
+	assert.Equal(t, nil, rawdb.QueryRow("SELECT name FROM sqlite_master WHERE type='index' AND tbl_name='ddltest';").Scan(&idxName))
+	assert.Equal(t, "ddltest_name", idxName)
+	assert.Equal(t, nil, rawdb.QueryRow("SELECT name FROM pragma_index_info('ddltest_name');").Scan(&idxCol))
+	assert.Equal(t, "title", idxCol)
+	_, err = rawdb.Exec("INSERT INTO ddltest (id, title) VALUES (3, 'a');")
+	assert.NotEqual(t, nil, err)
+
+	rows, err := sdb.Query(`SELECT title, score FROM ddltest WHERE id = 2`)
+	assert.Equal(t, nil, err)
+	var title string
+	var score int64
+	assert.True(t, rows.Next())
+	assert.Equal(t, nil, rows.Scan(&title, &score))
+	assert.Equal(t, "b", title)
+	assert.Equal(t, int64(7), score)
+	rows.Close()
+
+	_, err = sdb.Exec(`DROP TABLE ddltest`)
+	assert.Equal(t, nil, err)
+	_, err = sch.Table("ddltest")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestInsertSelectSelf(t *testing.T) {
+	LoadTestDataOnce(t)
+	exec.RegisterSqlDriver()
+
+	sdb, err := sql.Open("qlbridge", "sqlite_test")
+	assert.Equal(t, nil, err)
+	defer sdb.Close()
+
+	_, err = sdb.Exec(`CREATE TABLE copies (id varchar(20) NOT NULL, name varchar(20), PRIMARY KEY (id))`)
+	assert.Equal(t, nil, err)
+	defer sdb.Exec(`DROP TABLE copies`)
+	_, err = sdb.Exec(`CREATE TABLE copies2 (id varchar(20) NOT NULL, name varchar(20), PRIMARY KEY (id))`)
+	assert.Equal(t, nil, err)
+	defer sdb.Exec(`DROP TABLE copies2`)
+	_, err = sdb.Exec(`INSERT INTO copies (id, name) VALUES ("1", "a"), ("2", "b")`)
+	assert.Equal(t, nil, err)
+
+	// reads and writes the same sqlite source, which allows one open conn
+	res, err := sdb.Exec(`INSERT INTO copies (id, name) SELECT name, name FROM copies`)
+	assert.Equal(t, nil, err)
+	affected, err := res.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), affected)
+	assert.Equal(t, []string{"1", "2", "a", "b"}, queryIds(t, sdb, `SELECT id FROM copies ORDER BY id`))
+
+	_, err = sdb.Exec(`INSERT INTO copies2 SELECT id, name FROM copies`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"1", "2", "a", "b"}, queryIds(t, sdb, `SELECT id FROM copies2 ORDER BY id`))
+}
+
+func queryIds(t *testing.T, sdb *sql.DB, qry string) []string {
+	rows, err := sdb.Query(qry)
+	assert.Equal(t, nil, err)
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		assert.Equal(t, nil, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestUpdate(t *testing.T) {
+	LoadTestDataOnce(t)
+	exec.RegisterSqlDriver()
+
+	sdb, err := sql.Open("qlbridge", "sqlite_test")
+	assert.Equal(t, nil, err)
+	defer sdb.Close()
+
+	_, err = sdb.Exec(`CREATE TABLE counters (id varchar(20) NOT NULL, name varchar(20), n int, PRIMARY KEY (id))`)
+	assert.Equal(t, nil, err)
+	defer sdb.Exec(`DROP TABLE counters`)
+	_, err = sdb.Exec(`INSERT INTO counters (id, name, n) VALUES ("1", "a", 10), ("2", "b", 20)`)
+	assert.Equal(t, nil, err)
+
+	counts := func() []string {
+		rows, err := sdb.Query(`SELECT id, name, n FROM counters ORDER BY id`)
+		assert.Equal(t, nil, err)
+		defer rows.Close()
+		var found []string
+		for rows.Next() {
+			var id, name string
+			var n int64
+			assert.Equal(t, nil, rows.Scan(&id, &name, &n))
+			found = append(found, fmt.Sprintf("%s:%s:%d", id, name, n))
+		}
+		return found
+	}
+
+	// sqlite can't patch by where, so the rows are read then written back
+	res, err := sdb.Exec(`UPDATE counters SET name = "big", n = 99 WHERE id = "2"`)
+	assert.Equal(t, nil, err)
+	affected, err := res.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), affected)
+	assert.Equal(t, []string{"1:a:10", "2:big:99"}, counts())
+
+	// writing the rows it reads puts over the existing ones
+	_, err = sdb.Exec(`INSERT INTO counters SELECT id, name, n FROM counters`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"1:a:10", "2:big:99"}, counts())
+
+	// changing the key moves the row
+	_, err = sdb.Exec(`UPDATE counters SET id = "3" WHERE id = "1"`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"2:big:99", "3:a:10"}, counts())
+
+	// conflicts are found by seeking the first column
+	_, err = sdb.Exec(`INSERT INTO counters (id, name, n) VALUES ("2", "x", 1), ("4", "d", 4) ON CONFLICT (id) DO NOTHING`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"2:big:99", "3:a:10", "4:d:4"}, counts())
+	_, err = sdb.Exec(`INSERT INTO counters (id, name, n) VALUES ("3", "c", 5) ON CONFLICT (id) DO UPDATE SET n = n + excluded.n`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"2:big:99", "3:a:15", "4:d:4"}, counts())
+	_, err = sdb.Exec(`INSERT INTO counters (id, name, n) VALUES ("3", "c", 5) ON CONFLICT (name) DO NOTHING`)
+	assert.NotEqual(t, nil, err)
+}
@@ -48,6 +48,10 @@ func (m *Command) Run() error {
 	//defer m.Ctx.Recover()
 	defer close(m.msgOutCh)
 
+	if m.p.Stmt.Keyword() == lex.TokenRefresh {
+		return m.runRefresh()
+	}
+
 	if m.Ctx.Session == nil {
 		u.Warnf("no Context.Session?")
 		return fmt.Errorf("no Context.Session?")
@@ -65,6 +69,24 @@ func (m *Command) Run() error {
 	return ErrNotImplemented
 
 }
+
+// runRefresh REFRESH MATERIALIZED VIEW view_name
+func (m *Command) runRefresh() error {
+	s := m.Ctx.Schema
+	if s == nil {
+		return fmt.Errorf("must have schema")
+	}
+	name := m.p.Stmt.Identity
+	if t, _ := s.Table(name); t == nil || t.View == nil || !t.View.Materialized {
+		return fmt.Errorf("%q is not a materialized view", name)
+	}
+	_, vs, err := viewSchema(s, false)
+	if err != nil {
+		return err
+	}
+	return vs.Refresh(name)
+}
+
 func (m *Command) runSet() error {
 
 	writeContext, ok := m.Ctx.Session.(expr.ContextWriter)
@@ -0,0 +1,703 @@
+package exec
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+	"github.com/fuhongbo/qlbridge/vm"
+)
+
+var (
+	// Ensure the continuous view conn implements the interfaces needed
+	// to be read as the source of a select.
+	_ schema.ConnColumns = (*continuousConn)(nil)
+	_ schema.ConnScanner = (*continuousConn)(nil)
+	_ RequiresContext    = (*continuousConn)(nil)
+
+	// ContinuousViewRetainWindows is the most closed windows a continuous
+	// view keeps unless it is created WITH retain_windows.
+	ContinuousViewRetainWindows = 10000
+)
+
+// window kinds of the GROUP BY of a continuous view
+const (
+	windowTumble  = "tumble"  // tumble(ts, "1m") fixed size, non-overlapping
+	windowHop     = "hop"     // hop(ts, "1m", "10s") fixed size, overlapping every slide
+	windowSession = "session" // session(ts, "5m") ends after a gap without rows
+)
+
+type (
+	// continuousView keeps windowed aggregates of the rows streaming from
+	// the source of a CREATE CONTINUOUSVIEW.
+	//
+	//    CREATE CONTINUOUSVIEW v AS
+	//       SELECT city, count(*) AS ct, sum(total) AS total FROM orders
+	//       GROUP BY tumble(ts, "1m"), city
+	//       WITH watermark = "10s", retention = "24h", retain_windows = 1000
+	//
+	// The rows of the view are window_start, window_end then the columns of
+	// the select.  A window is closed once the watermark, the latest event
+	// time seen less the watermark delay, passes its end.  Rows arriving
+	// for closed windows are late and dropped.  Closed windows are final and
+	// are what a SUBSCRIBE SELECT of the view emits.  The oldest closed
+	// windows are dropped once there are more than retain_windows of them,
+	// or they ended more than the retention before the watermark.
+	continuousView struct {
+		tbl    *schema.Table
+		kind   string        // tumble, hop, session
+		ts     expr.Node     // event time of a row
+		size   time.Duration // tumble, hop window size, session gap
+		slide  time.Duration // hop slide
+		delay  time.Duration // watermark delay
+		retain int           // most closed windows kept
+		expire time.Duration // closed windows ending this long before the watermark are dropped if > 0
+		keys   []expr.Node   // GROUP BY columns other than the window
+		cols   []*windowCol  // the select columns
+		cancel context.CancelFunc
+
+		mu        sync.Mutex
+		open      map[string]*window   // open tumble, hop windows by window + group key
+		sessions  map[string][]*window // open sessions by group key
+		closed    [][]driver.Value     // rows of the closed windows in closing order
+		trimmed   int                  // closed rows dropped from the front of closed
+		changed   chan bool            // closed, and replaced, when a window closes
+		maxEvent  time.Time
+		watermark time.Time
+		late      int64 // late rows dropped
+		stopped   bool
+	}
+	// windowCol a select column of a continuous view, either a group key
+	// or an aggregate.
+	windowCol struct {
+		key  int    // position in keys of a group key column, else -1
+		fn   string // count, sum, avg, min, max
+		arg  expr.Node
+		star bool // count(*)
+	}
+	window struct {
+		start, end time.Time
+		key        string
+		keyVals    []driver.Value
+		aggs       []windowAgg
+	}
+	// windowAgg the mergeable state of an aggregate of a window.
+	windowAgg struct {
+		ct       int64
+		sum      float64
+		min, max float64
+	}
+	// continuousConn reads the windows of a continuous view, following
+	// the windows as they close in a SUBSCRIBE query.
+	continuousConn struct {
+		cv      *continuousView
+		ctx     *plan.Context
+		rows    [][]driver.Value
+		pos     int  // position of the next closed row to read in follow mode
+		started bool // has the snapshot, or follow position been taken
+		exit    chan bool
+	}
+)
+
+// newContinuousView check the SELECT @sel of a continuous view and find
+// its window, group keys and aggregates.
+func newContinuousView(s *schema.Schema, cs *rel.SqlCreate) (*continuousView, error) {
+	sel := cs.Select
+	if sel == nil {
+		return nil, fmt.Errorf("CREATE CONTINUOUSVIEW %q requires a SELECT", cs.Identity)
+	}
+	if len(sel.From) != 1 || sel.From[0].SubQuery != nil {
+		return nil, fmt.Errorf("continuous view %q must select from a single source", cs.Identity)
+	}
+	if sel.Having != nil || len(sel.OrderBy) > 0 || sel.Limit > 0 {
+		return nil, fmt.Errorf("continuous view %q does not support HAVING, ORDER BY or LIMIT", cs.Identity)
+	}
+
+	m := &continuousView{
+		open:     make(map[string]*window),
+		sessions: make(map[string][]*window),
+		changed:  make(chan bool),
+	}
+	if delay := cs.With.String("watermark"); delay != "" {
+		dur, err := time.ParseDuration(delay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watermark %q: %v", delay, err)
+		}
+		m.delay = dur
+	}
+	var retain int
+	if cs.With.Get("retain_windows") != nil {
+		var ok bool
+		if retain, ok = cs.With.IntSafe("retain_windows"); !ok || retain < 1 {
+			return nil, fmt.Errorf("invalid retain_windows %v", cs.With.Get("retain_windows"))
+		}
+		m.retain = retain
+	} else {
+		m.retain = ContinuousViewRetainWindows
+	}
+	if expire := cs.With.String("retention"); expire != "" {
+		dur, err := time.ParseDuration(expire)
+		if err != nil || dur <= 0 {
+			return nil, fmt.Errorf("invalid retention %q", expire)
+		}
+		m.expire = dur
+	}
+
+	var keyCols []*rel.Column
+	for _, col := range sel.GroupBy {
+		fn, ok := col.Expr.(*expr.FuncNode)
+		if !ok || !isWindowFunc(fn.Name) {
+			m.keys = append(m.keys, col.Expr)
+			keyCols = append(keyCols, col)
+			continue
+		}
+		if m.ts != nil {
+			return nil, fmt.Errorf("continuous view %q may only GROUP BY one window", cs.Identity)
+		}
+		if err := m.setWindow(fn); err != nil {
+			return nil, err
+		}
+	}
+	if m.ts == nil {
+		return nil, fmt.Errorf("continuous view %q must GROUP BY tumble(), hop() or session()", cs.Identity)
+	}
+
+	srcTbl, _ := s.Table(sel.From[0].SourceName())
+	tbl := schema.NewTable(cs.Identity)
+	tbl.AddField(schema.NewFieldBase("window_start", value.TimeType, 0, ""))
+	tbl.AddField(schema.NewFieldBase("window_end", value.TimeType, 0, ""))
+colLoop:
+	for _, col := range sel.Columns {
+		if col.Star || col.Expr == nil {
+			return nil, fmt.Errorf("continuous view %q must name its columns", cs.Identity)
+		}
+		for i, kc := range keyCols {
+			if kc.As == col.As || col.Expr.Equal(kc.Expr) {
+				m.cols = append(m.cols, &windowCol{key: i})
+				vt := value.StringType
+				if srcTbl != nil {
+					if f, ok := srcTbl.FieldMap[col.SourceField]; ok {
+						vt = f.ValueType()
+					}
+				}
+				tbl.AddField(schema.NewFieldBase(col.As, vt, 0, ""))
+				continue colLoop
+			}
+		}
+		fn, ok := col.Expr.(*expr.FuncNode)
+		if !ok {
+			return nil, fmt.Errorf("column %q of continuous view %q must be a GROUP BY column or aggregate", col.As, cs.Identity)
+		}
+		wc := &windowCol{key: -1, fn: strings.ToLower(fn.Name)}
+		vt := value.NumberType
+		switch wc.fn {
+		case "count":
+			vt = value.IntType
+		case "sum", "avg", "min", "max":
+		default:
+			return nil, fmt.Errorf("unsupported continuous view aggregate %s", col.Expr)
+		}
+		if len(fn.Args) != 1 {
+			return nil, fmt.Errorf("expected 1 arg for %s", col.Expr)
+		}
+		wc.arg = fn.Args[0]
+		if in, ok := wc.arg.(*expr.IdentityNode); ok && in.Text == "*" {
+			wc.star = true
+		}
+		m.cols = append(m.cols, wc)
+		if tbl.HasField(col.As) {
+			return nil, fmt.Errorf("duplicate column %q in view %q", col.As, cs.Identity)
+		}
+		tbl.AddField(schema.NewFieldBase(col.As, vt, 0, ""))
+	}
+	tbl.SetColumnsFromFields()
+	tbl.View = &schema.View{Sql: sel.String(), Continuous: true, WatermarkDelay: m.delay,
+		Retention: m.expire, RetainWindows: retain}
+	m.tbl = tbl
+	return m, nil
+}
+
+func isWindowFunc(name string) bool {
+	switch strings.ToLower(name) {
+	case windowTumble, windowHop, windowSession:
+		return true
+	}
+	return false
+}
+
+// setWindow from the tumble(ts, size), hop(ts, size, slide) or
+// session(ts, gap) of the GROUP BY.
+func (m *continuousView) setWindow(fn *expr.FuncNode) error {
+	m.kind = strings.ToLower(fn.Name)
+	want := 2
+	if m.kind == windowHop {
+		want = 3
+	}
+	if len(fn.Args) != want {
+		return fmt.Errorf("expected %d args for %s", want, fn)
+	}
+	durs := make([]time.Duration, 0, 2)
+	for _, arg := range fn.Args[1:] {
+		sn, ok := arg.(*expr.StringNode)
+		if !ok {
+			return fmt.Errorf("window duration must be a string like \"1m\" in %s", fn)
+		}
+		dur, err := time.ParseDuration(sn.Text)
+		if err != nil || dur <= 0 {
+			return fmt.Errorf("invalid window duration %q in %s", sn.Text, fn)
+		}
+		durs = append(durs, dur)
+	}
+	m.ts = fn.Args[0]
+	m.size = durs[0]
+	if m.kind == windowHop {
+		m.slide = durs[1]
+		if m.slide > m.size {
+			return fmt.Errorf("hop slide may not be larger than its size in %s", fn)
+		}
+	}
+	return nil
+}
+
+// sourceSql the SUBSCRIBE query reading the rows of the source.
+func (m *continuousView) sourceSql(sel *rel.SqlSelect) string {
+	from := sel.From[0]
+	sql := fmt.Sprintf("SUBSCRIBE SELECT * FROM %s", from.Name)
+	if from.Alias != "" {
+		sql += " AS " + from.Alias
+	}
+	if sel.Where != nil && sel.Where.Expr != nil {
+		sql += " WHERE " + sel.Where.Expr.String()
+	}
+	return sql
+}
+
+// addContinuousView start the stream of continuous view @cv reading the
+// source of its SELECT @sel and add it to the views.
+func (m *ViewSource) addContinuousView(cv *continuousView, sel *rel.SqlSelect) error {
+	cv.tbl.Source = m
+	if err := cv.start(m, sel); err != nil {
+		return err
+	}
+	m.setView(&view{tbl: cv.tbl, cv: cv})
+	return nil
+}
+
+// start reading the source through the views of @vs.
+func (m *continuousView) start(vs *ViewSource, sel *rel.SqlSelect) error {
+	goCtx, cancel := context.WithCancel(context.Background())
+	ctx := plan.NewContext(m.sourceSql(sel))
+	ctx.Context = goCtx
+	job, err := vs.newJob(ctx, m.add)
+	if err != nil {
+		cancel()
+		return err
+	}
+	m.cancel = func() {
+		cancel()
+		job.Close()
+	}
+	go func() {
+		if err := job.Run(); err != nil {
+			u.Warnf("continuous view %q stopped: %v", m.tbl.Name, err)
+		}
+		job.Close()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if !m.stopped {
+			// the end of a stream closes all of its windows
+			m.closeWindows(time.Time{}, true)
+		}
+	}()
+	return nil
+}
+
+// stop reading the source, readers following the view are ended.
+func (m *continuousView) stop() {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return
+	}
+	m.stopped = true
+	close(m.changed)
+	m.mu.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// add a row from the source to the windows it belongs to.
+func (m *continuousView) add(msg *datasource.SqlDriverMessageMap) {
+	tv, ok := vm.Eval(msg, m.ts)
+	if !ok || tv == nil || tv.Nil() {
+		u.Debugf("continuous view %q skipping row without event time", m.tbl.Name)
+		return
+	}
+	ts, ok := value.ValueToTime(tv)
+	if !ok {
+		u.Debugf("continuous view %q skipping row with event time %v", m.tbl.Name, tv)
+		return
+	}
+
+	keyVals := make([]driver.Value, len(m.keys))
+	keys := make([]string, len(m.keys))
+	for i, k := range m.keys {
+		if v, ok := vm.Eval(msg, k); ok && v != nil && !v.Nil() {
+			keyVals[i] = v.Value()
+			keys[i] = v.ToString()
+		}
+	}
+	key := strings.Join(keys, ",")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return
+	}
+
+	switch m.kind {
+	case windowSession:
+		w := &window{start: ts, end: ts.Add(m.size), key: key, keyVals: keyVals}
+		if m.isLate(w.end) {
+			m.late++
+			return
+		}
+		w.aggs = make([]windowAgg, len(m.cols))
+		m.addTo(w, msg)
+		// merge the sessions this row joins into one
+		sessions := m.sessions[key][:0]
+		for _, s := range m.sessions[key] {
+			if !s.start.After(w.end) && !w.start.After(s.end) {
+				w.merge(s)
+				continue
+			}
+			sessions = append(sessions, s)
+		}
+		m.sessions[key] = append(sessions, w)
+	default:
+		added := false
+		for _, start := range m.windowStarts(ts) {
+			end := start.Add(m.size)
+			if m.isLate(end) {
+				continue
+			}
+			wkey := fmt.Sprintf("%d,%s", start.UnixNano(), key)
+			w, ok := m.open[wkey]
+			if !ok {
+				w = &window{start: start, end: end, key: key, keyVals: keyVals, aggs: make([]windowAgg, len(m.cols))}
+				m.open[wkey] = w
+			}
+			m.addTo(w, msg)
+			added = true
+		}
+		if !added {
+			m.late++
+			return
+		}
+	}
+
+	if ts.After(m.maxEvent) {
+		m.maxEvent = ts
+		if wm := ts.Add(-m.delay); wm.After(m.watermark) {
+			m.watermark = wm
+			m.closeWindows(wm, false)
+		}
+	}
+}
+
+func (m *continuousView) isLate(end time.Time) bool {
+	return !m.watermark.IsZero() && !end.After(m.watermark)
+}
+
+// windowStarts the start of the tumble or hop windows containing @ts.
+func (m *continuousView) windowStarts(ts time.Time) []time.Time {
+	if m.kind == windowTumble {
+		return []time.Time{ts.Truncate(m.size)}
+	}
+	var starts []time.Time
+	for start := ts.Truncate(m.slide); start.Add(m.size).After(ts); start = start.Add(-m.slide) {
+		starts = append(starts, start)
+	}
+	// oldest first
+	for i, j := 0, len(starts)-1; i < j; i, j = i+1, j-1 {
+		starts[i], starts[j] = starts[j], starts[i]
+	}
+	return starts
+}
+
+func (m *continuousView) addTo(w *window, msg *datasource.SqlDriverMessageMap) {
+	for i, col := range m.cols {
+		if col.key >= 0 {
+			continue
+		}
+		if col.star {
+			w.aggs[i].ct++
+			continue
+		}
+		v, ok := vm.Eval(msg, col.arg)
+		if !ok || v == nil || v.Nil() {
+			continue
+		}
+		if col.fn == "count" {
+			w.aggs[i].ct++
+			continue
+		}
+		if f, ok := value.ValueToFloat64(v); ok && !math.IsNaN(f) {
+			w.aggs[i].add(f)
+		}
+	}
+}
+
+// closeWindows close the windows ending at or before @watermark, or all
+// of them if @all, moving their rows to the closed rows.
+func (m *continuousView) closeWindows(watermark time.Time, all bool) {
+	var closing []*window
+	for wkey, w := range m.open {
+		if all || !w.end.After(watermark) {
+			closing = append(closing, w)
+			delete(m.open, wkey)
+		}
+	}
+	for key, sessions := range m.sessions {
+		open := sessions[:0]
+		for _, w := range sessions {
+			if all || !w.end.After(watermark) {
+				closing = append(closing, w)
+			} else {
+				open = append(open, w)
+			}
+		}
+		if len(open) == 0 {
+			delete(m.sessions, key)
+		} else {
+			m.sessions[key] = open
+		}
+	}
+	if len(closing) == 0 {
+		return
+	}
+	sortWindows(closing)
+	for _, w := range closing {
+		m.closed = append(m.closed, m.row(w))
+	}
+	m.trim()
+	close(m.changed)
+	m.changed = make(chan bool)
+}
+
+// trim drop the oldest closed rows beyond the retained count, and those
+// of windows that ended more than the retention before the watermark.
+func (m *continuousView) trim() {
+	drop := 0
+	if m.retain > 0 && len(m.closed) > m.retain {
+		drop = len(m.closed) - m.retain
+	}
+	if m.expire > 0 && !m.watermark.IsZero() {
+		expired := m.watermark.Add(-m.expire)
+		for drop < len(m.closed) && m.closed[drop][1].(time.Time).Before(expired) {
+			drop++
+		}
+	}
+	if drop == 0 {
+		return
+	}
+	m.closed = append([][]driver.Value(nil), m.closed[drop:]...)
+	m.trimmed += drop
+}
+
+// row the view row of window @w
+func (m *continuousView) row(w *window) []driver.Value {
+	row := make([]driver.Value, 0, len(m.cols)+2)
+	row = append(row, w.start, w.end)
+	for i, col := range m.cols {
+		if col.key >= 0 {
+			row = append(row, w.keyVals[col.key])
+			continue
+		}
+		row = append(row, w.aggs[i].result(col.fn))
+	}
+	return row
+}
+
+// snapshot the rows of the closed then open windows.
+func (m *continuousView) snapshot() [][]driver.Value {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rows := make([][]driver.Value, 0, len(m.closed)+len(m.open))
+	rows = append(rows, m.closed...)
+	open := make([]*window, 0, len(m.open))
+	for _, w := range m.open {
+		open = append(open, w)
+	}
+	for _, sessions := range m.sessions {
+		open = append(open, sessions...)
+	}
+	sortWindows(open)
+	for _, w := range open {
+		rows = append(rows, m.row(w))
+	}
+	return rows
+}
+
+// closedSince the closed rows from position @pos, skipping those already
+// dropped, the position after them and a channel closed when more are
+// added.  Once the view is stopped changed is nil.
+func (m *continuousView) closedSince(pos int) ([][]driver.Value, int, chan bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rows [][]driver.Value
+	if i := pos - m.trimmed; i < len(m.closed) {
+		if i < 0 {
+			i = 0
+		}
+		rows = m.closed[i:]
+	}
+	next := m.trimmed + len(m.closed)
+	if m.stopped {
+		return rows, next, nil
+	}
+	return rows, next, m.changed
+}
+
+func sortWindows(ws []*window) {
+	sort.Slice(ws, func(i, j int) bool {
+		if !ws[i].start.Equal(ws[j].start) {
+			return ws[i].start.Before(ws[j].start)
+		}
+		return ws[i].key < ws[j].key
+	})
+}
+
+// merge session @o into w
+func (m *window) merge(o *window) {
+	if o.start.Before(m.start) {
+		m.start = o.start
+	}
+	if o.end.After(m.end) {
+		m.end = o.end
+	}
+	for i := range m.aggs {
+		m.aggs[i].merge(&o.aggs[i])
+	}
+}
+
+func (m *windowAgg) add(f float64) {
+	if m.ct == 0 || f < m.min {
+		m.min = f
+	}
+	if m.ct == 0 || f > m.max {
+		m.max = f
+	}
+	m.ct++
+	m.sum += f
+}
+
+func (m *windowAgg) merge(o *windowAgg) {
+	if o.ct == 0 {
+		return
+	}
+	if m.ct == 0 || o.min < m.min {
+		m.min = o.min
+	}
+	if m.ct == 0 || o.max > m.max {
+		m.max = o.max
+	}
+	m.ct += o.ct
+	m.sum += o.sum
+}
+
+func (m *windowAgg) result(fn string) driver.Value {
+	switch fn {
+	case "count":
+		return m.ct
+	case "sum":
+		return m.sum
+	}
+	if m.ct == 0 {
+		return nil
+	}
+	switch fn {
+	case "avg":
+		return m.sum / float64(m.ct)
+	case "min":
+		return m.min
+	}
+	return m.max
+}
+
+// SetContext the context of the query reading this view.
+func (m *continuousConn) SetContext(ctx *plan.Context) { m.ctx = ctx }
+
+// Columns of the view
+func (m *continuousConn) Columns() []string { return m.cv.tbl.Columns() }
+
+// Next row of the view.  A SELECT reads the closed and open windows at
+// the time of the first call, a SUBSCRIBE SELECT reads the closed windows
+// and waits for more to close.
+func (m *continuousConn) Next() schema.Message {
+	subscribe := false
+	if m.ctx != nil {
+		sel, ok := m.ctx.Stmt.(*rel.SqlSelect)
+		subscribe = ok && sel.Subscribe
+	}
+	if !subscribe {
+		if !m.started {
+			m.started = true
+			m.rows = m.cv.snapshot()
+		}
+		if m.pos >= len(m.rows) {
+			return nil
+		}
+		m.pos++
+		return datasource.NewSqlDriverMessageMap(uint64(m.pos), m.rows[m.pos-1], m.cv.tbl.FieldPositions)
+	}
+
+	for {
+		if len(m.rows) > 0 {
+			row := m.rows[0]
+			m.rows = m.rows[1:]
+			return datasource.NewSqlDriverMessageMap(uint64(m.pos), row, m.cv.tbl.FieldPositions)
+		}
+		rows, next, changed := m.cv.closedSince(m.pos)
+		if len(rows) > 0 {
+			m.pos = next
+			m.rows = rows
+			continue
+		}
+		if changed == nil {
+			return nil
+		}
+		var done <-chan struct{}
+		if m.ctx != nil {
+			done = m.ctx.Done()
+		}
+		select {
+		case <-changed:
+		case <-done:
+			return nil
+		case <-m.exit:
+			return nil
+		}
+	}
+}
+
+// Close stops following the view.
+func (m *continuousConn) Close() error {
+	defer func() { recover() }()
+	close(m.exit)
+	return nil
+}
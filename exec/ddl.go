@@ -1,14 +1,19 @@
 package exec
 
 import (
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	u "github.com/araddon/gou"
 
+	"github.com/fuhongbo/qlbridge/expr"
 	"github.com/fuhongbo/qlbridge/lex"
 	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/rel"
 	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
 )
 
 var (
@@ -90,6 +95,108 @@ func (m *Create) Run() error {
 		reg := schema.DefaultRegistry()
 
 		return reg.SchemaAddFromConfig(sourceConf)
+	case lex.TokenIndex:
+
+		// CREATE [UNIQUE] INDEX idx_name ON tbl_name (col, ...)
+		s := m.Ctx.Schema
+		if s == nil {
+			return fmt.Errorf("must have schema")
+		}
+		ss, err := s.SchemaForTable(cs.Table)
+		if err != nil {
+			return fmt.Errorf("could not find table %q for index %q", cs.Table, cs.Identity)
+		}
+		ic, ok := ss.DS.(schema.SourceIndexCreator)
+		if !ok {
+			u.Warnf("source %T does not support CREATE INDEX: %s", ss.DS, m.p.Stmt.Raw)
+			return ErrNotImplemented
+		}
+		idx := &schema.Index{Name: cs.Identity, Unique: cs.Unique}
+		for _, col := range cs.Cols {
+			idx.Fields = append(idx.Fields, col.Name)
+		}
+		return ic.CreateIndex(cs.Table, idx)
+	case lex.TokenTable:
+
+		// CREATE TABLE [IF NOT EXISTS] tbl_name (create_definition,...)
+		//    [WITH source=source_name]
+		s := m.Ctx.Schema
+		if s == nil {
+			return fmt.Errorf("must have schema")
+		}
+		if t, _ := s.Table(cs.Identity); t != nil {
+			if cs.IfNotExists {
+				return nil
+			}
+			return fmt.Errorf("table %q already exists", cs.Identity)
+		}
+		ss := s
+		if sourceName := cs.With.String("source"); sourceName != "" {
+			child, err := s.Schema(sourceName)
+			if err != nil {
+				return err
+			}
+			ss = child
+		}
+		ddl, ok := ss.DS.(schema.SourceDDL)
+		if !ok {
+			u.Warnf("source %T does not support CREATE TABLE: %s", ss.DS, m.p.Stmt.Raw)
+			return ErrNotImplemented
+		}
+		tbl, err := tableFromDdl(cs.Identity, cs.Cols)
+		if err != nil {
+			return err
+		}
+		if err = ddl.CreateTable(tbl); err != nil {
+			return err
+		}
+		return registerTable(ss, tbl.Name)
+	case lex.TokenView, lex.TokenContinuousView:
+
+		// CREATE [OR REPLACE] [MATERIALIZED] VIEW view_name AS select
+		//    [WITH refresh_interval="5m"]
+		// CREATE [OR REPLACE] CONTINUOUSVIEW view_name AS select
+		//    GROUP BY tumble(ts, "1m") [WITH watermark="10s"]
+		s := m.Ctx.Schema
+		if s == nil {
+			return fmt.Errorf("must have schema")
+		}
+		if t, _ := s.Table(cs.Identity); t != nil {
+			switch {
+			case t.View == nil:
+				return fmt.Errorf("table %q already exists", cs.Identity)
+			case cs.IfNotExists:
+				return nil
+			case !cs.OrReplace:
+				return fmt.Errorf("view %q already exists", cs.Identity)
+			}
+		}
+		if cs.Tok.T == lex.TokenContinuousView {
+			cv, err := newContinuousView(s, cs)
+			if err != nil {
+				return err
+			}
+			ss, vs, err := viewSchema(s, true)
+			if err != nil {
+				return err
+			}
+			if err = vs.addContinuousView(cv, cs.Select); err != nil {
+				return err
+			}
+			return registerTable(ss, cv.tbl.Name)
+		}
+		tbl, err := viewTable(m.Ctx, cs)
+		if err != nil {
+			return err
+		}
+		ss, vs, err := viewSchema(s, true)
+		if err != nil {
+			return err
+		}
+		if err = vs.addView(tbl); err != nil {
+			return err
+		}
+		return registerTable(ss, tbl.Name)
 	default:
 		u.Warnf("unrecognized create/alter: kw=%v   stmt:%s", cs.Tok, m.p.Stmt)
 	}
@@ -123,8 +230,35 @@ func (m *Drop) Run() error {
 	switch cs.Tok.T {
 	case lex.TokenSource, lex.TokenSchema, lex.TokenTable:
 
+		if cs.Tok.T == lex.TokenTable {
+			if t, _ := s.Table(cs.Identity); t != nil && t.View != nil {
+				return fmt.Errorf("%q is a view, use DROP VIEW", cs.Identity)
+			}
+		}
 		reg := schema.DefaultRegistry()
-		return reg.SchemaDrop(s.Name, cs.Identity, cs.Tok.T)
+		err := reg.SchemaDrop(s.Name, cs.Identity, cs.Tok.T)
+		if err == schema.ErrNotFound && cs.IfExists {
+			return nil
+		}
+		return err
+
+	case lex.TokenView, lex.TokenContinuousView:
+
+		// DROP [MATERIALIZED] VIEW [IF EXISTS] view_name
+		// DROP CONTINUOUSVIEW [IF EXISTS] view_name
+		t, _ := s.Table(cs.Identity)
+		switch {
+		case t == nil && cs.IfExists:
+			return nil
+		case t == nil:
+			return schema.ErrNotFound
+		case t.View == nil:
+			return fmt.Errorf("%q is not a view", cs.Identity)
+		case t.View.Materialized != cs.Materialized,
+			t.View.Continuous != (cs.Tok.T == lex.TokenContinuousView):
+			return fmt.Errorf("%q is a %s, use DROP %s", cs.Identity, t.TableType(), t.View.Keyword())
+		}
+		return schema.DefaultRegistry().SchemaDrop(s.Name, cs.Identity, lex.TokenTable)
 
 	default:
 		u.Warnf("unrecognized DROP: kw=%v   stmt:%s", cs.Tok, m.p.Stmt)
@@ -153,8 +287,186 @@ func (m *Alter) Run() error {
 	cs := m.p.Stmt
 
 	switch cs.Tok.T {
+	case lex.TokenTable:
+
+		s := m.Ctx.Schema
+		if s == nil {
+			return fmt.Errorf("must have schema")
+		}
+		ss, err := s.SchemaForTable(cs.Identity)
+		if err != nil {
+			return fmt.Errorf("could not find table %q", cs.Identity)
+		}
+		ddl, ok := ss.DS.(schema.SourceDDL)
+		if !ok {
+			u.Warnf("source %T does not support ALTER TABLE: %s", ss.DS, m.p.Stmt.Raw)
+			return ErrNotImplemented
+		}
+		changes := make([]*schema.ColumnChange, 0, len(cs.Cols))
+		for _, col := range cs.Cols {
+			cc := &schema.ColumnChange{Name: col.Name, First: col.First, After: col.After}
+			switch col.Op {
+			case lex.TokenAdd:
+				cc.Op = lex.TokenAdd
+			case lex.TokenDrop:
+				cc.Op = lex.TokenDrop
+			case lex.TokenChange:
+				cc.Op = lex.TokenChange
+				cc.Name = col.OldName
+			case lex.TokenModify:
+				cc.Op = lex.TokenChange
+			default:
+				return fmt.Errorf("unsupported ALTER TABLE action %s", col.Op)
+			}
+			if cc.Op != lex.TokenDrop {
+				cc.Field = fieldFromDdl(col)
+			}
+			changes = append(changes, cc)
+		}
+		if err = ddl.AlterTable(strings.ToLower(cs.Identity), changes); err != nil {
+			return err
+		}
+		return registerTable(ss, cs.Identity)
 	default:
 		u.Warnf("unrecognized ALTER: kw=%v   stmt:%s", cs.Tok, m.p.Stmt)
 	}
 	return ErrNotImplemented
 }
+
+// registerTable fetch the (new, altered) table from the source of schema s
+// and register it so that the schema, its parents and info-schema see it.
+func registerTable(s *schema.Schema, name string) error {
+	tbl, err := s.DS.Table(strings.ToLower(name))
+	if err != nil {
+		return err
+	}
+	if tbl == nil {
+		return schema.ErrNotFound
+	}
+	return schema.DefaultRegistry().SchemaTableAdd(s, tbl)
+}
+
+// tableFromDdl convert the column definitions of a CREATE TABLE
+// into a schema table, including its PRIMARY KEY and UNIQUE indexes.
+func tableFromDdl(name string, cols []*rel.DdlColumn) (*schema.Table, error) {
+
+	tbl := schema.NewTable(name)
+	addIndex := func(idxName string, fields []string, key lex.TokenType) error {
+		idx := &schema.Index{Name: idxName, Fields: fields, Unique: true}
+		keyName := "UNI"
+		if key == lex.TokenPrimary {
+			for _, existing := range tbl.Indexes {
+				if existing.PrimaryKey {
+					return fmt.Errorf("multiple primary keys defined for %q", name)
+				}
+			}
+			idx.Name = "primary"
+			idx.PrimaryKey = true
+			keyName = "PRI"
+		}
+		for _, fn := range fields {
+			f, ok := tbl.FieldMap[fn]
+			if !ok {
+				return fmt.Errorf("key column %q does not exist in %q", fn, name)
+			}
+			if len(fields) == 1 || key == lex.TokenPrimary {
+				f.Key = keyName
+			}
+		}
+		tbl.Indexes = append(tbl.Indexes, idx)
+		return nil
+	}
+
+	var keys []*rel.DdlColumn
+	for _, col := range cols {
+		switch col.Kw {
+		case lex.TokenIdentity:
+			tbl.AddField(fieldFromDdl(col))
+		}
+		keys = append(keys, col)
+	}
+	if len(tbl.Fields) == 0 {
+		return nil, fmt.Errorf("table %q must have at least one column", name)
+	}
+
+	// Keys are declared after all columns are known as table level
+	// constraints may precede the columns they reference.
+	for _, col := range keys {
+		var err error
+		switch col.Kw {
+		case lex.TokenIdentity:
+			switch col.Key {
+			case lex.TokenPrimary, lex.TokenUnique:
+				err = addIndex(col.Name, []string{col.Name}, col.Key)
+			}
+		case lex.TokenPrimary:
+			err = addIndex("primary", col.IndexCols, lex.TokenPrimary)
+		case lex.TokenConstraint:
+			switch col.Key {
+			case lex.TokenPrimary, lex.TokenUnique:
+				err = addIndex(col.Name, col.IndexCols, col.Key)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tbl.SetColumnsFromFields()
+	return tbl, nil
+}
+
+// fieldFromDdl convert a column definition into a schema field carrying
+// its NOT NULL, DEFAULT, key and AUTO_INCREMENT constraints.
+func fieldFromDdl(col *rel.DdlColumn) *schema.Field {
+
+	vt := ddlValueType(col.DataType)
+
+	var def driver.Value
+	if col.Default != nil {
+		var dv string
+		switch n := col.Default.(type) {
+		case *expr.StringNode:
+			dv = n.Text
+		default:
+			dv = n.String()
+		}
+		if !strings.EqualFold(dv, "null") {
+			def = dv
+			if v, err := value.Cast(vt, value.NewStringValue(dv)); err == nil && v != nil && !v.Nil() {
+				def = v.Value()
+			}
+		}
+	}
+
+	key := ""
+	switch col.Key {
+	case lex.TokenPrimary:
+		key = "PRI"
+	case lex.TokenUnique:
+		key = "UNI"
+	}
+	f := schema.NewField(col.Name, vt, col.DataTypeSize, col.Null, def, key, "", col.Comment)
+	f.Extra = ""
+	if col.AutoIncrement {
+		f.Extra = "auto_increment"
+	}
+	return f
+}
+
+// ddlValueType the value type of a column data type name.
+func ddlValueType(dataType string) value.ValueType {
+	switch strings.ToLower(dataType) {
+	case "int", "integer", "bigint":
+		return value.IntType
+	case "float", "double", "real", "decimal":
+		return value.NumberType
+	case "bool", "boolean":
+		return value.BoolType
+	case "date", "datetime", "timestamp":
+		return value.TimeType
+	case "json":
+		return value.JsonType
+	}
+	return value.StringType
+}
@@ -98,6 +98,7 @@ type (
 		WalkUpdate(p *plan.Update) (Task, error)
 		WalkDelete(p *plan.Delete) (Task, error)
 		// DML Child Tasks
+		WalkInto(p *plan.Into) (Task, error)
 		WalkSource(p *plan.Source) (Task, error)
 		WalkJoin(p *plan.JoinMerge) (Task, error)
 		WalkJoinKey(p *plan.JoinKey) (Task, error)
@@ -1,9 +1,13 @@
 package exec_test
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,11 +15,15 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/datasource/memdb"
 	"github.com/fuhongbo/qlbridge/datasource/mockcsv"
 	td "github.com/fuhongbo/qlbridge/datasource/mockcsvtestdata"
 	"github.com/fuhongbo/qlbridge/exec"
+	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/rel"
 	"github.com/fuhongbo/qlbridge/schema"
 	"github.com/fuhongbo/qlbridge/testutil"
+	"github.com/fuhongbo/qlbridge/value"
 )
 
 func TestMain(m *testing.M) {
@@ -520,3 +528,846 @@ func testSubselect(t *testing.T) {
 	assert.True(t, err == nil, "no error %v", err)
 	assert.True(t, len(msgs) == 1, "should have filtered out 2 messages")
 }
+
+func TestExecInsertSelect(t *testing.T) {
+
+	sqlDb := openMemDb(t, "memdb_insert_select",
+		`CREATE TABLE users (id int, name varchar(50), age int, PRIMARY KEY (id))`,
+		`CREATE TABLE archive (
+		id int,
+		name varchar(50),
+		age varchar(10),
+		status varchar(10) DEFAULT "archived",
+		PRIMARY KEY (id)
+	)`)
+	defer sqlDb.Close()
+
+	// more rows than a single PutMulti batch
+	vals := make([]string, 0, 250)
+	for i := 1; i <= 250; i++ {
+		vals = append(vals, fmt.Sprintf(`(%d, "user%d", %d)`, i, i, i%50))
+	}
+	result, err := sqlDb.Exec(`INSERT INTO users (id, name, age) VALUES ` + strings.Join(vals, ", "))
+	assert.Equal(t, nil, err)
+	insertedCt, err := result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(250), insertedCt)
+
+	result, err = sqlDb.Exec(`INSERT INTO archive (id, name, age) SELECT id, name, age FROM users WHERE age >= 10`)
+	assert.Equal(t, nil, err)
+	insertedCt, err = result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(200), insertedCt)
+
+	var name, age, status string
+	err = sqlDb.QueryRow(`SELECT name, age, status FROM archive WHERE id = 42`).Scan(&name, &age, &status)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "user42", name)
+	assert.Equal(t, "42", age)
+	assert.Equal(t, "archived", status)
+	err = sqlDb.QueryRow(`SELECT name FROM archive WHERE id = 3`).Scan(&name)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// without a column list the select provides every column of the table
+	_, err = sqlDb.Exec(`INSERT INTO archive SELECT id, name, age FROM users WHERE id = 3`)
+	assert.NotEqual(t, nil, err)
+	result, err = sqlDb.Exec(`INSERT INTO archive SELECT id, name, age, "restored" FROM users WHERE id = 3`)
+	assert.Equal(t, nil, err)
+	insertedCt, err = result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), insertedCt)
+	err = sqlDb.QueryRow(`SELECT status FROM archive WHERE id = 3`).Scan(&status)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "restored", status)
+
+	_, err = sqlDb.Exec(`INSERT INTO archive (id, nope) SELECT id, name FROM users`)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestExecUpdateRows(t *testing.T) {
+
+	// memdb has no PatchWhere, so updates read, modify and write back rows
+	sqlDb := openMemDb(t, "memdb_update_rows",
+		`CREATE TABLE counters (id int, name varchar(50), n int, PRIMARY KEY (id))`)
+	defer sqlDb.Close()
+	_, err := sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (1, "a", 10), (2, "b", 20), (3, "c", 30)`)
+	assert.Equal(t, nil, err)
+
+	result, err := sqlDb.Exec(`UPDATE counters SET n = n + 1, name = "big" WHERE n >= 20`)
+	assert.Equal(t, nil, err)
+	updatedCt, err := result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), updatedCt)
+
+	counts := func() map[int64]string {
+		rows, err := sqlDb.Query(`SELECT id, name, n FROM counters`)
+		assert.Equal(t, nil, err)
+		defer rows.Close()
+		found := make(map[int64]string)
+		for rows.Next() {
+			var id, n int64
+			var name string
+			assert.Equal(t, nil, rows.Scan(&id, &name, &n))
+			found[id] = fmt.Sprintf("%s:%d", name, n)
+		}
+		return found
+	}
+	assert.Equal(t, map[int64]string{1: "a:10", 2: "big:21", 3: "big:31"}, counts())
+
+	// no where updates every row
+	result, err = sqlDb.Exec(`UPDATE counters SET n = n * 2`)
+	assert.Equal(t, nil, err)
+	updatedCt, err = result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(3), updatedCt)
+	assert.Equal(t, map[int64]string{1: "a:20", 2: "big:42", 3: "big:62"}, counts())
+
+	result, err = sqlDb.Exec(`UPDATE counters SET name = "none" WHERE id = 99`)
+	assert.Equal(t, nil, err)
+	updatedCt, err = result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(0), updatedCt)
+
+	// a key can't be moved onto the key of another row
+	_, err = sqlDb.Exec(`UPDATE counters SET id = 2 WHERE id = 1`)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, map[int64]string{1: "a:20", 2: "big:42", 3: "big:62"}, counts())
+
+	// changing the key moves the row rather than copying it
+	result, err = sqlDb.Exec(`UPDATE counters SET id = 10 WHERE id = 1`)
+	assert.Equal(t, nil, err)
+	updatedCt, err = result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), updatedCt)
+	assert.Equal(t, map[int64]string{10: "a:20", 2: "big:42", 3: "big:62"}, counts())
+
+	_, err = sqlDb.Exec(`UPDATE counters SET nope = 1 WHERE id = 1`)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestExecInsertOnConflict(t *testing.T) {
+
+	sqlDb := openMemDb(t, "memdb_insert_conflict",
+		`CREATE TABLE counters (id int, name varchar(50), n int, PRIMARY KEY (id))`)
+	defer sqlDb.Close()
+	_, err := sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (1, "a", 1), (2, "b", 2)`)
+	assert.Equal(t, nil, err)
+
+	counter := func(id int) (string, int64) {
+		var name string
+		var n int64
+		err := sqlDb.QueryRow(fmt.Sprintf(`SELECT name, n FROM counters WHERE id = %d`, id)).Scan(&name, &n)
+		assert.Equal(t, nil, err)
+		return name, n
+	}
+
+	// existing keys are skipped, new ones inserted
+	result, err := sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (1, "x", 10), (3, "c", 3) ON CONFLICT (id) DO NOTHING`)
+	assert.Equal(t, nil, err)
+	affected, err := result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), affected)
+	name, n := counter(1)
+	assert.Equal(t, "a", name)
+	assert.Equal(t, int64(1), n)
+	name, _ = counter(3)
+	assert.Equal(t, "c", name)
+
+	result, err = sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (1, "x", 10), (4, "d", 4)
+		ON CONFLICT (id) DO UPDATE SET n = n + excluded.n`)
+	assert.Equal(t, nil, err)
+	affected, err = result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), affected)
+	name, n = counter(1)
+	assert.Equal(t, "a", name)
+	assert.Equal(t, int64(11), n)
+	name, n = counter(4)
+	assert.Equal(t, "d", name)
+	assert.Equal(t, int64(4), n)
+
+	_, err = sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (2, "bb", 5) ON DUPLICATE KEY UPDATE name = VALUES(name), n = n * VALUES(n)`)
+	assert.Equal(t, nil, err)
+	name, n = counter(2)
+	assert.Equal(t, "bb", name)
+	assert.Equal(t, int64(10), n)
+
+	// the conflict target must be the key the source seeks on
+	_, err = sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (1, "a", 1) ON CONFLICT (name) DO NOTHING`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (1, "a", 1) ON CONFLICT (id) DO UPDATE SET id = 7`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (1, "a", 1) ON CONFLICT (id) DO UPDATE SET nope = 7`)
+	assert.NotEqual(t, nil, err)
+
+	// the written rows stream back through the result rows
+	rows, err := sqlDb.Query(`INSERT INTO counters (id, name, n) VALUES (1, "a", 1), (5, "e", 5)
+		ON CONFLICT (id) DO UPDATE SET n = n + excluded.n RETURNING id, n * 2 AS double`)
+	assert.Equal(t, nil, err)
+	cols, err := rows.Columns()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "double"}, cols)
+	got := make(map[int64]int64)
+	for rows.Next() {
+		var id, double int64
+		assert.Equal(t, nil, rows.Scan(&id, &double))
+		got[id] = double
+	}
+	assert.Equal(t, nil, rows.Err())
+	rows.Close()
+	assert.Equal(t, map[int64]int64{1: 24, 5: 10}, got)
+
+	rows, err = sqlDb.Query(`INSERT INTO counters (id, name, n) VALUES (6, "f", 6) RETURNING *`)
+	assert.Equal(t, nil, err)
+	cols, _ = rows.Columns()
+	assert.Equal(t, []string{"id", "name", "n"}, cols)
+	assert.True(t, rows.Next())
+	var id int64
+	assert.Equal(t, nil, rows.Scan(&id, &name, &n))
+	assert.Equal(t, int64(6), id)
+	assert.Equal(t, "f", name)
+	assert.Equal(t, int64(6), n)
+	assert.False(t, rows.Next())
+	rows.Close()
+
+	// Exec of a RETURNING insert counts the rows written
+	result, err = sqlDb.Exec(`INSERT INTO counters (id, name, n) VALUES (7, "g", 7), (8, "h", 8) RETURNING id`)
+	assert.Equal(t, nil, err)
+	affected, err = result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), affected)
+
+	result, err = sqlDb.Exec(`INSERT INTO counters (id, name, n) SELECT id, name, n FROM counters WHERE id >= 7
+		ON CONFLICT (id) DO UPDATE SET n = n + excluded.n`)
+	assert.Equal(t, nil, err)
+	affected, err = result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), affected)
+	_, n = counter(8)
+	assert.Equal(t, int64(16), n)
+
+	_, err = sqlDb.Query(`INSERT INTO counters (id, name, n) VALUES (9, "i", 9)`)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestExecViews(t *testing.T) {
+
+	sqlDb := openMemDb(t, "memdb_views",
+		`CREATE TABLE orders (id int, city varchar(50), total int, PRIMARY KEY (id))`)
+	defer sqlDb.Close()
+	_, err := sqlDb.Exec(`INSERT INTO orders (id, city, total) VALUES (1, "denver", 10), (2, "boulder", 20), (3, "denver", 30)`)
+	assert.Equal(t, nil, err)
+
+	_, err = sqlDb.Exec(`CREATE VIEW big_orders AS SELECT id, city, total FROM orders WHERE total > 15`)
+	assert.Equal(t, nil, err)
+	_, err = sqlDb.Exec(`CREATE MATERIALIZED VIEW cities AS SELECT city FROM orders`)
+	assert.Equal(t, nil, err)
+
+	ids := func(sql string) []int64 {
+		rows, err := sqlDb.Query(sql)
+		assert.Equal(t, nil, err)
+		defer rows.Close()
+		var vals []int64
+		for rows.Next() {
+			var id int64
+			assert.Equal(t, nil, rows.Scan(&id))
+			vals = append(vals, id)
+		}
+		return vals
+	}
+	count := func(sql string) int64 {
+		var ct int64
+		assert.Equal(t, nil, sqlDb.QueryRow(sql).Scan(&ct))
+		return ct
+	}
+
+	// logical views read the current rows of the tables
+	assert.Equal(t, []int64{2, 3}, ids(`SELECT id FROM big_orders`))
+	assert.Equal(t, []int64{3}, ids(`SELECT id FROM big_orders WHERE city = "denver"`))
+	// the materialized view keeps duplicate rows
+	assert.Equal(t, int64(2), count(`SELECT count(*) FROM cities WHERE city = "denver"`))
+
+	_, err = sqlDb.Exec(`INSERT INTO orders (id, city, total) VALUES (4, "denver", 40)`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int64{2, 3, 4}, ids(`SELECT id FROM big_orders`))
+	assert.Equal(t, int64(2), count(`SELECT count(*) FROM cities WHERE city = "denver"`))
+	_, err = sqlDb.Exec(`REFRESH MATERIALIZED VIEW cities`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(3), count(`SELECT count(*) FROM cities WHERE city = "denver"`))
+	_, err = sqlDb.Exec(`REFRESH MATERIALIZED VIEW big_orders`)
+	assert.NotEqual(t, nil, err)
+
+	// views of views, but not of themselves
+	_, err = sqlDb.Exec(`CREATE VIEW denver_big AS SELECT id FROM big_orders WHERE city = "denver"`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int64{3, 4}, ids(`SELECT id FROM denver_big`))
+	assert.Equal(t, []int64{4}, ids(`SELECT id FROM denver_big WHERE id > 3`))
+	assert.Equal(t, []int64{4, 3}, ids(`SELECT * FROM denver_big ORDER BY id DESC`))
+
+	// logical views are inlined, planned as a select of their table
+	ss, ok := schema.DefaultRegistry().Schema("memdb_views")
+	assert.True(t, ok)
+	ctx := plan.NewContext(`SELECT id FROM denver_big WHERE id > 3`)
+	ctx.Schema = ss
+	ctx.Stmt, err = rel.ParseSql(ctx.Raw)
+	assert.Equal(t, nil, err)
+	p, err := plan.WalkStmt(ctx, ctx.Stmt, plan.NewPlanner(ctx))
+	assert.Equal(t, nil, err)
+	sel := p.(*plan.Select)
+	assert.Equal(t, "orders", sel.From[0].Stmt.Name)
+	assert.Equal(t, "SELECT id FROM orders WHERE (total > 15) AND ((city = \"denver\") AND (id > 3))", sel.Stmt.String())
+	_, err = sqlDb.Exec(`CREATE VIEW doubled AS SELECT id, total * 2 AS dbl FROM orders`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int64{4, 3}, ids(`SELECT id FROM doubled WHERE dbl + 1 > 50 ORDER BY dbl DESC`))
+	assert.Equal(t, int64(2), count(`SELECT count(*) FROM doubled WHERE dbl > 50`))
+	_, err = sqlDb.Exec(`CREATE OR REPLACE VIEW big_orders AS SELECT id, city FROM denver_big`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`CREATE VIEW big_orders AS SELECT id FROM orders`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`CREATE VIEW orders AS SELECT id FROM orders`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`CREATE OR REPLACE VIEW big_orders AS SELECT id, city, total FROM orders WHERE total > 35`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int64{4}, ids(`SELECT id FROM denver_big`))
+
+	// refreshed on an interval
+	_, err = sqlDb.Exec(`CREATE MATERIALIZED VIEW order_ct AS SELECT count(*) AS ct FROM orders WITH refresh_interval="20ms"`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(4), count(`SELECT ct FROM order_ct`))
+	_, err = sqlDb.Exec(`INSERT INTO orders (id, city, total) VALUES (5, "golden", 50)`)
+	assert.Equal(t, nil, err)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int64(5), count(`SELECT ct FROM order_ct`))
+
+	tableTypes := make(map[string]string)
+	rows, err := sqlDb.Query(`SHOW FULL TABLES`)
+	assert.Equal(t, nil, err)
+	for rows.Next() {
+		var name, tableType string
+		assert.Equal(t, nil, rows.Scan(&name, &tableType))
+		tableTypes[name] = tableType
+	}
+	rows.Close()
+	assert.Equal(t, "BASE TABLE", tableTypes["orders"])
+	assert.Equal(t, "VIEW", tableTypes["big_orders"])
+	assert.Equal(t, "MATERIALIZED VIEW", tableTypes["cities"])
+
+	var name, create string
+	err = sqlDb.QueryRow(`SHOW CREATE VIEW cities`).Scan(&name, &create)
+	assert.Equal(t, nil, err)
+	assert.True(t, strings.HasPrefix(create, "CREATE MATERIALIZED VIEW `cities` AS SELECT city FROM orders"), create)
+	var definition, interval string
+	err = sqlDb.QueryRow("SELECT View_definition, Refresh_interval FROM `schema`.`views` WHERE Table = \"order_ct\"").Scan(&definition, &interval)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "20ms", interval)
+
+	// views are not tables
+	_, err = sqlDb.Exec(`INSERT INTO big_orders (id, city, total) VALUES (9, "x", 1)`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`DROP TABLE cities`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`DROP VIEW cities`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`DROP MATERIALIZED VIEW cities`)
+	assert.Equal(t, nil, err)
+	_, err = sqlDb.Exec(`DROP MATERIALIZED VIEW order_ct`)
+	assert.Equal(t, nil, err)
+	_, err = sqlDb.Exec(`DROP VIEW IF EXISTS cities`)
+	assert.Equal(t, nil, err)
+	_, err = sqlDb.Query(`SELECT city FROM cities`)
+	assert.NotEqual(t, nil, err)
+}
+
+type closeCountStore struct {
+	schema.Source
+	closed *int
+}
+
+func (m *closeCountStore) Close() error {
+	*m.closed++
+	return m.Source.Close()
+}
+
+func TestExecViewsRefreshStore(t *testing.T) {
+
+	closed := 0
+	exec.RegisterViewStore(func(tbl *schema.Table) (schema.Source, error) {
+		s, err := exec.NewRowStore(tbl)
+		return &closeCountStore{Source: s, closed: &closed}, err
+	})
+	defer exec.RegisterViewStore(exec.NewRowStore)
+
+	sqlDb := openMemDb(t, "memdb_views_refresh",
+		`CREATE TABLE orders (id int, city varchar(50), PRIMARY KEY (id))`,
+		`INSERT INTO orders (id, city) VALUES (1, "denver"), (2, "boulder")`,
+		`CREATE MATERIALIZED VIEW cities AS SELECT city FROM orders`)
+	defer sqlDb.Close()
+
+	rows, err := sqlDb.Query(`SELECT city FROM cities`)
+	assert.Equal(t, nil, err)
+	_, err = sqlDb.Exec(`INSERT INTO orders (id, city) VALUES (3, "golden")`)
+	assert.Equal(t, nil, err)
+	_, err = sqlDb.Exec(`REFRESH MATERIALIZED VIEW cities`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, closed)
+
+	// a reader of the previous rows still reads all of them
+	var cities []string
+	for rows.Next() {
+		var city string
+		assert.Equal(t, nil, rows.Scan(&city))
+		cities = append(cities, city)
+	}
+	rows.Close()
+	assert.Equal(t, 2, len(cities))
+
+	var ct int64
+	assert.Equal(t, nil, sqlDb.QueryRow(`SELECT count(*) FROM cities`).Scan(&ct))
+	assert.Equal(t, int64(3), ct)
+
+	_, err = sqlDb.Exec(`DROP MATERIALIZED VIEW cities`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, closed)
+}
+
+func TestExecContinuousViews(t *testing.T) {
+
+	sqlDb := openMemDb(t, "memdb_cviews",
+		`CREATE TABLE orders (id int, city varchar(50), total int, ts datetime, PRIMARY KEY (id))`)
+	defer sqlDb.Close()
+	// rows are read in id order, the 00:00:20 order arrives after the
+	// watermark has passed its window so is dropped as late
+	_, err := sqlDb.Exec(`INSERT INTO orders (id, city, total, ts) VALUES
+		(1, "denver", 10, "2020-01-01T00:00:10Z"),
+		(2, "boulder", 20, "2020-01-01T00:00:40Z"),
+		(3, "denver", 30, "2020-01-01T00:00:50Z"),
+		(4, "denver", 40, "2020-01-01T00:01:30Z"),
+		(5, "denver", 50, "2020-01-01T00:00:20Z")`)
+	assert.Equal(t, nil, err)
+
+	_, err = sqlDb.Exec(`CREATE CONTINUOUSVIEW city_totals AS
+		SELECT city, count(*) AS ct, sum(total) AS total, max(total) AS biggest
+		FROM orders
+		GROUP BY tumble(ts, "1m"), city
+		WITH watermark = "10s"`)
+	assert.Equal(t, nil, err)
+
+	type windowRow struct {
+		start   time.Time
+		city    string
+		ct      int64
+		total   float64
+		biggest float64
+	}
+	windows := func() []windowRow {
+		rows, err := sqlDb.Query(`SELECT window_start, city, ct, total, biggest FROM city_totals`)
+		assert.Equal(t, nil, err)
+		defer rows.Close()
+		var vals []windowRow
+		for rows.Next() {
+			var w windowRow
+			assert.Equal(t, nil, rows.Scan(&w.start, &w.city, &w.ct, &w.total, &w.biggest))
+			vals = append(vals, w)
+		}
+		return vals
+	}
+	// the stream of a memdb table ends after its rows, closing all windows
+	var got []windowRow
+	for i := 0; i < 100; i++ {
+		if got = windows(); len(got) == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []windowRow{
+		{t0, "boulder", 1, 20, 20},
+		{t0, "denver", 2, 40, 30},
+		{t0.Add(time.Minute), "denver", 1, 40, 40},
+	}, got)
+
+	// hopping and session windows
+	_, err = sqlDb.Exec(`CREATE CONTINUOUSVIEW hops AS SELECT count(*) AS ct FROM orders GROUP BY hop(ts, "1m", "30s")`)
+	assert.Equal(t, nil, err)
+	_, err = sqlDb.Exec(`CREATE CONTINUOUSVIEW visits AS SELECT city, count(*) AS ct FROM orders WHERE total < 50 GROUP BY session(ts, "15s"), city`)
+	assert.Equal(t, nil, err)
+	counts := func(sql string, want int) []int64 {
+		var vals []int64
+		for i := 0; i < 100 && len(vals) != want; i++ {
+			time.Sleep(10 * time.Millisecond)
+			rows, err := sqlDb.Query(sql)
+			assert.Equal(t, nil, err)
+			vals = vals[:0]
+			for rows.Next() {
+				var ct int64
+				assert.Equal(t, nil, rows.Scan(&ct))
+				vals = append(vals, ct)
+			}
+			rows.Close()
+		}
+		return vals
+	}
+	// [-30s,30s) [0,1m) [30s,1m30s) [1m,2m) [1m30s,2m30s)
+	assert.Equal(t, []int64{1, 3, 2, 1, 1}, counts(`SELECT ct FROM hops`, 5))
+	// boulder 00:40, denver 00:10, denver 00:50 - 01:30 are separate sessions
+	assert.Equal(t, []int64{1, 1, 1, 1}, counts(`SELECT ct FROM visits`, 4))
+
+	var tableType string
+	err = sqlDb.QueryRow("SELECT Table_Type FROM `schema`.`views` WHERE Table = \"city_totals\"").Scan(&tableType)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "CONTINUOUS VIEW", tableType)
+	var name, create string
+	err = sqlDb.QueryRow(`SHOW CREATE VIEW city_totals`).Scan(&name, &create)
+	assert.Equal(t, nil, err)
+	assert.True(t, strings.HasPrefix(create, "CREATE CONTINUOUSVIEW `city_totals` AS SELECT"), create)
+	assert.True(t, strings.HasSuffix(create, `WITH watermark = "10s"`), create)
+
+	// the oldest closed windows beyond the retained count, or that ended
+	// more than the retention before the watermark, are dropped
+	_, err = sqlDb.Exec(`CREATE CONTINUOUSVIEW last_ct AS SELECT count(*) AS ct FROM orders GROUP BY tumble(ts, "1m") WITH retain_windows = 1`)
+	assert.Equal(t, nil, err)
+	_, err = sqlDb.Exec(`CREATE CONTINUOUSVIEW recent_ct AS SELECT count(*) AS ct FROM orders GROUP BY tumble(ts, "1m") WITH retention = "5s"`)
+	assert.Equal(t, nil, err)
+	starts := func(sql string) []time.Time {
+		var vals []time.Time
+		for i := 0; i < 100; i++ {
+			time.Sleep(10 * time.Millisecond)
+			rows, err := sqlDb.Query(sql)
+			assert.Equal(t, nil, err)
+			vals = vals[:0]
+			for rows.Next() {
+				var start time.Time
+				assert.Equal(t, nil, rows.Scan(&start))
+				vals = append(vals, start)
+			}
+			rows.Close()
+			if len(vals) == 1 && vals[0].Equal(t0.Add(time.Minute)) {
+				break
+			}
+		}
+		return vals
+	}
+	assert.Equal(t, []time.Time{t0.Add(time.Minute)}, starts(`SELECT window_start FROM last_ct`))
+	assert.Equal(t, []time.Time{t0.Add(time.Minute)}, starts(`SELECT window_start FROM recent_ct`))
+	err = sqlDb.QueryRow(`SHOW CREATE VIEW recent_ct`).Scan(&name, &create)
+	assert.Equal(t, nil, err)
+	assert.True(t, strings.HasSuffix(create, `WITH retention = "5s"`), create)
+
+	for _, bad := range []string{
+		`CREATE CONTINUOUSVIEW bad AS SELECT count(*) AS ct FROM orders GROUP BY tumble(ts, "1m") WITH retain_windows = 0`,
+		`CREATE CONTINUOUSVIEW bad AS SELECT count(*) AS ct FROM orders GROUP BY tumble(ts, "1m") WITH retention = "soon"`,
+		`CREATE CONTINUOUSVIEW bad AS SELECT city, count(*) AS ct FROM orders GROUP BY city`,
+		`CREATE CONTINUOUSVIEW bad AS SELECT city, total FROM orders GROUP BY tumble(ts, "1m"), city`,
+		`CREATE CONTINUOUSVIEW bad AS SELECT count(*) AS ct FROM orders GROUP BY tumble(ts, "1m"), session(ts, "1m")`,
+		`CREATE CONTINUOUSVIEW bad AS SELECT count(*) AS ct FROM orders GROUP BY tumble(ts, "soon")`,
+		`CREATE CONTINUOUSVIEW bad AS SELECT count(*) AS ct FROM orders GROUP BY hop(ts, "10s", "1m")`,
+	} {
+		_, err = sqlDb.Exec(bad)
+		assert.NotEqual(t, nil, err, bad)
+	}
+
+	_, err = sqlDb.Exec(`REFRESH MATERIALIZED VIEW city_totals`)
+	assert.NotEqual(t, nil, err)
+	_, err = sqlDb.Exec(`DROP VIEW city_totals`)
+	assert.NotEqual(t, nil, err)
+	for _, v := range []string{"city_totals", "hops", "visits", "last_ct", "recent_ct"} {
+		_, err = sqlDb.Exec(`DROP CONTINUOUSVIEW ` + v)
+		assert.Equal(t, nil, err)
+	}
+	_, err = sqlDb.Query(`SELECT ct FROM city_totals`)
+	assert.NotEqual(t, nil, err)
+}
+
+// streamSource a single table source whose rows are sent on a channel,
+// reads block until the next row or the conn is closed.
+type streamSource struct {
+	tbl  *schema.Table
+	rows chan []driver.Value
+}
+type streamConn struct {
+	*streamSource
+	exit chan bool
+}
+
+func (m *streamSource) Init()                      {}
+func (m *streamSource) Setup(*schema.Schema) error { return nil }
+func (m *streamSource) Tables() []string           { return []string{m.tbl.Name} }
+func (m *streamSource) Close() error               { return nil }
+func (m *streamSource) Table(table string) (*schema.Table, error) {
+	if table != m.tbl.Name {
+		return nil, schema.ErrNotFound
+	}
+	return m.tbl, nil
+}
+func (m *streamSource) Open(table string) (schema.Conn, error) {
+	return &streamConn{streamSource: m, exit: make(chan bool)}, nil
+}
+func (m *streamConn) Columns() []string { return m.tbl.Columns() }
+func (m *streamConn) Close() error {
+	close(m.exit)
+	return nil
+}
+func (m *streamConn) Next() schema.Message {
+	select {
+	case row := <-m.rows:
+		return datasource.NewSqlDriverMessageMap(0, row, m.tbl.FieldPositions)
+	case <-m.exit:
+		return nil
+	}
+}
+
+func TestExecContinuousViewSubscribe(t *testing.T) {
+
+	tbl := schema.NewTable("clicks")
+	tbl.AddField(schema.NewFieldBase("ts", value.TimeType, 0, ""))
+	tbl.AddField(schema.NewFieldBase("page", value.StringType, 50, ""))
+	tbl.SetColumnsFromFields()
+	src := &streamSource{tbl: tbl, rows: make(chan []driver.Value)}
+	assert.Equal(t, nil, schema.RegisterSourceAsSchema("stream_cviews", src))
+	sqlDb, err := sql.Open("qlbridge", "stream_cviews")
+	assert.Equal(t, nil, err)
+	defer sqlDb.Close()
+
+	_, err = sqlDb.Exec(`CREATE CONTINUOUSVIEW page_views AS
+		SELECT page, count(*) AS ct FROM clicks GROUP BY tumble(ts, "1m"), page`)
+	assert.Equal(t, nil, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows, err := sqlDb.QueryContext(ctx, `SUBSCRIBE SELECT window_start, page, ct FROM page_views`)
+	assert.Equal(t, nil, err)
+
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, click := range []struct {
+		ts   time.Duration
+		page string
+	}{{10 * time.Second, "/a"}, {20 * time.Second, "/b"}, {30 * time.Second, "/a"}, {70 * time.Second, "/a"}} {
+		select {
+		case src.rows <- []driver.Value{t0.Add(click.ts), click.page}:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("stream not read at row %d", i)
+		}
+	}
+
+	// the first minute is closed by the row at 00:01:10
+	type windowRow struct {
+		start time.Time
+		page  string
+		ct    int64
+	}
+	var got []windowRow
+	for len(got) < 2 && rows.Next() {
+		var w windowRow
+		assert.Equal(t, nil, rows.Scan(&w.start, &w.page, &w.ct))
+		got = append(got, w)
+	}
+	assert.Equal(t, []windowRow{{t0, "/a", 2}, {t0, "/b", 1}}, got)
+	cancel()
+	rows.Close()
+
+	// a plain select sees the open window too
+	var ct int64
+	assert.Equal(t, nil, sqlDb.QueryRow(`SELECT ct FROM page_views WHERE window_start = "2020-01-01T00:01:00Z"`).Scan(&ct))
+	assert.Equal(t, int64(1), ct)
+
+	_, err = sqlDb.Exec(`DROP CONTINUOUSVIEW page_views`)
+	assert.Equal(t, nil, err)
+}
+
+func TestExecConstraints(t *testing.T) {
+
+	sqlDb := openMemDb(t, "memdb_constraints",
+		`CREATE TABLE accounts (
+		id int NOT NULL,
+		email varchar(10) NOT NULL,
+		age int DEFAULT 21,
+		plan varchar(10),
+		PRIMARY KEY (id)
+	)`)
+	defer sqlDb.Close()
+
+	constraintErr := func(sql string) *schema.ConstraintError {
+		_, err := sqlDb.Exec(sql)
+		var cerr *schema.ConstraintError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("expected ConstraintError for %s but got %v", sql, err)
+		}
+		return cerr
+	}
+
+	// defaults applied, values cast to the column types
+	_, err := sqlDb.Exec(`INSERT INTO accounts (id, email) VALUES ("1", "a@x.com")`)
+	assert.Equal(t, nil, err)
+	var id, age int64
+	var plan sql.NullString
+	assert.Equal(t, nil, sqlDb.QueryRow(`SELECT id, age, plan FROM accounts WHERE email = "a@x.com"`).Scan(&id, &age, &plan))
+	assert.Equal(t, int64(1), id)
+	assert.Equal(t, int64(21), age)
+	assert.False(t, plan.Valid)
+
+	cerr := constraintErr(`INSERT INTO accounts (id, email) VALUES (2, "b@x.com"), (3, NULL)`)
+	assert.Equal(t, "email", cerr.Column)
+	assert.Equal(t, 2, cerr.Row)
+	assert.Equal(t, schema.ErrNotNull, cerr.Err)
+
+	cerr = constraintErr(`INSERT INTO accounts (id, plan) VALUES (4, "free")`)
+	assert.Equal(t, "email", cerr.Column)
+	assert.Equal(t, schema.ErrNoDefault, cerr.Err)
+
+	cerr = constraintErr(`INSERT INTO accounts (id, email) VALUES (5, "much-too-long@x.com")`)
+	assert.Equal(t, "email", cerr.Column)
+	assert.Equal(t, 1, cerr.Row)
+	assert.Equal(t, schema.ErrTooLong, cerr.Err)
+
+	cerr = constraintErr(`INSERT INTO accounts (id, email, age) VALUES (6, "f@x.com", "old")`)
+	assert.Equal(t, "age", cerr.Column)
+
+	// INSERT ... SELECT rows are checked too
+	cerr = constraintErr(`INSERT INTO accounts (id, email) SELECT age, plan FROM accounts`)
+	assert.Equal(t, "email", cerr.Column)
+	assert.Equal(t, schema.ErrNotNull, cerr.Err)
+
+	// as are the columns an UPDATE sets
+	cerr = constraintErr(`UPDATE accounts SET plan = "enterprise-plus" WHERE id = 1`)
+	assert.Equal(t, "plan", cerr.Column)
+	assert.Equal(t, 1, cerr.Row)
+	assert.Equal(t, schema.ErrTooLong, cerr.Err)
+	_, err = sqlDb.Exec(`UPDATE accounts SET plan = "pro", age = "30" WHERE id = 1`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, sqlDb.QueryRow(`SELECT age, plan FROM accounts WHERE id = 1`).Scan(&age, &plan))
+	assert.Equal(t, int64(30), age)
+	assert.Equal(t, "pro", plan.String)
+
+	// no row is changed when any updated row fails a check
+	_, err = sqlDb.Exec(`INSERT INTO accounts (id, email) VALUES (7, "g@x.com")`)
+	assert.Equal(t, nil, err)
+	cerr = constraintErr(`UPDATE accounts SET email = plan`)
+	assert.Equal(t, "email", cerr.Column)
+	assert.Equal(t, 2, cerr.Row)
+	assert.Equal(t, schema.ErrNotNull, cerr.Err)
+	var email string
+	assert.Equal(t, nil, sqlDb.QueryRow(`SELECT email FROM accounts WHERE id = 1`).Scan(&email))
+	assert.Equal(t, "a@x.com", email)
+
+	var ct int64
+	assert.Equal(t, nil, sqlDb.QueryRow(`SELECT count(*) FROM accounts`).Scan(&ct))
+	assert.Equal(t, int64(2), ct)
+}
+
+func TestExecInfoSchema(t *testing.T) {
+
+	sqlDb := openMemDb(t, "memdb_infoschema",
+		`CREATE TABLE accounts (
+		id int NOT NULL,
+		email varchar(100) NOT NULL,
+		plan varchar(10) DEFAULT "free",
+		PRIMARY KEY (id),
+		CONSTRAINT uniq_email UNIQUE (email)
+	)`)
+	defer sqlDb.Close()
+
+	var name, create string
+	assert.Equal(t, nil, sqlDb.QueryRow(`SHOW CREATE TABLE accounts`).Scan(&name, &create))
+	assert.Equal(t, "CREATE TABLE `accounts` (\n"+
+		"    `id` bigint NOT NULL,\n"+
+		"    `email` varchar(100) NOT NULL,\n"+
+		"    `plan` varchar(10) DEFAULT \"free\",\n"+
+		"    PRIMARY KEY (`id`),\n"+
+		"    UNIQUE KEY `uniq_email` (`email`)\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8;", create)
+
+	strRows := func(qry string) [][]string {
+		rows, err := sqlDb.Query(qry)
+		assert.Equal(t, nil, err, qry)
+		defer rows.Close()
+		cols, _ := rows.Columns()
+		var out [][]string
+		for rows.Next() {
+			vals := make([]sql.NullString, len(cols))
+			dest := make([]interface{}, len(cols))
+			for i := range vals {
+				dest[i] = &vals[i]
+			}
+			assert.Equal(t, nil, rows.Scan(dest...))
+			row := make([]string, len(cols))
+			for i, v := range vals {
+				row[i] = v.String
+			}
+			out = append(out, row)
+		}
+		return out
+	}
+
+	// Table, Non_unique, Key_name, Seq_in_index, Column_name ...
+	idx := strRows(`SHOW INDEX FROM accounts`)
+	assert.Equal(t, 2, len(idx))
+	assert.Equal(t, []string{"accounts", "false", "PRIMARY", "1", "id"}, idx[0][:5])
+	assert.Equal(t, []string{"accounts", "false", "uniq_email", "1", "email"}, idx[1][:5])
+	idx = strRows(`SHOW KEYS FROM accounts WHERE Key_name = "uniq_email"`)
+	assert.Equal(t, 1, len(idx))
+
+	assert.Equal(t, [][]string{{"PRIMARY", "PRIMARY KEY"}, {"uniq_email", "UNIQUE"}},
+		strRows(`SELECT CONSTRAINT_NAME, CONSTRAINT_TYPE FROM information_schema.table_constraints
+			WHERE TABLE_SCHEMA = "memdb_infoschema"`))
+	assert.Equal(t, [][]string{{"PRIMARY", "id", "1"}, {"uniq_email", "email", "1"}},
+		strRows(`SELECT CONSTRAINT_NAME, COLUMN_NAME, ORDINAL_POSITION FROM information_schema.key_column_usage
+			WHERE TABLE_SCHEMA = "memdb_infoschema" AND TABLE_NAME = "accounts"`))
+	assert.Equal(t, [][]string{{"id", "NO", "PRI", ""}, {"email", "NO", "UNI", ""}, {"plan", "YES", "", "free"}},
+		strRows(`SELECT COLUMN_NAME, IS_NULLABLE, COLUMN_KEY, COLUMN_DEFAULT FROM information_schema.columns
+			WHERE TABLE_SCHEMA = "memdb_infoschema"`))
+
+	// a plain index is not unique, nor a constraint
+	_, err := sqlDb.Exec(`CREATE INDEX idx_plan ON accounts (plan)`)
+	assert.Equal(t, nil, err)
+	idx = strRows(`SHOW INDEX FROM accounts WHERE Key_name = "idx_plan"`)
+	assert.Equal(t, 1, len(idx))
+	assert.Equal(t, []string{"accounts", "true", "idx_plan", "1", "plan"}, idx[0][:5])
+	assert.Equal(t, [][]string{{"PRIMARY", "0", ""}, {"uniq_email", "0", ""}, {"idx_plan", "1", "YES"}},
+		strRows(`SELECT INDEX_NAME, NON_UNIQUE, NULLABLE FROM information_schema.statistics
+			WHERE TABLE_SCHEMA = "memdb_infoschema" AND TABLE_NAME = "accounts"`))
+	assert.Equal(t, 2, len(strRows(`SELECT CONSTRAINT_NAME FROM information_schema.table_constraints
+			WHERE TABLE_SCHEMA = "memdb_infoschema"`)))
+	assert.Equal(t, 2, len(strRows(`SELECT CONSTRAINT_NAME FROM information_schema.key_column_usage
+			WHERE TABLE_SCHEMA = "memdb_infoschema"`)))
+	assert.Equal(t, nil, sqlDb.QueryRow(`SHOW CREATE TABLE accounts`).Scan(&name, &create))
+	assert.True(t, strings.Contains(create, "    UNIQUE KEY `uniq_email` (`email`),\n    KEY `idx_plan` (`plan`)\n"), create)
+
+	_, err = sqlDb.Exec(`CREATE VIEW free_accounts AS SELECT id, email FROM accounts WHERE plan = "free"`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, [][]string{{"free_accounts", "VIEW"}},
+		strRows(`SELECT TABLE_NAME, TABLE_TYPE FROM information_schema.tables
+			WHERE TABLE_SCHEMA = "memdb_infoschema" AND TABLE_TYPE = "VIEW"`))
+	views := strRows(`SELECT TABLE_NAME, VIEW_DEFINITION FROM information_schema.views WHERE TABLE_SCHEMA = "memdb_infoschema"`)
+	assert.Equal(t, 1, len(views))
+	assert.Equal(t, "free_accounts", views[0][0])
+}
+
+// openMemDb registers an empty memdb source as schema @name, opens a
+// qlbridge db on it and runs the CREATE TABLE @stmts.
+func openMemDb(t *testing.T, name string, stmts ...string) *sql.DB {
+	assert.Equal(t, nil, schema.RegisterSourceAsSchema(name, memdb.NewSource()))
+	sqlDb, err := sql.Open("qlbridge", name)
+	assert.Equal(t, nil, err)
+	for _, stmt := range stmts {
+		_, err = sqlDb.Exec(stmt)
+		assert.Equal(t, nil, err)
+	}
+	return sqlDb
+}
+
+func TestResultRowsValues(t *testing.T) {
+
+	rw := exec.NewResultRows(td.TestContext("SELECT name, ct FROM t"), []string{"name", "ct"})
+	ch := make(exec.MessageChan, 2)
+	ch <- &datasource.SqlDriverMessage{Vals: []driver.Value{"bob", int64(2)}, IdVal: 1}
+	ch <- &datasource.SqlDriverMessage{Vals: []driver.Value{"no such column", -1}, IdVal: 2}
+	close(ch)
+	rw.MessageInSet(ch)
+
+	// a row of values in column order, not mistaken for an error
+	dest := make([]driver.Value, 2)
+	assert.Equal(t, nil, rw.Next(dest))
+	assert.Equal(t, []driver.Value{"bob", int64(2)}, dest)
+	// the error message of a failed mutation
+	assert.Equal(t, "no such column", rw.Next(dest).Error())
+}
@@ -158,16 +158,31 @@ func (m *JobExecutor) WalkUpsert(p *plan.Upsert) (Task, error) {
 }
 func (m *JobExecutor) WalkInsert(p *plan.Insert) (Task, error) {
 	root := m.NewTask(p)
+	if p.Select != nil {
+		// INSERT ... SELECT, the select tasks feed their rows to the insert
+		if err := m.WalkChildren(p.Select, root); err != nil {
+			return nil, err
+		}
+	}
 	return root, root.Add(NewInsert(m.Ctx, p))
 }
 func (m *JobExecutor) WalkUpdate(p *plan.Update) (Task, error) {
 	root := m.NewTask(p)
+	if p.Select != nil {
+		// read-modify-write, the select tasks feed the rows to update
+		if err := m.WalkChildren(p.Select, root); err != nil {
+			return nil, err
+		}
+	}
 	return root, root.Add(NewUpdate(m.Ctx, p))
 }
 func (m *JobExecutor) WalkDelete(p *plan.Delete) (Task, error) {
 	root := m.NewTask(p)
 	return root, root.Add(NewDelete(m.Ctx, p))
 }
+func (m *JobExecutor) WalkInto(p *plan.Into) (Task, error) {
+	return NewInto(m.Ctx, p), nil
+}
 func (m *JobExecutor) WalkSource(p *plan.Source) (Task, error) {
 	if len(p.Static) > 0 {
 		static := membtree.NewStaticData("static")
@@ -183,7 +198,7 @@ func (m *JobExecutor) WalkSource(p *plan.Source) (Task, error) {
 			u.Warnf("no datasource")
 			return nil, fmt.Errorf("missing data source")
 		}
-		source, err := p.DataSource.Open(p.Stmt.SourceName())
+		source, err := p.DataSource.Open(p.SourceName())
 		if err != nil {
 			return nil, err
 		}
@@ -204,7 +219,7 @@ func (m *JobExecutor) WalkSourceExec(p *plan.Source) (Task, error) {
 			u.Warnf("no datasource")
 			return nil, fmt.Errorf("missing data source")
 		}
-		source, err := p.DataSource.Open(p.Stmt.SourceName())
+		source, err := p.DataSource.Open(p.SourceName())
 		if err != nil {
 			return nil, err
 		}
@@ -235,6 +250,28 @@ func (m *JobExecutor) WalkProjection(p *plan.Projection) (Task, error) {
 }
 func (m *JobExecutor) WalkJoin(p *plan.JoinMerge) (Task, error) {
 	execTask := NewTaskParallel(m.Ctx)
+	if p.Strategy.IsLookup() {
+		// Only the probe side is scanned, the other is seeked by key
+		probe := p.Left
+		if p.Strategy == plan.JoinLookupLeft {
+			probe = p.Right
+		}
+		pt, err := m.WalkPlanAll(probe)
+		if err != nil {
+			return nil, err
+		}
+		if err = execTask.Add(pt); err != nil {
+			return nil, err
+		}
+		jl, err := NewJoinLookup(m.Ctx, pt.(TaskRunner), p)
+		if err != nil {
+			return nil, err
+		}
+		if err = execTask.Add(jl); err != nil {
+			return nil, err
+		}
+		return execTask, nil
+	}
 	//u.Debugf("join.Left: %#v    \nright:%#v", p.Left, p.Right)
 	l, err := m.WalkPlanAll(p.Left)
 	if err != nil {
@@ -302,6 +339,8 @@ func (m *JobExecutor) WalkPlanTask(p plan.Task) (Task, error) {
 		return m.Executor.WalkJoin(p)
 	case *plan.JoinKey:
 		return m.Executor.WalkJoinKey(p)
+	case *plan.Into:
+		return m.Executor.WalkInto(p)
 	}
 	panic(fmt.Sprintf("Task plan-exec Not implemented for %T", p))
 }
@@ -341,6 +380,7 @@ func (m *JobExecutor) WalkChildren(p plan.Task, root Task) error {
 		et, err := m.WalkPlanTask(t)
 		if err != nil {
 			u.Errorf("could not create task %#v err=%v", t, err)
+			return err
 		}
 		if len(t.Children()) == 0 {
 			err = root.Add(et)
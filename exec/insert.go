@@ -0,0 +1,410 @@
+package exec
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/vm"
+)
+
+// insertBatchSize the number of rows of an INSERT ... SELECT written
+// per PutMulti call.
+var insertBatchSize = 100
+
+type (
+	// insertTarget the table an INSERT writes to, and the position in
+	// the table row of each of the insert columns.
+	insertTarget struct {
+		tbl       *schema.Table
+		cols      []string
+		colIndex  map[string]int
+		positions []int
+		n         int // rows built, for the row of constraint errors
+	}
+	// returning the RETURNING columns of an insert, evaluated against
+	// each written row.
+	returning struct {
+		names    []string
+		colIndex map[string]int
+		exprs    []expr.Node
+	}
+)
+
+// newInsertTarget the target for writing @cols of @table, all of the
+// table columns if none.
+func newInsertTarget(ctx *plan.Context, table string, cols []string) (*insertTarget, error) {
+	tbl, err := ctx.Schema.Table(table)
+	if err != nil {
+		return nil, err
+	}
+	t := &insertTarget{tbl: tbl, cols: tbl.Columns()}
+	t.colIndex = make(map[string]int, len(t.cols))
+	for i, col := range t.cols {
+		t.colIndex[col] = i
+	}
+	if len(cols) == 0 {
+		cols = t.cols
+	}
+	t.positions = make([]int, len(cols))
+	for i, col := range cols {
+		pos, ok := t.colIndex[col]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q in table %q", col, table)
+		}
+		t.positions[i] = pos
+	}
+	return t, nil
+}
+
+// row the table row for the insert @vals, table columns not inserted
+// get their default.  Each value is checked against and coerced to the
+// schema of its column.
+func (m *insertTarget) row(vals []driver.Value) ([]driver.Value, error) {
+	m.n++
+	if len(vals) != len(m.positions) {
+		return nil, fmt.Errorf("INSERT INTO %q has %d columns but got %d values at row %d",
+			m.tbl.Name, len(m.positions), len(vals), m.n)
+	}
+	row := make([]driver.Value, len(m.cols))
+	written := make([]bool, len(m.cols))
+	for i, val := range vals {
+		row[m.positions[i]] = val
+		written[m.positions[i]] = true
+	}
+	for pos, col := range m.cols {
+		f, ok := m.tbl.FieldMap[col]
+		if !ok || written[pos] {
+			continue
+		}
+		def, err := f.OmittedValue()
+		if err != nil {
+			return nil, &schema.ConstraintError{Table: m.tbl.Name, Column: col, Row: m.n, Err: err}
+		}
+		row[pos] = def
+	}
+	for pos, val := range row {
+		if err := m.set(row, pos, val); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+// set @row[@pos] = @val coerced to the type of that column.
+func (m *insertTarget) set(row []driver.Value, pos int, val driver.Value) error {
+	val, err := coerceField(m.tbl, m.cols[pos], m.n, val)
+	if err != nil {
+		return err
+	}
+	row[pos] = val
+	return nil
+}
+
+// coerceField coerce @val written to column @col of @tbl at @rowNum to the
+// schema of that column, columns without a field are not checked.
+func coerceField(tbl *schema.Table, col string, rowNum int, val driver.Value) (driver.Value, error) {
+	f, ok := tbl.FieldMap[col]
+	if !ok {
+		return val, nil
+	}
+	cv, err := f.Coerce(val)
+	if err != nil {
+		return nil, &schema.ConstraintError{Table: tbl.Name, Column: col, Row: rowNum, Value: val, Err: err}
+	}
+	return cv, nil
+}
+
+// eachInsertRow call @fn with the values of each row of the insert, either
+// the VALUES of the statement or the rows of the select feeding this task.
+func (m *Upsert) eachInsertRow(fn func(vals []driver.Value) error) error {
+
+	if m.insert.Select == nil {
+		for _, row := range m.insert.Rows {
+			select {
+			case <-m.SigChan():
+				return nil
+			default:
+			}
+			vals := make([]driver.Value, len(row))
+			for x, val := range row {
+				if val.Expr != nil {
+					exprVal, ok := vm.Eval(nil, val.Expr)
+					if !ok {
+						u.Errorf("Could not evaluate: %v", val.Expr)
+						return fmt.Errorf("Could not evaluate expression: %v", val.Expr)
+					}
+					vals[x] = exprVal.Value()
+				} else {
+					vals[x] = val.Value.Value()
+				}
+			}
+			if err := fn(vals); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	inCh := m.MessageIn()
+	for {
+		select {
+		case <-m.SigChan():
+			return nil
+		case msg, ok := <-inCh:
+			if !ok || msg == nil {
+				return nil
+			}
+			var vals []driver.Value
+			switch mt := msg.(type) {
+			case *datasource.SqlDriverMessageMap:
+				vals = mt.Values()
+			case *datasource.SqlDriverMessage:
+				vals = mt.Vals
+			default:
+				return fmt.Errorf("INSERT ... SELECT requires SqlDriverMessageMap but got %T", msg)
+			}
+			if err := fn(vals); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// insertSelect write the rows of the select feeding this task, in batches
+// of insertBatchSize through PutMulti.  Each projected column is written
+// to the insert column of the same position (all table columns if none
+// listed) coerced to that columns type, table columns not inserted get
+// their default.
+func (m *Upsert) insertSelect() (int64, error) {
+
+	target, err := newInsertTarget(m.Ctx, m.insert.Table, m.insert.ColumnNames())
+	if err != nil {
+		return 0, err
+	}
+
+	var affectedCt int64
+	batch := make([][]driver.Value, 0, insertBatchSize)
+	put := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := m.db.PutMulti(m.Ctx.Context, nil, batch); err != nil {
+			u.Errorf("Could not put values into %q: %v", m.insert.Table, err)
+			return err
+		}
+		affectedCt += int64(len(batch))
+		batch = make([][]driver.Value, 0, insertBatchSize)
+		return nil
+	}
+
+	err = m.eachInsertRow(func(vals []driver.Value) error {
+		row, err := target.row(vals)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, row)
+		if len(batch) >= insertBatchSize {
+			return put()
+		}
+		return nil
+	})
+	if err != nil {
+		return affectedCt, err
+	}
+	return affectedCt, put()
+}
+
+// insertEach write the insert rows one at a time, for an insert with an
+// ON CONFLICT clause and/or RETURNING columns.  The existing row of a
+// conflicting key is read with the sources Get(), so its key must be
+// the column the source seeks on, its first column unless it is a
+// schema.ConnSeekerKey.  The conflict is resolved here rather than by
+// the source, so it is not atomic with concurrent writers.  The updated
+// row is Put() with its key.  Rows skipped by DO NOTHING are not counted
+// or returned.
+func (m *Upsert) insertEach() (int64, error) {
+
+	target, err := newInsertTarget(m.Ctx, m.insert.Table, m.insert.ColumnNames())
+	if err != nil {
+		return 0, err
+	}
+	var ret *returning
+	if len(m.insert.Returning) > 0 {
+		ret = newReturning(target, m.insert.Returning)
+	}
+
+	oc := m.insert.OnConflict
+	var seeker schema.ConnSeeker
+	var keyCol string
+	keyPos := -1
+	if oc != nil {
+		sk, ok := m.db.(schema.ConnSeeker)
+		if !ok {
+			return 0, fmt.Errorf("%T does not support ON CONFLICT for %q, it can't seek rows by key", m.db, m.insert.Table)
+		}
+		if skk, ok := m.db.(schema.ConnSeekerKey); ok {
+			keyCol = skk.SeekColumn()
+		} else if len(target.cols) > 0 {
+			keyCol = target.cols[0]
+		}
+		if len(oc.Columns) > 1 || (len(oc.Columns) == 1 && !strings.EqualFold(oc.Columns[0], keyCol)) {
+			return 0, fmt.Errorf("ON CONFLICT (%s) must be the key column %q of %q",
+				strings.Join(oc.Columns, ", "), keyCol, m.insert.Table)
+		}
+		keyPos = target.colIndex[keyCol]
+		for col := range oc.Values {
+			if _, ok := target.colIndex[col]; !ok {
+				return 0, fmt.Errorf("unknown column %q in table %q", col, m.insert.Table)
+			}
+			if col == keyCol {
+				return 0, fmt.Errorf("ON CONFLICT may not update the key column %q", keyCol)
+			}
+		}
+		seeker = sk
+	}
+
+	var affectedCt int64
+	err = m.eachInsertRow(func(vals []driver.Value) error {
+		row, err := target.row(vals)
+		if err != nil {
+			return err
+		}
+		var key schema.Key
+		if seeker != nil {
+			existing, err := seekRow(seeker, row[keyPos])
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				if oc.DoNothing {
+					return nil
+				}
+				if row, err = conflictUpdate(target, oc, existing, row); err != nil {
+					return err
+				}
+				key = datasource.NewKeyCol(keyCol, row[keyPos])
+			}
+		}
+		if _, err = m.db.Put(m.Ctx.Context, key, row); err != nil {
+			u.Errorf("Could not put values into %q: %v", m.insert.Table, err)
+			return err
+		}
+		affectedCt++
+		if ret != nil {
+			msg, err := ret.message(uint64(affectedCt), target, row)
+			if err != nil {
+				return err
+			}
+			m.msgOutCh <- msg
+		}
+		return nil
+	})
+	return affectedCt, err
+}
+
+// seekRow the values of the existing row of @key, nil if there is none.
+func seekRow(seeker schema.ConnSeeker, key driver.Value) ([]driver.Value, error) {
+	msg, err := seeker.Get(key)
+	if err == schema.ErrNotFound || (err == nil && msg == nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	switch mt := msg.(type) {
+	case *datasource.SqlDriverMessage:
+		return mt.Vals, nil
+	case *datasource.SqlDriverMessageMap:
+		return mt.Vals, nil
+	}
+	return nil, fmt.Errorf("unexpected message type %T", msg)
+}
+
+// conflictUpdate the row to write for the conflict DO UPDATE of @existing
+// by the @proposed row.  The expressions see the existing rows columns by
+// name and the proposed rows as excluded.col.
+func conflictUpdate(target *insertTarget, oc *rel.SqlOnConflict, existing, proposed []driver.Value) ([]driver.Value, error) {
+	n := len(target.cols)
+	vals := make([]driver.Value, 0, n*2)
+	vals = append(vals, existing...)
+	vals = append(vals, proposed...)
+	colIndex := make(map[string]int, n*2)
+	for i, col := range target.cols {
+		colIndex[col] = i
+		colIndex["excluded."+col] = n + i
+	}
+	reader := datasource.NewSqlDriverMessageMap(0, vals, colIndex)
+
+	row := make([]driver.Value, n)
+	copy(row, existing)
+	for col, vc := range oc.Values {
+		var val driver.Value
+		if vc.Expr != nil {
+			exprVal, ok := vm.Eval(reader, vc.Expr)
+			if !ok {
+				return nil, fmt.Errorf("Could not evaluate expression: %v", vc.Expr)
+			}
+			val = exprVal.Value()
+		} else {
+			val = vc.Value.Value()
+		}
+		if err := target.set(row, target.colIndex[col], val); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+func newReturning(target *insertTarget, cols rel.Columns) *returning {
+	m := &returning{colIndex: make(map[string]int)}
+	add := func(name string, node expr.Node) {
+		m.colIndex[name] = len(m.names)
+		m.names = append(m.names, name)
+		m.exprs = append(m.exprs, node)
+	}
+	for _, col := range cols {
+		switch {
+		case col.Star:
+			for _, tc := range target.cols {
+				add(tc, expr.NewIdentityNodeVal(tc))
+			}
+		case col.As != "":
+			add(col.As, col.Expr)
+		default:
+			add(col.Expr.String(), col.Expr)
+		}
+	}
+	return m
+}
+
+// returningColumns the names of the RETURNING columns of @ins.
+func returningColumns(ctx *plan.Context, ins *rel.SqlInsert) ([]string, error) {
+	target, err := newInsertTarget(ctx, ins.Table, ins.ColumnNames())
+	if err != nil {
+		return nil, err
+	}
+	return newReturning(target, ins.Returning).names, nil
+}
+
+// message the RETURNING message of the written table @row.
+func (m *returning) message(id uint64, target *insertTarget, row []driver.Value) (*datasource.SqlDriverMessageMap, error) {
+	reader := datasource.NewSqlDriverMessageMap(id, row, target.colIndex)
+	vals := make([]driver.Value, len(m.exprs))
+	for i, node := range m.exprs {
+		val, ok := vm.Eval(reader, node)
+		if !ok {
+			return nil, fmt.Errorf("Could not evaluate RETURNING %v", node)
+		}
+		if val != nil && !val.Nil() {
+			vals[i] = val.Value()
+		}
+	}
+	return datasource.NewSqlDriverMessageMap(id, vals, m.colIndex), nil
+}
@@ -9,9 +9,11 @@ import (
 	u "github.com/araddon/gou"
 
 	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/expr"
 	"github.com/fuhongbo/qlbridge/plan"
 	"github.com/fuhongbo/qlbridge/rel"
 	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
 	"github.com/fuhongbo/qlbridge/vm"
 )
 
@@ -20,6 +22,7 @@ var (
 
 	// Ensure that we implement the Task Runner interface
 	_ TaskRunner = (*JoinMerge)(nil)
+	_ TaskRunner = (*JoinLookup)(nil)
 )
 
 type KeyEvaluator func(msg schema.Message) driver.Value
@@ -106,6 +109,8 @@ type JoinMerge struct {
 	ltask     TaskRunner
 	rtask     TaskRunner
 	colIndex  map[string]int
+	width     int
+	strategy  plan.JoinStrategy
 }
 
 // A very stupid naive parallel join merge, uses Key() as value to merge
@@ -138,11 +143,17 @@ func NewJoinNaiveMerge(ctx *plan.Context, l, r TaskRunner, p *plan.JoinMerge) *J
 	m.rtask = r
 	m.leftStmt = p.LeftFrom
 	m.rightStmt = p.RightFrom
+	m.strategy = p.Strategy
+	m.width = joinWidth(p)
 
 	return m
 }
 
 func (m *JoinMerge) Run() error {
+	switch m.strategy {
+	case plan.JoinBuildLeft, plan.JoinBuildRight:
+		return m.runBuildProbe()
+	}
 	defer m.Ctx.Recover()
 	defer close(m.msgOutCh)
 
@@ -252,6 +263,87 @@ func (m *JoinMerge) Run() error {
 	return nil
 }
 
+// runBuildProbe hash the rows of the smaller (build) side, then stream the
+// other (probe) side through it emitting rows as they match instead of
+// buffering both sides.
+func (m *JoinMerge) runBuildProbe() error {
+	defer m.Ctx.Recover()
+	defer close(m.msgOutCh)
+
+	outCh := m.MessageOut()
+	buildLeft := m.strategy == plan.JoinBuildLeft
+	buildIn, probeIn := m.ltask.MessageOut(), m.rtask.MessageOut()
+	if !buildLeft {
+		buildIn, probeIn = probeIn, buildIn
+	}
+
+	built := make(map[driver.Value][]*datasource.SqlDriverMessageMap)
+	for building := true; building; {
+		select {
+		case <-m.SigChan():
+			return nil
+		case msg, ok := <-buildIn:
+			if !ok {
+				building = false
+				break
+			}
+			mt, err := keyedJoinMsg(msg)
+			if err != nil {
+				u.Errorf("%v", err)
+				return err
+			}
+			built[mt.Key()] = append(built[mt.Key()], mt)
+		}
+	}
+
+	i := uint64(0)
+	for {
+		select {
+		case <-m.SigChan():
+			return nil
+		case msg, ok := <-probeIn:
+			if !ok {
+				return nil
+			}
+			mt, err := keyedJoinMsg(msg)
+			if err != nil {
+				u.Errorf("%v", err)
+				return err
+			}
+			matches, ok := built[mt.Key()]
+			if !ok {
+				continue
+			}
+			var msgs []*datasource.SqlDriverMessageMap
+			if buildLeft {
+				msgs = m.mergeValueMessages(matches, []*datasource.SqlDriverMessageMap{mt})
+			} else {
+				msgs = m.mergeValueMessages([]*datasource.SqlDriverMessageMap{mt}, matches)
+			}
+			for _, out := range msgs {
+				out.IdVal = i
+				i++
+				select {
+				case outCh <- out:
+				case <-m.SigChan():
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func keyedJoinMsg(msg schema.Message) (*datasource.SqlDriverMessageMap, error) {
+	mt, ok := msg.(*datasource.SqlDriverMessageMap)
+	if !ok {
+		return nil, fmt.Errorf("To use Join must use SqlDriverMessageMap but got %T", msg)
+	}
+	if mt.Key() == "" {
+		return nil, fmt.Errorf(`To use Join msgs must have keys but got "" for %+v`, mt)
+	}
+	return mt, nil
+}
+
 func (m *JoinMerge) mergeValueMessages(lmsgs, rmsgs []*datasource.SqlDriverMessageMap) []*datasource.SqlDriverMessageMap {
 	// m.leftStmt.Columns, m.rightStmt.Columns, nil
 	//func mergeValuesMsgs(lmsgs, rmsgs []datasource.Message, lcols, rcols []*rel.Column, cols map[string]*rel.Column) []*datasource.SqlDriverMessageMap {
@@ -260,9 +352,9 @@ func (m *JoinMerge) mergeValueMessages(lmsgs, rmsgs []*datasource.SqlDriverMessa
 	for _, lm := range lmsgs {
 		//u.Warnf("nice SqlDriverMessageMap: %#v", lmt)
 		for _, rm := range rmsgs {
-			vals := make([]driver.Value, len(m.colIndex))
-			vals = m.valIndexing(vals, lm.Values(), m.leftStmt.Source.Columns)
-			vals = m.valIndexing(vals, rm.Values(), m.rightStmt.Source.Columns)
+			vals := make([]driver.Value, m.width)
+			vals = valIndexing(vals, lm.Values(), m.leftStmt.Source.Columns)
+			vals = valIndexing(vals, rm.Values(), m.rightStmt.Source.Columns)
 			newMsg := datasource.NewSqlDriverMessageMap(0, vals, m.colIndex)
 			//u.Infof("out: %+v", newMsg)
 			out = append(out, newMsg)
@@ -271,7 +363,19 @@ func (m *JoinMerge) mergeValueMessages(lmsgs, rmsgs []*datasource.SqlDriverMessa
 	return out
 }
 
-func (m *JoinMerge) valIndexing(valOut, valSource []driver.Value, cols []*rel.Column) []driver.Value {
+// joinWidth the number of values in a joined row of @p
+func joinWidth(p *plan.JoinMerge) int {
+	width := len(p.ColIndex)
+	for _, idx := range p.ColIndex {
+		if idx >= width {
+			width = idx + 1
+		}
+	}
+	return width
+}
+
+// valIndexing copy the @valSource of a join side into the joined row @valOut
+func valIndexing(valOut, valSource []driver.Value, cols []*rel.Column) []driver.Value {
 	for _, col := range cols {
 		if col.ParentIndex < 0 {
 			continue
@@ -294,3 +398,170 @@ func (m *JoinMerge) valIndexing(valOut, valSource []driver.Value, cols []*rel.Co
 	}
 	return valOut
 }
+
+// JoinLookup joins a scanned (probe) side to a seekable side by fetching
+// the row of each probe rows join key with ConnSeeker.Get() instead of
+// scanning the seekable side.
+//
+//	probe source  ->  JoinKey  ->  lookup  -->
+//	                                 |
+//	                       seek source.Get(key)
+type JoinLookup struct {
+	*TaskBase
+	probe     TaskRunner
+	probeStmt *rel.SqlSource
+	seekStmt  *rel.SqlSource
+	seekLeft  bool
+	seekConn  schema.Conn
+	seeker    schema.ConnSeeker
+	seekCol   string
+	seekTbl   *schema.Table
+	colIndex  map[string]int
+	width     int
+}
+
+// NewJoinLookup create a lookup join of the rows of @probe, the scanned
+// side of the lookup strategy JoinMerge @p, to its seek side.
+func NewJoinLookup(ctx *plan.Context, probe TaskRunner, p *plan.JoinMerge) (*JoinLookup, error) {
+	m := &JoinLookup{
+		TaskBase: NewTaskBase(ctx),
+		probe:    probe,
+		colIndex: p.ColIndex,
+	}
+	var seek plan.Task
+	switch p.Strategy {
+	case plan.JoinLookupLeft:
+		seek, m.seekStmt, m.probeStmt, m.seekLeft = p.Left, p.LeftFrom, p.RightFrom, true
+	case plan.JoinLookupRight:
+		seek, m.seekStmt, m.probeStmt = p.Right, p.RightFrom, p.LeftFrom
+	default:
+		return nil, fmt.Errorf("not a lookup join strategy %s", p.Strategy)
+	}
+	sp, ok := seek.(*plan.Source)
+	if !ok {
+		return nil, fmt.Errorf("lookup join requires a source to seek but got %T", seek)
+	}
+	if err := sp.LoadConn(); err != nil {
+		return nil, err
+	}
+	seeker, ok := sp.Conn.(schema.ConnSeekerKey)
+	if !ok {
+		return nil, fmt.Errorf("lookup join requires schema.ConnSeekerKey but got %T", sp.Conn)
+	}
+	if len(m.probeStmt.JoinNodes()) != 1 {
+		return nil, fmt.Errorf("lookup join requires a single join key but got %d", len(m.probeStmt.JoinNodes()))
+	}
+	m.seekConn = sp.Conn
+	m.seeker = seeker
+	m.seekCol = seeker.SeekColumn()
+	m.seekTbl = sp.Tbl
+	m.width = joinWidth(p)
+	return m, nil
+}
+
+// Close the seek side connection
+func (m *JoinLookup) Close() error {
+	if m.seekConn != nil {
+		if err := m.seekConn.Close(); err != nil {
+			return err
+		}
+	}
+	return m.TaskBase.Close()
+}
+
+func (m *JoinLookup) Run() error {
+	defer m.Ctx.Recover()
+	defer close(m.msgOutCh)
+
+	outCh := m.MessageOut()
+	inCh := m.probe.MessageOut()
+	joinNode := m.probeStmt.JoinNodes()[0]
+
+	var where expr.Node
+	if m.seekStmt.Source != nil && m.seekStmt.Source.Where != nil {
+		where = m.seekStmt.Source.Where.Expr
+	}
+
+	i := uint64(0)
+	for {
+		select {
+		case <-m.SigChan():
+			return nil
+		case msg, ok := <-inCh:
+			if !ok {
+				return nil
+			}
+			mt, ok := msg.(*datasource.SqlDriverMessageMap)
+			if !ok {
+				return fmt.Errorf("To use Join must use SqlDriverMessageMap but got %T", msg)
+			}
+			key, ok := vm.Eval(mt, joinNode)
+			if !ok || key == nil || key.Nil() {
+				continue
+			}
+			row, err := m.get(key)
+			if err != nil {
+				u.Errorf("could not seek %v err=%v", key.Value(), err)
+				return err
+			}
+			if row == nil {
+				continue
+			}
+			if where != nil {
+				wv, ok := vm.Eval(row, where)
+				if bv, isBool := wv.(value.BoolValue); !ok || !isBool || !bv.Val() {
+					continue
+				}
+			}
+
+			vals := make([]driver.Value, m.width)
+			for _, col := range m.seekStmt.Source.Columns {
+				if col.ParentIndex < 0 || col.ParentIndex >= len(vals) {
+					continue
+				}
+				if idx, ok := row.ColIndex[col.SourceField]; ok && idx < len(row.Vals) {
+					vals[col.ParentIndex] = row.Vals[idx]
+				}
+			}
+			vals = valIndexing(vals, mt.Values(), m.probeStmt.Source.Columns)
+			out := datasource.NewSqlDriverMessageMap(i, vals, m.colIndex)
+			i++
+			select {
+			case outCh <- out:
+			case <-m.SigChan():
+				return nil
+			}
+		}
+	}
+}
+
+// get the seek side row of join @key, nil if there is none
+func (m *JoinLookup) get(key value.Value) (*datasource.SqlDriverMessageMap, error) {
+	msg, err := m.seeker.Get(key.Value())
+	if err == schema.ErrNotFound || msg == nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var row *datasource.SqlDriverMessageMap
+	switch mt := msg.(type) {
+	case *datasource.SqlDriverMessageMap:
+		row = mt
+		if len(row.ColIndex) == 0 && m.seekTbl != nil {
+			row = datasource.NewSqlDriverMessageMap(mt.IdVal, mt.Vals, m.seekTbl.FieldPositions)
+		}
+	case *datasource.SqlDriverMessage:
+		if m.seekTbl == nil {
+			return nil, fmt.Errorf("no table to read %T", msg)
+		}
+		row = mt.ToMsgMap(m.seekTbl.FieldPositions)
+	default:
+		return nil, fmt.Errorf("To use Join must use SqlDriverMessageMap but got %T", msg)
+	}
+	// The seeker may find keys by hash, ensure it is the same value as
+	// a hash join would have matched.
+	if sv, ok := row.Get(m.seekCol); !ok || sv.ToString() != key.ToString() {
+		return nil, nil
+	}
+	return row, nil
+}
@@ -3,10 +3,12 @@ package exec
 import (
 	"database/sql/driver"
 	"fmt"
+	"reflect"
 
 	u "github.com/araddon/gou"
 
 	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/expr"
 	"github.com/fuhongbo/qlbridge/plan"
 	"github.com/fuhongbo/qlbridge/rel"
 	"github.com/fuhongbo/qlbridge/schema"
@@ -17,6 +19,7 @@ var (
 	_ = u.EMPTY
 
 	_ TaskRunner = (*Upsert)(nil)
+	_ TaskRunner = (*Into)(nil)
 	_ TaskRunner = (*DeletionTask)(nil)
 	_ TaskRunner = (*DeletionScanner)(nil)
 )
@@ -26,12 +29,21 @@ type (
 	Upsert struct {
 		*TaskBase
 		closed  bool
+		table   string
 		insert  *rel.SqlInsert
 		update  *rel.SqlUpdate
 		upsert  *rel.SqlUpsert
 		db      schema.ConnUpsert
 		dbpatch schema.ConnPatchWhere
 	}
+	// Into task for SELECT ... INTO table, writes each row
+	// of the select into the table
+	Into struct {
+		*TaskBase
+		closed bool
+		p      *plan.Into
+		db     schema.ConnUpsert
+	}
 	// Delete task for sources that natively support delete
 	DeletionTask struct {
 		*TaskBase
@@ -52,6 +64,7 @@ func NewInsert(ctx *plan.Context, p *plan.Insert) *Upsert {
 	m := &Upsert{
 		TaskBase: NewTaskBase(ctx),
 		db:       p.Source,
+		table:    p.Stmt.Table,
 		insert:   p.Stmt,
 	}
 	return m
@@ -60,6 +73,7 @@ func NewUpdate(ctx *plan.Context, p *plan.Update) *Upsert {
 	m := &Upsert{
 		TaskBase: NewTaskBase(ctx),
 		db:       p.Source,
+		table:    p.Stmt.Table,
 		update:   p.Stmt,
 	}
 	return m
@@ -68,11 +82,22 @@ func NewUpsert(ctx *plan.Context, p *plan.Upsert) *Upsert {
 	m := &Upsert{
 		TaskBase: NewTaskBase(ctx),
 		db:       p.Source,
+		table:    p.Stmt.Table,
 		upsert:   p.Stmt,
 	}
 	return m
 }
 
+// NewInto task to write select results into a data source
+func NewInto(ctx *plan.Context, p *plan.Into) *Into {
+	m := &Into{
+		TaskBase: NewTaskBase(ctx),
+		db:       p.Source,
+		p:        p,
+	}
+	return m
+}
+
 // An inserter to write to data source
 func NewDelete(ctx *plan.Context, p *plan.Delete) *DeletionTask {
 	m := &DeletionTask{
@@ -89,7 +114,7 @@ func (m *Upsert) Close() error {
 		return nil
 	}
 	m.closed = true
-	if closer, ok := m.db.(schema.Source); ok {
+	if closer, ok := m.db.(schema.Conn); ok {
 		if err := closer.Close(); err != nil {
 			return err
 		}
@@ -103,16 +128,30 @@ func (m *Upsert) Run() error {
 
 	var err error
 	var affectedCt int64
+	if m.db == nil {
+		if err = m.openAfterInput(); err == nil && m.db == nil {
+			// quit before the input was read
+			return nil
+		}
+	}
 	switch {
+	case err != nil:
+	case m.insert != nil && (m.insert.OnConflict != nil || len(m.insert.Returning) > 0):
+		affectedCt, err = m.insertEach()
+	case m.insert != nil && m.insert.Select != nil:
+		affectedCt, err = m.insertSelect()
 	case m.insert != nil:
-		affectedCt, err = m.insertRows(m.insert.Rows)
+		affectedCt, err = m.insertRows(m.insert.Table, m.insert.ColumnNames(), m.insert.Rows)
 	case m.upsert != nil && len(m.upsert.Rows) > 0:
-		affectedCt, err = m.insertRows(m.upsert.Rows)
+		affectedCt, err = m.insertRows(m.upsert.Table, m.upsert.Columns.FieldNames(), m.upsert.Rows)
 	case m.update != nil:
 		affectedCt, err = m.updateValues()
 	default:
 		u.Warnf("unknown mutation op?  %v", m)
 	}
+	if err == nil {
+		err = flush(m.Ctx, m.db)
+	}
 
 	vals := make([]driver.Value, 2)
 	if err != nil {
@@ -122,6 +161,10 @@ func (m *Upsert) Run() error {
 		m.msgOutCh <- &datasource.SqlDriverMessage{Vals: vals, IdVal: 1}
 		return err
 	}
+	if m.insert != nil && len(m.insert.Returning) > 0 {
+		// the RETURNING rows written are the result
+		return nil
+	}
 	vals[0] = int64(0) // status?
 	vals[1] = affectedCt
 	u.Infof("affected? %v", affectedCt)
@@ -129,6 +172,40 @@ func (m *Upsert) Run() error {
 	return nil
 }
 
+// openAfterInput read all of the messages feeding this task before
+// opening the source written to.  The planner leaves the source for
+// the task to open when the select feeding it reads the same source,
+// which may only allow one open conn at a time.
+func (m *Upsert) openAfterInput() error {
+	var msgs []schema.Message
+	inCh := m.MessageIn()
+msgReadLoop:
+	for {
+		select {
+		case <-m.SigChan():
+			return nil
+		case msg, ok := <-inCh:
+			if !ok || msg == nil {
+				break msgReadLoop
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	bufCh := make(MessageChan, len(msgs))
+	for _, msg := range msgs {
+		bufCh <- msg
+	}
+	close(bufCh)
+	m.MessageInSet(bufCh)
+
+	db, err := plan.UpsertSource(m.Ctx, m.table)
+	if err != nil {
+		return err
+	}
+	m.db = db
+	return nil
+}
+
 func (m *Upsert) updateValues() (int64, error) {
 
 	select {
@@ -138,12 +215,25 @@ func (m *Upsert) updateValues() (int64, error) {
 		// fall through
 	}
 
+	// if our backend source supports Where-Patches, ie update multiple
+	dbpatch, ok := m.db.(schema.ConnPatchWhere)
+	if !ok {
+		return m.updateRows()
+	}
+
+	tbl, err := m.Ctx.Schema.Table(m.update.Table)
+	if err != nil {
+		return 0, err
+	}
 	valmap := make(map[string]driver.Value, len(m.update.Values))
 	for key, valcol := range m.update.Values {
 
-		// TODO: qlbridge#13  Need a way of expressing which layer (here, db) this expr should run in?
-		//  - ie, run in backend datasource?   or here?  translate the expr to native language
-		if valcol.Expr != nil {
+		if valcol.Expr != nil && len(expr.FindAllIdentityField(valcol.Expr)) > 0 {
+			// the expression is of the columns of each row patched, so is
+			// passed to the source to evaluate
+			valmap[key] = valcol.Expr
+			continue
+		} else if valcol.Expr != nil {
 			exprVal, ok := vm.Eval(nil, valcol.Expr)
 			if !ok {
 				u.Errorf("Could not evaluate: %s", valcol.Expr)
@@ -154,35 +244,171 @@ func (m *Upsert) updateValues() (int64, error) {
 			u.Debugf("%T  %v", valcol.Value.Value(), valcol.Value.Value())
 			valmap[key] = valcol.Value.Value()
 		}
-		//u.Debugf("key:%v col: %v   vals:%v", key, valcol, valmap[key])
+		if valmap[key], err = coerceField(tbl, key, 0, valmap[key]); err != nil {
+			return 0, err
+		}
 	}
 
-	// if our backend source supports Where-Patches, ie update multiple
-	dbpatch, ok := m.db.(schema.ConnPatchWhere)
-	if ok {
-		updated, err := dbpatch.PatchWhere(m.Ctx, m.update.Where.Expr, valmap)
-		u.Infof("patch: %v %v", updated, err)
-		if err != nil {
-			return updated, err
-		}
-		return updated, nil
+	var where expr.Node
+	if m.update.Where != nil {
+		where = m.update.Where.Expr
 	}
+	updated, err := dbpatch.PatchWhere(m.Ctx, where, valmap)
+	u.Infof("patch: %v %v", updated, err)
+	if err != nil {
+		return updated, err
+	}
+	return updated, nil
+}
 
-	// TODO:   If it does not implement Where Patch then we need to do a poly fill
-	//      Do we have to recognize if the Where is on a primary key?
-	// - for sources/queries that can't do partial updates we need to do a read first
+// updateRows the read-modify-write polyfill for sources which can't patch
+// by where.  The rows matching the where are read from the select feeding
+// this task, all of them before any is written so updated rows are not
+// seen again.  The SET expressions are evaluated against each row and
+// every row checked against the schema before any whole row is Put back,
+// a row whose key is changed is deleted from its old key after it is
+// written to a new key no other row has.
+func (m *Upsert) updateRows() (int64, error) {
 
-	// Create a key from Where
-	key := datasource.KeyFromWhere(m.update.Where)
-	if _, err := m.db.Put(m.Ctx, key, valmap); err != nil {
-		u.Errorf("Could not put values: %v", err)
+	tbl, err := m.Ctx.Schema.Table(m.update.Table)
+	if err != nil {
 		return 0, err
 	}
-	return 1, nil
+	cols := tbl.Columns()
+	positions := make(map[string]int, len(cols))
+	for i, col := range cols {
+		positions[col] = i
+	}
+	for key := range m.update.Values {
+		if _, ok := positions[key]; !ok {
+			return 0, fmt.Errorf("unknown column %q in table %q", key, m.update.Table)
+		}
+	}
+
+	// the key column, a SET of it moves the row to a new key
+	keyCol := cols[0]
+	if sk, ok := m.db.(schema.ConnSeekerKey); ok {
+		keyCol = sk.SeekColumn()
+	}
+	keyPos, hasKey := positions[keyCol]
+	deleter, canDelete := m.db.(schema.ConnDeletion)
+	seeker, canSeek := m.db.(schema.ConnSeeker)
+
+	var msgs []*datasource.SqlDriverMessageMap
+	inCh := m.MessageIn()
+msgReadLoop:
+	for {
+		select {
+		case <-m.SigChan():
+			return 0, nil
+		case msg, ok := <-inCh:
+			if !ok || msg == nil {
+				break msgReadLoop
+			}
+			mm, ok := msg.(*datasource.SqlDriverMessageMap)
+			if !ok {
+				return 0, fmt.Errorf("UPDATE requires SqlDriverMessageMap but got %T", msg)
+			}
+			msgs = append(msgs, mm)
+		}
+	}
+
+	// each row is updated and checked before any is written
+	rows := make([][]driver.Value, len(msgs))
+	movedFrom := make([]driver.Value, len(msgs))
+	moved := make([]bool, len(msgs))
+	movedTo := make(map[string]bool)
+	for i, msg := range msgs {
+		row := make([]driver.Value, len(cols))
+		for ci, col := range cols {
+			if idx, ok := msg.ColIndex[col]; ok && idx < len(msg.Vals) {
+				row[ci] = msg.Vals[idx]
+			}
+		}
+		var prevKey driver.Value
+		if hasKey {
+			prevKey = row[keyPos]
+		}
+		for key, valcol := range m.update.Values {
+			var val driver.Value
+			if valcol.Expr != nil {
+				exprVal, ok := vm.Eval(msg, valcol.Expr)
+				if !ok {
+					u.Errorf("Could not evaluate: %s", valcol.Expr)
+					return 0, fmt.Errorf("Could not evaluate expression: %v", valcol.Expr)
+				}
+				val = exprVal.Value()
+			} else {
+				val = valcol.Value.Value()
+			}
+			if val, err = coerceField(tbl, key, i+1, val); err != nil {
+				return 0, err
+			}
+			row[positions[key]] = val
+		}
+		rows[i] = row
+		if !hasKey || reflect.DeepEqual(prevKey, row[keyPos]) {
+			continue
+		}
+		// the put writes the new key, which must not overwrite another row
+		if !canDelete || !canSeek {
+			return 0, fmt.Errorf("%T can't change the key column %q of %q", m.db, keyCol, m.update.Table)
+		}
+		newKey := fmt.Sprintf("%v", row[keyPos])
+		existing, err := seekRow(seeker, row[keyPos])
+		if err != nil {
+			return 0, err
+		}
+		if existing != nil || movedTo[newKey] {
+			return 0, fmt.Errorf("duplicate value %v for key %q of %q", row[keyPos], keyCol, m.update.Table)
+		}
+		movedTo[newKey] = true
+		moved[i], movedFrom[i] = true, prevKey
+	}
+
+	for i, row := range rows {
+		if _, err = m.db.Put(m.Ctx.Context, nil, row); err != nil {
+			u.Errorf("Could not put values: %v", err)
+			return int64(i), err
+		}
+		if moved[i] {
+			// the row is at its new key, so the old one is removed
+			if _, err = deleter.Delete(movedFrom[i]); err != nil {
+				return int64(i), err
+			}
+		}
+	}
+	return int64(len(msgs)), nil
 }
 
-func (m *Upsert) insertRows(rows [][]*rel.ValueColumn) (int64, error) {
+// insertRows write the VALUES @rows of @cols of @table.  Each row is
+// checked against and coerced to the schema of the table before any
+// are written.
+func (m *Upsert) insertRows(table string, cols []string, rows [][]*rel.ValueColumn) (int64, error) {
+	target, err := newInsertTarget(m.Ctx, table, cols)
+	if err != nil {
+		return 0, err
+	}
+	tblRows := make([][]driver.Value, len(rows))
 	for i, row := range rows {
+		vals := make([]driver.Value, len(row))
+		for x, val := range row {
+			if val.Expr != nil {
+				exprVal, ok := vm.Eval(nil, val.Expr)
+				if !ok {
+					u.Errorf("Could not evaluate: %v", val.Expr)
+					return 0, fmt.Errorf("Could not evaluate expression: %v", val.Expr)
+				}
+				vals[x] = exprVal.Value()
+			} else {
+				vals[x] = val.Value.Value()
+			}
+		}
+		if tblRows[i], err = target.row(vals); err != nil {
+			return 0, err
+		}
+	}
+	for i, row := range tblRows {
 		select {
 		case <-m.SigChan():
 			if i == 0 {
@@ -190,21 +416,7 @@ func (m *Upsert) insertRows(rows [][]*rel.ValueColumn) (int64, error) {
 			}
 			return int64(i) - 1, nil
 		default:
-			vals := make([]driver.Value, len(row))
-			for x, val := range row {
-				if val.Expr != nil {
-					exprVal, ok := vm.Eval(nil, val.Expr)
-					if !ok {
-						u.Errorf("Could not evaluate: %v", val.Expr)
-						return 0, fmt.Errorf("Could not evaluate expression: %v", val.Expr)
-					}
-					vals[x] = exprVal.Value()
-				} else {
-					vals[x] = val.Value.Value()
-				}
-			}
-
-			if _, err := m.db.Put(m.Ctx.Context, nil, vals); err != nil {
+			if _, err := m.db.Put(m.Ctx.Context, nil, row); err != nil {
 				u.Errorf("Could not put values: fordb T:%T  %v", m.db, err)
 				return 0, err
 			}
@@ -213,6 +425,87 @@ func (m *Upsert) insertRows(rows [][]*rel.ValueColumn) (int64, error) {
 	return int64(len(rows)), nil
 }
 
+// flush the writes to db if it buffers them
+func flush(ctx *plan.Context, db schema.ConnUpsert) error {
+	if flusher, ok := db.(schema.ConnFlusher); ok {
+		return flusher.Flush(ctx.Context)
+	}
+	return nil
+}
+
+func (m *Into) Close() error {
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	if closer, ok := m.db.(schema.Conn); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return m.TaskBase.Close()
+}
+
+func (m *Into) Run() error {
+	defer m.Ctx.Recover()
+	defer close(m.msgOutCh)
+
+	inCh := m.MessageIn()
+	var affectedCt int64
+	var err error
+
+msgReadLoop:
+	for {
+		select {
+		case <-m.SigChan():
+			return nil
+		case msg, ok := <-inCh:
+			if !ok || msg == nil {
+				break msgReadLoop
+			}
+			var row map[string]driver.Value
+			switch mt := msg.(type) {
+			case *datasource.SqlDriverMessageMap:
+				row = make(map[string]driver.Value, len(mt.ColIndex))
+				for col, idx := range mt.ColIndex {
+					if idx < len(mt.Vals) {
+						row[col] = mt.Vals[idx]
+					}
+				}
+			case expr.ContextReader:
+				ctxRow := mt.Row()
+				row = make(map[string]driver.Value, len(ctxRow))
+				for col, val := range ctxRow {
+					row[col] = val.Value()
+				}
+			default:
+				err = fmt.Errorf("Into requires SqlDriverMessageMap but got %T", msg)
+				break msgReadLoop
+			}
+			if _, err = m.db.Put(m.Ctx.Context, nil, row); err != nil {
+				u.Errorf("Could not put values into %q: %v", m.p.Stmt.Table, err)
+				break msgReadLoop
+			}
+			affectedCt++
+		}
+	}
+	if err == nil {
+		err = flush(m.Ctx, m.db)
+	}
+
+	vals := make([]driver.Value, 2)
+	if err != nil {
+		vals[0] = err.Error()
+		vals[1] = int64(-1)
+		m.msgOutCh <- &datasource.SqlDriverMessage{Vals: vals, IdVal: 1}
+		return err
+	}
+	vals[0] = int64(0)
+	vals[1] = affectedCt
+	m.msgOutCh <- &datasource.SqlDriverMessage{Vals: vals, IdVal: 1}
+	return nil
+}
+
 func (m *DeletionTask) Close() error {
 	m.Lock()
 	if m.closed {
@@ -221,7 +514,7 @@ func (m *DeletionTask) Close() error {
 	}
 	m.closed = true
 	m.Unlock()
-	if closer, ok := m.db.(schema.Source); ok {
+	if closer, ok := m.db.(schema.Conn); ok {
 		if err := closer.Close(); err != nil {
 			return err
 		}
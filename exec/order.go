@@ -3,6 +3,7 @@ package exec
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	u "github.com/araddon/gou"
@@ -149,10 +150,9 @@ func NewOrderMessages(p *plan.Order) *OrderMessages {
 	invert := make([]bool, len(p.Stmt.OrderBy))
 	for i, col := range p.Stmt.OrderBy {
 		//u.Debugf("invert?  %s ORDER %v", col.Expr, col.Order)
-		if col.Expr != nil {
-			if !col.Asc() {
-				invert[i] = true
-			}
+		// ASC is the default if neither is given
+		if col.Expr != nil && strings.EqualFold(col.Order, "desc") {
+			invert[i] = true
 		}
 	}
 	return &OrderMessages{
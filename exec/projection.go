@@ -123,6 +123,13 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 	// If we have a projection, use that as col count
 	if m.p.Proj != nil {
 		colCt = len(m.p.Proj.Columns)
+		// select * the star has been expanded into the projection columns
+		if m.p.Stmt.Star {
+			colIndex = make(map[string]int, colCt)
+			for i, col := range m.p.Proj.Columns {
+				colIndex[col.As] = i
+			}
+		}
 	}
 
 	rowCt := 0
@@ -1,7 +1,9 @@
 package exec
 
 import (
+	"context"
 	"database/sql/driver"
+	"fmt"
 	"io"
 
 	u "github.com/araddon/gou"
@@ -42,6 +44,7 @@ type (
 		*TaskBase
 		closed bool
 		cols   []string
+		cancel context.CancelFunc // cancels the query context on Close
 	}
 	// ResultBuffer for writing tasks results
 	ResultBuffer struct {
@@ -60,9 +63,17 @@ func NewResultExecWriter(ctx *plan.Context) *ResultExecWriter {
 		switch mt := msg.(type) {
 		case *datasource.SqlDriverMessage:
 			if len(mt.Vals) > 1 {
+				// mutation tasks send the error message instead of id on failure
+				if errMsg, isErr := mt.Vals[0].(string); isErr {
+					m.err = fmt.Errorf("%s", errMsg)
+					return true
+				}
 				m.lastInsertID = mt.Vals[0].(int64)
 				m.rowsAffected = mt.Vals[1].(int64)
 			}
+		case *datasource.SqlDriverMessageMap:
+			// the RETURNING rows of a mutation, one per row written
+			m.rowsAffected++
 		case nil:
 			u.Warnf("got nil")
 			// Signal to quit
@@ -143,6 +154,9 @@ func (m *ResultWriter) Close() error {
 	}
 	m.closed = true
 	m.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+	}
 	return m.TaskBase.Close()
 }
 
@@ -167,6 +181,8 @@ func (m *ResultWriter) Next(dest []driver.Value) error {
 	select {
 	case <-m.SigChan():
 		return ErrShuttingDown
+	case <-m.Ctx.Done():
+		return m.Ctx.Err()
 	case err := <-m.ErrChan():
 		return err
 	case msg, ok := <-m.MessageIn():
@@ -257,13 +273,30 @@ func msgToRow(msg schema.Message, cols []string, dest []driver.Value) error {
 			if ok && val != nil && !val.Nil() {
 				dest[i] = val.Value()
 				//u.Infof("key=%v   val=%v", key, val)
-			} else if val == nil {
-				u.Errorf("could not evaluate? %v  %#v", key, mt)
 			} else {
-				u.Warnf("missing value? %v %T %v", key, val.Value(), val.Value())
+				// dest is re-used across rows, don't leave the prior row's value
+				dest[i] = nil
+				if val == nil {
+					u.Errorf("could not evaluate? %v  %#v", key, mt)
+				}
 			}
 		}
 		//u.Debugf("got msg in row result writer: %#v", dest)
+	case []driver.Value:
+		// mutation tasks send the error message and -1 affected on failure
+		if len(mt) == 2 && (mt[1] == -1 || mt[1] == int64(-1)) {
+			if errMsg, isErr := mt[0].(string); isErr {
+				return fmt.Errorf("%s", errMsg)
+			}
+		}
+		// otherwise the values are in column order
+		for i := range dest {
+			if i < len(mt) {
+				dest[i] = mt[i]
+			} else {
+				dest[i] = nil
+			}
+		}
 	default:
 		u.Errorf("unknown message type: %T", mt)
 	}
@@ -123,6 +123,9 @@ func (m *Source) Close() error {
 func (m *Source) Run() error {
 	defer m.Ctx.Recover()
 	defer close(m.msgOutCh)
+	// release the conn before downstream tasks see the end of the rows,
+	// a source may only allow one open conn, see Upsert.
+	defer m.closeSource()
 
 	if m.Scanner == nil {
 		u.Warnf("no datasource configured?")
@@ -2,6 +2,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
@@ -22,13 +23,14 @@ import (
 
 var (
 	// Ensure our driver implements appropriate database/sql interfaces
-	_ driver.Conn    = (*qlbConn)(nil)
-	_ driver.Driver  = (*qlbdriver)(nil)
-	_ driver.Execer  = (*qlbConn)(nil)
-	_ driver.Queryer = (*qlbConn)(nil)
-	_ driver.Result  = (*qlbResult)(nil)
-	_ driver.Rows    = (*qlbRows)(nil)
-	_ driver.Stmt    = (*qlbStmt)(nil)
+	_ driver.Conn           = (*qlbConn)(nil)
+	_ driver.Driver         = (*qlbdriver)(nil)
+	_ driver.Execer         = (*qlbConn)(nil)
+	_ driver.Queryer        = (*qlbConn)(nil)
+	_ driver.QueryerContext = (*qlbConn)(nil)
+	_ driver.Result         = (*qlbResult)(nil)
+	_ driver.Rows           = (*qlbRows)(nil)
+	_ driver.Stmt           = (*qlbStmt)(nil)
 	//_ driver.Tx      = (*driverConn)(nil)
 
 	// Create an instance of our driver
@@ -114,6 +116,9 @@ type qlbConn struct {
 // such as Create Index, Insert, Upset, Delete etc
 func (m *qlbConn) Exec(query string, args []driver.Value) (driver.Result, error) {
 	stmt := &qlbStmt{conn: m, query: query}
+	// the job is done once Exec returns, close it so sources release
+	// their connections (sqlite holds a lock until then)
+	defer stmt.Close()
 	return stmt.Exec(args)
 }
 
@@ -125,6 +130,20 @@ func (m *qlbConn) Query(query string, args []driver.Value) (driver.Rows, error)
 	return stmt.Query(args)
 }
 
+// QueryerContext implementation, cancelling @ctx stops the query which
+// is the only way to end a SUBSCRIBE query.
+func (m *qlbConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("named args are not supported %q", arg.Name)
+		}
+		vals[i] = arg.Value
+	}
+	stmt := &qlbStmt{conn: m, query: query}
+	return stmt.queryContext(ctx, vals)
+}
+
 // Prepare returns a prepared statement, bound to this connection.
 func (m *qlbConn) Prepare(query string) (driver.Stmt, error) {
 	return nil, expr.ErrNotImplemented
@@ -218,14 +237,20 @@ func (m *qlbStmt) Exec(args []driver.Value) (driver.Result, error) {
 	//u.Debugf("After qlb driver.Run() in Exec()")
 	if err != nil {
 		u.Errorf("error on Query.Run(): %v", err)
-		//resultWriter.ErrChan() <- err
-		//job.Close()
+		return nil, err
+	}
+	if resultWriter.err != nil {
+		return nil, resultWriter.err
 	}
 	return resultWriter.Result(), nil
 }
 
 // Query executes a query that may return rows, such as a SELECT
 func (m *qlbStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return m.queryContext(context.Background(), args)
+}
+
+func (m *qlbStmt) queryContext(parent context.Context, args []driver.Value) (driver.Rows, error) {
 	var err error
 	if len(args) > 0 {
 		m.query, err = queryArgsConvert(m.query, args)
@@ -238,24 +263,41 @@ func (m *qlbStmt) Query(args []driver.Value) (driver.Rows, error) {
 	// Create a Job, which is Dag of Tasks that Run()
 	ctx := plan.NewContext(m.query)
 	ctx.Schema = m.conn.schema
+	goCtx, cancel := context.WithCancel(parent)
+	ctx.Context = goCtx
 	job, err := BuildSqlJob(ctx)
 	if err != nil {
+		cancel()
 		u.Warnf("return error? %v", err)
 		return nil, err
 	}
 	m.job = job
 
-	// The only type of stmt that makes sense for Query is SELECT
-	//  and we need list of columns that requires casing
-	sqlSelect, ok := job.Ctx.Stmt.(*rel.SqlSelect)
-	if !ok {
+	// The only type of stmt that makes sense for Query is SELECT, or
+	//  an INSERT ... RETURNING, and we need list of columns that requires casing
+	var cols []string
+	switch stmt := job.Ctx.Stmt.(type) {
+	case *rel.SqlSelect:
+		cols = resultColumns(job.Ctx, stmt)
+	case *rel.SqlInsert:
+		if len(stmt.Returning) == 0 {
+			cancel()
+			return nil, fmt.Errorf("Query requires INSERT ... RETURNING: %s", stmt)
+		}
+		if cols, err = returningColumns(job.Ctx, stmt); err != nil {
+			cancel()
+			return nil, err
+		}
+	default:
+		cancel()
 		u.Warnf("ctx? %v", job.Ctx)
 		return nil, fmt.Errorf("We could not recognize that as a select query: %T", job.Ctx.Stmt)
 	}
 
 	// Prepare a result writer, we manually append this task to end
 	// of job?
-	resultWriter := NewResultRows(ctx, sqlSelect.Columns.AliasedFieldNames())
+	resultWriter := NewResultRows(ctx, cols)
+	resultWriter.cancel = cancel
 
 	job.RootTask.Add(resultWriter)
 
@@ -442,3 +484,32 @@ func escapeQuotes(txt string) string {
 	io.WriteString(&buf, txt[last:])
 	return buf.String()
 }
+
+// resultColumns list of column names for the result set, for select *
+// the planner has already expanded the star into the projection columns.
+func resultColumns(ctx *plan.Context, sqlSelect *rel.SqlSelect) []string {
+	if ctx.Projection != nil && ctx.Projection.Proj != nil && sqlSelect.Star {
+		cols := make([]string, len(ctx.Projection.Proj.Columns))
+		for i, col := range ctx.Projection.Proj.Columns {
+			cols[i] = col.As
+		}
+		return cols
+	}
+	return sqlSelect.Columns.AliasedFieldNames()
+}
+
+// ResultColumns the column names of the rows returned by the statement
+// of a built job, a SELECT or INSERT ... RETURNING.  False if it returns
+// no rows, only a count of those affected.
+func ResultColumns(ctx *plan.Context) ([]string, bool, error) {
+	switch stmt := ctx.Stmt.(type) {
+	case *rel.SqlSelect:
+		return resultColumns(ctx, stmt), true, nil
+	case *rel.SqlInsert:
+		if len(stmt.Returning) > 0 {
+			cols, err := returningColumns(ctx, stmt)
+			return cols, err == nil, err
+		}
+	}
+	return nil, false, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/plan"
 )
 
 type user struct {
@@ -207,6 +208,45 @@ func TestSqlCsvDriverJoinWithWhere2(t *testing.T) {
 	assert.True(t, uo1.Price == 22.5, "? %#v", uo1)
 }
 
+func TestSqlCsvDriverJoinStrategies(t *testing.T) {
+
+	// Where on both sides of join, orders is small enough to seek users
+	// by key (lookup), else of both known sizes hash the smaller (build).
+	sqlText := `
+		SELECT 
+			u.user_id, o.item_id, u.reg_date, u.email, o.price, o.order_date
+		FROM users AS u 
+		INNER JOIN orders AS o 
+			ON u.user_id = o.user_id
+		WHERE o.price > 30 AND u.email LIKE "%@email.com";
+	`
+	db, err := sql.Open("qlbridge", "mockcsv")
+	assert.True(t, err == nil, "no error: %v", err)
+	defer db.Close()
+
+	defer func(max int) { plan.LookupJoinMaxRows = max }(plan.LookupJoinMaxRows)
+	for _, max := range []int{1000, 0} {
+		plan.LookupJoinMaxRows = max
+
+		rows, err := db.Query(sqlText)
+		assert.True(t, err == nil, "no error: %v", err)
+		userOrders := make([]userorder, 0)
+		for rows.Next() {
+			var uo userorder
+			err = rows.Scan(&uo.UserId, &uo.ItemId, &uo.RegDate, &uo.Email, &uo.Price, &uo.OrderDate)
+			assert.True(t, err == nil, "no error: %v", err)
+			userOrders = append(userOrders, uo)
+		}
+		assert.True(t, rows.Err() == nil, "no error: %v", err)
+		rows.Close()
+		assert.True(t, len(userOrders) == 1, "want 1 userOrders row: %+v", userOrders)
+		if len(userOrders) == 1 {
+			assert.Equal(t, "aaron@email.com", userOrders[0].Email)
+			assert.Equal(t, 37.5, userOrders[0].Price)
+		}
+	}
+}
+
 func TestSqlCsvDriverSubQuery(t *testing.T) {
 	// Sub-Query
 	sqlText := `
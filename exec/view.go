@@ -0,0 +1,648 @@
+package exec
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+const (
+	// ViewSchemaName is the name of the child schema the views of a schema
+	// are registered on.
+	ViewSchemaName = "views"
+
+	// viewRowID the hidden key column of the stored rows of a materialized
+	// view, as view rows need not have a unique first column.
+	viewRowID = "_rowid"
+)
+
+var (
+	// Ensure our ViewSource implements schema.Source, schema.Alter
+	_ schema.Source = (*ViewSource)(nil)
+	_ schema.Alter  = (*ViewSource)(nil)
+
+	// Ensure the view conns implement the interfaces needed to be
+	// read as the source of a select.
+	_ schema.ConnColumns = (*viewConn)(nil)
+	_ schema.ConnScanner = (*viewConn)(nil)
+	_ RequiresContext    = (*viewConn)(nil)
+	_ schema.ConnColumns = (*viewStoreConn)(nil)
+	_ schema.ConnScanner = (*viewStoreConn)(nil)
+
+	// Ensure the default view store implements the interfaces of a ViewStore
+	_ schema.Source      = (*rowStore)(nil)
+	_ schema.ConnUpsert  = (*rowStoreConn)(nil)
+	_ schema.ConnScanner = (*rowStoreConn)(nil)
+
+	viewStoreMu sync.RWMutex
+	viewStore   ViewStore = NewRowStore
+)
+
+// ViewStore creates the source the rows of a materialized view are stored
+// in, the table given has the columns of the view.  Its conns must implement
+// schema.ConnUpsert and schema.ConnScanner.  A refresh closes the store of
+// the previous rows, conns opened before Close must still read to the end.
+type ViewStore func(tbl *schema.Table) (schema.Source, error)
+
+// RegisterViewStore sets the store for the rows of materialized views,
+// by default they are kept in memory by NewRowStore.
+func RegisterViewStore(store ViewStore) {
+	viewStoreMu.Lock()
+	defer viewStoreMu.Unlock()
+	viewStore = store
+}
+
+func getViewStore() ViewStore {
+	viewStoreMu.RLock()
+	defer viewStoreMu.RUnlock()
+	return viewStore
+}
+
+type (
+	// ViewSource is the schema.Source of the views of a schema.  A logical
+	// view runs its SELECT against the schema each time it is read, unless
+	// the planner inlined it into the select reading it.  A materialized
+	// view reads the rows stored in the ViewStore at the last refresh, a
+	// continuous view reads the windows it has aggregated.
+	ViewSource struct {
+		s     *schema.Schema // the schema views select from
+		mu    sync.RWMutex
+		views map[string]*view
+		names []string
+	}
+	view struct {
+		tbl   *schema.Table
+		store schema.Source // stored rows of a materialized view
+		exit  chan bool     // stops the refresh of a materialized view
+		cv    *continuousView
+	}
+	// viewConn reads a logical view the planner could not inline by
+	// running its SELECT.
+	viewConn struct {
+		vs   *ViewSource
+		tbl  *schema.Table
+		ctx  *plan.Context
+		mu   sync.Mutex
+		job  *JobExecutor
+		rows chan schema.Message
+		done chan bool
+	}
+	// viewStoreConn reads the stored rows of a materialized view.
+	viewStoreConn struct {
+		schema.ConnScanner
+		tbl *schema.Table
+	}
+	// rowStore is the default ViewStore, the rows of a materialized view
+	// in a slice that is only appended to.
+	rowStore struct {
+		tbl  *schema.Table
+		mu   sync.RWMutex
+		rows [][]driver.Value
+	}
+	// rowStoreConn reads the rows of a rowStore at the time it was opened.
+	rowStoreConn struct {
+		s    *rowStore
+		rows [][]driver.Value
+		pos  int
+	}
+)
+
+// NewViewSource creates the source for the views selecting from schema s.
+func NewViewSource(s *schema.Schema) *ViewSource {
+	return &ViewSource{s: s, views: make(map[string]*view)}
+}
+
+// Init initilize this source
+func (m *ViewSource) Init() {}
+
+// Setup this source with parent schema.
+func (m *ViewSource) Setup(*schema.Schema) error { return nil }
+
+// Open a Conn for reading view @table
+func (m *ViewSource) Open(table string) (schema.Conn, error) {
+	v, err := m.view(table)
+	if err != nil {
+		return nil, err
+	}
+	if v.cv != nil {
+		return &continuousConn{cv: v.cv, exit: make(chan bool)}, nil
+	}
+	m.mu.RLock()
+	store := v.store
+	m.mu.RUnlock()
+	if store == nil {
+		return &viewConn{vs: m, tbl: v.tbl}, nil
+	}
+	conn, err := store.Open(v.tbl.Name)
+	if err != nil {
+		return nil, err
+	}
+	scanner, ok := conn.(schema.ConnScanner)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("materialized view store %T is not a scanner", conn)
+	}
+	return &viewStoreConn{ConnScanner: scanner, tbl: v.tbl}, nil
+}
+
+// Table by name
+func (m *ViewSource) Table(table string) (*schema.Table, error) {
+	v, err := m.view(table)
+	if err != nil {
+		return nil, err
+	}
+	return v.tbl, nil
+}
+
+// Tables list of view names
+func (m *ViewSource) Tables() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.names...)
+}
+
+// Close this source, stopping the refresh of materialized views and the
+// streams of continuous views.
+func (m *ViewSource) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.views {
+		v.close()
+	}
+	m.views = make(map[string]*view)
+	m.names = nil
+	return nil
+}
+
+// DropTable drops the view.
+func (m *ViewSource) DropTable(table string) error {
+	table = strings.ToLower(table)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.views[table]
+	if !ok {
+		return schema.ErrNotFound
+	}
+	delete(m.views, table)
+	names := make([]string, 0, len(m.names))
+	for _, n := range m.names {
+		if n != table {
+			names = append(names, n)
+		}
+	}
+	m.names = names
+	v.close()
+	return nil
+}
+
+// Refresh re-runs the SELECT of materialized view @table and replaces its
+// stored rows.
+func (m *ViewSource) Refresh(table string) error {
+	v, err := m.view(table)
+	if err != nil {
+		return err
+	}
+	if !v.tbl.View.Materialized {
+		return fmt.Errorf("%q is not a materialized view", table)
+	}
+	store, err := m.materialize(v.tbl)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	prev := v.store
+	v.store = store
+	m.mu.Unlock()
+	if prev != nil {
+		return prev.Close()
+	}
+	return nil
+}
+
+// addView add or replace the view described by @tbl, storing the rows
+// of a materialized view.
+func (m *ViewSource) addView(tbl *schema.Table) error {
+	v := &view{tbl: tbl}
+	tbl.Source = m
+	if tbl.View.Materialized {
+		store, err := m.materialize(tbl)
+		if err != nil {
+			return err
+		}
+		v.store = store
+	}
+	m.setView(v)
+
+	if tbl.View.Materialized && tbl.View.RefreshInterval > 0 {
+		v.exit = make(chan bool)
+		go m.refreshEvery(tbl.Name, tbl.View.RefreshInterval, v.exit)
+	}
+	return nil
+}
+
+// setView add or replace view @v
+func (m *ViewSource) setView(v *view) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if prev, exists := m.views[v.tbl.Name]; exists {
+		prev.close()
+	} else {
+		m.names = append(m.names, v.tbl.Name)
+		sort.Strings(m.names)
+	}
+	m.views[v.tbl.Name] = v
+}
+
+// refreshEvery refresh materialized view @table on @interval until exit.
+func (m *ViewSource) refreshEvery(table string, interval time.Duration, exit chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exit:
+			return
+		case <-ticker.C:
+			if err := m.Refresh(table); err != nil {
+				u.Warnf("could not refresh materialized view %q: %v", table, err)
+			}
+		}
+	}
+}
+
+// materialize run the SELECT of view @tbl storing its rows in a new store.
+func (m *ViewSource) materialize(tbl *schema.Table) (schema.Source, error) {
+	newStore := getViewStore()
+	if newStore == nil {
+		return nil, fmt.Errorf("no store registered for materialized views")
+	}
+
+	stbl := schema.NewTable(tbl.Name)
+	stbl.AddField(schema.NewFieldBase(viewRowID, value.IntType, 64, "row id"))
+	for _, f := range tbl.Fields {
+		stbl.AddField(schema.NewFieldBase(f.Name, f.ValueType(), int(f.Length), f.Description))
+	}
+	stbl.SetColumnsFromFields()
+	stbl.Indexes = []*schema.Index{{Name: "primary", Fields: []string{viewRowID}, PrimaryKey: true}}
+
+	store, err := newStore(stbl)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := store.Open(stbl.Name)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	defer conn.Close()
+	db, ok := conn.(schema.ConnUpsert)
+	if !ok {
+		store.Close()
+		return nil, fmt.Errorf("materialized view store %T does not accept writes", conn)
+	}
+
+	var rows [][]driver.Value
+	err = m.run(plan.NewContext(tbl.View.Sql), func(msg *datasource.SqlDriverMessageMap) {
+		vals := msg.Values()
+		row := make([]driver.Value, 0, len(vals)+1)
+		row = append(row, int64(len(rows)+1))
+		rows = append(rows, append(row, vals...))
+	})
+	if err == nil && len(rows) > 0 {
+		_, err = db.PutMulti(nil, nil, rows)
+	}
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// newJob plan the SELECT of @ctx against the schema of the views adding a
+// final task that calls fn with each row.  Planning errors
+// such as a table that no longer exists are returned.
+func (m *ViewSource) newJob(ctx *plan.Context, fn func(msg *datasource.SqlDriverMessageMap)) (*JobExecutor, error) {
+	ctx.Schema = m.s
+	job, err := BuildSqlJob(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Stmt.(*rel.SqlSelect); !ok {
+		job.Close()
+		return nil, fmt.Errorf("view must be a SELECT but got %T", ctx.Stmt)
+	}
+	rows := NewResultBuffer(ctx, nil)
+	rows.Handler = func(_ *plan.Context, msg schema.Message) bool {
+		if mm, ok := msg.(*datasource.SqlDriverMessageMap); ok {
+			fn(mm)
+		}
+		return true
+	}
+	job.RootTask.Add(rows)
+	if err = job.Setup(); err != nil {
+		job.Close()
+		return nil, err
+	}
+	return job, nil
+}
+
+// run the SELECT of @ctx to completion calling fn with each row.
+func (m *ViewSource) run(ctx *plan.Context, fn func(msg *datasource.SqlDriverMessageMap)) error {
+	job, err := m.newJob(ctx, fn)
+	if err != nil {
+		return err
+	}
+	defer job.Close()
+	return job.Run()
+}
+
+func (m *ViewSource) view(table string) (*view, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.views[strings.ToLower(table)]
+	if !ok {
+		return nil, schema.ErrNotFound
+	}
+	return v, nil
+}
+
+func (m *view) close() {
+	if m.exit != nil {
+		close(m.exit)
+		m.exit = nil
+	}
+	if m.store != nil {
+		m.store.Close()
+	}
+	if m.cv != nil {
+		m.cv.stop()
+	}
+}
+
+// SetContext the context of the query reading this view.
+func (m *viewConn) SetContext(ctx *plan.Context) { m.ctx = ctx }
+
+// Columns of the view
+func (m *viewConn) Columns() []string { return m.tbl.Columns() }
+
+// Next row of the view, the SELECT of the view is started on first call.
+func (m *viewConn) Next() schema.Message {
+	m.mu.Lock()
+	if m.rows == nil {
+		m.start()
+	}
+	rows := m.rows
+	m.mu.Unlock()
+	msg, ok := <-rows
+	if !ok {
+		return nil
+	}
+	return msg
+}
+
+func (m *viewConn) start() {
+	m.rows = make(chan schema.Message, ItemDefaultChannelSize)
+	m.done = make(chan bool)
+	ctx := plan.NewContext(m.tbl.View.Sql)
+	if m.ctx != nil {
+		ctx.Context = m.ctx.Context
+	}
+	rows, done := m.rows, m.done
+	job, err := m.vs.newJob(ctx, func(mm *datasource.SqlDriverMessageMap) {
+		msg := datasource.NewSqlDriverMessageMap(0, mm.Values(), m.tbl.FieldPositions)
+		select {
+		case rows <- msg:
+		case <-done:
+			// the reader went away, drain the rest of the job
+		}
+	})
+	if err != nil {
+		u.Warnf("could not read view %q: %v", m.tbl.Name, err)
+		close(rows)
+		return
+	}
+	m.job = job
+	go func() {
+		defer close(rows)
+		if err := job.Run(); err != nil {
+			u.Warnf("could not read view %q: %v", m.tbl.Name, err)
+		}
+		job.Close()
+	}()
+}
+
+// Close stops reading the view.
+func (m *viewConn) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+		if m.job != nil {
+			m.job.Close()
+		}
+	}
+	return nil
+}
+
+// Columns of the view
+func (m *viewStoreConn) Columns() []string { return m.tbl.Columns() }
+
+// Next stored row of the view without the hidden row id.
+func (m *viewStoreConn) Next() schema.Message {
+	msg := m.ConnScanner.Next()
+	if msg == nil {
+		return nil
+	}
+	mm, ok := msg.(*datasource.SqlDriverMessageMap)
+	if !ok || len(mm.Values()) == 0 {
+		u.Warnf("unexpected materialized view row %T", msg)
+		return nil
+	}
+	return datasource.NewSqlDriverMessageMap(mm.Id(), mm.Values()[1:], m.tbl.FieldPositions)
+}
+
+// NewRowStore is the default ViewStore, keeping the rows of a materialized
+// view in memory.
+func NewRowStore(tbl *schema.Table) (schema.Source, error) {
+	return &rowStore{tbl: tbl}, nil
+}
+
+// Init initilize this source
+func (m *rowStore) Init() {}
+
+// Setup this source with parent schema.
+func (m *rowStore) Setup(*schema.Schema) error { return nil }
+
+// Open a Conn reading the rows stored so far.
+func (m *rowStore) Open(table string) (schema.Conn, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return &rowStoreConn{s: m, rows: m.rows}, nil
+}
+
+// Table of the stored rows
+func (m *rowStore) Table(table string) (*schema.Table, error) { return m.tbl, nil }
+
+// Tables the single table of the stored rows
+func (m *rowStore) Tables() []string { return []string{m.tbl.Name} }
+
+// Close releases the rows, conns already open keep reading them.
+func (m *rowStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows = nil
+	return nil
+}
+
+// Columns of the stored rows
+func (m *rowStoreConn) Columns() []string { return m.s.tbl.Columns() }
+
+// Close this conn
+func (m *rowStoreConn) Close() error { return nil }
+
+// Next stored row
+func (m *rowStoreConn) Next() schema.Message {
+	if m.pos >= len(m.rows) {
+		return nil
+	}
+	m.pos++
+	return datasource.NewSqlDriverMessageMap(uint64(m.pos), m.rows[m.pos-1], m.s.tbl.FieldPositions)
+}
+
+// Put appends @row ([]driver.Value) to the store
+func (m *rowStoreConn) Put(ctx context.Context, key schema.Key, row interface{}) (schema.Key, error) {
+	vals, ok := row.([]driver.Value)
+	if !ok {
+		return nil, fmt.Errorf("Expected []driver.Value but got %T", row)
+	}
+	keys, err := m.PutMulti(ctx, nil, [][]driver.Value{vals})
+	if err != nil {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+// PutMulti appends @src ([][]driver.Value) to the store
+func (m *rowStoreConn) PutMulti(ctx context.Context, keys []schema.Key, src interface{}) ([]schema.Key, error) {
+	rows, ok := src.([][]driver.Value)
+	if !ok {
+		return nil, fmt.Errorf("Expected [][]driver.Value but got %T", src)
+	}
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	out := make([]schema.Key, len(rows))
+	for i, row := range rows {
+		if len(row) != len(m.s.tbl.Fields) {
+			return nil, fmt.Errorf("Wrong number of columns, got %v expected %v", len(row), len(m.s.tbl.Fields))
+		}
+		m.s.rows = append(m.s.rows, row)
+		key := datasource.NewKeyInt(len(m.s.rows))
+		out[i] = &key
+	}
+	return out, nil
+}
+
+// viewSchema the child schema of @s the views of s are registered on,
+// created if @create.
+func viewSchema(s *schema.Schema, create bool) (*schema.Schema, *ViewSource, error) {
+	if child, err := s.Schema(ViewSchemaName); err == nil && child != nil {
+		vs, ok := child.DS.(*ViewSource)
+		if !ok {
+			return nil, nil, fmt.Errorf("schema %q has a %q source that is not for views", s.Name, ViewSchemaName)
+		}
+		return child, vs, nil
+	}
+	if !create {
+		return nil, nil, schema.ErrNotFound
+	}
+	vs := NewViewSource(s)
+	child := schema.NewSchemaSource(ViewSchemaName, vs)
+	if err := schema.DefaultRegistry().SchemaAddChild(s.Name, child); err != nil {
+		return nil, nil, err
+	}
+	return child, vs, nil
+}
+
+// viewTable plan the SELECT of a CREATE VIEW to find the columns of the view.
+func viewTable(ctx *plan.Context, cs *rel.SqlCreate) (*schema.Table, error) {
+	if cs.Select == nil {
+		return nil, fmt.Errorf("CREATE VIEW %q requires a SELECT", cs.Identity)
+	}
+	if viewReads(ctx.Schema, strings.ToLower(cs.Identity), cs.Select, make(map[string]bool)) {
+		return nil, fmt.Errorf("view %q may not select from itself", cs.Identity)
+	}
+
+	v := &schema.View{Sql: cs.Select.String(), Materialized: cs.Materialized}
+	if interval := cs.With.String("refresh_interval"); interval != "" {
+		if !cs.Materialized {
+			return nil, fmt.Errorf("refresh_interval requires a MATERIALIZED VIEW")
+		}
+		dur, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid refresh_interval %q: %v", interval, err)
+		}
+		v.RefreshInterval = dur
+	}
+
+	sctx := plan.NewContext(v.Sql)
+	sctx.Schema = ctx.Schema
+	sctx.Context = ctx.Context
+	job, err := BuildSqlJob(sctx)
+	if err != nil {
+		return nil, err
+	}
+	job.Close()
+	if sctx.Projection == nil || sctx.Projection.Proj == nil {
+		return nil, fmt.Errorf("could not find the columns of view %q", cs.Identity)
+	}
+
+	tbl := schema.NewTable(cs.Identity)
+	for _, col := range sctx.Projection.Proj.Columns {
+		if tbl.HasField(col.As) {
+			return nil, fmt.Errorf("duplicate column %q in view %q", col.As, cs.Identity)
+		}
+		tbl.AddField(schema.NewFieldBase(col.As, col.Type, 0, ""))
+	}
+	tbl.SetColumnsFromFields()
+	tbl.View = v
+	return tbl, nil
+}
+
+// viewReads is true if the SELECT @sel reads from view @name directly or
+// through the views it selects from.
+func viewReads(s *schema.Schema, name string, sel *rel.SqlSelect, seen map[string]bool) bool {
+	for _, from := range sel.From {
+		if from.SubQuery != nil && viewReads(s, name, from.SubQuery, seen) {
+			return true
+		}
+		fromName := strings.ToLower(from.SourceName())
+		if fromName == name {
+			return true
+		}
+		if seen[fromName] {
+			continue
+		}
+		seen[fromName] = true
+		tbl, _ := s.Table(fromName)
+		if tbl == nil || tbl.View == nil {
+			continue
+		}
+		vsel, err := rel.ParseSqlSelect(tbl.View.Sql)
+		if err == nil && viewReads(s, name, vsel, seen) {
+			return true
+		}
+	}
+	return false
+}
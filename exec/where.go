@@ -98,9 +98,9 @@ func whereFilter(filter expr.Node, task TaskRunner, cols map[string]int) Message
 			//u.Debugf("WHERE:  T:%T  vals:%#v", msg, mt.Vals)
 			//u.Debugf("cols:  %#v", cols)
 			msgReader := mt.ToMsgMap(cols)
-			filterValue, ok = vm.Eval(msgReader, filter)
+			filterValue, ok = vm.Eval(withSession(ctx, msgReader), filter)
 		case *datasource.SqlDriverMessageMap:
-			filterValue, ok = vm.Eval(mt, filter)
+			filterValue, ok = vm.Eval(withSession(ctx, mt), filter)
 			if !ok {
 				u.Warnf("wtf %s    %#v", filter, mt)
 			}
@@ -108,7 +108,7 @@ func whereFilter(filter expr.Node, task TaskRunner, cols map[string]int) Message
 			//u.Debugf("cols:  %#v", cols)
 		default:
 			if msgReader, isContextReader := msg.(expr.ContextReader); isContextReader {
-				filterValue, ok = vm.Eval(msgReader, filter)
+				filterValue, ok = vm.Eval(withSession(ctx, msgReader), filter)
 				if !ok {
 					u.Warnf("wat? %v  filterval:%#v expr: %s", filter.String(), filterValue, filter)
 				}
@@ -145,3 +145,12 @@ func whereFilter(filter expr.Node, task TaskRunner, cols map[string]int) Message
 		}
 	}
 }
+
+// withSession allow session variables (@name) to be referenced in
+// filters by nesting the message reader with the session.
+func withSession(ctx *plan.Context, rdr expr.ContextReader) expr.ContextReader {
+	if ctx == nil || ctx.Session == nil {
+		return rdr
+	}
+	return datasource.NewNestedContextReader([]expr.ContextReader{rdr, ctx.Session}, rdr.Ts())
+}
@@ -1,6 +1,7 @@
 package expr
 
 import (
+	"sort"
 	"strings"
 	"sync"
 
@@ -80,6 +81,23 @@ func (m *FuncRegistry) FuncGet(name string) (Func, bool) {
 	return fn, ok
 }
 
+// Funcs list of all functions in this registry, sorted by name.
+func (m *FuncRegistry) Funcs() []Func {
+	m.mu.RLock()
+	funcs := make([]Func, 0, len(m.funcs))
+	for _, fn := range m.funcs {
+		funcs = append(funcs, fn)
+	}
+	m.mu.RUnlock()
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Name < funcs[j].Name })
+	return funcs
+}
+
+// Funcs list of all the global functions, sorted by name.
+func Funcs() []Func {
+	return funcReg.Funcs()
+}
+
 // FuncAdd Global add Functions to the VM func registry occurs here.
 func FuncAdd(name string, fn CustomFunc) {
 	funcReg.Add(name, fn)
@@ -399,6 +399,35 @@ func findIdentities(node Node, l IdentityNodes) IdentityNodes {
 	return l
 }
 
+// FindConjuncts the predicates AND'd together at top level of node,
+// for either the binary or boolean form of AND
+//
+//	a = 1 AND (b = 2 AND c > 3)    == {a = 1, b = 2, c > 3}
+//	AND ( a = 1, b = 2 )           == {a = 1, b = 2}
+//	a = 1 OR b = 2                 == {a = 1 OR b = 2}
+func FindConjuncts(node Node) []Node {
+	return findConjuncts(node, nil)
+}
+func findConjuncts(node Node, l []Node) []Node {
+	switch n := node.(type) {
+	case *BinaryNode:
+		if n.Operator.T == lex.TokenLogicAnd {
+			for _, arg := range n.Args {
+				l = findConjuncts(arg, l)
+			}
+			return l
+		}
+	case *BooleanNode:
+		if !n.Negated() && (n.Operator.T == lex.TokenLogicAnd || n.Operator.T == lex.TokenAnd) {
+			for _, arg := range n.Args {
+				l = findConjuncts(arg, l)
+			}
+			return l
+		}
+	}
+	return append(l, node)
+}
+
 // FilterSpecialIdentities given a list of identities, filter out
 // special identities such as "null", "*", "match_all"
 func FilterSpecialIdentities(l []string) []string {
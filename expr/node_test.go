@@ -126,6 +126,25 @@ func TestIdentityNames(t *testing.T) {
 	assert.Equal(t, []string{"email", "name"}, expr.FilterSpecialIdentities([]string{"email", "name", "TRUE"}))
 }
 
+func TestFindConjuncts(t *testing.T) {
+	tests := map[string][]string{
+		`a = 1`:                        {"a = 1"},
+		`a = 1 AND (b = 2 AND c > 3)`:  {"a = 1", "b = 2", "c > 3"},
+		`AND (a = 1, b = 2)`:           {"a = 1", "b = 2"},
+		`a = 1 AND AND (b = 2, c > 3)`: {"a = 1", "b = 2", "c > 3"},
+		`a = 1 OR b = 2`:               {"a = 1 OR b = 2"},
+		`NOT AND (a = 1, b = 2)`:       {"NOT AND ( a = 1, b = 2 )"},
+	}
+	for exprStr, expected := range tests {
+		nodes := expr.FindConjuncts(expr.MustParse(exprStr))
+		got := make([]string, len(nodes))
+		for i, n := range nodes {
+			got[i] = n.String()
+		}
+		assert.Equal(t, expected, got, exprStr)
+	}
+}
+
 func TestValueTypeFromExpression(t *testing.T) {
 	assert.Equal(t, value.UnknownType, expr.ValueTypeFromNode(expr.MustParse(`username`)))
 	assert.Equal(t, value.StringType, expr.ValueTypeFromNode(expr.MustParse(`"hello"`)))
@@ -5,14 +5,17 @@ go 1.13
 require (
 	github.com/araddon/dateparse v0.0.0-20190622164848-0fb0a474d195
 	github.com/araddon/gou v0.0.0-20190110011759-c797efecbb61
+	github.com/chzyer/readline v1.5.1
 	github.com/dchest/siphash v1.2.1
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/gogo/protobuf v1.3.1
 	github.com/golang/protobuf v1.3.2
+	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db
 	github.com/google/btree v1.0.0
 	github.com/hashicorp/go-memdb v1.0.4
 	github.com/jmespath/go-jmespath v0.0.0-20180206201540-c2b33e8439af
 	github.com/jmoiron/sqlx v1.2.0
+	github.com/klauspost/compress v1.9.7
 	github.com/leekchan/timeutil v0.0.0-20150802142658-28917288c48d
 	github.com/lytics/cloudstorage v0.2.1
 	github.com/lytics/datemath v0.0.0-20180727225141-3ada1c10b5de
@@ -21,6 +24,7 @@ require (
 	github.com/mssola/user_agent v0.5.0
 	github.com/pborman/uuid v1.2.0
 	github.com/stretchr/testify v1.4.0
+	github.com/xitongsys/parquet-go v1.5.1
 	golang.org/x/net v0.0.0-20191021144547-ec77196f6094
 	google.golang.org/api v0.11.0
 )
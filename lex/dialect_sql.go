@@ -11,6 +11,7 @@ var (
 	// SqlDialect is a SQL dialect
 	//
 	//    SELECT
+	//    SUBSCRIBE SELECT
 	//    UPDATE
 	//    INSERT
 	//    UPSERT
@@ -31,6 +32,7 @@ var (
 		Statements: []*Clause{
 			{Token: TokenPrepare, Clauses: SqlPrepare},
 			{Token: TokenSelect, Clauses: SqlSelect},
+			{Token: TokenSubscribe, Clauses: SqlSubscribe},
 			{Token: TokenUpdate, Clauses: SqlUpdate},
 			{Token: TokenUpsert, Clauses: SqlUpsert},
 			{Token: TokenInsert, Clauses: SqlInsert},
@@ -46,6 +48,7 @@ var (
 			{Token: TokenUse, Clauses: SqlUse},
 			{Token: TokenRollback, Clauses: SqlRollback},
 			{Token: TokenCommit, Clauses: SqlCommit},
+			{Token: TokenRefresh, Clauses: SqlRefresh},
 		},
 	}
 	// SqlSelect Select statement.
@@ -64,6 +67,11 @@ var (
 		{Token: TokenAlias, Lexer: LexIdentifier, Optional: true, Name: "sqlSelect.alias"},
 		{Token: TokenEOF, Lexer: LexEndOfStatement, Optional: false, Name: "sqlSelect.eos"},
 	}
+	// SqlSubscribe SUBSCRIBE SELECT ... a select which keeps emitting rows
+	SqlSubscribe = []*Clause{
+		{Token: TokenSubscribe, Lexer: LexEmpty, Name: "subscribe.entry"},
+		{Token: TokenSelect, Clauses: SqlSelect, Name: "subscribe.select"},
+	}
 	fromSource = []*Clause{
 		{KeywordMatcher: sourceMatch, Lexer: LexTableReferenceFirst, Name: "fromSource.matcher"},
 		{Token: TokenSelect, Lexer: LexSelectClause, Name: "fromSource.Select"},
@@ -124,6 +132,9 @@ var (
 		{Token: TokenSet, Lexer: LexTableColumns, Optional: true},
 		{Token: TokenSelect, Optional: true, Clauses: insertSubQuery},
 		{Token: TokenValues, Lexer: LexTableColumns, Optional: true},
+		{Token: TokenOn, Lexer: LexOnConflict, Optional: true},
+		{Token: TokenUpdate, Lexer: LexConflictUpdate, Optional: true},
+		{Token: TokenReturning, Lexer: LexSelectClause, Optional: true},
 		{Token: TokenWith, Lexer: LexJsonOrKeyValue, Optional: true},
 	}
 	insertSubQuery = []*Clause{
@@ -156,7 +167,7 @@ var (
 	SqlAlter = []*Clause{
 		{Token: TokenAlter, Lexer: LexEmpty},
 		{Token: TokenTable, Lexer: LexIdentifier},
-		{Token: TokenChange, Lexer: LexDdlAlterColumn},
+		{KeywordMatcher: alterMatch, Lexer: LexDdlAlterColumn, Name: "sqlAlter.columns"},
 		{Token: TokenWith, Lexer: LexJsonOrKeyValue, Optional: true},
 	}
 	// SqlCreate CREATE {SCHEMA | DATABASE | SOURCE | TABLE | VIEW | CONTINUOUSVIEW}
@@ -186,6 +197,7 @@ var (
 	SqlShow = []*Clause{
 		{Token: TokenShow, Lexer: LexShowClause},
 		{Token: TokenWhere, Lexer: LexConditionalClause, Optional: true},
+		{Token: TokenWith, Lexer: LexJsonOrKeyValue, Optional: true},
 	}
 	// SqlPrepare
 	SqlPrepare = []*Clause{
@@ -208,6 +220,10 @@ var (
 	SqlCommit = []*Clause{
 		{Token: TokenCommit, Lexer: LexEmpty},
 	}
+	// SqlRefresh REFRESH MATERIALIZED VIEW
+	SqlRefresh = []*Clause{
+		{Token: TokenRefresh, Lexer: LexRefresh},
+	}
 )
 
 // NewSqlLexer creates a new lexer for the input string using SqlDialect
@@ -233,6 +249,15 @@ func sourceMatch(c *Clause, peekWord string, l *Lexer) bool {
 	return false
 }
 
+// alterMatch matches the first action of ALTER TABLE
+func alterMatch(c *Clause, peekWord string, l *Lexer) bool {
+	switch peekWord {
+	case "change", "add", "drop", "modify":
+		return true
+	}
+	return false
+}
+
 // LexEndOfSubStatement Look for end of statement defined by either
 // a semicolon or end of file.
 func LexEndOfSubStatement(l *Lexer) StateFn {
@@ -389,16 +414,16 @@ func LexLimit(l *Lexer) StateFn {
 
 // LexCreate allows us to lex the words after CREATE
 //
-//    CREATE {SCHEMA|DATABASE|SOURCE} [IF NOT EXISTS] <identity>  <WITH>
-//    CREATE {TABLE} <identity> [IF NOT EXISTS] <table_spec> [WITH]
-//    CREATE [OR REPLACE] {VIEW|CONTINUOUSVIEW} <identity> AS <select_statement> [WITH]
-//
+//	CREATE {SCHEMA|DATABASE|SOURCE} [IF NOT EXISTS] <identity>  <WITH>
+//	CREATE {TABLE} <identity> [IF NOT EXISTS] <table_spec> [WITH]
+//	CREATE [OR REPLACE] [MATERIALIZED] {VIEW|CONTINUOUSVIEW} <identity> AS <select_statement> [WITH]
 func LexCreate(l *Lexer) StateFn {
 
 	/*
 		CREATE TABLE [IF NOT EXISTS] <identity> [WITH]
 		CREATE SOURCE [IF NOT EXISTS] <identity> [WITH]
 		CREATE [OR REPLACE] VIEW <identity> AS <select_statement> [WITH]
+		CREATE [UNIQUE] INDEX <identity> ON <identity> (<col> [, <col>]*) [WITH]
 	*/
 
 	l.SkipWhiteSpaces()
@@ -413,7 +438,7 @@ func LexCreate(l *Lexer) StateFn {
 		l.ConsumeWord(keyWord)
 		l.Emit(TokenTable)
 		l.Push("LexDdlTable", LexDdlTable)
-		return nil
+		return lexNotExists
 	case "source":
 		l.ConsumeWord(keyWord)
 		l.Emit(TokenSource)
@@ -429,6 +454,10 @@ func LexCreate(l *Lexer) StateFn {
 		l.Emit(TokenDatabase)
 		l.Push("LexIdentifier", LexIdentifier)
 		return LexCreate
+	case "materialized":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenMaterialized)
+		return LexCreate
 	case "view":
 		l.ConsumeWord(keyWord)
 		l.Emit(TokenView)
@@ -439,6 +468,15 @@ func LexCreate(l *Lexer) StateFn {
 		l.Emit(TokenContinuousView)
 		l.Push("lexAs", lexAs)
 		return LexIdentifier
+	case "unique":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenUnique)
+		return LexCreate
+	case "index":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenIndex)
+		l.Push("lexIndexOn", lexIndexOn)
+		return LexIdentifier
 	case "if":
 		l.Push("LexCreate", LexCreate)
 		return lexNotExists
@@ -447,6 +485,20 @@ func LexCreate(l *Lexer) StateFn {
 	}
 	return nil
 }
+
+// lexIndexOn lexes the ON <identity> (<col>, ...) part of CREATE INDEX.
+func lexIndexOn(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	keyWord := strings.ToLower(l.PeekWord())
+	switch keyWord {
+	case "on":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenOn)
+		l.Push("LexColumnNames", LexColumnNames)
+		return LexIdentifier
+	}
+	return nil
+}
 func lexAs(l *Lexer) StateFn {
 	l.SkipWhiteSpaces()
 	keyWord := strings.ToLower(l.PeekWord())
@@ -497,12 +549,14 @@ func lexOrReplace(l *Lexer) StateFn {
 
 // LexDrop allows us to lex the words after DROP
 //
-//    DROP {DATABASE | SCHEMA} [IF EXISTS] db_name
+//	DROP {DATABASE | SCHEMA} [IF EXISTS] db_name
+//
+//	DROP [TEMPORARY] TABLE [IF EXISTS] tbl_name [, tbl_name] [RESTRICT | CASCADE]
 //
-//    DROP [TEMPORARY] TABLE [IF EXISTS] tbl_name [, tbl_name] [RESTRICT | CASCADE]
+//	DROP [MATERIALIZED] VIEW [IF EXISTS] view_name
 //
-//    DROP INDEX index_name ON tbl_name
-//        [algorithm_option | lock_option] ...
+//	DROP INDEX index_name ON tbl_name
+//	    [algorithm_option | lock_option] ...
 func LexDrop(l *Lexer) StateFn {
 
 	/*
@@ -527,6 +581,10 @@ func LexDrop(l *Lexer) StateFn {
 		l.ConsumeWord(keyWord)
 		l.Emit(TokenTemp)
 		return LexDrop
+	case "materialized":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenMaterialized)
+		return LexDrop
 	case "table":
 		l.ConsumeWord(keyWord)
 		l.Emit(TokenTable)
@@ -552,6 +610,25 @@ func LexDrop(l *Lexer) StateFn {
 	return lexNotExists
 }
 
+// LexRefresh allows us to lex the words after REFRESH
+//
+//	REFRESH MATERIALIZED VIEW <identity>
+func LexRefresh(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	keyWord := strings.ToLower(l.PeekWord())
+	switch keyWord {
+	case "materialized":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenMaterialized)
+		return LexRefresh
+	case "view":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenView)
+		return LexIdentifier
+	}
+	return nil
+}
+
 // LexDdlTable data definition language table
 func LexDdlTable(l *Lexer) StateFn {
 
@@ -653,11 +730,12 @@ func LexDdlTableStorage(l *Lexer) StateFn {
 
 // LexDdlAlterColumn data definition language column alter
 //
-//   CHANGE col1_old col1_new varchar(10),
-//   CHANGE col2_old col2_new TEXT
-//   ADD col3 BIGINT AFTER col1_new
-//   ADD col2 TEXT FIRST,
-//
+//	CHANGE col1_old col1_new varchar(10),
+//	CHANGE col2_old col2_new TEXT
+//	ADD col3 BIGINT AFTER col1_new
+//	ADD col2 TEXT FIRST,
+//	MODIFY COLUMN col4 INT NOT NULL DEFAULT 0,
+//	DROP COLUMN col5
 func LexDdlAlterColumn(l *Lexer) StateFn {
 
 	l.SkipWhiteSpaces()
@@ -694,6 +772,52 @@ func LexDdlAlterColumn(l *Lexer) StateFn {
 		l.ConsumeWord(word)
 		l.Emit(TokenAdd)
 		return LexDdlAlterColumn
+	case "drop":
+		l.ConsumeWord(word)
+		l.Emit(TokenDrop)
+		return LexDdlAlterColumn
+	case "modify":
+		l.ConsumeWord(word)
+		l.Emit(TokenModify)
+		return LexDdlAlterColumn
+	case "column":
+		l.ConsumeWord(word)
+		l.Emit(TokenColumn)
+		return LexDdlAlterColumn
+	case "not":
+		l.ConsumeWord(word)
+		l.Emit(TokenNegate)
+		return LexDdlAlterColumn
+	case "null":
+		l.ConsumeWord(word)
+		l.Emit(TokenNull)
+		return LexDdlAlterColumn
+	case "default":
+		l.ConsumeWord(word)
+		l.Emit(TokenDefault)
+		l.Push("LexDdlAlterColumn", l.clauseState())
+		return LexValue
+	case "auto_increment":
+		l.ConsumeWord(word)
+		l.Emit(TokenIdentity)
+		return LexDdlAlterColumn
+	case "unique":
+		l.ConsumeWord(word)
+		l.Emit(TokenUnique)
+		return LexDdlAlterColumn
+	case "primary":
+		l.ConsumeWord(word)
+		l.Emit(TokenPrimary)
+		return LexDdlAlterColumn
+	case "key":
+		l.ConsumeWord(word)
+		l.Emit(TokenKey)
+		return LexDdlAlterColumn
+	case "comment":
+		l.ConsumeWord(word)
+		l.Emit(TokenIdentity)
+		l.Push("LexDdlAlterColumn", l.clauseState())
+		return LexValue
 	case "after":
 		l.ConsumeWord(word)
 		l.Emit(TokenAfter)
@@ -718,18 +842,21 @@ func LexDdlAlterColumn(l *Lexer) StateFn {
 		l.ConsumeWord(word)
 		l.Emit(TokenTypeText)
 		return l.clauseState()
-	case "bigint":
+	case "bigint", "int", "integer", "char", "varchar":
 		l.ConsumeWord(word)
-		l.Emit(TokenTypeBigInt)
+		l.Emit(ddlSizedTypes[word])
+		if l.Peek() == '(' {
+			l.Push("LexDdlAlterColumn", l.clauseState())
+			l.Push("LexParenRight", LexParenRight)
+			return LexListOfArgs
+		}
 		return l.clauseState()
-	case "varchar":
-		l.ConsumeWord(word)
-		l.Emit(TokenTypeVarChar)
-		l.Push("LexDdlAlterColumn", l.clauseState())
-		l.Push("LexParenRight", LexParenRight)
-		return LexListOfArgs
-
 	default:
+		if tok, ok := ddlTypes[word]; ok {
+			l.ConsumeWord(word)
+			l.Emit(tok)
+			return l.clauseState()
+		}
 		r = l.Peek()
 		if r == ',' {
 			l.Emit(TokenComma)
@@ -753,6 +880,32 @@ func LexDdlAlterColumn(l *Lexer) StateFn {
 	return LexExpressionOrIdentity
 }
 
+var (
+	// ddlSizedTypes data types which may have a size, ie varchar(255)
+	ddlSizedTypes = map[string]TokenType{
+		"int":     TokenTypeInteger,
+		"integer": TokenTypeInteger,
+		"bigint":  TokenTypeBigInt,
+		"char":    TokenTypeChar,
+		"varchar": TokenTypeVarChar,
+	}
+	// ddlTypes other column data types
+	ddlTypes = map[string]TokenType{
+		"text":      TokenTypeText,
+		"string":    TokenTypeString,
+		"float":     TokenTypeFloat,
+		"double":    TokenTypeFloat,
+		"real":      TokenTypeFloat,
+		"decimal":   TokenTypeFloat,
+		"bool":      TokenTypeBool,
+		"boolean":   TokenTypeBool,
+		"date":      TokenTypeTime,
+		"datetime":  TokenTypeTime,
+		"timestamp": TokenTypeTime,
+		"json":      TokenTypeJson,
+	}
+)
+
 // LexDdlTableColumn data definition language column (repeated)
 //
 //   col1_new varchar(10),
@@ -917,6 +1070,11 @@ func LexDdlTableColumn(l *Lexer) StateFn {
 		l.Push("LexParenRight", LexParenRight)
 		return LexListOfArgs
 	default:
+		if tok, ok := ddlTypes[word]; ok {
+			l.ConsumeWord(word)
+			l.Emit(tok)
+			return LexDdlTableColumn
+		}
 		if l.isIdentity() {
 			l.ConsumeWord(word)
 			l.Emit(TokenIdentity)
@@ -966,3 +1124,80 @@ func LexEngineKeyValue(l *Lexer) StateFn {
 	u.Debugf("Did not find key-value? %v", l.PeekX(20))
 	return nil
 }
+
+// LexOnConflict lex the conflict clause of an INSERT, after the ON.
+//
+//	ON CONFLICT (id) DO NOTHING
+//	ON CONFLICT (id) DO UPDATE SET name = excluded.name
+//	ON DUPLICATE KEY UPDATE name = VALUES(name)
+//
+func LexOnConflict(l *Lexer) StateFn {
+
+	l.SkipWhiteSpaces()
+	r := l.Peek()
+
+	//u.Debugf("LexOnConflict  r= '%v'", string(r))
+
+	switch r {
+	case '(':
+		l.Next()
+		l.Emit(TokenLeftParenthesis)
+		return LexOnConflict
+	case ')':
+		l.Next()
+		l.Emit(TokenRightParenthesis)
+		return LexOnConflict
+	case ',':
+		l.Next()
+		l.Emit(TokenComma)
+		return LexOnConflict
+	case ';':
+		return nil
+	}
+
+	word := strings.ToLower(l.PeekWord())
+	switch word {
+	case "":
+		return nil
+	case "conflict":
+		l.ConsumeWord(word)
+		l.Emit(TokenConflict)
+		return LexOnConflict
+	case "duplicate":
+		l.ConsumeWord(word)
+		l.Emit(TokenDuplicate)
+		return LexOnConflict
+	case "key":
+		l.ConsumeWord(word)
+		l.Emit(TokenKey)
+		return LexOnConflict
+	case "do":
+		l.ConsumeWord(word)
+		l.Emit(TokenDo)
+		return LexOnConflict
+	case "nothing":
+		l.ConsumeWord(word)
+		l.Emit(TokenNothing)
+		return nil
+	}
+	if l.isNextKeyword(word) {
+		return nil
+	}
+	// conflict target columns
+	l.Push("LexOnConflict", LexOnConflict)
+	return LexIdentifier
+}
+
+// LexConflictUpdate lex the assignments of the UPDATE of an insert
+// conflict clause, with the SET of DO UPDATE SET.
+//
+//	SET name = excluded.name, ct = ct + 1
+//
+func LexConflictUpdate(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	if word := strings.ToLower(l.PeekWord()); word == "set" {
+		l.ConsumeWord(word)
+		l.Emit(TokenSet)
+	}
+	return LexColumns
+}
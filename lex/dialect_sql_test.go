@@ -46,6 +46,17 @@ func TestLexSqlShow(t *testing.T) {
 			tv(TokenIdentity, "TRIGGER"),
 			tv(TokenIdentity, "mytrigger"),
 		})
+	verifyTokens(t, `SHOW CREATE TABLE mytable WITH dialect = "postgres";`,
+		[]Token{
+			tv(TokenShow, "SHOW"),
+			tv(TokenCreate, "CREATE"),
+			tv(TokenIdentity, "TABLE"),
+			tv(TokenIdentity, "mytable"),
+			tv(TokenWith, "WITH"),
+			tv(TokenIdentity, "dialect"),
+			tv(TokenEqual, "="),
+			tv(TokenValue, "postgres"),
+		})
 	verifyTokenTypes(t, "SHOW FULL TABLES FROM `ourschema` LIKE '%'",
 		[]TokenType{TokenShow,
 			TokenFull, TokenTables,
@@ -167,6 +178,19 @@ func TestLexSqlCreate(t *testing.T) {
 			tv(TokenValue, "hello"),
 		})
 
+	verifyTokens(t, `CREATE MATERIALIZED VIEW viewx AS SELECT a FROM tbl`,
+		[]Token{
+			tv(TokenCreate, "CREATE"),
+			tv(TokenMaterialized, "MATERIALIZED"),
+			tv(TokenView, "VIEW"),
+			tv(TokenIdentity, "viewx"),
+			tv(TokenAs, "AS"),
+			tv(TokenSelect, "SELECT"),
+			tv(TokenIdentity, "a"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "tbl"),
+		})
+
 	verifyTokens(t, `CREATE TABLE articles 
 		 (
 		  ID int(11) NOT NULL AUTO_INCREMENT,
@@ -296,6 +320,22 @@ func TestLexSqlDrop(t *testing.T) {
 			tv(TokenContinuousView, "CONTINUOUSVIEW"),
 			tv(TokenIdentity, "myv"),
 		})
+	verifyTokens(t, `DROP MATERIALIZED VIEW IF EXISTS myv;`,
+		[]Token{
+			tv(TokenDrop, "DROP"),
+			tv(TokenMaterialized, "MATERIALIZED"),
+			tv(TokenView, "VIEW"),
+			tv(TokenIf, "IF"),
+			tv(TokenExists, "EXISTS"),
+			tv(TokenIdentity, "myv"),
+		})
+	verifyTokens(t, `REFRESH MATERIALIZED VIEW myv;`,
+		[]Token{
+			tv(TokenRefresh, "REFRESH"),
+			tv(TokenMaterialized, "MATERIALIZED"),
+			tv(TokenView, "VIEW"),
+			tv(TokenIdentity, "myv"),
+		})
 }
 
 func TestLexSqlSelect(t *testing.T) {
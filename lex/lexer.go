@@ -678,6 +678,12 @@ func (l *Lexer) isNextKeyword(peekWord string) bool {
 		if !clause.Optional {
 			return false
 		}
+		if clause.next == nil && clause.parent != nil {
+			// end of the sub-clauses, the clauses following the parent
+			// such as ON after an INSERT ... SELECT are next.
+			clause = clause.parent.next
+			continue
+		}
 		clause = clause.next
 	}
 
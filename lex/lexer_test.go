@@ -1087,6 +1087,37 @@ func TestLexAlter(t *testing.T) {
 			tv(TokenIdentity, "utf8"),
 			tv(TokenEOS, ";"),
 		})
+
+	verifyTokens(t, `ALTER TABLE users ADD COLUMN age int NOT NULL DEFAULT 0 AFTER name,
+		 DROP COLUMN email, MODIFY name varchar(100) NULL;`,
+		[]Token{
+			tv(TokenAlter, "ALTER"),
+			tv(TokenTable, "TABLE"),
+			tv(TokenIdentity, "users"),
+			tv(TokenAdd, "ADD"),
+			tv(TokenColumn, "COLUMN"),
+			tv(TokenIdentity, "age"),
+			tv(TokenTypeInteger, "int"),
+			tv(TokenNegate, "NOT"),
+			tv(TokenNull, "NULL"),
+			tv(TokenDefault, "DEFAULT"),
+			tv(TokenInteger, "0"),
+			tv(TokenAfter, "AFTER"),
+			tv(TokenIdentity, "name"),
+			tv(TokenComma, ","),
+			tv(TokenDrop, "DROP"),
+			tv(TokenColumn, "COLUMN"),
+			tv(TokenIdentity, "email"),
+			tv(TokenComma, ","),
+			tv(TokenModify, "MODIFY"),
+			tv(TokenIdentity, "name"),
+			tv(TokenTypeVarChar, "varchar"),
+			tv(TokenLeftParenthesis, "("),
+			tv(TokenInteger, "100"),
+			tv(TokenRightParenthesis, ")"),
+			tv(TokenNull, "NULL"),
+			tv(TokenEOS, ";"),
+		})
 }
 
 func TestLexUpdate(t *testing.T) {
@@ -142,6 +142,7 @@ const (
 	TokenReplace   TokenType = 214 // Insert/Replace are interchangeable on insert statements
 	TokenRollback  TokenType = 215
 	TokenCommit    TokenType = 216
+	TokenRefresh   TokenType = 217 // REFRESH MATERIALIZED VIEW
 
 	// Other QL Keywords, These are clause-level keywords that mark separation between clauses
 	TokenFrom     TokenType = 300 // from
@@ -180,6 +181,8 @@ const (
 	TokenView           TokenType = 404 // VIEW
 	TokenContinuousView TokenType = 405 // CONTINUOUSVIEW
 	TokenTemp           TokenType = 406 // TEMP or TEMPORARY
+	TokenIndex          TokenType = 407 // INDEX
+	TokenMaterialized   TokenType = 408 // MATERIALIZED
 
 	// ddl other
 	TokenChange       TokenType = 410 // change
@@ -195,6 +198,8 @@ const (
 	TokenForeign      TokenType = 420 // foreign
 	TokenReferences   TokenType = 421 // references
 	TokenEngine       TokenType = 422 // engine
+	TokenModify       TokenType = 423 // modify
+	TokenColumn       TokenType = 424 // column
 
 	// Other QL keywords
 	TokenSet  TokenType = 500 // set
@@ -203,6 +208,13 @@ const (
 	TokenDesc TokenType = 503 // descending
 	TokenUse  TokenType = 504 // use
 
+	// insert conflict and returning
+	TokenConflict  TokenType = 510 // conflict
+	TokenDuplicate TokenType = 511 // duplicate
+	TokenDo        TokenType = 512 // do
+	TokenNothing   TokenType = 513 // nothing
+	TokenReturning TokenType = 514 // returning
+
 	// User defined function/expression
 	TokenUdfExpr TokenType = 550
 
@@ -334,6 +346,7 @@ var (
 		TokenReplace:   {Description: "replace"},
 		TokenRollback:  {Description: "rollback"},
 		TokenCommit:    {Description: "commit"},
+		TokenRefresh:   {Description: "refresh"},
 
 		// Top Level dml ql clause keywords
 		TokenInto:    {Description: "into"},
@@ -373,6 +386,8 @@ var (
 		TokenView:           {Description: "view"},
 		TokenContinuousView: {Description: "continuousview"},
 		TokenTemp:           {Description: "temp"},
+		TokenIndex:          {Description: "index"},
+		TokenMaterialized:   {Description: "materialized"},
 		// ddl other
 		TokenChange:       {Description: "change"},
 		TokenCharacterSet: {Description: "character set"},
@@ -387,6 +402,8 @@ var (
 		TokenForeign:      {Description: "foreign"},
 		TokenReferences:   {Description: "references"},
 		TokenEngine:       {Description: "engine"},
+		TokenModify:       {Description: "modify"},
+		TokenColumn:       {Description: "column"},
 
 		// QL Keywords, all lower-case
 		TokenSet:  {Description: "set"},
@@ -395,6 +412,12 @@ var (
 		TokenDesc: {Description: "desc"},
 		TokenUse:  {Description: "use"},
 
+		TokenConflict:  {Description: "conflict"},
+		TokenDuplicate: {Description: "duplicate"},
+		TokenDo:        {Description: "do"},
+		TokenNothing:   {Description: "nothing"},
+		TokenReturning: {Description: "returning"},
+
 		// special value types
 		TokenIdentity:     {Description: "identity"},
 		TokenValue:        {Description: "value"},
@@ -48,6 +48,43 @@ var (
 	_ Proto = (*Select)(nil)
 )
 
+// LookupJoinMaxRows is the most rows a join side may have for the join to
+// seek each of its rows in a seekable other side instead of scanning it.
+var LookupJoinMaxRows = 1000
+
+// JoinStrategy is how a JoinMerge combines the rows of its two sides.
+type JoinStrategy uint8
+
+const (
+	// JoinHash buffer both sides by join key, then merge.
+	JoinHash JoinStrategy = iota
+	// JoinBuildLeft hash the (smaller) left side, stream the right through it.
+	JoinBuildLeft
+	// JoinBuildRight hash the (smaller) right side, stream the left through it.
+	JoinBuildRight
+	// JoinLookupRight scan the (small) left side seeking each key in the right.
+	JoinLookupRight
+	// JoinLookupLeft scan the (small) right side seeking each key in the left.
+	JoinLookupLeft
+)
+
+func (m JoinStrategy) String() string {
+	switch m {
+	case JoinBuildLeft:
+		return "build-left"
+	case JoinBuildRight:
+		return "build-right"
+	case JoinLookupRight:
+		return "lookup-right"
+	case JoinLookupLeft:
+		return "lookup-left"
+	}
+	return "hash"
+}
+
+// IsLookup is this a lookup (seek) join
+func (m JoinStrategy) IsLookup() bool { return m == JoinLookupLeft || m == JoinLookupRight }
+
 type (
 	// SchemaLoader func interface for loading schema.
 	SchemaLoader func(name string) (*schema.Schema, error)
@@ -143,11 +180,15 @@ type (
 		ChildDag bool
 		pbplan   *PlanPb
 	}
-	// Insert plan
+	// Insert plan, for INSERT ... SELECT the Select plan
+	// whose rows are written to Source.  Source is nil if the
+	// select reads from the same data source, it is opened by
+	// the executor once the select has been read.
 	Insert struct {
 		*PlanBase
 		Stmt   *rel.SqlInsert
 		Source schema.ConnUpsert
+		Select *Select
 	}
 	// Upsert task (not official sql) for sql Upsert.
 	Upsert struct {
@@ -155,11 +196,15 @@ type (
 		Stmt   *rel.SqlUpsert
 		Source schema.ConnUpsert
 	}
-	// Update plan for sql Update statements.
+	// Update plan for sql Update statements.  For sources which can't
+	// patch by where, the Select plan of rows to be read, modified and
+	// written back to Source, which is then nil for the executor to
+	// open once the select has been read.
 	Update struct {
 		*PlanBase
 		Stmt   *rel.SqlUpdate
 		Source schema.ConnUpsert
+		Select *Select
 	}
 	// Delete plan for sql DELETE where
 	Delete struct {
@@ -202,11 +247,13 @@ type (
 		Tbl        *schema.Table  // Table schema for this From
 		Static     []driver.Value // this is static data source
 		Cols       []string
+		Ordered    bool // rows are read in the ORDER BY order, no sort needed
 	}
 	// Into Select INTO table
 	Into struct {
 		*PlanBase
-		Stmt *rel.SqlInto
+		Stmt   *rel.SqlInto
+		Source schema.ConnUpsert
 	}
 	// GroupBy clause plan
 	GroupBy struct {
@@ -238,6 +285,7 @@ type (
 		LeftFrom  *rel.SqlSource
 		RightFrom *rel.SqlSource
 		ColIndex  map[string]int
+		Strategy  JoinStrategy
 	}
 	// JoinKey plan
 	JoinKey struct {
@@ -413,6 +461,7 @@ func (m *Source) Walk(p Planner) error            { return p.WalkSourceSelect(m)
 func (m *Create) Walk(p Planner) error            { return p.WalkCreate(m) }
 func (m *Drop) Walk(p Planner) error              { return p.WalkDrop(m) }
 func (m *Alter) Walk(p Planner) error             { return p.WalkAlter(m) }
+func (m *Into) Walk(p Planner) error              { return p.WalkInto(m) }
 
 // NewCreate creates a new Create Task plan.
 func NewCreate(ctx *Context, stmt *rel.SqlCreate) *Create {
@@ -514,7 +563,7 @@ func (m *Select) IsSchemaQuery() bool {
 	if len(m.From) == 1 {
 		//u.Debugf("schema:%q name:%q", m.From[0].Stmt.Schema, m.From[0].Stmt.Name)
 		schemaName := strings.ToLower(m.From[0].Stmt.Schema)
-		if schemaName == "context" || schemaName == "schema" {
+		if schemaName == "context" || schemaName == "schema" || schemaName == schema.InformationSchema {
 			return true
 		}
 	}
@@ -661,9 +710,9 @@ func (m *Source) LoadConn() error {
 			return nil
 		}
 	}
-	source, err := m.DataSource.Open(m.Stmt.SourceName())
+	source, err := m.DataSource.Open(m.SourceName())
 	if err != nil {
-		u.Debugf("no source? %T for source %q", m.DataSource, m.Stmt.SourceName())
+		u.Debugf("no source? %T for source %q", m.DataSource, m.SourceName())
 		return err
 	}
 	m.Conn = source
@@ -673,12 +722,36 @@ func (m *Source) IsSchemaQuery() bool {
 	if m.Stmt != nil && len(m.Stmt.Schema) > 0 {
 		//u.Debugf("schema:%q name:%q", m.Stmt.Schema, m.Stmt.Name)
 		schemaName := strings.ToLower(m.Stmt.Schema)
-		if schemaName == "context" || schemaName == "schema" {
+		if schemaName == "context" || schemaName == "schema" || schemaName == schema.InformationSchema {
 			return true
 		}
 	}
 	return false
 }
+
+// SourceName the name of the table to open on the DataSource, tables of
+// information_schema are qualified by it, see schema.InformationSchema.
+func (m *Source) SourceName() string { return sourceTableName(m.Stmt) }
+
+func sourceTableName(from *rel.SqlSource) string {
+	if from == nil {
+		return ""
+	}
+	if strings.EqualFold(from.Schema, schema.InformationSchema) {
+		return schema.InformationSchema + "." + strings.ToLower(from.SourceName())
+	}
+	return from.SourceName()
+}
+
+// Subscribe is this source part of a SUBSCRIBE query, sources which can
+// should keep emitting rows appended after reaching the end.
+func (m *Source) Subscribe() bool {
+	if m.ctx == nil {
+		return false
+	}
+	sel, ok := m.ctx.Stmt.(*rel.SqlSelect)
+	return ok && sel.Subscribe
+}
 func (m *Source) ToPb() (*PlanPb, error) {
 	m.serializeToPb()
 	return m.pbplan, nil
@@ -753,7 +826,7 @@ func (m *Source) load() error {
 	if m.Stmt == nil {
 		return nil
 	}
-	fromName := strings.ToLower(m.Stmt.SourceName())
+	fromName := strings.ToLower(m.SourceName())
 	if m.ctx == nil {
 		return fmt.Errorf("missing context in Source")
 	}
@@ -901,6 +974,11 @@ func NewGroupBy(stmt *rel.SqlSelect) *GroupBy {
 	return &GroupBy{Stmt: stmt, PlanBase: NewPlanBase(false)}
 }
 
+// NewInto from SqlInto statement.
+func NewInto(stmt *rel.SqlInto) *Into {
+	return &Into{Stmt: stmt, PlanBase: NewPlanBase(false)}
+}
+
 // NewOrder from SqlSelect statement.
 func NewOrder(stmt *rel.SqlSelect) *Order {
 	return &Order{Stmt: stmt, PlanBase: NewPlanBase(false)}
@@ -1086,6 +1164,9 @@ func (m *JoinMerge) Equal(t Task) bool {
 	if !ok {
 		return false
 	}
+	if m.Strategy != s.Strategy {
+		return false
+	}
 
 	if !m.PlanBase.EqualBase(s.PlanBase) {
 		return false
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/lex"
 )
 
 var (
@@ -54,6 +56,14 @@ func (m *PlannerDefault) WalkDrop(p *Drop) error {
 // WalkCreate walk a Create Plan to create the dag of tasks for Create.
 func (m *PlannerDefault) WalkCreate(p *Create) error {
 	u.Debugf("WalkCreate %#v", p)
+	switch p.Stmt.Tok.T {
+	case lex.TokenIndex, lex.TokenTable:
+		// CREATE INDEX, TABLE are declared on the source of the table
+		return nil
+	case lex.TokenView, lex.TokenContinuousView:
+		// CREATE VIEW plans its select when executed
+		return nil
+	}
 	if len(p.Stmt.With) == 0 {
 		return fmt.Errorf("CREATE {SCHEMA|SOURCE|DATABASE}")
 	}
@@ -5,6 +5,7 @@ import (
 
 	u "github.com/araddon/gou"
 
+	"github.com/fuhongbo/qlbridge/rel"
 	"github.com/fuhongbo/qlbridge/schema"
 )
 
@@ -14,10 +15,17 @@ var (
 
 func (m *PlannerDefault) WalkInto(p *Into) error {
 	u.Debugf("VisitInto %+v", p.Stmt)
-	return ErrNotImplemented
+	src, err := UpsertSource(m.Ctx, p.Stmt.Table)
+	if err != nil {
+		return err
+	}
+	p.Source = src
+	return nil
 }
 
-func upsertSource(ctx *Context, table string) (schema.ConnUpsert, error) {
+// UpsertSource open the conn writes to @table are made through, the
+// sources mutator if it has one.
+func UpsertSource(ctx *Context, table string) (schema.ConnUpsert, error) {
 
 	conn, err := ctx.Schema.OpenConn(table)
 	if err != nil {
@@ -45,7 +53,21 @@ func upsertSource(ctx *Context, table string) (schema.ConnUpsert, error) {
 
 func (m *PlannerDefault) WalkInsert(p *Insert) error {
 	u.Debugf("VisitInsert %s", p.Stmt)
-	src, err := upsertSource(m.Ctx, p.Stmt.Table)
+	if p.Stmt.Select != nil {
+		// INSERT ... SELECT, plan the select whose projected rows
+		// are written to the insert source.
+		p.Select = &Select{Stmt: p.Stmt.Select, PlanBase: NewPlanBase(false), Ctx: m.Ctx}
+		if err := m.Planner.WalkSelect(p.Select); err != nil {
+			return err
+		}
+		if readsSource(m.Ctx, p.Select, p.Stmt.Table) {
+			// sources such as sqlite allow one open conn at a time, so
+			// Source is left nil for the executor to open once the
+			// select has been read.
+			return nil
+		}
+	}
+	src, err := UpsertSource(m.Ctx, p.Stmt.Table)
 	if err != nil {
 		return err
 	}
@@ -53,19 +75,65 @@ func (m *PlannerDefault) WalkInsert(p *Insert) error {
 	return nil
 }
 
+// readsSource does the select @t read from the data source of @table.
+func readsSource(ctx *Context, t Task, table string) bool {
+	ss, err := ctx.Schema.SchemaForTable(table)
+	if err != nil {
+		return false
+	}
+	switch tt := t.(type) {
+	case *Source:
+		if tt.DataSource == ss.DS {
+			return true
+		}
+	case *JoinMerge:
+		if readsSource(ctx, tt.Left, table) || readsSource(ctx, tt.Right, table) {
+			return true
+		}
+	}
+	for _, child := range t.Children() {
+		if readsSource(ctx, child, table) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *PlannerDefault) WalkUpdate(p *Update) error {
 	u.Debugf("VisitUpdate %+v", p.Stmt)
-	src, err := upsertSource(m.Ctx, p.Stmt.Table)
+	src, err := UpsertSource(m.Ctx, p.Stmt.Table)
 	if err != nil {
 		return err
 	}
-	p.Source = src
-	return nil
+	if _, ok := src.(schema.ConnPatchWhere); ok {
+		p.Source = src
+		return nil
+	}
+	// source can't update by where, so plan a select of the rows to be
+	// read, modified and written back.  Sources such as sqlite allow
+	// one open conn at a time, so Source is closed and left nil for the
+	// executor to open once the select has been read.
+	if conn, ok := src.(schema.Conn); ok {
+		if err = conn.Close(); err != nil {
+			return err
+		}
+	}
+	sel := p.Stmt.SqlSelect()
+	if tbl, err := m.Ctx.Schema.Table(p.Stmt.Table); err == nil && len(tbl.Columns()) > 0 {
+		// the whole row is written back so read every column, not
+		// only those a source pushing down the select finds referenced
+		sel.Columns, sel.Star = nil, false
+		for _, col := range tbl.Columns() {
+			sel.AddColumn(*rel.NewColumn(col))
+		}
+	}
+	p.Select = &Select{Stmt: sel, PlanBase: NewPlanBase(false), Ctx: m.Ctx}
+	return m.Planner.WalkSelect(p.Select)
 }
 
 func (m *PlannerDefault) WalkUpsert(p *Upsert) error {
 	u.Debugf("VisitUpsert %+v", p.Stmt)
-	src, err := upsertSource(m.Ctx, p.Stmt.Table)
+	src, err := UpsertSource(m.Ctx, p.Stmt.Table)
 	if err != nil {
 		return err
 	}
@@ -1,10 +1,14 @@
 package plan
 
 import (
+	"database/sql/driver"
 	"fmt"
+	"strings"
 
 	u "github.com/araddon/gou"
 
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/lex"
 	"github.com/fuhongbo/qlbridge/rel"
 	"github.com/fuhongbo/qlbridge/schema"
 )
@@ -36,6 +40,11 @@ func (m *PlannerDefault) WalkSelect(p *Select) error {
 
 	} else if len(p.Stmt.From) == 1 {
 
+		if inlined := inlineViews(m.Ctx.Schema, p.Stmt); inlined != nil {
+			u.Debugf("inlined views of %s as %s", p.Stmt, inlined)
+			p.Stmt = inlined
+		}
+
 		p.Stmt.From[0].Source = p.Stmt // TODO:   move to a Finalize() in query parser/planner
 
 		srcPlan, err := NewSource(m.Ctx, p.Stmt.From[0], true)
@@ -59,26 +68,43 @@ func (m *PlannerDefault) WalkSelect(p *Select) error {
 		var prevSource *Source
 		var prevTask Task
 
-		for i, from := range p.Stmt.From {
-
+		sources := make([]*Source, 0, len(p.Stmt.From))
+		for _, from := range p.Stmt.From {
 			// Need to rewrite the From statement to ensure all fields necessary to support
 			//  joins, wheres, etc exist but is standalone query
 			from.Rewrite(p.Stmt)
 			srcPlan, err := NewSource(m.Ctx, from, false)
 			if err != nil {
-				return nil
-			}
-			err = m.Planner.WalkSourceSelect(srcPlan)
-			if err != nil {
-				u.Errorf("Could not visitsubselect %v  %s", err, from)
 				return err
 			}
+			sources = append(sources, srcPlan)
+		}
+
+		strategy := JoinHash
+		if len(sources) == 2 {
+			strategy = joinStrategy(sources[0], sources[1])
+			u.Debugf("join strategy %s for %s", strategy, p.Stmt)
+		}
+
+		for i, srcPlan := range sources {
+			from := srcPlan.Stmt
+			// The seek side of a lookup join is never scanned, its rows
+			// are fetched by key as the other side is read.
+			seekSide := (i == 0 && strategy == JoinLookupLeft) || (i == 1 && strategy == JoinLookupRight)
+			if !seekSide {
+				err := m.Planner.WalkSourceSelect(srcPlan)
+				if err != nil {
+					u.Errorf("Could not visitsubselect %v  %s", err, from)
+					return err
+				}
+			}
 
 			// now fold into previous task
 			if i != 0 {
 				from.Seekable = true
 				// fold this source into previous
 				curMergeTask := NewJoinMerge(prevTask, srcPlan, prevSource.Stmt, srcPlan.Stmt)
+				curMergeTask.Strategy = strategy
 				prevTask = curMergeTask
 			} else {
 				prevTask = srcPlan
@@ -114,7 +140,7 @@ func (m *PlannerDefault) WalkSelect(p *Select) error {
 		p.Add(NewHaving(p.Stmt))
 	}
 
-	if len(p.Stmt.OrderBy) > 0 {
+	if len(p.Stmt.OrderBy) > 0 && !(len(p.From) == 1 && p.From[0].Ordered) {
 		p.Add(NewOrder(p.Stmt))
 	}
 
@@ -137,6 +163,15 @@ finalProjection:
 		//u.Debugf("m.Ctx: %p m.Ctx.Projection:    %T:%p", m.Ctx, m.Ctx.Projection, m.Ctx.Projection)
 	}
 
+	if p.Stmt.Into != nil {
+		// SELECT ... INTO table,  write projected rows to table
+		into := NewInto(p.Stmt.Into)
+		if err := m.Planner.WalkInto(into); err != nil {
+			return err
+		}
+		p.Add(into)
+	}
+
 	return nil
 }
 
@@ -167,6 +202,294 @@ func buildColIndex(colSchema schema.ConnColumns, p *Source) error {
 	return p.Stmt.BuildColIndex(colSchema.Columns())
 }
 
+// joinStrategy choose how to join sources @l, @r by their size if known
+// and whether either side can seek its rows by the join key.
+func joinStrategy(l, r *Source) JoinStrategy {
+	ll, lok := sourceLength(l)
+	rl, rok := sourceLength(r)
+	switch {
+	case lok && ll <= LookupJoinMaxRows && canSeek(r, l):
+		return JoinLookupRight
+	case rok && rl <= LookupJoinMaxRows && canSeek(l, r):
+		return JoinLookupLeft
+	case lok && rok && ll <= rl:
+		return JoinBuildLeft
+	case lok && rok:
+		return JoinBuildRight
+	}
+	return JoinHash
+}
+
+// sourceLength the number of rows of source @p if its conn knows it.
+func sourceLength(p *Source) (int, bool) {
+	if err := p.LoadConn(); err != nil || p.Conn == nil {
+		return 0, false
+	}
+	if lc, ok := p.Conn.(schema.ConnLength); ok {
+		return lc.Length(), true
+	}
+	return 0, false
+}
+
+// canSeek can the rows of @seek be fetched by key for each row of @probe,
+// ie joined on a single column which is the unique seek column of @seek
+// and of the same type on both sides.
+func canSeek(seek, probe *Source) bool {
+	if err := seek.LoadConn(); err != nil || seek.Conn == nil {
+		return false
+	}
+	sk, ok := seek.Conn.(schema.ConnSeekerKey)
+	if !ok || seek.Tbl == nil || probe.Tbl == nil {
+		return false
+	}
+	seekNodes, probeNodes := seek.Stmt.JoinNodes(), probe.Stmt.JoinNodes()
+	if len(seekNodes) != 1 || len(probeNodes) != 1 {
+		return false
+	}
+	seekCol, ok := seekNodes[0].(*expr.IdentityNode)
+	if !ok || !strings.EqualFold(seekCol.Text, sk.SeekColumn()) {
+		return false
+	}
+	probeCol, ok := probeNodes[0].(*expr.IdentityNode)
+	if !ok {
+		return false
+	}
+	st, sok := seek.Tbl.Column(seekCol.Text)
+	pt, pok := probe.Tbl.Column(probeCol.Text)
+	return sok && pok && st == pt
+}
+
+// indexScan find an index of @indexes the where clause @where of source @p
+// can be looked up on:  every field of the index compared by equality or IN,
+// or a single field index with a LIKE "prefix%".  The where is still applied
+// to the rows scanned, the index only narrows them.
+func indexScan(p *Source, where expr.Node, indexes []*schema.Index) *schema.IndexScan {
+
+	eq := make(map[string][]driver.Value)
+	prefix := make(map[string]string)
+	for _, n := range expr.FindConjuncts(where) {
+		bn, ok := n.(*expr.BinaryNode)
+		if !ok || len(bn.Args) != 2 {
+			continue
+		}
+		left, right := bn.Args[0], bn.Args[1]
+		switch bn.Operator.T {
+		case lex.TokenEqual, lex.TokenEqualEqual:
+			if _, isIdent := right.(*expr.IdentityNode); isIdent {
+				left, right = right, left
+			}
+			col, ok := sourceColumn(p, left)
+			if !ok {
+				continue
+			}
+			if v, ok := literalValue(right); ok {
+				eq[col] = []driver.Value{v}
+			}
+		case lex.TokenIN:
+			col, ok := sourceColumn(p, left)
+			an, isArray := right.(*expr.ArrayNode)
+			if !ok || !isArray || len(an.Args) == 0 {
+				continue
+			}
+			vals := make([]driver.Value, 0, len(an.Args))
+			for _, arg := range an.Args {
+				v, ok := literalValue(arg)
+				if !ok {
+					vals = nil
+					break
+				}
+				vals = append(vals, v)
+			}
+			if len(vals) > 0 {
+				eq[col] = vals
+			}
+		case lex.TokenLike:
+			col, ok := sourceColumn(p, left)
+			sn, isString := right.(*expr.StringNode)
+			if !ok || !isString {
+				continue
+			}
+			if pre, ok := likePrefix(sn.Text); ok {
+				prefix[col] = pre
+			}
+		}
+	}
+
+	var prefixScan *schema.IndexScan
+	for _, idx := range indexes {
+		if len(idx.Fields) == 0 {
+			continue
+		}
+		rows := [][]driver.Value{{}}
+		for _, f := range idx.Fields {
+			vals, ok := eq[strings.ToLower(f)]
+			if !ok {
+				rows = nil
+				break
+			}
+			next := make([][]driver.Value, 0, len(rows)*len(vals))
+			for _, row := range rows {
+				for _, v := range vals {
+					next = append(next, append(append(make([]driver.Value, 0, len(idx.Fields)), row...), v))
+				}
+			}
+			rows = next
+		}
+		if len(rows) > 0 {
+			return &schema.IndexScan{Index: idx, Values: rows}
+		}
+		if pre, ok := prefix[strings.ToLower(idx.Fields[0])]; ok && len(idx.Fields) == 1 && prefixScan == nil {
+			prefixScan = &schema.IndexScan{Index: idx, Values: [][]driver.Value{{pre}}, Prefix: true}
+		}
+	}
+	return prefixScan
+}
+
+// rangeScan narrow the scan of source @p whose conn is ordered by a key to
+// the range of the key in the where clause:  comparisons, BETWEEN, and
+// LIKE "prefix%".  If the final statement is ordered only by the key the
+// rows are read in that order and the sort is skipped.
+func rangeScan(p *Source, rs schema.ConnRangeScanner) error {
+
+	key := strings.ToLower(rs.KeyColumn())
+	r := &schema.KeyRange{}
+	narrowed := false
+	lower := func(v driver.Value, inclusive bool) {
+		if r.Start == nil {
+			r.Start, r.StartInclusive, narrowed = v, inclusive, true
+		}
+	}
+	upper := func(v driver.Value, inclusive bool) {
+		if r.End == nil {
+			r.End, r.EndInclusive, narrowed = v, inclusive, true
+		}
+	}
+
+	if p.Stmt.Source.Where != nil && p.Stmt.Source.Where.Expr != nil {
+		for _, n := range expr.FindConjuncts(p.Stmt.Source.Where.Expr) {
+			switch nt := n.(type) {
+			case *expr.TriNode:
+				if nt.Operator.T != lex.TokenBetween || nt.Negated() || len(nt.Args) != 3 {
+					continue
+				}
+				col, ok := sourceColumn(p, nt.Args[0])
+				if !ok || col != key {
+					continue
+				}
+				if v, ok := literalValue(nt.Args[1]); ok {
+					lower(v, true)
+				}
+				if v, ok := literalValue(nt.Args[2]); ok {
+					upper(v, true)
+				}
+			case *expr.BinaryNode:
+				if len(nt.Args) != 2 {
+					continue
+				}
+				op := nt.Operator.T
+				left, right := nt.Args[0], nt.Args[1]
+				if _, isIdent := right.(*expr.IdentityNode); isIdent {
+					// 5 < x  is  x > 5
+					left, right = right, left
+					switch op {
+					case lex.TokenGT:
+						op = lex.TokenLT
+					case lex.TokenGE:
+						op = lex.TokenLE
+					case lex.TokenLT:
+						op = lex.TokenGT
+					case lex.TokenLE:
+						op = lex.TokenGE
+					}
+				}
+				col, ok := sourceColumn(p, left)
+				if !ok || col != key {
+					continue
+				}
+				if sn, isString := right.(*expr.StringNode); isString && op == lex.TokenLike {
+					if pre, ok := likePrefix(sn.Text); ok && r.Prefix == "" {
+						r.Prefix, narrowed = pre, true
+					}
+					continue
+				}
+				v, ok := literalValue(right)
+				if !ok {
+					continue
+				}
+				switch op {
+				case lex.TokenEqual, lex.TokenEqualEqual:
+					lower(v, true)
+					upper(v, true)
+				case lex.TokenGT, lex.TokenGE:
+					lower(v, op == lex.TokenGE)
+				case lex.TokenLT, lex.TokenLE:
+					upper(v, op == lex.TokenLE)
+				}
+			}
+		}
+	}
+
+	// the source of a single from final statement is the statement itself
+	sel := p.Stmt.Source
+	if p.Final && len(sel.OrderBy) == 1 && !sel.IsAggQuery() && sel.Having == nil {
+		if col, ok := sourceColumn(p, sel.OrderBy[0].Expr); ok && col == key {
+			r.Desc = strings.EqualFold(sel.OrderBy[0].Order, "desc")
+			p.Ordered = true
+		}
+	}
+	if !narrowed && !p.Ordered {
+		return nil
+	}
+	u.Debugf("range scan %+v ordered:%v for %s", r, p.Ordered, sel)
+	if err := rs.RangeScan(r); err != nil {
+		if err == schema.ErrNotImplemented {
+			// the rows are filtered and sorted as any other source
+			p.Ordered = false
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// likePrefix the literal prefix of a LIKE "prefix%" pattern, false if
+// the pattern is not a plain prefix (wildcards, escapes or braces).
+func likePrefix(pattern string) (string, bool) {
+	pre := strings.TrimSuffix(pattern, "%")
+	if pre == pattern || pre == "" || strings.ContainsAny(pre, "%*?[{\\") {
+		return "", false
+	}
+	return pre, true
+}
+
+// sourceColumn the lower-cased column name of @n if it is an identity
+// of source @p.
+func sourceColumn(p *Source, n expr.Node) (string, bool) {
+	in, ok := n.(*expr.IdentityNode)
+	if !ok {
+		return "", false
+	}
+	l, r, hasLeft := in.LeftRight()
+	if hasLeft && !strings.EqualFold(l, p.Stmt.Alias) && !strings.EqualFold(l, p.Stmt.Name) {
+		return "", false
+	}
+	return strings.ToLower(r), true
+}
+
+// literalValue the value of a string or number literal @n.
+func literalValue(n expr.Node) (driver.Value, bool) {
+	switch nt := n.(type) {
+	case *expr.StringNode:
+		return nt.Text, true
+	case *expr.NumberNode:
+		if nt.IsInt {
+			return nt.Int64, true
+		}
+		return nt.Float64, true
+	}
+	return nil, false
+}
+
 // WalkSourceSelect is a single source select
 func (m *PlannerDefault) WalkSourceSelect(p *Source) error {
 
@@ -225,9 +548,23 @@ func (m *PlannerDefault) WalkSourceSelect(p *Source) error {
 			return fmt.Errorf("%q Didn't implement schema.ConnColumns: %T", p.Stmt.SourceName(), p.Conn)
 		}
 
+		if rs, ok := p.Conn.(schema.ConnRangeScanner); ok && p.Stmt.Source != nil {
+			if err := rangeScan(p, rs); err != nil {
+				return err
+			}
+		}
+
 		if p.Stmt.Source != nil && p.Stmt.Source.Where != nil {
 			switch {
 			case p.Stmt.Source.Where.Expr != nil:
+				if is, ok := p.Conn.(schema.ConnIndexScanner); ok {
+					if scan := indexScan(p, p.Stmt.Source.Where.Expr, is.Indexes()); scan != nil {
+						u.Debugf("index scan %q for %s", scan.Index.Name, p.Stmt.Source.Where.Expr)
+						if err := is.IndexScan(scan); err != nil {
+							return err
+						}
+					}
+				}
 				p.Add(NewWhere(p.Stmt.Source))
 			default:
 				u.Warnf("Found un-supported where type: %#v", p.Stmt.Source)
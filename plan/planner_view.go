@@ -0,0 +1,283 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
+)
+
+// MaxViewDepth is the most views of views inlined into a single select.
+var MaxViewDepth = 16
+
+// inlineViews rewrite the single source select @sel of a logical view,
+// and of the views that view selects from, into a select of the source
+// of the view.  The columns of the view are replaced by their expressions
+// and the where clauses are AND'd so the filters and projection of @sel
+// are planned against, and pushed down to, the source of the view.
+// Views that can't be merged (aggregates, DISTINCT, ORDER BY, LIMIT) are
+// left for their conn to read through their own SELECT.  Returns nil if
+// @sel does not read a view that can be inlined.
+func inlineViews(s *schema.Schema, sel *rel.SqlSelect) *rel.SqlSelect {
+	var inlined *rel.SqlSelect
+	for i := 0; i < MaxViewDepth; i++ {
+		merged, err := inlineView(s, sel)
+		if err != nil {
+			u.Debugf("could not inline view of %s: %v", sel, err)
+			break
+		}
+		if merged == nil {
+			break
+		}
+		inlined, sel = merged, merged
+	}
+	return inlined
+}
+
+// inlineView merge the logical view @sel selects from into @sel, nil if
+// it does not select from a view that can be merged.
+func inlineView(s *schema.Schema, sel *rel.SqlSelect) (*rel.SqlSelect, error) {
+	if s == nil || !mergeableSelect(sel) || sel.Into != nil || sel.Subscribe || len(sel.With) > 0 {
+		return nil, nil
+	}
+	from := sel.From[0]
+	if from.Schema != "" {
+		return nil, nil
+	}
+	tbl, _ := s.Table(strings.ToLower(from.Name))
+	if tbl == nil || tbl.View == nil || tbl.View.Materialized || tbl.View.Continuous {
+		return nil, nil
+	}
+	vsel, err := rel.ParseSqlSelect(tbl.View.Sql)
+	if err != nil {
+		return nil, err
+	}
+	if !mergeableSelect(vsel) || vsel.IsAggQuery() || len(vsel.GroupBy) > 0 || vsel.Having != nil ||
+		vsel.Distinct || len(vsel.OrderBy) > 0 || vsel.Limit > 0 || vsel.Offset > 0 {
+		return nil, nil
+	}
+
+	v := &viewInliner{from: from, cols: make(map[string]expr.Node)}
+	v.names = make([]string, len(vsel.Columns))
+	for i, col := range vsel.Columns {
+		if col.Star {
+			v.star = true
+			continue
+		}
+		v.names[i] = col.As
+		if len(vsel.Columns) == len(tbl.Fields) {
+			v.names[i] = tbl.Fields[i].Name
+		}
+		v.cols[strings.ToLower(v.names[i])] = col.Expr
+	}
+
+	w := expr.NewDefaultWriter()
+	io.WriteString(w, "SELECT ")
+	if sel.Distinct {
+		io.WriteString(w, "DISTINCT ")
+	}
+	for i, col := range sel.Columns {
+		if i > 0 {
+			io.WriteString(w, ", ")
+		}
+		if col.Star {
+			// the columns of the view under their view names
+			for j, vcol := range vsel.Columns {
+				if j > 0 {
+					io.WriteString(w, ", ")
+				}
+				if vcol.Star {
+					io.WriteString(w, "*")
+					continue
+				}
+				vcol.Expr.WriteDialect(w)
+				io.WriteString(w, " AS ")
+				w.WriteIdentity(v.names[j])
+			}
+			continue
+		}
+		if err = v.write(w, col.Expr); err != nil {
+			return nil, err
+		}
+		io.WriteString(w, " AS ")
+		w.WriteIdentity(col.As)
+	}
+	io.WriteString(w, " FROM ")
+	vsel.From[0].WriteDialect(w)
+
+	var where []expr.Node
+	if vsel.Where != nil {
+		where = append(where, vsel.Where.Expr)
+	}
+	if sel.Where != nil {
+		n, err := v.node(sel.Where.Expr)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, n)
+	}
+	for i, n := range where {
+		if i == 0 {
+			io.WriteString(w, " WHERE ")
+		} else {
+			io.WriteString(w, " AND ")
+		}
+		io.WriteString(w, "(")
+		n.WriteDialect(w)
+		io.WriteString(w, ")")
+	}
+	for i, col := range sel.GroupBy {
+		if i == 0 {
+			io.WriteString(w, " GROUP BY ")
+		} else {
+			io.WriteString(w, ", ")
+		}
+		if err = v.write(w, col.Expr); err != nil {
+			return nil, err
+		}
+	}
+	if sel.Having != nil {
+		io.WriteString(w, " HAVING ")
+		if err = v.write(w, sel.Having); err != nil {
+			return nil, err
+		}
+	}
+	for i, col := range sel.OrderBy {
+		if i == 0 {
+			io.WriteString(w, " ORDER BY ")
+		} else {
+			io.WriteString(w, ", ")
+		}
+		if err = v.write(w, col.Expr); err != nil {
+			return nil, err
+		}
+		if col.Order != "" {
+			io.WriteString(w, " ")
+			io.WriteString(w, col.Order)
+		}
+	}
+	if sel.Limit > 0 {
+		io.WriteString(w, fmt.Sprintf(" LIMIT %d", sel.Limit))
+	}
+	if sel.Offset > 0 {
+		io.WriteString(w, fmt.Sprintf(" OFFSET %d", sel.Offset))
+	}
+	return rel.ParseSqlSelect(w.String())
+}
+
+// mergeableSelect is @sel a select of a single table whose clauses are
+// all expressions, ie no joins, sub-queries or column guards.
+func mergeableSelect(sel *rel.SqlSelect) bool {
+	if len(sel.From) != 1 || sel.From[0].SubQuery != nil || sel.From[0].JoinExpr != nil {
+		return false
+	}
+	if sel.Where != nil && (sel.Where.Source != nil || sel.Where.Expr == nil) {
+		return false
+	}
+	for _, col := range sel.Columns {
+		if col.Guard != nil || (col.Expr == nil && !col.Star) {
+			return false
+		}
+	}
+	for _, cols := range []rel.Columns{sel.GroupBy, sel.OrderBy} {
+		for _, col := range cols {
+			if col.Expr == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// viewInliner replaces the identities of the columns of a view by their
+// expressions in the view.
+type viewInliner struct {
+	from  *rel.SqlSource       // the view as the source of the outer select
+	cols  map[string]expr.Node // lower-cased view column name -> expression
+	names []string             // the view column names, by view column position
+	star  bool                 // the view selects * from its source
+}
+
+// write @n with the view columns replaced to @w
+func (m *viewInliner) write(w expr.DialectWriter, n expr.Node) error {
+	n, err := m.node(n)
+	if err != nil {
+		return err
+	}
+	n.WriteDialect(w)
+	return nil
+}
+
+// node a copy of @n with the view columns replaced.
+func (m *viewInliner) node(n expr.Node) (expr.Node, error) {
+	n, err := expr.ParseExpression(n.String())
+	if err != nil {
+		return nil, err
+	}
+	return m.replace(n)
+}
+
+func (m *viewInliner) replace(n expr.Node) (expr.Node, error) {
+	var err error
+	replaceArgs := func(args []expr.Node) error {
+		for i, arg := range args {
+			if args[i], err = m.replace(arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch nt := n.(type) {
+	case *expr.IdentityNode:
+		return m.identity(nt)
+	case *expr.BinaryNode:
+		err = replaceArgs(nt.Args)
+	case *expr.BooleanNode:
+		err = replaceArgs(nt.Args)
+	case *expr.TriNode:
+		err = replaceArgs(nt.Args)
+	case *expr.ArrayNode:
+		err = replaceArgs(nt.Args)
+	case *expr.FuncNode:
+		err = replaceArgs(nt.Args)
+	case *expr.UnaryNode:
+		nt.Arg, err = m.replace(nt.Arg)
+	case *expr.IncludeNode:
+		return nil, fmt.Errorf("INCLUDE is not inlined")
+	}
+	return n, err
+}
+
+// identity the expression of view column @in, or @in unqualified
+// if it is a column of the source of a SELECT * view.
+func (m *viewInliner) identity(in *expr.IdentityNode) (expr.Node, error) {
+	if in.Text == "*" || in.IsBooleanIdentity() {
+		return in, nil
+	}
+	left, right, hasLeft := in.LeftRight()
+	if hasLeft && !strings.EqualFold(left, m.from.Alias) && !strings.EqualFold(left, m.from.Name) {
+		return in, nil
+	}
+	if ve, ok := m.cols[strings.ToLower(right)]; ok {
+		n, err := expr.ParseExpression(ve.String())
+		if err != nil {
+			return nil, err
+		}
+		if bn, ok := n.(*expr.BinaryNode); ok {
+			bn.Paren = true
+		}
+		return n, nil
+	}
+	if !m.star {
+		return nil, fmt.Errorf("%q is not a column of view %q", in.Text, m.from.Name)
+	}
+	if hasLeft {
+		return expr.NewIdentityNodeVal(right), nil
+	}
+	return in, nil
+}
@@ -95,7 +95,7 @@ func (m *Projection) loadFinal(ctx *Context, isFinal bool) error {
 
 	for _, from := range m.Stmt.From {
 
-		fromName := strings.ToLower(from.SourceName())
+		fromName := strings.ToLower(sourceTableName(from))
 		tbl, err := ctx.Schema.Table(fromName)
 		if err != nil {
 			u.Errorf("could not get table: %v", err)
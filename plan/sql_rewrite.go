@@ -9,6 +9,7 @@ import (
 	"github.com/fuhongbo/qlbridge/expr"
 	"github.com/fuhongbo/qlbridge/lex"
 	"github.com/fuhongbo/qlbridge/rel"
+	"github.com/fuhongbo/qlbridge/schema"
 	"github.com/fuhongbo/qlbridge/value"
 )
 
@@ -79,10 +80,23 @@ func RewriteShowAsSelect(stmt *rel.SqlShow, ctx *Context) (*rel.SqlSelect, error
 			sqlStatement = "select Table from `schema`.`tables`;"
 		}
 	case "create":
-		// SHOW CREATE {TABLE | DATABASE | EVENT | VIEW }
+		// SHOW CREATE {TABLE | DATABASE | EVENT | VIEW } [WITH dialect = "postgres"]
+		dialect := "mysql"
+		if d := stmt.With.String("dialect"); d != "" {
+			writer, ok := schema.DialectWriterGet(d)
+			if !ok {
+				return nil, fmt.Errorf("Unsupported show create dialect %q", d)
+			}
+			dialect = writer.Dialect()
+		}
 		switch strings.ToLower(stmt.CreateWhat) {
 		case "table":
-			sqlStatement = fmt.Sprintf("select Table , mysql_create as `Create Table` FROM `schema`.`%s`", from)
+			sqlStatement = fmt.Sprintf("select Table , %s_create as `Create Table` FROM `schema`.`%s`", dialect, from)
+			vn := expr.NewStringNode(stmt.Identity)
+			lh := expr.NewIdentityNodeVal("Table")
+			stmt.Where = expr.NewBinaryNode(lex.Token{T: lex.TokenEqual, V: "="}, lh, vn)
+		case "view":
+			sqlStatement = fmt.Sprintf("select Table as View, %s_create as `Create View` FROM `schema`.`%s`", dialect, from)
 			vn := expr.NewStringNode(stmt.Identity)
 			lh := expr.NewIdentityNodeVal("Table")
 			stmt.Where = expr.NewBinaryNode(lex.Token{T: lex.TokenEqual, V: "="}, lh, vn)
@@ -131,6 +145,17 @@ func RewriteShowAsSelect(stmt *rel.SqlShow, ctx *Context) (*rel.SqlSelect, error
 
 		*/
 		sqlStatement = fmt.Sprintf("select Table, Non_unique, Key_name, Seq_in_index, Column_name, Collation, Cardinality, Sub_part, Packed, `Null`, Index_type, Index_comment from `schema`.`indexes`;")
+		if stmt.Identity != "" {
+			// SHOW INDEX FROM tbl_name [WHERE expr]
+			var tn expr.Node = expr.NewBinaryNode(lex.Token{T: lex.TokenEqual, V: "="},
+				expr.NewIdentityNodeVal("Table"), expr.NewStringNode(stmt.Identity))
+			for _, cond := range []expr.Node{stmt.Where, stmt.Like} {
+				if cond != nil {
+					tn = expr.NewBinaryNode(lex.Token{T: lex.TokenLogicAnd, V: "AND"}, tn, cond)
+				}
+			}
+			stmt.Where, stmt.Like = tn, nil
+		}
 
 	case "variables":
 		// SHOW [GLOBAL | SESSION] VARIABLES [like_or_where]
@@ -155,12 +180,7 @@ func RewriteShowAsSelect(stmt *rel.SqlShow, ctx *Context) (*rel.SqlSelect, error
 		// http://dev.mysql.com/doc/refman/5.7/en/server-status-variables.html
 
 		// SHOW [GLOBAL | SESSION | SLAVE ] STATUS [like_or_where]
-		scope := stmt.Scope
-		switch scope {
-		case "session", "":
-			scope = "session"
-		}
-		sqlStatement = fmt.Sprintf("select Variable_name, Value from `context`.`%s_variables`;", scope)
+		sqlStatement = "select Variable_name, Value from `context`.`status`;"
 		/*
 			mysql> show global status;
 			+--------------------------------+-----------------+
@@ -194,6 +214,7 @@ func RewriteShowAsSelect(stmt *rel.SqlShow, ctx *Context) (*rel.SqlSelect, error
 		/*
 			show procuedure status;
 			show function status;
+			show functions;
 
 				| Db  | Name | Type | Definer | Modified | Created | Security_type | Comment| character_set_client | collation_connection | Database Collation |
 		*/
@@ -0,0 +1,359 @@
+// Package qlbhttp implements a net/http handler exposing qlbridge sql
+// execution as a json api.  Sql (plus named parameters and schema) is POSTed
+// as json, result rows are streamed back as they are produced by the
+// exec.ResultWriter, either as newline delimited json or a chunked json array.
+// Errors, row counts, and timing are reported in http trailers.
+package qlbhttp
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	u "github.com/araddon/gou"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/exec"
+	"github.com/fuhongbo/qlbridge/expr"
+	"github.com/fuhongbo/qlbridge/plan"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+const (
+	// FormatNDJson newline delimited json, one json object per row.
+	FormatNDJson = "ndjson"
+	// FormatJson a single (chunked) json array of row objects.
+	FormatJson = "json"
+
+	// TrailerError http trailer holding error message if query failed
+	// after the response was started.
+	TrailerError = "Qlb-Error"
+	// TrailerRowCount http trailer for count of rows written, or affected
+	// for statements that do not return rows.
+	TrailerRowCount = "Qlb-Row-Count"
+	// TrailerElapsed http trailer for duration of query in milliseconds.
+	TrailerElapsed = "Qlb-Elapsed-Ms"
+)
+
+var (
+	_ = u.EMPTY
+
+	// Ensure our handler is an http.Handler
+	_ http.Handler = (*Handler)(nil)
+)
+
+type (
+	// QueryRequest is the json body accepted by the Handler.
+	//
+	//   {
+	//     "sql":"SELECT user_id, email FROM users WHERE email = @email",
+	//     "schema":"mockcsv",
+	//     "params":{"email":"bob@email.com"},
+	//     "format":"ndjson"
+	//   }
+	//
+	// Params are written into the session so are referenced as @name in sql.
+	QueryRequest struct {
+		Sql    string                 `json:"sql"`
+		Schema string                 `json:"schema"`
+		Params map[string]interface{} `json:"params"`
+		Format string                 `json:"format"`
+	}
+	// Handler is an http.Handler for running sql against schemas
+	// found in a schema.Registry.
+	Handler struct {
+		// DefaultSchema is used if request does not specify a schema.
+		DefaultSchema string
+		reg           *schema.Registry
+	}
+	// errorResponse is the json body for errors that occur before
+	// any rows have been written.
+	errorResponse struct {
+		Error string `json:"error"`
+	}
+)
+
+// NewHandler create a new sql http handler using given registry
+// to find schemas.
+func NewHandler(reg *schema.Registry) *Handler {
+	return &Handler{reg: reg}
+}
+
+// ServeHTTP implement http.Handler.
+func (m *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	req := &QueryRequest{}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("could not read request json: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Sql) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no sql provided"))
+		return
+	}
+	format, err := requestFormat(r, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	schemaName := req.Schema
+	if schemaName == "" {
+		schemaName = m.DefaultSchema
+	}
+	s, ok := m.reg.Schema(strings.ToLower(schemaName))
+	if !ok || s == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no schema was found for %q", schemaName))
+		return
+	}
+
+	ctx := plan.NewContext(req.Sql)
+	ctx.Context = r.Context()
+	ctx.Schema = s
+	ctx.Session = datasource.NewMySqlSessionVars()
+	for name, v := range req.Params {
+		if err := ctx.Session.Put(paramKey(name), nil, paramValue(v)); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid param %q: %v", name, err))
+			return
+		}
+	}
+
+	started := time.Now()
+	job, err := exec.BuildSqlJob(ctx)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cols, hasRows, err := exec.ResultColumns(job.Ctx)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !hasRows {
+		m.runExec(w, job, started)
+		return
+	}
+	m.runQuery(w, r, job, cols, format, started)
+}
+
+// runExec run statements that do not return rows (insert, update, ddl).
+func (m *Handler) runExec(w http.ResponseWriter, job *exec.JobExecutor, started time.Time) {
+
+	resultWriter := exec.NewResultExecWriter(job.Ctx)
+	job.RootTask.Add(resultWriter)
+	if err := job.Setup(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err := job.Run()
+	job.Close()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	affected, _ := resultWriter.Result().RowsAffected()
+
+	declareTrailers(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"affected": affected})
+	writeTrailers(w, nil, affected, started)
+}
+
+// runQuery run statements returning rows (select, insert ... returning)
+// streaming rows to response as they arrive.
+func (m *Handler) runQuery(w http.ResponseWriter, r *http.Request, job *exec.JobExecutor,
+	cols []string, format string, started time.Time) {
+
+	resultWriter := exec.NewResultRows(job.Ctx, cols)
+	job.RootTask.Add(resultWriter)
+	if err := job.Setup(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	jobErr := make(chan error, 1)
+	go func() {
+		err := job.Run()
+		job.Close()
+		jobErr <- err
+	}()
+
+	// If the client goes away, stop the job.
+	go func() {
+		select {
+		case <-r.Context().Done():
+			resultWriter.Close()
+		case <-resultWriter.SigChan():
+		}
+	}()
+
+	declareTrailers(w)
+	switch format {
+	case FormatJson:
+		w.Header().Set("Content-Type", "application/json")
+	default:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	if format == FormatJson {
+		io.WriteString(w, "[")
+	}
+
+	var rowCt int64
+	var err error
+	buf := &bytes.Buffer{}
+	dest := make([]driver.Value, len(cols))
+	for {
+		for i := range dest {
+			dest[i] = nil
+		}
+		if err = resultWriter.Next(dest); err != nil {
+			break
+		}
+		buf.Reset()
+		if format == FormatJson && rowCt > 0 {
+			buf.WriteByte(',')
+		}
+		if err = writeRow(buf, cols, dest); err != nil {
+			break
+		}
+		if format != FormatJson {
+			buf.WriteByte('\n')
+		}
+		if _, err = w.Write(buf.Bytes()); err != nil {
+			break
+		}
+		rowCt++
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if err == io.EOF || err == exec.ErrShuttingDown {
+		err = nil
+	}
+	resultWriter.Close()
+	job.Close()
+	if runErr := <-jobErr; err == nil && runErr != nil {
+		err = runErr
+	}
+	if err == nil && len(job.Ctx.Errors) > 0 {
+		err = job.Ctx.Errors[0]
+	}
+	if err == nil {
+		err = r.Context().Err()
+	}
+
+	if format == FormatJson {
+		io.WriteString(w, "]")
+	}
+	writeTrailers(w, err, rowCt, started)
+}
+
+// requestFormat determine output format from json body, url query
+// param ?format=json or Accept header in that order.
+func requestFormat(r *http.Request, req *QueryRequest) (string, error) {
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = strings.ToLower(r.URL.Query().Get("format"))
+	}
+	if format == "" {
+		accept := r.Header.Get("Accept")
+		if strings.Contains(accept, "application/json") && !strings.Contains(accept, "ndjson") {
+			format = FormatJson
+		}
+	}
+	switch format {
+	case "":
+		return FormatNDJson, nil
+	case FormatNDJson, FormatJson:
+		return format, nil
+	}
+	return "", fmt.Errorf("unrecognized format %q, expected one of [ndjson, json]", format)
+}
+
+// paramKey named params are session variables, ensure @ prefix.
+func paramKey(name string) expr.SchemaInfoString {
+	if !strings.HasPrefix(name, "@") {
+		name = "@" + name
+	}
+	return expr.SchemaInfoString(name)
+}
+
+// paramValue convert json param into a value, json numbers are
+// converted to int if possible.
+func paramValue(v interface{}) value.Value {
+	if n, ok := v.(json.Number); ok {
+		if iv, err := n.Int64(); err == nil {
+			return value.NewIntValue(iv)
+		}
+		if fv, err := n.Float64(); err == nil {
+			return value.NewNumberValue(fv)
+		}
+		return value.NewStringValue(n.String())
+	}
+	return value.NewValue(v)
+}
+
+// writeRow write a single row as json object preserving column order.
+func writeRow(buf *bytes.Buffer, cols []string, row []driver.Value) error {
+	buf.WriteByte('{')
+	for i, col := range cols {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, err := json.Marshal(col)
+		if err != nil {
+			return err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		var v interface{} = row[i]
+		if by, isBytes := v.([]byte); isBytes {
+			v = string(by)
+		}
+		vb, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func declareTrailers(w http.ResponseWriter) {
+	w.Header().Set("Trailer", strings.Join([]string{TrailerError, TrailerRowCount, TrailerElapsed}, ", "))
+}
+
+func writeTrailers(w http.ResponseWriter, err error, rowCt int64, started time.Time) {
+	if err != nil {
+		w.Header().Set(TrailerError, err.Error())
+	}
+	w.Header().Set(TrailerRowCount, strconv.FormatInt(rowCt, 10))
+	w.Header().Set(TrailerElapsed, strconv.FormatInt(int64(time.Since(started)/time.Millisecond), 10))
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&errorResponse{Error: err.Error()})
+}
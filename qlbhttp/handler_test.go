@@ -0,0 +1,174 @@
+package qlbhttp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/datasource/memdb"
+	td "github.com/fuhongbo/qlbridge/datasource/mockcsvtestdata"
+	"github.com/fuhongbo/qlbridge/qlbhttp"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Setup() // will call flag.Parse()
+
+	// load our mock data sources "users", "orders"
+	td.LoadTestDataOnce()
+
+	// Now run the actual Tests
+	os.Exit(m.Run())
+}
+
+func postQuery(t *testing.T, srv *httptest.Server, req *qlbhttp.QueryRequest) *http.Response {
+	by, err := json.Marshal(req)
+	assert.Equal(t, nil, err)
+	resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(by))
+	assert.Equal(t, nil, err)
+	return resp
+}
+
+func TestHandlerNDJson(t *testing.T) {
+	srv := httptest.NewServer(qlbhttp.NewHandler(schema.DefaultRegistry()))
+	defer srv.Close()
+
+	resp := postQuery(t, srv, &qlbhttp.QueryRequest{
+		Sql:    `SELECT user_id, email, referral_count FROM users WHERE email = @email`,
+		Schema: "mockcsv",
+		Params: map[string]interface{}{"email": "bob@email.com"},
+	})
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	rows := make([]map[string]interface{}, 0)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		row := make(map[string]interface{})
+		assert.Equal(t, nil, json.Unmarshal(scanner.Bytes(), &row), "line %s", scanner.Text())
+		rows = append(rows, row)
+	}
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "hT2impsOPUREcVPc", rows[0]["user_id"])
+	assert.Equal(t, "12", rows[0]["referral_count"])
+
+	// Trailers are only available after body is read
+	assert.Equal(t, "", resp.Trailer.Get(qlbhttp.TrailerError))
+	assert.Equal(t, "1", resp.Trailer.Get(qlbhttp.TrailerRowCount))
+	assert.NotEqual(t, "", resp.Trailer.Get(qlbhttp.TrailerElapsed))
+}
+
+func TestHandlerJsonArray(t *testing.T) {
+	srv := httptest.NewServer(qlbhttp.NewHandler(schema.DefaultRegistry()))
+	defer srv.Close()
+
+	resp := postQuery(t, srv, &qlbhttp.QueryRequest{
+		Sql:    `SELECT user_id, email FROM users WHERE referral_count > @ct`,
+		Schema: "mockcsv",
+		Params: map[string]interface{}{"ct": 10},
+		Format: qlbhttp.FormatJson,
+	})
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	by, err := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, nil, err)
+	rows := make([]map[string]interface{}, 0)
+	assert.Equal(t, nil, json.Unmarshal(by, &rows), "body %s", string(by))
+	assert.Equal(t, 3, len(rows))
+	// column order preserved
+	assert.True(t, strings.HasPrefix(string(by), `[{"user_id":`), string(by))
+	assert.Equal(t, "3", resp.Trailer.Get(qlbhttp.TrailerRowCount))
+}
+
+func TestHandlerRecorder(t *testing.T) {
+	h := qlbhttp.NewHandler(schema.DefaultRegistry())
+	h.DefaultSchema = "mockcsv"
+
+	body := `{"sql":"SELECT user_id FROM users WHERE user_id = \"9Ip1aKbeZe2njCDM\""}`
+	req := httptest.NewRequest("POST", "/query?format=json", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	resp := rec.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `[{"user_id":"9Ip1aKbeZe2njCDM"}]`, rec.Body.String())
+	assert.Equal(t, "1", resp.Trailer.Get(qlbhttp.TrailerRowCount))
+}
+
+func TestHandlerStar(t *testing.T) {
+	h := qlbhttp.NewHandler(schema.DefaultRegistry())
+	h.DefaultSchema = "mockcsv"
+
+	// the columns of * are those of the table
+	body := `{"sql":"SELECT * FROM users WHERE user_id = \"9Ip1aKbeZe2njCDM\""}`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	row := make(map[string]interface{})
+	assert.Equal(t, nil, json.Unmarshal(rec.Body.Bytes(), &row), "body %s", rec.Body.String())
+	assert.Equal(t, "9Ip1aKbeZe2njCDM", row["user_id"])
+	assert.Equal(t, "aaron@email.com", row["email"])
+	_, hasEmpty := row[""]
+	assert.False(t, hasEmpty, rec.Body.String())
+}
+
+func TestHandlerReturning(t *testing.T) {
+	assert.Equal(t, nil, schema.RegisterSourceAsSchema("qlbhttp_returning", memdb.NewSource()))
+	h := qlbhttp.NewHandler(schema.DefaultRegistry())
+	h.DefaultSchema = "qlbhttp_returning"
+
+	post := func(sql string) *httptest.ResponseRecorder {
+		by, err := json.Marshal(&qlbhttp.QueryRequest{Sql: sql, Format: qlbhttp.FormatJson})
+		assert.Equal(t, nil, err)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", bytes.NewReader(by)))
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		return rec
+	}
+	post(`CREATE TABLE counters (id int, name varchar(50), n int, PRIMARY KEY (id))`)
+
+	// the rows written are returned rather than the count affected
+	rec := post(`INSERT INTO counters (id, name, n) VALUES (1, "a", 10), (2, "b", 20) RETURNING id, n * 2 AS double`)
+	assert.Equal(t, `[{"id":1,"double":20},{"id":2,"double":40}]`, rec.Body.String())
+	assert.Equal(t, "2", rec.Result().Trailer.Get(qlbhttp.TrailerRowCount))
+
+	rec = post(`INSERT INTO counters (id, name, n) VALUES (3, "c", 30)`)
+	assert.Equal(t, `{"affected":1}`, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := qlbhttp.NewHandler(schema.DefaultRegistry())
+
+	tests := []struct {
+		method string
+		body   string
+		code   int
+	}{
+		{"GET", ``, http.StatusMethodNotAllowed},
+		{"POST", `not json`, http.StatusBadRequest},
+		{"POST", `{"schema":"mockcsv"}`, http.StatusBadRequest},
+		{"POST", `{"sql":"SELECT 1","schema":"not_a_schema"}`, http.StatusNotFound},
+		{"POST", `{"sql":"NOTASTATEMENT x","schema":"mockcsv"}`, http.StatusBadRequest},
+		{"POST", `{"sql":"SELECT 1","schema":"mockcsv","format":"xml"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, tt.code, rec.Code, "body=%s resp=%s", tt.body, rec.Body.String())
+		errResp := make(map[string]string)
+		assert.Equal(t, nil, json.Unmarshal(rec.Body.Bytes(), &errResp))
+		assert.NotEqual(t, "", errResp["error"])
+	}
+}
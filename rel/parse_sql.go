@@ -105,6 +105,8 @@ func (m *Sqlbridge) parse() (SqlStatement, error) {
 		return m.parsePrepare()
 	case lex.TokenSelect:
 		return m.parseSqlSelect()
+	case lex.TokenSubscribe:
+		return m.parseSubscribe()
 	case lex.TokenInsert, lex.TokenReplace:
 		return m.parseSqlInsert()
 	case lex.TokenUpdate:
@@ -121,10 +123,14 @@ func (m *Sqlbridge) parse() (SqlStatement, error) {
 		return m.parseCommand()
 	case lex.TokenRollback, lex.TokenCommit:
 		return m.parseTransaction()
+	case lex.TokenRefresh:
+		return m.parseRefresh()
 	case lex.TokenCreate:
 		return m.parseCreate()
 	case lex.TokenDrop:
 		return m.parseDrop()
+	case lex.TokenAlter:
+		return m.parseAlter()
 	}
 	return nil, fmt.Errorf("Unrecognized request type: %v", m.l.PeekWord())
 }
@@ -166,6 +172,21 @@ func discardComments(m expr.TokenPager) {
 	}
 }
 
+// First keyword was SUBSCRIBE, SUBSCRIBE SELECT ... is a select that keeps
+// emitting rows as its sources are appended to, until cancelled.
+func (m *Sqlbridge) parseSubscribe() (*SqlSelect, error) {
+	m.Next() // Consume Subscribe
+	if m.Cur().T != lex.TokenSelect {
+		return nil, fmt.Errorf("expected SELECT after SUBSCRIBE but got: %v", m.Cur())
+	}
+	req, err := m.parseSqlSelect()
+	if err != nil {
+		return nil, err
+	}
+	req.Subscribe = true
+	return req, nil
+}
+
 // First keyword was SELECT, so use the SELECT parser rule-set
 func (m *Sqlbridge) parseSqlSelect() (*SqlSelect, error) {
 
@@ -263,7 +284,8 @@ func (m *Sqlbridge) parseSqlSelect() (*SqlSelect, error) {
 		return nil, err
 	}
 
-	if m.Cur().T == lex.TokenEOF || m.Cur().T == lex.TokenEOS || m.Cur().T == lex.TokenRightParenthesis {
+	if m.Cur().T == lex.TokenEOF || m.Cur().T == lex.TokenEOS || m.Cur().T == lex.TokenRightParenthesis ||
+		m.isInsertSelectEnd() {
 
 		if err := req.Finalize(); err != nil {
 			return nil, err
@@ -277,6 +299,16 @@ func (m *Sqlbridge) parseSqlSelect() (*SqlSelect, error) {
 	return nil, fmt.Errorf("Did not complete parsing input: %v", m.LexTokenPager.Cur().V)
 }
 
+// isInsertSelectEnd is this the ON CONFLICT or RETURNING following the
+// select of an INSERT ... SELECT
+func (m *Sqlbridge) isInsertSelectEnd() bool {
+	switch m.firstToken.T {
+	case lex.TokenInsert, lex.TokenReplace:
+		return m.Cur().T == lex.TokenOn || m.Cur().T == lex.TokenReturning
+	}
+	return false
+}
+
 // First keyword was INSERT, REPLACE
 func (m *Sqlbridge) parseSqlInsert() (*SqlInsert, error) {
 
@@ -300,14 +332,16 @@ func (m *Sqlbridge) parseSqlInsert() (*SqlInsert, error) {
 		return nil, fmt.Errorf("expected table name but got : %v", m.Cur().V)
 	}
 
-	// list of fields
-	cols, err := m.parseFieldList()
-	if err != nil {
-		return nil, err
+	// list of fields, optional for INSERT INTO t SELECT ...
+	if m.Cur().T != lex.TokenSelect {
+		cols, err := m.parseFieldList()
+		if err != nil {
+			return nil, err
+		}
+		req.Columns = cols
+		m.Next() // left paren starts lisf of values
 	}
-	req.Columns = cols
 
-	m.Next() // left paren starts lisf of values
 	switch m.Cur().T {
 	case lex.TokenValues:
 		m.Next() // Consume Values keyword
@@ -320,7 +354,7 @@ func (m *Sqlbridge) parseSqlInsert() (*SqlInsert, error) {
 			return nil, m.ErrMsg("Expected FROM <sources>")
 		}
 		req.Select = sel
-		return req, nil
+		return m.parseInsertConflict(req)
 	default:
 		return nil, m.ErrMsg("expected INSERT (columns) VALUES <values>")
 	}
@@ -330,9 +364,154 @@ func (m *Sqlbridge) parseSqlInsert() (*SqlInsert, error) {
 		return nil, err
 	}
 	req.Rows = colVals
+	return m.parseInsertConflict(req)
+}
+
+// parseInsertConflict the optional ON CONFLICT / ON DUPLICATE KEY UPDATE
+// and RETURNING clauses at the end of an INSERT.
+func (m *Sqlbridge) parseInsertConflict(req *SqlInsert) (*SqlInsert, error) {
+
+	if m.Cur().T == lex.TokenOn {
+		m.Next() // Consume ON
+		oc := &SqlOnConflict{}
+		switch m.Cur().T {
+		case lex.TokenDuplicate:
+			m.Next()
+			if m.Cur().T != lex.TokenKey {
+				return nil, m.ErrMsg("expected ON DUPLICATE KEY UPDATE")
+			}
+			m.Next()
+			if m.Cur().T != lex.TokenUpdate {
+				return nil, m.ErrMsg("expected ON DUPLICATE KEY UPDATE")
+			}
+			oc.Duplicate = true
+		case lex.TokenConflict:
+			m.Next()
+			if m.Cur().T == lex.TokenLeftParenthesis {
+				m.Next()
+			targetLoop:
+				for {
+					switch m.Cur().T {
+					case lex.TokenIdentity:
+						oc.Columns = append(oc.Columns, m.Cur().V)
+					case lex.TokenComma:
+					case lex.TokenRightParenthesis:
+						m.Next()
+						break targetLoop
+					default:
+						return nil, m.ErrMsg("expected ON CONFLICT (columns)")
+					}
+					m.Next()
+				}
+			}
+			if m.Cur().T != lex.TokenDo {
+				return nil, m.ErrMsg("expected DO NOTHING or DO UPDATE")
+			}
+			m.Next()
+			switch m.Cur().T {
+			case lex.TokenNothing:
+				oc.DoNothing = true
+			case lex.TokenUpdate:
+			default:
+				return nil, m.ErrMsg("expected DO NOTHING or DO UPDATE")
+			}
+		default:
+			return nil, m.ErrMsg("expected ON CONFLICT or ON DUPLICATE KEY UPDATE")
+		}
+		m.Next()
+
+		if !oc.DoNothing {
+			if m.Cur().T == lex.TokenSet {
+				m.Next()
+			}
+			funcs := m.funcs
+			if funcs != nil {
+				m.funcs = conflictFuncs{funcs}
+			}
+			vals, err := m.parseUpdateList()
+			m.funcs = funcs
+			if err != nil {
+				return nil, err
+			}
+			if len(vals) == 0 {
+				return nil, m.ErrMsg("expected col = value after UPDATE")
+			}
+			for _, vc := range vals {
+				if vc.Expr != nil {
+					vc.Expr = rewriteConflictValues(vc.Expr)
+				}
+			}
+			oc.Values = vals
+		}
+		req.OnConflict = oc
+	}
+
+	if m.Cur().T == lex.TokenReturning {
+		m.Next() // Consume RETURNING
+		rc := &returningColumns{}
+		if err := parseColumns(m, m.funcs, rc); err != nil {
+			return nil, err
+		}
+		req.Returning = rc.cols
+	}
 	return req, nil
 }
 
+// returningColumns collects the RETURNING columns of a mutation.
+type returningColumns struct {
+	cols Columns
+}
+
+func (m *returningColumns) AddColumn(col Column) error {
+	col.Index = len(m.cols)
+	m.cols = append(m.cols, &col)
+	return nil
+}
+
+// conflictFuncs resolves the mysql VALUES(col) of ON DUPLICATE KEY UPDATE,
+// which is rewritten by rewriteConflictValues.
+type conflictFuncs struct {
+	expr.FuncResolver
+}
+
+func (m conflictFuncs) FuncGet(name string) (expr.Func, bool) {
+	if strings.EqualFold(name, "values") {
+		return expr.Func{Name: name, Eval: expr.EmptyEvalFunc}, true
+	}
+	return m.FuncResolver.FuncGet(name)
+}
+
+// rewriteConflictValues rewrite VALUES(col) to excluded.col, the value of
+// col in the row which was to be inserted.
+func rewriteConflictValues(n expr.Node) expr.Node {
+	switch nt := n.(type) {
+	case *expr.FuncNode:
+		if strings.EqualFold(nt.Name, "values") && len(nt.Args) == 1 {
+			if in, ok := nt.Args[0].(*expr.IdentityNode); ok {
+				return expr.NewIdentityNodeVal("excluded." + in.Text)
+			}
+		}
+		for i, arg := range nt.Args {
+			nt.Args[i] = rewriteConflictValues(arg)
+		}
+	case *expr.BinaryNode:
+		for i, arg := range nt.Args {
+			nt.Args[i] = rewriteConflictValues(arg)
+		}
+	case *expr.BooleanNode:
+		for i, arg := range nt.Args {
+			nt.Args[i] = rewriteConflictValues(arg)
+		}
+	case *expr.TriNode:
+		for i, arg := range nt.Args {
+			nt.Args[i] = rewriteConflictValues(arg)
+		}
+	case *expr.UnaryNode:
+		nt.Arg = rewriteConflictValues(nt.Arg)
+	}
+	return n
+}
+
 // First keyword was UPDATE
 func (m *Sqlbridge) parseSqlUpdate() (*SqlUpdate, error) {
 
@@ -550,13 +729,15 @@ func (m *Sqlbridge) parseShow() (*SqlShow, error) {
 
 		SHOW [FULL] COLUMNS FROM tbl_name [FROM db_name] [like_or_where]
 		SHOW CREATE DATABASE db_name
-		SHOW CREATE TABLE tbl_name
+		SHOW CREATE TABLE tbl_name [WITH dialect = "postgres"]
 		SHOW CREATE TRIGGER trigger_name
 		SHOW CREATE VIEW view_name
 		SHOW DATABASES [like_or_where]
 		SHOW ENGINE engine_name {STATUS | MUTEX}
 		SHOW [STORAGE] ENGINES
-		SHOW INDEX FROM tbl_name [FROM db_name]
+		SHOW {INDEX | INDEXES | KEYS} [{FROM | IN} tbl_name [{FROM | IN} db_name]] [WHERE expr]
+		SHOW FUNCTIONS [like_or_where]
+		SHOW {FUNCTION | PROCEDURE} STATUS [like_or_where]
 		SHOW [FULL] TABLES [FROM db_name] [like_or_where]
 		SHOW TRIGGERS [FROM db_name] [like_or_where]
 		SHOW [GLOBAL | SESSION] VARIABLES [like_or_where]
@@ -586,6 +767,11 @@ func (m *Sqlbridge) parseShow() (*SqlShow, error) {
 		//u.Debugf("create which %v", m.Cur())
 		if m.Cur().T == lex.TokenIdentity {
 			req.Identity = m.Next().V
+			with, err := ParseWith(m.SqlTokenPager)
+			if err != nil {
+				return nil, err
+			}
+			req.With = with
 			return req, nil
 		}
 		return nil, m.ErrMsg("Expected IDENTITY for SHOW CREATE {TABLE | DATABASE | EVENT} IDENTITY")
@@ -597,9 +783,18 @@ func (m *Sqlbridge) parseShow() (*SqlShow, error) {
 	case "databases":
 		req.ShowType = "databases"
 		m.Next()
-	case "indexes", "keys":
+	case "indexes", "index", "keys":
 		req.ShowType = "indexes"
+		likeLhs = "Key_name"
 		m.Next()
+		if m.Cur().T == lex.TokenFrom || m.Cur().T == lex.TokenIN {
+			if err := m.parseShowFromTable(req); err != nil {
+				return nil, err
+			}
+			if err := m.parseShowFromDatabase(req); err != nil {
+				return nil, err
+			}
+		}
 	case "variables":
 		req.ShowType = "variables"
 		likeLhs = "Variable_name"
@@ -613,13 +808,20 @@ func (m *Sqlbridge) parseShow() (*SqlShow, error) {
 		likeLhs = "Engine"
 		m.Next()
 	case "engines":
-		req.ShowType = "status"
+		req.ShowType = "engines"
 		likeLhs = "Engine"
 		m.Next()
 	case "procedure", "function":
 		req.ShowType = objectType
 		likeLhs = "Name"
 		m.Next()
+		if strings.ToLower(m.Cur().V) == "status" {
+			m.Next()
+		}
+	case "functions":
+		req.ShowType = "function"
+		likeLhs = "Name"
+		m.Next()
 	case "columns":
 		m.Next() // consume columns
 		likeLhs = "Field"
@@ -716,10 +918,24 @@ func (m *Sqlbridge) parseCreate() (*SqlCreate, error) {
 		}
 		req.OrReplace = true
 	}
-	// CREATE {DATABASE|SCHEMA|TABLE|VIEW|SOURCE|CONTINUOUSVIEW} <identity>
+	// CREATE {DATABASE|SCHEMA|TABLE|VIEW|SOURCE|CONTINUOUSVIEW|INDEX} <identity>
 	switch m.Cur().T {
-	case lex.TokenTable, lex.TokenSource, lex.TokenDatabase, lex.TokenSchema:
+	case lex.TokenTable, lex.TokenSource, lex.TokenDatabase, lex.TokenSchema, lex.TokenIndex:
 		req.Tok = m.Next()
+	case lex.TokenUnique:
+		m.Next() // Consume UNIQUE
+		if m.Cur().T != lex.TokenIndex {
+			return nil, m.ErrMsg("Expected CREATE UNIQUE INDEX")
+		}
+		req.Unique = true
+		req.Tok = m.Next()
+	case lex.TokenMaterialized:
+		m.Next() // Consume MATERIALIZED
+		if m.Cur().T != lex.TokenView {
+			return nil, m.ErrMsg("Expected CREATE [OR REPLACE] MATERIALIZED VIEW <identity> AS <select_stmt>")
+		}
+		req.Materialized = true
+		fallthrough
 	case lex.TokenView, lex.TokenContinuousView:
 		req.Tok = m.Next()
 		if m.Cur().T != lex.TokenIdentity {
@@ -741,6 +957,8 @@ func (m *Sqlbridge) parseCreate() (*SqlCreate, error) {
 		if err != nil {
 			return nil, err
 		}
+		// the trailing WITH describes the view not the select
+		req.With, sel.With = sel.With, nil
 		req.Select = sel
 		return req, nil
 	default:
@@ -782,16 +1000,44 @@ func (m *Sqlbridge) parseCreate() (*SqlCreate, error) {
 		}
 		req.Cols = cols
 
-		// ENGINE
+		// [ENGINE ...]
 		discardComments(m)
-		if strings.ToLower(m.Cur().V) != "engine" {
-			return nil, m.ErrMsg("Expected (cols) ENGINE ... ")
+		if strings.ToLower(m.Cur().V) == "engine" {
+			engine, err := ParseWith(m.SqlTokenPager)
+			if err != nil {
+				return nil, err
+			}
+			req.Engine = engine
 		}
-		engine, err := ParseWith(m.SqlTokenPager)
-		if err != nil {
-			return nil, err
+	case lex.TokenIndex:
+		// ON <table> (<col> [, <col>]*)
+		if m.Next().T != lex.TokenOn {
+			return nil, m.ErrMsg("Expected CREATE INDEX <identity> ON <table> (cols)")
+		}
+		if m.Cur().T != lex.TokenIdentity {
+			return nil, m.ErrMsg("Expected CREATE INDEX <identity> ON <table> (cols)")
+		}
+		req.Table = m.Next().V
+		if m.Next().T != lex.TokenLeftParenthesis {
+			return nil, m.ErrMsg("Expected CREATE INDEX <identity> ON <table> (cols)")
+		}
+	IndexColLoop:
+		for {
+			switch m.Cur().T {
+			case lex.TokenIdentity:
+				req.Cols = append(req.Cols, &DdlColumn{Name: strings.ToLower(m.Next().V), Kw: lex.TokenIdentity})
+			case lex.TokenComma:
+				m.Next()
+			case lex.TokenRightParenthesis:
+				m.Next()
+				break IndexColLoop
+			default:
+				return nil, m.ErrMsg("Expected CREATE INDEX <identity> ON <table> (cols)")
+			}
+		}
+		if len(req.Cols) == 0 {
+			return nil, m.ErrMsg("Expected at least one column for CREATE INDEX")
 		}
-		req.Engine = engine
 	case lex.TokenSource:
 		// just with
 	case lex.TokenSchema:
@@ -824,6 +1070,15 @@ func (m *Sqlbridge) parseDrop() (*SqlDrop, error) {
 		req.Temp = true
 	}
 
+	// DROP MATERIALIZED VIEW x
+	if m.Cur().T == lex.TokenMaterialized {
+		m.Next()
+		if m.Cur().T != lex.TokenView {
+			return nil, m.ErrMsg("Expected DROP MATERIALIZED VIEW <identity>")
+		}
+		req.Materialized = true
+	}
+
 	// DROP (TABLE|VIEW|SOURCE|CONTINUOUSVIEW) <identity>
 	switch m.Cur().T {
 	case lex.TokenTable, lex.TokenView, lex.TokenSource, lex.TokenContinuousView,
@@ -836,6 +1091,15 @@ func (m *Sqlbridge) parseDrop() (*SqlDrop, error) {
 		return nil, m.ErrMsg("Expected view, database,schema, table, source, continuousview for DROP got")
 	}
 
+	// [IF EXISTS]
+	if m.Cur().T == lex.TokenIf {
+		m.Next() // Consume IF
+		if m.Next().T != lex.TokenExists {
+			return nil, m.ErrMsg("Expected DROP {TABLE|SCHEMA|DATABASE} IF EXISTS <identity>")
+		}
+		req.IfExists = true
+	}
+
 	switch m.Cur().T {
 	case lex.TokenTable, lex.TokenIdentity:
 		req.Identity = m.Next().V
@@ -864,6 +1128,90 @@ func (m *Sqlbridge) parseDrop() (*SqlDrop, error) {
 	return req, nil
 }
 
+// First keyword was ALTER
+func (m *Sqlbridge) parseAlter() (*SqlAlter, error) {
+
+	req := NewSqlAlter()
+	m.Next() // Consume ALTER token
+	req.Raw = m.l.RawInput()
+
+	// ALTER TABLE <identity>
+	if m.Cur().T != lex.TokenTable {
+		return nil, m.ErrMsg("Expected ALTER TABLE <identity>")
+	}
+	req.Tok = m.Next()
+	if m.Cur().T != lex.TokenIdentity {
+		return nil, m.ErrMsg("Expected ALTER TABLE <identity>")
+	}
+	req.Identity = m.Next().V
+
+	/*
+		ADD [COLUMN] col_name column_definition [FIRST | AFTER col_name]
+		DROP [COLUMN] col_name
+		CHANGE [COLUMN] old_col_name new_col_name column_definition [FIRST | AFTER col_name]
+		MODIFY [COLUMN] col_name column_definition [FIRST | AFTER col_name]
+	*/
+	for {
+		discardComments(m)
+		col := &DdlColumn{Kw: lex.TokenIdentity}
+		switch m.Cur().T {
+		case lex.TokenAdd, lex.TokenDrop, lex.TokenChange, lex.TokenModify:
+			col.Op = m.Next().T
+		default:
+			return nil, m.ErrMsg("Expected ALTER TABLE <identity> {ADD|DROP|CHANGE|MODIFY}")
+		}
+		if m.Cur().T == lex.TokenColumn {
+			m.Next()
+		}
+		if m.Cur().T != lex.TokenIdentity {
+			return nil, m.ErrMsg("Expected column name")
+		}
+		col.Name = strings.ToLower(m.Next().V)
+		if col.Op == lex.TokenChange {
+			if m.Cur().T != lex.TokenIdentity {
+				return nil, m.ErrMsg("Expected CHANGE old_col_name new_col_name")
+			}
+			col.OldName = col.Name
+			col.Name = strings.ToLower(m.Next().V)
+		}
+		if col.Op != lex.TokenDrop {
+			if err := m.parseDdlColumn(col); err != nil {
+				return nil, err
+			}
+			switch m.Cur().T {
+			case lex.TokenFirst:
+				m.Next()
+				col.First = true
+			case lex.TokenAfter:
+				m.Next()
+				if m.Cur().T != lex.TokenIdentity {
+					return nil, m.ErrMsg("Expected AFTER col_name")
+				}
+				col.After = strings.ToLower(m.Next().V)
+			}
+			// CHARACTER SET is accepted but not kept
+			if m.Cur().T == lex.TokenCharacterSet {
+				m.Next()
+				m.Next()
+			}
+		}
+		req.Cols = append(req.Cols, col)
+		if m.Cur().T != lex.TokenComma {
+			break
+		}
+		m.Next()
+	}
+
+	// WITH
+	discardComments(m)
+	with, err := ParseWith(m.SqlTokenPager)
+	if err != nil {
+		return nil, err
+	}
+	req.With = with
+	return req, nil
+}
+
 func (m *Sqlbridge) parseTransaction() (*SqlCommand, error) {
 
 	// rollback, commit
@@ -873,6 +1221,22 @@ func (m *Sqlbridge) parseTransaction() (*SqlCommand, error) {
 	return req, nil
 }
 
+// First keyword was REFRESH
+func (m *Sqlbridge) parseRefresh() (*SqlCommand, error) {
+
+	// REFRESH MATERIALIZED VIEW <identity>
+	req := &SqlCommand{Columns: make(CommandColumns, 0)}
+	req.kw = m.Next().T
+	if m.Next().T != lex.TokenMaterialized || m.Next().T != lex.TokenView {
+		return nil, m.ErrMsg("Expected REFRESH MATERIALIZED VIEW <identity>")
+	}
+	if m.Cur().T != lex.TokenIdentity {
+		return nil, m.ErrMsg("Expected REFRESH MATERIALIZED VIEW <identity>")
+	}
+	req.Identity = m.Next().V
+	return req, nil
+}
+
 func parseColumns(m expr.TokenPager, fr expr.FuncResolver, stmt ColumnsStatement) error {
 
 	var col *Column
@@ -1039,40 +1403,83 @@ func (m *Sqlbridge) parseFieldList() (Columns, error) {
 func (m *Sqlbridge) parseUpdateList() (map[string]*ValueColumn, error) {
 
 	cols := make(map[string]*ValueColumn)
-	lastColName := ""
 	for {
 
-		//u.Debugf("col:%v    cur:%v", lastColName, m.Cur().String())
+		//u.Debugf("cur:%v", m.Cur().String())
 		switch m.Cur().T {
-		case lex.TokenWhere, lex.TokenLimit, lex.TokenEOS, lex.TokenEOF:
+		case lex.TokenWhere, lex.TokenLimit, lex.TokenWith, lex.TokenReturning, lex.TokenEOS, lex.TokenEOF:
 			return cols, nil
-		case lex.TokenValue:
-			cols[lastColName] = &ValueColumn{Value: value.NewStringValue(m.Cur().V)}
-		case lex.TokenInteger:
-			iv, _ := strconv.ParseInt(m.Cur().V, 10, 64)
-			cols[lastColName] = &ValueColumn{Value: value.NewIntValue(iv)}
-		case lex.TokenComma, lex.TokenEqual:
-			// don't need to do anything
+		case lex.TokenComma:
+			m.Next()
+			continue
 		case lex.TokenIdentity:
-			// TODO:  this is a bug in lexer
-			lv := m.Cur().V
-			if bv, err := strconv.ParseBool(lv); err == nil {
-				cols[lastColName] = &ValueColumn{Value: value.NewBoolValue(bv)}
-			} else {
-				lastColName = m.Cur().V
-			}
-		case lex.TokenUdfExpr:
-			exprNode, err := expr.ParseExprWithFuncs(m, m.funcs)
-			if err != nil {
-				return nil, err
-			}
-			cols[lastColName] = &ValueColumn{Expr: exprNode}
+			// column name
 		default:
 			u.Warnf("don't know how to handle ?  %v", m.Cur())
 			return nil, m.ErrMsg("expected column")
 		}
+		colName := m.Cur().V
 		m.Next()
+		if m.Cur().T != lex.TokenEqual {
+			return nil, m.ErrMsg("expected = after column")
+		}
+		m.Next() // Consume =
+
+		// a single literal value is kept as a value, anything else
+		// such as SET n = n + 1 is an expression evaluated per row.
+		if isUpdateListEnd(m.Peek().T) {
+			var val value.Value
+			switch m.Cur().T {
+			case lex.TokenValue:
+				val = value.NewStringValue(m.Cur().V)
+			case lex.TokenInteger:
+				iv, _ := strconv.ParseInt(m.Cur().V, 10, 64)
+				val = value.NewIntValue(iv)
+			case lex.TokenFloat:
+				fv, err := strconv.ParseFloat(m.Cur().V, 64)
+				if err != nil {
+					return nil, err
+				}
+				val = value.NewNumberValue(fv)
+			case lex.TokenBool:
+				bv, err := strconv.ParseBool(m.Cur().V)
+				if err != nil {
+					return nil, err
+				}
+				val = value.NewBoolValue(bv)
+			case lex.TokenNull:
+				val = value.NewNilValue()
+			case lex.TokenIdentity:
+				// TODO:  this is a bug in lexer
+				lv := m.Cur().V
+				if bv, err := strconv.ParseBool(lv); err == nil {
+					val = value.NewBoolValue(bv)
+				} else if strings.EqualFold(lv, "null") {
+					val = value.NewNilValue()
+				}
+			}
+			if val != nil {
+				cols[colName] = &ValueColumn{Value: val}
+				m.Next()
+				continue
+			}
+		}
+		exprNode, err := expr.ParseExprWithFuncs(m, m.funcs)
+		if err != nil {
+			return nil, err
+		}
+		cols[colName] = &ValueColumn{Expr: exprNode}
+	}
+}
+
+// isUpdateListEnd is @t the end of a single SET col = value
+func isUpdateListEnd(t lex.TokenType) bool {
+	switch t {
+	case lex.TokenComma, lex.TokenWhere, lex.TokenLimit, lex.TokenWith, lex.TokenReturning,
+		lex.TokenEOS, lex.TokenEOF:
+		return true
 	}
+	return false
 }
 
 func (m *Sqlbridge) parseValueList() ([][]*ValueColumn, error) {
@@ -1095,8 +1502,11 @@ func (m *Sqlbridge) parseValueList() ([][]*ValueColumn, error) {
 			}
 			row = make([]*ValueColumn, 0)
 		case lex.TokenRightParenthesis:
+			// end of row
 			values = append(values, row)
-		case lex.TokenFrom, lex.TokenInto, lex.TokenLimit, lex.TokenEOS, lex.TokenEOF:
+			row = nil
+		case lex.TokenFrom, lex.TokenInto, lex.TokenLimit, lex.TokenEOS, lex.TokenEOF,
+			lex.TokenOn, lex.TokenReturning:
 			if len(row) > 0 {
 				values = append(values, row)
 			}
@@ -1126,6 +1536,8 @@ func (m *Sqlbridge) parseValueList() ([][]*ValueColumn, error) {
 			lv := m.Cur().V
 			if bv, err := strconv.ParseBool(lv); err == nil {
 				row = append(row, &ValueColumn{Value: value.NewBoolValue(bv)})
+			} else if strings.EqualFold(lv, "null") {
+				row = append(row, &ValueColumn{Value: value.NewNilValue()})
 			} else {
 				// error?
 				u.Warnf("Could not figure out how to use: %v", m.Cur())
@@ -1139,6 +1551,8 @@ func (m *Sqlbridge) parseValueList() ([][]*ValueColumn, error) {
 			}
 			row = append(row, &ValueColumn{Value: arrayVal})
 			u.Infof("what is token?  %v peek:%v", m.Cur(), m.Peek())
+		case lex.TokenNull:
+			row = append(row, &ValueColumn{Value: value.NewNilValue()})
 		case lex.TokenComma:
 			// don't need to do anything
 		case lex.TokenUdfExpr:
@@ -1680,7 +2094,7 @@ func (m *Sqlbridge) parseCreateCols() ([]*DdlColumn, error) {
 				return nil, err
 			}
 		case lex.TokenPrimary:
-			col = &DdlColumn{Kw: m.Next().T}
+			col = &DdlColumn{Kw: m.Next().T, Key: lex.TokenPrimary}
 			if strings.ToLower(m.Next().V) != "key" {
 				return nil, m.ErrMsg("expected 'PRIMARY KEY'")
 			}
@@ -1696,16 +2110,9 @@ func (m *Sqlbridge) parseCreateCols() ([]*DdlColumn, error) {
 					m.Next() // consume )
 					break PrimaryKeyLoop
 				case lex.TokenIdentity:
-					col = &DdlColumn{Name: strings.ToLower(m.Next().V), Kw: lex.TokenIdentity}
-				case lex.TokenConstraint:
-					col = &DdlColumn{Kw: m.Next().T}
-				case lex.TokenPrimary:
-					col = &DdlColumn{Kw: m.Next().T}
-					if strings.ToLower(m.Cur().V) != "key" {
-						return nil, m.ErrMsg("expected 'PRIMARY KEY'")
-					}
+					col.IndexCols = append(col.IndexCols, strings.ToLower(m.Next().V))
+				case lex.TokenComma:
 					m.Next()
-
 				default:
 					return nil, m.ErrMsg("expected identity")
 				}
@@ -213,8 +213,9 @@ func TestSqlParseOnly(t *testing.T) {
 		INNER JOIN orders AS t3
 			ON t3.id = t2.fake_id;`)
 
+	parseSqlTest(t, `INSERT INTO events (id,event_date,event) SELECT id,last_logon,"last_logon" FROM users;`)
+	parseSqlTest(t, `INSERT INTO events SELECT id,last_logon FROM users WHERE id > 10;`)
 	// TODO:
-	//parseSqlTest(t, `INSERT INTO events (id,event_date,event) SELECT id,last_logon,"last_logon" FROM users;`)
 	// parseSqlTest(t, `REPLACE INTO tbl_3 (id,lastname) SELECT id,lastname FROM tbl_1;`)
 	parseSqlTest(t, `insert into mytable (id, str) values (0, "a")`)
 	parseSqlTest(t, `upsert into mytable (id, str) values (0, "a")`)
@@ -545,6 +546,14 @@ func TestSqlShowAst(t *testing.T) {
 	assert.True(t, show.Db == "dbx", "has SHOW db: %q", show.Db)
 	assert.True(t, show.Identity == "tablex", "has identity: %q", show.Identity)
 	assert.True(t, show.Like.String() == "Field LIKE \"%\"", "has Like? %q", show.Like.String())
+
+	sql = `SHOW CREATE TABLE users WITH dialect = "postgres";`
+	req, err = rel.ParseSql(sql)
+	assert.True(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	show, ok = req.(*rel.SqlShow)
+	assert.True(t, ok, "is SqlShow: %T", req)
+	assert.Equal(t, "users", show.Identity)
+	assert.Equal(t, "postgres", show.With.String("dialect"))
 }
 
 func TestSqlCommands(t *testing.T) {
@@ -604,6 +613,114 @@ func TestSqlUpsert(t *testing.T) {
 	//assert.True(t, sel.Alias == "user_query", "has alias: %v", sel.Alias)
 }
 
+func TestSqlInsertRows(t *testing.T) {
+	t.Parallel()
+	sql := `insert into users (id, str) values (0, "a"), (1, NULL)`
+	req, err := rel.ParseSql(sql)
+	assert.True(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	ins, ok := req.(*rel.SqlInsert)
+	assert.True(t, ok, "is SqlInsert: %T", req)
+	assert.Equal(t, 2, len(ins.Rows))
+	assert.Equal(t, 2, len(ins.Rows[1]))
+	assert.Equal(t, "a", ins.Rows[0][1].Value.Value())
+	assert.Equal(t, nil, ins.Rows[1][1].Value.Value())
+}
+
+func TestSqlInsertSelect(t *testing.T) {
+	t.Parallel()
+	sql := `INSERT INTO archive (id, name) SELECT user_id, name FROM users WHERE deleted = true`
+	req, err := rel.ParseSql(sql)
+	assert.True(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	ins, ok := req.(*rel.SqlInsert)
+	assert.True(t, ok, "is SqlInsert: %T", req)
+	assert.Equal(t, []string{"id", "name"}, ins.ColumnNames())
+	assert.Equal(t, 0, len(ins.Rows))
+	assert.NotEqual(t, nil, ins.Select)
+	assert.Equal(t, "users", ins.Select.From[0].Name)
+	assert.Equal(t, sql, ins.String())
+
+	sql = `INSERT INTO archive SELECT * FROM users`
+	req, err = rel.ParseSql(sql)
+	assert.Equal(t, nil, err)
+	ins = req.(*rel.SqlInsert)
+	assert.Equal(t, 0, len(ins.Columns))
+	assert.Equal(t, 1, len(ins.Select.Columns))
+	assert.Equal(t, sql, ins.String())
+}
+
+func TestSqlInsertOnConflict(t *testing.T) {
+	t.Parallel()
+	sql := `INSERT INTO counters (id, n) VALUES (1, 2) ON CONFLICT (id) DO UPDATE SET n = n + excluded.n`
+	req, err := rel.ParseSql(sql)
+	assert.True(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	ins, ok := req.(*rel.SqlInsert)
+	assert.True(t, ok, "is SqlInsert: %T", req)
+	assert.NotEqual(t, nil, ins.OnConflict)
+	assert.Equal(t, []string{"id"}, ins.OnConflict.Columns)
+	assert.False(t, ins.OnConflict.DoNothing)
+	assert.Equal(t, "n + excluded.n", ins.OnConflict.Values["n"].Expr.String())
+	assert.Equal(t, `INSERT INTO counters (id, n) VALUES (1 ,2) ON CONFLICT (id) DO UPDATE SET n = n + excluded.n`, ins.String())
+
+	sql = `INSERT INTO counters (id, n) VALUES (1, 2) ON CONFLICT DO NOTHING`
+	req, err = rel.ParseSql(sql)
+	assert.Equal(t, nil, err)
+	ins = req.(*rel.SqlInsert)
+	assert.True(t, ins.OnConflict.DoNothing)
+	assert.Equal(t, 0, len(ins.OnConflict.Columns))
+	assert.Equal(t, `INSERT INTO counters (id, n) VALUES (1 ,2) ON CONFLICT DO NOTHING`, ins.String())
+
+	// mysql VALUES(col) is the proposed row, same as excluded.col
+	req, err = rel.ParseSql(`INSERT INTO counters (id, n) VALUES (1, 2) ON DUPLICATE KEY UPDATE n = n + VALUES(n)`)
+	assert.Equal(t, nil, err)
+	ins = req.(*rel.SqlInsert)
+	assert.True(t, ins.OnConflict.Duplicate)
+	assert.Equal(t, "n + excluded.n", ins.OnConflict.Values["n"].Expr.String())
+	assert.Equal(t, `INSERT INTO counters (id, n) VALUES (1 ,2) ON DUPLICATE KEY UPDATE n = n + excluded.n`, ins.String())
+
+	// as in postgres the select needs a WHERE, else ON is read as a join condition
+	sql = `INSERT INTO archive (id, n) SELECT id, n FROM counters WHERE n > 0 ON CONFLICT (id) DO NOTHING RETURNING id, n * 2 AS double`
+	req, err = rel.ParseSql(sql)
+	assert.True(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	ins = req.(*rel.SqlInsert)
+	assert.NotEqual(t, nil, ins.Select)
+	assert.True(t, ins.OnConflict.DoNothing)
+	assert.Equal(t, 2, len(ins.Returning))
+	assert.Equal(t, "double", ins.Returning[1].As)
+	assert.Equal(t, sql, ins.String())
+
+	req, err = rel.ParseSql(`INSERT INTO counters (id, n) VALUES (1, 2) RETURNING *`)
+	assert.Equal(t, nil, err)
+	ins = req.(*rel.SqlInsert)
+	assert.True(t, ins.OnConflict == nil)
+	assert.True(t, ins.Returning[0].Star)
+
+	_, err = rel.ParseSql(`INSERT INTO counters (id, n) VALUES (1, 2) ON CONFLICT (id) DO UPDATE SET`)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSqlSubscribe(t *testing.T) {
+	t.Parallel()
+	sql := `SUBSCRIBE SELECT path, status FROM access_log WHERE status >= 500`
+	req, err := rel.ParseSql(sql)
+	assert.True(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel, ok := req.(*rel.SqlSelect)
+	assert.True(t, ok, "is SqlSelect: %T", req)
+	assert.True(t, sel.Subscribe)
+	assert.Equal(t, sql, sel.String())
+
+	sel2, err := rel.ParseSqlSelect(sel.String())
+	assert.Equal(t, nil, err)
+	assert.True(t, sel.Equal(sel2))
+
+	sel2, err = rel.ParseSqlSelect("SELECT path, status FROM access_log WHERE status >= 500")
+	assert.Equal(t, nil, err)
+	assert.False(t, sel2.Subscribe)
+	assert.False(t, sel.Equal(sel2))
+
+	_, err = rel.ParseSql("SUBSCRIBE DELETE FROM access_log")
+	assert.NotEqual(t, nil, err)
+}
+
 func TestSqlMultiStatement(t *testing.T) {
 	t.Parallel()
 	sql := `SET @var1 = "hello"; select a, b from accounts where name = @var1;`
@@ -629,6 +746,21 @@ func TestSqlUpdate(t *testing.T) {
 	assert.True(t, ok, "is SqlUpdate: %T", req)
 	assert.True(t, up.Table == "users", "has users: %v", up.Table)
 	assert.True(t, len(up.Values) == 2, "%v", up)
+	assert.Equal(t, true, up.Values["deleted"].Value.Value())
+
+	sql = `UPDATE counters SET n = n + 1, name = "big", score = 1.5 WHERE n >= 20`
+	req, err = rel.ParseSql(sql)
+	assert.Equal(t, nil, err)
+	up = req.(*rel.SqlUpdate)
+	assert.Equal(t, 3, len(up.Values))
+	assert.Equal(t, "n + 1", up.Values["n"].Expr.String())
+	assert.Equal(t, "big", up.Values["name"].Value.Value())
+	assert.Equal(t, 1.5, up.Values["score"].Value.Value())
+	assert.Equal(t, "n >= 20", up.Where.Expr.String())
+	assert.Equal(t, `UPDATE counters SET n = n + 1, name = "big", score = 1.5 WHERE n >= 20`, up.String())
+
+	_, err = rel.ParseSql(`UPDATE counters SET n n + 1`)
+	assert.NotEqual(t, nil, err)
 }
 
 func TestSqlCreate(t *testing.T) {
@@ -659,6 +791,90 @@ func TestSqlCreate(t *testing.T) {
 	assert.Equal(t, 150, c2.DataTypeSize, "%+v", c2)
 }
 
+func TestSqlCreateTableConstraints(t *testing.T) {
+	t.Parallel()
+	req, err := rel.ParseSql(`CREATE TABLE IF NOT EXISTS people (
+		id bigint NOT NULL,
+		name varchar(100) NOT NULL DEFAULT 'anon',
+		score float,
+		active bool DEFAULT true,
+		email text UNIQUE,
+		PRIMARY KEY (id)
+	);`)
+	assert.Equal(t, nil, err)
+	cs, ok := req.(*rel.SqlCreate)
+	assert.True(t, ok, "wanted SqlCreate got %T", req)
+	assert.True(t, cs.IfNotExists)
+	assert.Equal(t, 6, len(cs.Cols))
+	assert.Equal(t, false, cs.Cols[0].Null)
+	assert.Equal(t, "anon", cs.Cols[1].Default.(*expr.StringNode).Text)
+	assert.Equal(t, 100, cs.Cols[1].DataTypeSize)
+	assert.Equal(t, "float", cs.Cols[2].DataType)
+	assert.Equal(t, true, cs.Cols[2].Null)
+	assert.Equal(t, "bool", cs.Cols[3].DataType)
+	assert.Equal(t, lex.TokenUnique, cs.Cols[4].Key)
+	assert.Equal(t, lex.TokenPrimary, cs.Cols[5].Kw)
+	assert.Equal(t, []string{"id"}, cs.Cols[5].IndexCols)
+}
+
+func TestSqlAlter(t *testing.T) {
+	t.Parallel()
+	req, err := rel.ParseSql(`ALTER TABLE people ADD COLUMN age int NOT NULL DEFAULT 0 AFTER name,
+		DROP COLUMN email, CHANGE score rating float FIRST, MODIFY name text`)
+	assert.Equal(t, nil, err)
+	as, ok := req.(*rel.SqlAlter)
+	assert.True(t, ok, "wanted SqlAlter got %T", req)
+	assert.Equal(t, lex.TokenAlter, as.Keyword())
+	assert.Equal(t, "people", as.Identity)
+	assert.Equal(t, 4, len(as.Cols))
+
+	add := as.Cols[0]
+	assert.Equal(t, lex.TokenAdd, add.Op)
+	assert.Equal(t, "age", add.Name)
+	assert.Equal(t, false, add.Null)
+	assert.Equal(t, "0", add.Default.(*expr.StringNode).Text)
+	assert.Equal(t, "name", add.After)
+
+	assert.Equal(t, lex.TokenDrop, as.Cols[1].Op)
+	assert.Equal(t, "email", as.Cols[1].Name)
+
+	change := as.Cols[2]
+	assert.Equal(t, lex.TokenChange, change.Op)
+	assert.Equal(t, "score", change.OldName)
+	assert.Equal(t, "rating", change.Name)
+	assert.True(t, change.First)
+
+	assert.Equal(t, lex.TokenModify, as.Cols[3].Op)
+	assert.Equal(t, "text", as.Cols[3].DataType)
+
+	_, err = rel.ParseSql(`ALTER TABLE people RENAME x`)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSqlCreateIndex(t *testing.T) {
+	t.Parallel()
+	req, err := rel.ParseSql(`CREATE UNIQUE INDEX idx_email ON users (email, name) WITH stuff = "hello";`)
+	assert.Equal(t, nil, err)
+	cs, ok := req.(*rel.SqlCreate)
+	assert.True(t, ok, "wanted SqlCreate got %T", req)
+	assert.Equal(t, lex.TokenIndex, cs.Tok.T)
+	assert.Equal(t, "idx_email", cs.Identity)
+	assert.Equal(t, "users", cs.Table)
+	assert.True(t, cs.Unique)
+	assert.Equal(t, 2, len(cs.Cols))
+	assert.Equal(t, "name", cs.Cols[1].Name)
+	assert.Equal(t, "hello", cs.With.String("stuff"))
+
+	req, err = rel.ParseSql(`CREATE INDEX idx_name ON users (name)`)
+	assert.Equal(t, nil, err)
+	cs = req.(*rel.SqlCreate)
+	assert.Equal(t, false, cs.Unique)
+	assert.Equal(t, 1, len(cs.Cols))
+
+	_, err = rel.ParseSql(`CREATE INDEX idx_name ON users ()`)
+	assert.NotEqual(t, nil, err)
+}
+
 func TestSqlDrop(t *testing.T) {
 	t.Parallel()
 	sql := `DROP TABLE articles;`
@@ -670,6 +886,51 @@ func TestSqlDrop(t *testing.T) {
 	assert.Equal(t, lex.TokenDrop, ds.Keyword(), "Has keyword DROP")
 	assert.Equal(t, "TABLE", ds.Tok.V, "Wanted TABLE: got %q", ds.Tok.V)
 	assert.Equal(t, "articles", ds.Identity, "has articles: %v", ds.Identity)
+
+	req, err = rel.ParseSql(`DROP TABLE IF EXISTS articles`)
+	assert.Equal(t, nil, err)
+	ds = req.(*rel.SqlDrop)
+	assert.True(t, ds.IfExists)
+	assert.Equal(t, "articles", ds.Identity)
+}
+
+func TestSqlViews(t *testing.T) {
+	t.Parallel()
+	req, err := rel.ParseSql(`CREATE OR REPLACE MATERIALIZED VIEW cities AS SELECT city FROM orders WHERE total > 5 WITH refresh_interval = "5m"`)
+	assert.Equal(t, nil, err)
+	cs, ok := req.(*rel.SqlCreate)
+	assert.True(t, ok, "wanted SqlCreate got %T", req)
+	assert.Equal(t, lex.TokenView, cs.Tok.T)
+	assert.True(t, cs.OrReplace)
+	assert.True(t, cs.Materialized)
+	assert.Equal(t, "cities", cs.Identity)
+	assert.Equal(t, "SELECT city FROM orders WHERE total > 5", cs.Select.String())
+	assert.Equal(t, "5m", cs.With.String("refresh_interval"))
+
+	req, err = rel.ParseSql(`CREATE VIEW v AS SELECT a FROM t`)
+	assert.Equal(t, nil, err)
+	cs = req.(*rel.SqlCreate)
+	assert.Equal(t, false, cs.Materialized)
+	_, err = rel.ParseSql(`CREATE MATERIALIZED TABLE t (a int)`)
+	assert.NotEqual(t, nil, err)
+
+	req, err = rel.ParseSql(`DROP MATERIALIZED VIEW IF EXISTS cities`)
+	assert.Equal(t, nil, err)
+	ds := req.(*rel.SqlDrop)
+	assert.Equal(t, lex.TokenView, ds.Tok.T)
+	assert.True(t, ds.Materialized)
+	assert.True(t, ds.IfExists)
+	assert.Equal(t, "cities", ds.Identity)
+
+	req, err = rel.ParseSql(`REFRESH MATERIALIZED VIEW cities`)
+	assert.Equal(t, nil, err)
+	cmd, ok := req.(*rel.SqlCommand)
+	assert.True(t, ok, "wanted SqlCommand got %T", req)
+	assert.Equal(t, lex.TokenRefresh, cmd.Keyword())
+	assert.Equal(t, "cities", cmd.Identity)
+	assert.Equal(t, "REFRESH MATERIALIZED VIEW cities", cmd.String())
+	_, err = rel.ParseSql(`REFRESH VIEW cities`)
+	assert.NotEqual(t, nil, err)
 }
 
 func TestWithNameValue(t *testing.T) {
@@ -36,16 +36,8 @@ var (
 
 	// Statements with Columns
 	_ ColumnsStatement = (*SqlSelect)(nil)
-
-	// A select * columns
-	starCols Columns
 )
 
-func init() {
-	starCols = make(Columns, 1)
-	starCols[0] = NewColumnFromToken(lex.Token{T: lex.TokenStar, V: "*"})
-}
-
 type (
 	// ColumnsStatement is a statement interface for those statements that
 	// have columns that need to be added during parse.
@@ -87,6 +79,7 @@ type (
 		Raw       string       // full original raw statement
 		Star      bool         // for select * from ...
 		Distinct  bool         // Distinct flag?
+		Subscribe bool         // SUBSCRIBE SELECT, keep emitting rows as sources are appended to
 		Columns   Columns      // An array (ordered) list of columns
 		From      []*SqlSource // From, Join
 		Into      *SqlInto     // Into "table"
@@ -149,11 +142,28 @@ type (
 	}
 	// SqlInsert SQL Insert Statement
 	SqlInsert struct {
-		kw      lex.TokenType    // Insert, Replace
-		Table   string           // table name
-		Columns Columns          // Column Names
-		Rows    [][]*ValueColumn // Values to insert
-		Select  *SqlSelect       //
+		kw         lex.TokenType    // Insert, Replace
+		Table      string           // table name
+		Columns    Columns          // Column Names
+		Rows       [][]*ValueColumn // Values to insert
+		Select     *SqlSelect       //
+		OnConflict *SqlOnConflict   // ON CONFLICT, ON DUPLICATE KEY UPDATE
+		Returning  Columns          // RETURNING columns
+	}
+	// SqlOnConflict what an INSERT does for a row whose key already exists
+	//
+	//    ON CONFLICT (id) DO NOTHING
+	//    ON CONFLICT (id) DO UPDATE SET ct = ct + excluded.ct
+	//    ON DUPLICATE KEY UPDATE ct = ct + VALUES(ct)
+	//
+	// In the update expressions the existing row's columns are referred to by
+	// name, and the row that was to be inserted as excluded.col (VALUES(col)
+	// is rewritten to that).
+	SqlOnConflict struct {
+		Duplicate bool                    // written as mysql ON DUPLICATE KEY UPDATE
+		Columns   []string                // conflict target columns
+		DoNothing bool                    // DO NOTHING
+		Values    map[string]*ValueColumn // DO UPDATE SET assignments
 	}
 	// SqlUpsert SQL Upsert Statement
 	SqlUpsert struct {
@@ -188,6 +198,7 @@ type (
 		CreateWhat string
 		Where      expr.Node
 		Like       expr.Node
+		With       u.JsonHelper // SHOW CREATE TABLE t WITH dialect = "postgres"
 	}
 	// SQL Describe statement
 	SqlDescribe struct {
@@ -202,37 +213,43 @@ type (
 	}
 	// SqlCommand is admin command such as "SET", "USE"
 	SqlCommand struct {
-		kw       lex.TokenType  // SET or USE
+		kw       lex.TokenType  // SET, USE or REFRESH
 		Columns  CommandColumns // can have multiple columns in command
-		Identity string         //
+		Identity string         // USE <identity>, REFRESH MATERIALIZED VIEW <identity>
 		Value    expr.Node      //
 	}
 	// SqlCreate SQL CREATE statement
 	SqlCreate struct {
-		Raw         string       // full original raw statement
-		Identity    string       // identity of table, view, etc
-		Tok         lex.Token    // CREATE [TABLE,VIEW,CONTINUOUSVIEW,TRIGGER] etc
-		OrReplace   bool         // OR REPLACE
-		IfNotExists bool         // IF NOT EXISTS
-		Cols        []*DdlColumn // columns
-		Engine      map[string]interface{}
-		With        u.JsonHelper
-		Select      *SqlSelect
+		Raw          string       // full original raw statement
+		Identity     string       // identity of table, view, etc
+		Tok          lex.Token    // CREATE [TABLE,VIEW,CONTINUOUSVIEW,TRIGGER,INDEX] etc
+		OrReplace    bool         // OR REPLACE
+		Materialized bool         // CREATE MATERIALIZED VIEW
+		IfNotExists  bool         // IF NOT EXISTS
+		Unique       bool         // CREATE UNIQUE INDEX
+		Table        string       // table of CREATE INDEX <identity> ON <table>
+		Cols         []*DdlColumn // columns
+		Engine       map[string]interface{}
+		With         u.JsonHelper
+		Select       *SqlSelect
 	}
 	// SqlDrop SQL DROP statement
 	SqlDrop struct {
-		Raw      string    // full original raw statement
-		Identity string    // identity of table, view, etc
-		Temp     bool      // Temp?
-		Tok      lex.Token // DROP [TEMP] [TABLE,VIEW,CONTINUOUSVIEW,TRIGGER] etc
-		With     u.JsonHelper
+		Raw          string    // full original raw statement
+		Identity     string    // identity of table, view, etc
+		Temp         bool      // Temp?
+		IfExists     bool      // IF EXISTS
+		Tok          lex.Token // DROP [TEMP] [TABLE,VIEW,CONTINUOUSVIEW,TRIGGER] etc
+		Materialized bool      // DROP MATERIALIZED VIEW
+		With         u.JsonHelper
 	}
 	// SqlAlter SQL ALTER statement
 	SqlAlter struct {
 		Raw      string       // full original raw statement
 		Identity string       // identity to alter
 		Tok      lex.Token    // ALTER [TABLE,VIEW,CONTINUOUSVIEW,TRIGGER] etc
-		Cols     []*DdlColumn // columns
+		Cols     []*DdlColumn // columns, each with Op of the change to make
+		With     u.JsonHelper
 	}
 	// Columns List of Columns in SELECT [columns]
 	Columns []*Column
@@ -280,6 +297,10 @@ type (
 		Name          string        // name
 		Comment       string        // optional in-line comments
 		Expr          expr.Node     // Expression, optional, often Identity.Node but could be composite key
+		Op            lex.TokenType // ALTER TABLE action:  ADD, DROP, CHANGE, MODIFY
+		OldName       string        // CHANGE old_name name
+		First         bool          // ALTER TABLE ADD ... FIRST
+		After         string        // ALTER TABLE ADD ... AFTER col
 	}
 	// ResultColumns List of ResultColumns used to describe projection response columns
 	ResultColumns []*ResultColumn
@@ -360,6 +381,10 @@ func NewSqlDrop() *SqlDrop {
 	req := &SqlDrop{}
 	return req
 }
+func NewSqlAlter() *SqlAlter {
+	req := &SqlAlter{}
+	return req
+}
 func NewSqlInto(table string) *SqlInto {
 	return &SqlInto{Table: table}
 }
@@ -1025,6 +1050,9 @@ func (m *SqlSelect) Equal(ss SqlStatement) bool {
 	if m.Distinct != s.Distinct {
 		return false
 	}
+	if m.Subscribe != s.Subscribe {
+		return false
+	}
 	if m.Limit != s.Limit {
 		return false
 	}
@@ -1151,6 +1179,9 @@ func (m *SqlSelect) String() string {
 }
 func (m *SqlSelect) writeDialectDepth(depth int, w expr.DialectWriter) {
 
+	if m.Subscribe {
+		io.WriteString(w, "SUBSCRIBE ")
+	}
 	io.WriteString(w, "SELECT ")
 	if m.Distinct {
 		io.WriteString(w, "DISTINCT ")
@@ -1788,6 +1819,12 @@ func (m *SqlInsert) WriteDialect(w expr.DialectWriter) {
 
 	io.WriteString(w, "INSERT INTO ")
 	w.WriteIdentity(m.Table)
+	defer m.writeConflictReturning(w)
+	if m.Select != nil && len(m.Columns) == 0 {
+		io.WriteString(w, " ")
+		m.Select.WriteDialect(w)
+		return
+	}
 	io.WriteString(w, " (")
 
 	for i, col := range m.Columns {
@@ -1796,6 +1833,11 @@ func (m *SqlInsert) WriteDialect(w expr.DialectWriter) {
 		}
 		col.WriteDialect(w)
 	}
+	if m.Select != nil {
+		io.WriteString(w, ") ")
+		m.Select.WriteDialect(w)
+		return
+	}
 	io.WriteString(w, ") VALUES")
 	for i, row := range m.Rows {
 		if i > 0 {
@@ -1816,6 +1858,22 @@ func (m *SqlInsert) WriteDialect(w expr.DialectWriter) {
 		w.Write([]byte{')'})
 	}
 }
+
+func (m *SqlInsert) writeConflictReturning(w expr.DialectWriter) {
+	if m.OnConflict != nil {
+		io.WriteString(w, " ")
+		m.OnConflict.WriteDialect(w)
+	}
+	if len(m.Returning) > 0 {
+		io.WriteString(w, " RETURNING ")
+		for i, col := range m.Returning {
+			if i > 0 {
+				io.WriteString(w, ", ")
+			}
+			col.WriteDialect(w)
+		}
+	}
+}
 func (m *SqlInsert) String() string {
 	w := expr.NewDefaultWriter()
 	m.WriteDialect(w)
@@ -1862,6 +1920,35 @@ func (m *SqlInsert) ColumnNames() []string {
 	return cols
 }
 
+func (m *SqlOnConflict) WriteDialect(w expr.DialectWriter) {
+	if m.Duplicate {
+		io.WriteString(w, "ON DUPLICATE KEY UPDATE ")
+	} else {
+		io.WriteString(w, "ON CONFLICT")
+		if len(m.Columns) > 0 {
+			io.WriteString(w, " (")
+			for i, col := range m.Columns {
+				if i > 0 {
+					io.WriteString(w, ", ")
+				}
+				w.WriteIdentity(col)
+			}
+			io.WriteString(w, ")")
+		}
+		if m.DoNothing {
+			io.WriteString(w, " DO NOTHING")
+			return
+		}
+		io.WriteString(w, " DO UPDATE SET ")
+	}
+	writeValueColumns(w, m.Values)
+}
+func (m *SqlOnConflict) String() string {
+	w := expr.NewDefaultWriter()
+	m.WriteDialect(w)
+	return w.String()
+}
+
 func (m *SqlUpsert) Keyword() lex.TokenType            { return lex.TokenUpsert }
 func (m *SqlUpsert) WriteDialect(w expr.DialectWriter) {}
 func (m *SqlUpsert) String() string                    { return fmt.Sprintf("%s ", m.Keyword()) }
@@ -1872,20 +1959,34 @@ func (m *SqlUpdate) WriteDialect(w expr.DialectWriter) {
 	io.WriteString(w, "UPDATE ")
 	w.WriteIdentity(m.Table)
 	io.WriteString(w, " SET ")
-	firstCol := true
-	for key, val := range m.Values {
-		if !firstCol {
-			w.Write([]byte{',', ' '})
-		}
-		firstCol = false
-		w.WriteIdentity(key)
-		w.WriteValue(val.Value)
-	}
+	writeValueColumns(w, m.Values)
 	if m.Where != nil {
 		io.WriteString(w, " WHERE ")
 		m.Where.WriteDialect(w)
 	}
 }
+
+// writeValueColumns write the col = value assignments of an UPDATE SET,
+// sorted by column.
+func writeValueColumns(w expr.DialectWriter, values map[string]*ValueColumn) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i > 0 {
+			w.Write([]byte{',', ' '})
+		}
+		w.WriteIdentity(key)
+		io.WriteString(w, " = ")
+		if val := values[key]; val.Expr != nil {
+			val.Expr.WriteDialect(w)
+		} else {
+			w.WriteValue(val.Value)
+		}
+	}
+}
 func (m *SqlUpdate) String() string {
 	w := expr.NewDefaultWriter()
 	m.WriteDialect(w)
@@ -1897,14 +1998,15 @@ func sqlSelectFromWhere(from string, where *SqlWhere) *SqlSelect {
 	req := NewSqlSelect()
 	req.From = []*SqlSource{NewSqlSource(from)}
 	switch {
+	case where == nil:
+		// all rows
 	case where.Expr != nil:
 		req.Where = NewSqlWhere(where.Expr)
 	default:
 		req.Where = where
 	}
 
-	req.Star = true
-	req.Columns = starCols
+	req.AddColumn(Column{Star: true})
 	return req
 }
 
@@ -1954,8 +2056,13 @@ func (m *CommandColumns) String() string {
 
 func (m *SqlCommand) Keyword() lex.TokenType            { return m.kw }
 func (m *SqlCommand) FingerPrint(r rune) string         { return m.String() }
-func (m *SqlCommand) String() string                    { return fmt.Sprintf("%s %s", m.Keyword(), m.Columns.String()) }
 func (m *SqlCommand) WriteDialect(w expr.DialectWriter) {}
+func (m *SqlCommand) String() string {
+	if m.kw == lex.TokenRefresh {
+		return fmt.Sprintf("REFRESH MATERIALIZED VIEW %s", m.Identity)
+	}
+	return fmt.Sprintf("%s %s", m.Keyword(), m.Columns.String())
+}
 
 func (m *SqlCreate) Keyword() lex.TokenType            { return lex.TokenCreate }
 func (m *SqlCreate) FingerPrint(r rune) string         { return m.String() }
@@ -91,8 +91,30 @@ func RewriteSqlSource(m *SqlSource, parentStmt *SqlSelect) *SqlSelect {
 			sql2.Where = &SqlWhere{Expr: node}
 		}
 		if len(cols) > 0 {
+			// Where columns not projected are appended to the joined row
+			// after those of the parent, and of any other source.
 			parentIdx := len(parentStmt.Columns)
+			for _, from := range parentStmt.From {
+				if from == m || from.Source == nil {
+					continue
+				}
+				for _, col := range from.Source.Columns {
+					if col.ParentIndex >= parentIdx {
+						parentIdx = col.ParentIndex + 1
+					}
+				}
+			}
+		whereCols:
 			for _, col := range cols {
+				for _, existing := range sql2.Columns {
+					if existing.SourceField == col.SourceField {
+						if existing.ParentIndex < 0 {
+							existing.ParentIndex = parentIdx
+							parentIdx++
+						}
+						continue whereCols
+					}
+				}
 				col.Index = len(sql2.Columns)
 				col.ParentIndex = parentIdx
 				parentIdx++
@@ -176,12 +198,12 @@ func rewriteWhere(stmt *SqlSelect, from *SqlSource, node expr.Node, cols Columns
 			//u.Debugf("returning original: %s", nt)
 			return node, cols
 		}
-	case *expr.NumberNode, *expr.NullNode, *expr.StringNode:
+	case *expr.NumberNode, *expr.NullNode, *expr.StringNode, *expr.ValueNode:
 		return nt, cols
 	case *expr.BinaryNode:
 		//u.Infof("binaryNode  T:%v", nt.Operator.T.String())
 		switch nt.Operator.T {
-		case lex.TokenAnd, lex.TokenLogicAnd, lex.TokenLogicOr:
+		case lex.TokenAnd, lex.TokenLogicAnd:
 			var n1, n2 expr.Node
 			n1, cols = rewriteWhere(stmt, from, nt.Args[0], cols)
 			n2, cols = rewriteWhere(stmt, from, nt.Args[1], cols)
@@ -195,23 +217,54 @@ func rewriteWhere(stmt *SqlSelect, from *SqlSource, node expr.Node, cols Columns
 			} else {
 				//u.Warnf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
 			}
-		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenGT, lex.TokenGE, lex.TokenLE, lex.TokenNE:
-			var n1, n2 expr.Node
-			n1, cols = rewriteWhere(stmt, from, nt.Args[0], cols)
-			n2, cols = rewriteWhere(stmt, from, nt.Args[1], cols)
-			//u.Debugf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
-			if n1 != nil && n2 != nil {
-				return &expr.BinaryNode{Operator: nt.Operator, Args: []expr.Node{n1, n2}}, cols
-				// } else if n1 != nil {
-				// 	return n1
-				// } else if n2 != nil {
-				// 	return n2
-			} else {
-				//u.Warnf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
+		case lex.TokenOr, lex.TokenLogicOr:
+			// Only if both sides are of this source, a partial OR would
+			// filter out rows the other side could have matched
+			args, newCols := rewriteWhereArgs(stmt, from, nt.Args, cols)
+			if args != nil {
+				nn := *nt
+				nn.Args = args
+				return &nn, newCols
+			}
+		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenGT, lex.TokenGE, lex.TokenLE, lex.TokenLT,
+			lex.TokenNE, lex.TokenLike, lex.TokenIN:
+			args, newCols := rewriteWhereArgs(stmt, from, nt.Args, cols)
+			if args != nil {
+				nn := *nt
+				nn.Args = args
+				return &nn, newCols
 			}
 		default:
 			//u.Warnf("un-implemented op: %#v", nt)
 		}
+	case *expr.ArrayNode:
+		args, newCols := rewriteWhereArgs(stmt, from, nt.Args, cols)
+		if args != nil {
+			nn := *nt
+			nn.Args = args
+			return &nn, newCols
+		}
+	case *expr.TriNode:
+		// BETWEEN
+		args, newCols := rewriteWhereArgs(stmt, from, nt.Args, cols)
+		if args != nil {
+			nn := *nt
+			nn.Args = args
+			return &nn, newCols
+		}
+	case *expr.UnaryNode:
+		var n1 expr.Node
+		n1, cols = rewriteWhere(stmt, from, nt.Arg, cols)
+		if n1 != nil {
+			return &expr.UnaryNode{Operator: nt.Operator, Arg: n1}, cols
+		}
+	case *expr.FuncNode:
+		args, newCols := rewriteWhereArgs(stmt, from, nt.Args, cols)
+		if args != nil {
+			fn := expr.NewFuncNode(nt.Name, nt.F)
+			fn.Args = args
+			return fn, newCols
+		}
 	default:
 		u.Warnf("%T node types are not suppored yet for where rewrite", node)
 	}
@@ -219,6 +272,20 @@ func rewriteWhere(stmt *SqlSelect, from *SqlSource, node expr.Node, cols Columns
 	return nil, cols
 }
 
+// rewriteWhereArgs rewrite each of @args for this @from, nil if any of
+// them is not of this source.
+func rewriteWhereArgs(stmt *SqlSelect, from *SqlSource, args []expr.Node, cols Columns) ([]expr.Node, Columns) {
+	out := make([]expr.Node, len(args))
+	newCols := cols
+	for i, arg := range args {
+		out[i], newCols = rewriteWhere(stmt, from, arg, newCols)
+		if out[i] == nil {
+			return nil, cols
+		}
+	}
+	return out, newCols
+}
+
 func joinNodesForFrom(stmt *SqlSelect, from *SqlSource, node expr.Node, depth int) expr.Node {
 
 	switch nt := node.(type) {
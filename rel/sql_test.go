@@ -303,6 +303,32 @@ func TestSqlRewrite(t *testing.T) {
 	assert.True(t, sql.String() == `SELECT p.actor, p.`+"`repository.name`"+`, a.title FROM article AS a
 	INNER JOIN github_push AS p ON p.actor = a.author WHERE p.follow_ct > 20 AND a.email != NULL`, "Wrong Full SQL?: '%v'", sql.String())
 
+	// Each source gets only the predicates it can fully evaluate, an OR
+	// spanning both sources is left to the join.
+	s = `SELECT p.actor, a.title
+		FROM article AS a
+		INNER JOIN github_push AS p
+			ON p.actor = a.author
+		WHERE p.follow_ct < 20 AND a.title LIKE "go%" AND a.lang IN ("go", "rust")
+			AND (p.repo = "qlbridge" OR a.stars > 5)
+			AND (p.org = "x" OR p.org = "y")
+	`
+	sql = parseOrPanic(t, s).(*rel.SqlSelect)
+	rw0 = sql.From[0].Rewrite(sql)
+	rw1 = sql.From[1].Rewrite(sql)
+	assert.Equal(t, `SELECT title, author, lang FROM article WHERE title LIKE "go%" AND lang IN ("go", "rust")`, rw0.String())
+	assert.Equal(t, `SELECT actor, follow_ct, org FROM github_push WHERE follow_ct < 20 AND (org = "x" OR org = "y")`, rw1.String())
+	// where only columns of each source have their own position in the joined row
+	parentIdx := func(cols rel.Columns) []int {
+		idx := make([]int, len(cols))
+		for i, col := range cols {
+			idx[i] = col.ParentIndex
+		}
+		return idx
+	}
+	assert.Equal(t, []int{1, -1, 2}, parentIdx(rw0.Columns))
+	assert.Equal(t, []int{0, 3, 4}, parentIdx(rw1.Columns))
+
 	s = `SELECT u.user_id, o.item_id, u.reg_date, u.email, o.price, o.order_date FROM users AS u
 	INNER JOIN (
 				SELECT price, order_date, user_id from ORDERS
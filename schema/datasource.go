@@ -61,6 +61,21 @@ type (
 		Partitions() []*Partition
 		PartitionSource(p *Partition) (Conn, error)
 	}
+	// SourceIndexCreator is an optional interface a source may implement to
+	// support CREATE INDEX, declaring a new index on one of its tables,
+	// unique if idx.Unique.
+	SourceIndexCreator interface {
+		CreateIndex(table string, idx *Index) error
+	}
+	// SourceDDL is an optional interface a source may implement to support
+	// CREATE TABLE, ALTER TABLE and DROP TABLE against its own storage.
+	SourceDDL interface {
+		Alter
+		// CreateTable create a new (empty) table described by tbl.
+		CreateTable(tbl *Table) error
+		// AlterTable apply the list of column changes to an existing table.
+		AlterTable(table string, changes []*ColumnChange) error
+	}
 	// SourceTableColumn is a partial source that just provides access to
 	// Column schema info, used in Generators.
 	SourceTableColumn interface {
@@ -129,6 +144,34 @@ type (
 	ConnSeeker interface {
 		Get(key driver.Value) (Message, error)
 	}
+	// ConnSeekerKey is a ConnSeeker whose Get() is a unique lookup on a
+	// single column, allowing a join on that column to seek rows instead
+	// of scanning the whole table.
+	ConnSeekerKey interface {
+		ConnSeeker
+		SeekColumn() string
+	}
+	// ConnLength is a conn that knows the number of rows in its table
+	// without scanning it, used in choosing how to join.
+	ConnLength interface {
+		Length() int
+	}
+	// ConnIndexScanner is a conn with secondary indexes.  The planner looks
+	// in the where clause for equality, IN and prefix predicates on indexed
+	// columns and narrows the scan to an index lookup instead of the full table.
+	ConnIndexScanner interface {
+		Indexes() []*Index
+		IndexScan(scan *IndexScan) error
+	}
+	// ConnRangeScanner is a conn whose rows are ordered by a key column.  The
+	// planner narrows its scan to a range of the key found in the where clause,
+	// and reads in key order to satisfy an ORDER BY on the key without a sort.
+	// RangeScan returns ErrNotImplemented if its keys don't sort as the
+	// values of the column, the scan is then of all rows in no order.
+	ConnRangeScanner interface {
+		KeyColumn() string
+		RangeScan(r *KeyRange) error
+	}
 	// ConnMutation creates a Mutator connection similar to Open() connection for select
 	// - accepts the plan context used in this upsert/insert/update
 	// - returns a connection which must be closed
@@ -146,8 +189,14 @@ type (
 		Put(ctx context.Context, key Key, value interface{}) (Key, error)
 		PutMulti(ctx context.Context, keys []Key, src interface{}) ([]Key, error)
 	}
+	// ConnFlusher optional interface for mutation connections which buffer
+	// writes, Flush is called after all rows of a statement have been Put.
+	ConnFlusher interface {
+		Flush(ctx context.Context) error
+	}
 	// ConnPatchWhere pass through where expression to underlying datasource
-	// Used for update statements WHERE x = y
+	// Used for update statements WHERE x = y.  A value of the patch which
+	// is of the columns of the row, SET n = n + 1, is given as its expr.Node.
 	ConnPatchWhere interface {
 		PatchWhere(ctx context.Context, where expr.Node, patch interface{}) (int64, error)
 	}
@@ -159,3 +208,22 @@ type (
 		DeleteExpression(p interface{} /* plan.Delete */, n expr.Node) (int, error)
 	}
 )
+
+// IndexScan is a lookup on an index found by the planner, narrowing a scan
+// to rows whose indexed fields equal one of Values.  If Prefix is set, the
+// index has a single field, and rows are those starting with Values[0][0].
+type IndexScan struct {
+	Index  *Index
+	Values [][]driver.Value
+	Prefix bool
+}
+
+// KeyRange is a range of the key column of a ConnRangeScanner to scan, a
+// nil Start or End is unbounded.  If Prefix is set only keys starting with
+// it are in range.  Desc reads the range in descending key order.
+type KeyRange struct {
+	Start, End                   driver.Value
+	StartInclusive, EndInclusive bool
+	Prefix                       string
+	Desc                         bool
+}
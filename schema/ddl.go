@@ -0,0 +1,285 @@
+package schema
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/fuhongbo/qlbridge/lex"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+var (
+	// ErrNotNull a NULL value for a NOT NULL column
+	ErrNotNull = fmt.Errorf("cannot be null")
+	// ErrTooLong a value longer than the declared length of its column
+	ErrTooLong = fmt.Errorf("value too long")
+	// ErrNoDefault a column left out of a write which has no default
+	ErrNoDefault = fmt.Errorf("has no default value")
+)
+
+// ConstraintError is a value written to a table which violates the
+// schema of its column.  Err is ErrNotNull, ErrTooLong or the error
+// converting the value to the columns type, or ErrNoDefault.
+type ConstraintError struct {
+	Table  string
+	Column string
+	Row    int // 1 based row of the statement, 0 if not for a single row
+	Value  driver.Value
+	Err    error
+}
+
+func (m *ConstraintError) Error() string {
+	if m.Row > 0 {
+		return fmt.Sprintf("column %q of %q at row %d: %v", m.Column, m.Table, m.Row, m.Err)
+	}
+	return fmt.Sprintf("column %q of %q: %v", m.Column, m.Table, m.Err)
+}
+
+// Unwrap the underlying error for errors.Is
+func (m *ConstraintError) Unwrap() error { return m.Err }
+
+// ColumnChange describes a single column change of an ALTER TABLE
+// statement, as handed to a SourceDDL.
+type ColumnChange struct {
+	// Op is one of lex.TokenAdd, lex.TokenDrop, lex.TokenChange.
+	// MODIFY is expressed as a CHANGE where the name does not change.
+	Op lex.TokenType
+	// Name of the existing column being changed or dropped, for ADD
+	// the name of the new column.
+	Name string
+	// Field the new definition for ADD, CHANGE.
+	Field *Field
+	// First, After position of column for ADD, CHANGE.
+	First bool
+	After string
+}
+
+// ApplyColumnChanges apply the list of changes to a copy of tbl, returning the
+// altered table as well as for each field of the new table the position
+// of that field in the original table (-1 for new fields) so that sources
+// may migrate existing rows.
+func ApplyColumnChanges(tbl *Table, changes []*ColumnChange) (*Table, []int, error) {
+
+	type pos struct {
+		f   *Field
+		src int
+	}
+	fields := make([]pos, len(tbl.Fields))
+	for i, f := range tbl.Fields {
+		fields[i] = pos{f, i}
+	}
+	find := func(name string) int {
+		for i, p := range fields {
+			if p.f.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+	insert := func(p pos, cc *ColumnChange) error {
+		at := len(fields)
+		if cc.First {
+			at = 0
+		} else if cc.After != "" {
+			ai := find(cc.After)
+			if ai < 0 {
+				return fmt.Errorf("column %q not found in %q", cc.After, tbl.Name)
+			}
+			at = ai + 1
+		}
+		fields = append(fields, pos{})
+		copy(fields[at+1:], fields[at:])
+		fields[at] = p
+		return nil
+	}
+
+	renames := make(map[string]string)
+	for _, cc := range changes {
+		switch cc.Op {
+		case lex.TokenAdd:
+			if cc.Field == nil {
+				return nil, nil, fmt.Errorf("missing column definition for %q", cc.Name)
+			}
+			if find(cc.Field.Name) >= 0 {
+				return nil, nil, fmt.Errorf("column %q already exists in %q", cc.Field.Name, tbl.Name)
+			}
+			if err := insert(pos{cc.Field, -1}, cc); err != nil {
+				return nil, nil, err
+			}
+		case lex.TokenDrop:
+			i := find(cc.Name)
+			if i < 0 {
+				return nil, nil, fmt.Errorf("column %q not found in %q", cc.Name, tbl.Name)
+			}
+			fields = append(fields[:i], fields[i+1:]...)
+			renames[cc.Name] = ""
+		case lex.TokenChange:
+			if cc.Field == nil {
+				return nil, nil, fmt.Errorf("missing column definition for %q", cc.Name)
+			}
+			i := find(cc.Name)
+			if i < 0 {
+				return nil, nil, fmt.Errorf("column %q not found in %q", cc.Name, tbl.Name)
+			}
+			if cc.Field.Name != cc.Name {
+				if find(cc.Field.Name) >= 0 {
+					return nil, nil, fmt.Errorf("column %q already exists in %q", cc.Field.Name, tbl.Name)
+				}
+				renames[cc.Name] = cc.Field.Name
+			}
+			// keys are declared on the table, they stay with the column
+			nf := *cc.Field
+			if nf.Key == "" {
+				nf.Key = fields[i].f.Key
+			}
+			p := pos{&nf, fields[i].src}
+			if cc.First || cc.After != "" {
+				fields = append(fields[:i], fields[i+1:]...)
+				if err := insert(p, cc); err != nil {
+					return nil, nil, err
+				}
+			} else {
+				fields[i] = p
+			}
+		default:
+			return nil, nil, fmt.Errorf("unsupported column change %s", cc.Op)
+		}
+	}
+
+	nt := &Table{
+		TablePb:  tbl.TablePb,
+		Fields:   make([]*Field, 0, len(fields)),
+		FieldMap: make(map[string]*Field, len(fields)),
+		Context:  tbl.Context,
+		Schema:   tbl.Schema,
+		Source:   tbl.Source,
+		tblID:    tbl.tblID,
+	}
+	nt.Fieldpbs = nil
+	nt.Indexes = nil
+	srcs := make([]int, len(fields))
+	for i, p := range fields {
+		f := *p.f
+		f.row = nil
+		nt.AddField(&f)
+		srcs[i] = p.src
+	}
+	nt.SetColumnsFromFields()
+
+	for _, idx := range tbl.Indexes {
+		ni := *idx
+		ni.Fields = make([]string, 0, len(idx.Fields))
+		for _, fn := range idx.Fields {
+			if to, ok := renames[fn]; ok {
+				if to == "" {
+					continue
+				}
+				fn = to
+			}
+			ni.Fields = append(ni.Fields, fn)
+		}
+		if len(ni.Fields) > 0 {
+			nt.Indexes = append(nt.Indexes, &ni)
+		}
+	}
+	return nt, srcs, nil
+}
+
+// DefaultValue the DEFAULT value of this field, decoded from its json
+// DefVal into the go type for the fields value type.  nil if none.
+func (m *Field) DefaultValue() driver.Value {
+	if len(m.DefVal) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(m.DefVal, &v); err != nil || v == nil {
+		return nil
+	}
+	switch value.ValueType(m.Type) {
+	case value.IntType:
+		switch vt := v.(type) {
+		case float64:
+			return int64(vt)
+		case string:
+			if iv, err := strconv.ParseInt(vt, 10, 64); err == nil {
+				return iv
+			}
+		}
+	case value.StringType:
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return fmt.Sprintf("%v", v)
+	}
+	return v
+}
+
+// OmittedValue the value of this field for a row written without it, its
+// default else NULL.  Fields declared NOT NULL without a default must be
+// written, fields only describing a sources values (NewFieldBase) are not
+// declared constraints so are NULL.
+func (m *Field) OmittedValue() (driver.Value, error) {
+	if def := m.DefaultValue(); def != nil {
+		return def, nil
+	}
+	if m.NoNulls && !m.described {
+		return nil, ErrNoDefault
+	}
+	return nil, nil
+}
+
+// Coerce @val to the go type of the value type of this field, checking the
+// NOT NULL and declared length of the field.  Values of types there is no
+// conversion for are returned as is, as are values that don't convert to
+// the type of a field only describing a sources values (NewFieldBase).
+func (m *Field) Coerce(val driver.Value) (driver.Value, error) {
+	var v value.Value
+	if val != nil {
+		v = value.NewValue(val)
+	}
+	if v == nil || v.Nil() {
+		if m.NoNulls {
+			return nil, ErrNotNull
+		}
+		return nil, nil
+	}
+	vt := m.ValueType()
+	switch vt {
+	case value.NumberType:
+		if f, ok := value.ValueToFloat64(v); ok {
+			return f, nil
+		}
+	case value.BoolType:
+		if b, ok := value.ValueToBool(v); ok {
+			return b, nil
+		}
+	case value.IntType, value.TimeType:
+		if cv, err := value.Cast(vt, v); err == nil {
+			return cv.Value(), nil
+		}
+	case value.StringType:
+		s := v.ToString()
+		if !m.described && m.Length > 0 && utf8.RuneCountInString(s) > int(m.Length) {
+			return nil, ErrTooLong
+		}
+		return s, nil
+	case value.ByteSliceType:
+		cv, err := value.Cast(vt, v)
+		if err != nil {
+			break
+		}
+		if b, ok := cv.Value().([]byte); ok && !m.described && m.Length > 0 && len(b) > int(m.Length) {
+			return nil, ErrTooLong
+		}
+		return cv.Value(), nil
+	default:
+		return val, nil
+	}
+	if m.described {
+		return val, nil
+	}
+	return nil, fmt.Errorf("could not convert %v to %s", val, vt)
+}
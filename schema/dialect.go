@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	// the dialect writers registry mutex
+	dialectMu sync.RWMutex
+	// dialect writers by lower-cased dialect name
+	dialectWriters = make(map[string]DialectWriter)
+	// dialect writers in order registered
+	dialectWriterList []DialectWriter
+)
+
+// RegisterDialectWriter makes a DialectWriter available by its Dialect() name
+// such as for SHOW CREATE TABLE t WITH dialect = "postgres".
+// If Register is called twice with the same dialect or if writer is nil, it panics.
+func RegisterDialectWriter(writer DialectWriter) {
+	if writer == nil {
+		panic("Register DialectWriter is nil")
+	}
+	dialect := strings.ToLower(writer.Dialect())
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	if _, dupe := dialectWriters[dialect]; dupe {
+		panic(fmt.Sprintf("Register called twice for dialect %q for %T", dialect, writer))
+	}
+	dialectWriters[dialect] = writer
+	dialectWriterList = append(dialectWriterList, writer)
+}
+
+// DialectWriterGet the registered DialectWriter for @dialect (case-insensitive).
+func DialectWriterGet(dialect string) (DialectWriter, bool) {
+	dialectMu.RLock()
+	defer dialectMu.RUnlock()
+	writer, ok := dialectWriters[strings.ToLower(dialect)]
+	return writer, ok
+}
+
+// DialectWriters list of registered dialect writers, in the order registered.
+func DialectWriters() []DialectWriter {
+	dialectMu.RLock()
+	defer dialectMu.RUnlock()
+	return append([]DialectWriter(nil), dialectWriterList...)
+}
@@ -33,6 +33,9 @@ type (
 		schemas     map[string]*Schema
 		schemaNames []string
 		mu          sync.RWMutex
+		watchers    []watcher
+		watchID     int
+		watchMu     sync.RWMutex
 	}
 )
 
@@ -80,7 +83,12 @@ func RegisterSourceAsSchema(name string, source Source) error {
 	if err := registry.SchemaAdd(s); err != nil {
 		return err
 	}
-	return discoverSchemaFromSource(s, registry.applyer)
+	before := tableFields(s)
+	if err := discoverSchemaFromSource(s, registry.applyer); err != nil {
+		return err
+	}
+	registry.notify(tableDiff(s, before, tableFields(s))...)
+	return nil
 }
 
 // RegisterSchema makes a named schema available by the provided @name
@@ -119,10 +127,25 @@ func (m *Registry) addSourceType(sourceType string, source Source) {
 		panic("Register Source is nil")
 	}
 
-	if _, dupe := registry.sources[sourceType]; dupe {
+	if _, dupe := m.sources[sourceType]; dupe {
 		panic(fmt.Sprintf("Register called twice for source %q for %T", sourceType, source))
 	}
-	registry.sources[sourceType] = source
+	m.sources[sourceType] = source
+}
+
+// SourceTypeAdd makes a datasource type available to this registry by the
+// provided @sourceType, see RegisterSourceType for the default registry.
+func (m *Registry) SourceTypeAdd(sourceType string, source Source) {
+	m.addSourceType(sourceType, source)
+}
+
+// SetApplyer replaces the Applyer of schema changes, such as with a
+// PersistentApplyer, and initializes it with this registry.
+func (m *Registry) SetApplyer(applyer Applyer) {
+	m.mu.Lock()
+	m.applyer = applyer
+	m.mu.Unlock()
+	applyer.Init(m)
 }
 
 // SchemaDrop removes a schema
@@ -136,7 +159,11 @@ func (m *Registry) SchemaDrop(schema, name string, objectType lex.TokenType) err
 		if !ok {
 			return ErrNotFound
 		}
-		return m.applyer.Drop(s, s)
+		if err := m.applyer.Drop(s, s); err != nil {
+			return err
+		}
+		m.notify(&Event{Type: EventSourceDropped, Schema: s.Name, Name: s.Name})
+		return nil
 	case lex.TokenTable:
 		m.mu.RLock()
 		s, ok := m.schemas[schema]
@@ -148,13 +175,48 @@ func (m *Registry) SchemaDrop(schema, name string, objectType lex.TokenType) err
 		if t == nil {
 			return ErrNotFound
 		}
-		return m.applyer.Drop(s, t)
+		if err := m.applyer.Drop(s, t); err != nil {
+			return err
+		}
+		m.notify(&Event{Type: EventTableDropped, Schema: s.Name, Name: t.Name})
+		return nil
 	}
 	return fmt.Errorf("Object type %s not recognized to DROP", objectType)
 }
 
+// SchemaTableAdd add or replace (after an ALTER) a table on the schema s
+// whose source owns it, and propagate it to the parent schemas.  The
+// event is of the registered schema, ie the parent of a child source.
+func (m *Registry) SchemaTableAdd(s *Schema, tbl *Table) error {
+	ev := &Event{Type: EventTableAdded, Schema: rootSchema(s).Name, Name: tbl.Name, Table: tbl}
+	if existing, _ := s.Table(tbl.Name); existing != nil {
+		ev.Type = EventTableAltered
+	}
+	if err := m.applyer.AddOrUpdateOnSchema(s, tbl); err != nil {
+		return err
+	}
+	m.setOnParents(s, tbl)
+	m.notify(ev)
+	return nil
+}
+
+// setOnParents replace table @tbl of child schema @s on its parents.
+func (m *Registry) setOnParents(s *Schema, tbl *Table) {
+	for p := s.parent; p != nil; p = p.parent {
+		p.mu.Lock()
+		p.setTableUnlocked(s, tbl)
+		p.mu.Unlock()
+		if p.InfoSchema != nil && p.InfoSchema.DS != nil {
+			p.InfoSchema.DS.Init()
+			p.InfoSchema.refreshSchemaUnlocked()
+		}
+	}
+}
+
 // SchemaRefresh means reload the schema from underlying store.  Possibly
-// requires introspection.
+// requires introspection.  The tables already known are re-read from
+// their source and replaced if their fields changed, ie altered outside
+// of this registry.
 func (m *Registry) SchemaRefresh(name string) error {
 	m.mu.RLock()
 	s, ok := m.schemas[name]
@@ -162,7 +224,47 @@ func (m *Registry) SchemaRefresh(name string) error {
 	if !ok {
 		return ErrNotFound
 	}
-	return m.applyer.AddOrUpdateOnSchema(s, s)
+	before := tableFields(s)
+	if err := m.applyer.AddOrUpdateOnSchema(s, s); err != nil {
+		return err
+	}
+	if err := m.reloadTables(s, before); err != nil {
+		return err
+	}
+	m.notify(tableDiff(s, before, tableFields(s))...)
+	return nil
+}
+
+// reloadTables re-read the tables of @s, and of its child sources, whose
+// fields differ from the field sets @before.
+func (m *Registry) reloadTables(s *Schema, before map[string]string) error {
+	s.mu.RLock()
+	schemas := []*Schema{s}
+	for _, child := range s.schemas {
+		schemas = append(schemas, child)
+	}
+	s.mu.RUnlock()
+	for _, ss := range schemas {
+		if ss.DS == nil {
+			continue
+		}
+		for _, name := range ss.DS.Tables() {
+			fields, ok := before[name]
+			if !ok {
+				// new tables were loaded by the refresh
+				continue
+			}
+			tbl, err := ss.DS.Table(name)
+			if err != nil || tbl == nil || fieldSet(tbl) == fields {
+				continue
+			}
+			if err = m.applyer.AddOrUpdateOnSchema(ss, tbl); err != nil {
+				return err
+			}
+			m.setOnParents(ss, tbl)
+		}
+	}
+	return nil
 }
 
 // Init pre-schema load call any sources that need pre-schema init
@@ -236,6 +338,8 @@ func (m *Registry) SchemaAdd(s *Schema) error {
 		s.InfoSchema = NewInfoSchema("schema", s)
 	}
 	m.applyer.AddOrUpdateOnSchema(s, s)
+	m.notify(&Event{Type: EventSourceAdded, Schema: s.Name, Name: s.Name})
+	m.notify(tableDiff(s, nil, tableFields(s))...)
 	return nil
 }
 
@@ -249,6 +353,7 @@ func (m *Registry) SchemaAddChild(name string, child *Schema) error {
 		return fmt.Errorf("Cannot find schema %q to add child", name)
 	}
 	m.applyer.AddOrUpdateOnSchema(parent, child)
+	m.notify(&Event{Type: EventSourceAdded, Schema: parent.Name, Name: child.Name})
 	return nil
 }
 
@@ -16,6 +16,7 @@ import (
 	"github.com/fuhongbo/qlbridge/datasource/mockcsv"
 	td "github.com/fuhongbo/qlbridge/datasource/mockcsvtestdata"
 	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
 )
 
 func TestRegistry(t *testing.T) {
@@ -136,3 +137,71 @@ func didPanic(f func()) (dp bool) {
 	f()
 	return dp
 }
+
+func TestRegistryWatch(t *testing.T) {
+	a := schema.NewApplyer(datasource.SchemaDBStoreProvider)
+	reg := schema.NewRegistry(a)
+	a.Init(reg)
+	reg.SourceTypeAdd("memdb", memdb.NewSource())
+
+	var events []string
+	stop := reg.Watch(func(ev *schema.Event) {
+		events = append(events, ev.String())
+	})
+
+	err := reg.SchemaAddFromConfig(&schema.ConfigSource{Name: "watched", SourceType: "memdb"})
+	assert.Equal(t, nil, err)
+	s, _ := reg.Schema("watched")
+
+	tbl := schema.NewTable("users")
+	tbl.AddField(schema.NewFieldBase("user_id", value.IntType, 64, "int"))
+	tbl.SetColumnsFromFields()
+	assert.Equal(t, nil, s.DS.(schema.SourceDDL).CreateTable(tbl))
+	assert.Equal(t, nil, reg.SchemaTableAdd(s, tbl))
+	assert.Equal(t, nil, reg.SchemaTableAdd(s, tbl))
+
+	// a refresh sends the tables altered in their source, not the others
+	assert.Equal(t, nil, reg.SchemaRefresh("watched"))
+	err = s.DS.(schema.SourceDDL).AlterTable("users", []*schema.ColumnChange{
+		{Op: lex.TokenAdd, Name: "email", Field: schema.NewFieldBase("email", value.StringType, 255, "varchar")},
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, reg.SchemaRefresh("watched"))
+	users, err := s.Table("users")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"user_id", "email"}, users.Columns())
+
+	assert.Equal(t, nil, reg.SchemaDrop("watched", "users", lex.TokenTable))
+	assert.Equal(t, nil, reg.SchemaDrop("watched", "watched", lex.TokenSource))
+	assert.Equal(t, []string{
+		"source_added watched.watched",
+		"table_added watched.users",
+		"table_altered watched.users",
+		"table_altered watched.users",
+		"table_dropped watched.users",
+		"source_dropped watched.watched",
+	}, events)
+
+	// the tables of a child source are of its registered parent schema
+	events = nil
+	err = reg.SchemaAddFromConfig(&schema.ConfigSource{Name: "child", Schema: "parent", SourceType: "memdb"})
+	assert.Equal(t, nil, err)
+	parent, _ := reg.Schema("parent")
+	child, err := parent.Schema("child")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, child.DS.(schema.SourceDDL).CreateTable(tbl))
+	assert.Equal(t, nil, reg.SchemaTableAdd(child, tbl))
+	assert.Equal(t, []string{
+		"source_added parent.parent",
+		"source_added parent.child",
+		"table_added parent.users",
+	}, events)
+
+	// failed changes, and changes after stop, are not sent
+	assert.NotEqual(t, nil, reg.SchemaDrop("watched", "watched", lex.TokenSource))
+	stop()
+	err = reg.SchemaAddFromConfig(&schema.ConfigSource{Name: "unwatched", SourceType: "memdb"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "EventType(9)", schema.EventType(9).String())
+}
@@ -19,18 +19,27 @@ import (
 	"github.com/fuhongbo/qlbridge/value"
 )
 
+const (
+	// InformationSchema the schema name of the mysql shaped tables such as
+	// information_schema.columns which the info schema of each schema
+	// provides, qualified as "information_schema.columns" as they differ
+	// from the SHOW tables of the same name.
+	InformationSchema = "information_schema"
+)
+
 var (
 	// SchemaRefreshInterval default schema Refresh Interval
 	SchemaRefreshInterval = -time.Minute * 5
 
 	// Static list of common field names for describe header on Show, Describe
 	EngineFullCols       = []string{"Engine", "Support", "Comment", "Transactions", "XA", "Savepoints"}
-	ProdedureFullCols    = []string{"Db", "Name", "Type", "Definer", "Modified", "Created", "Security_type", "Comment", "character_set_client ", "collation_connection", "Database Collation"}
+	ProdedureFullCols    = []string{"Db", "Name", "Type", "Definer", "Modified", "Created", "Security_type", "Comment", "character_set_client", "collation_connection", "Database Collation"}
 	DescribeFullCols     = []string{"Field", "Type", "Collation", "Null", "Key", "Default", "Extra", "Privileges", "Comment"}
 	DescribeFullColMap   = map[string]int{"Field": 0, "Type": 1, "Collation": 2, "Null": 3, "Key": 4, "Default": 5, "Extra": 6, "Privileges": 7, "Comment": 8}
 	DescribeCols         = []string{"Field", "Type", "Null", "Key", "Default", "Extra"}
 	DescribeColMap       = map[string]int{"Field": 0, "Type": 1, "Null": 2, "Key": 3, "Default": 4, "Extra": 5}
 	ShowTableColumns     = []string{"Table", "Table_Type"}
+	ShowViewColumns      = []string{"Table", "Table_Type", "View_definition", "Refresh_interval"}
 	ShowVariablesColumns = []string{"Variable_name", "Value"}
 	ShowDatabasesColumns = []string{"Database"}
 	ShowTableColumnMap   = map[string]int{"Table": 0}
@@ -94,6 +103,7 @@ type (
 		tableMap      map[string]*Table  // Tables and their field info, flattened from all child schemas
 		tableNames    []string           // List Table names, flattened all schemas into one list
 		lastRefreshed time.Time          // Last time we refreshed this schema
+		version       uint64             // Version, incremented by a PersistentApplyer
 		mu            sync.RWMutex       // lock for schema mods
 	}
 
@@ -107,18 +117,34 @@ type (
 		FieldMap       map[string]*Field      // Map of Field-name -> Field
 		Schema         *Schema                // The schema this is member of
 		Source         Source                 // The source
+		View           *View                  // The view definition if this table is a view
 		tblID          uint64                 // internal tableid, hash of table name + schema?
 		cols           []string               // array of column names
 		lastRefreshed  time.Time              // Last time we refreshed this schema
 		rows           [][]driver.Value
 	}
 
+	// View is the definition of a table that is the result of a SELECT.
+	// A logical view runs its SELECT when read, a materialized view stores
+	// the rows and only runs it when refreshed, a continuous view keeps
+	// windowed aggregates of the rows streaming from its source.
+	View struct {
+		Sql             string        // the SELECT statement of the view
+		Materialized    bool          // are the rows stored
+		RefreshInterval time.Duration // materialized views refresh on this interval if > 0
+		Continuous      bool          // is this a continuous view
+		WatermarkDelay  time.Duration // the watermark of a continuous view lags its latest event time by this
+		Retention       time.Duration // closed windows of a continuous view are dropped this long after the watermark passes them if > 0
+		RetainWindows   int           // the most closed windows of a continuous view kept if > 0, else a default
+	}
+
 	// Field Describes the column info, name, data type, defaults, index, null
 	// - dialects (mysql, mongo, cassandra) have their own descriptors for these,
 	//   so this is generic meant to be converted to Frontend at runtime
 	Field struct {
-		idx uint64         // Positional index in array of fields
-		row []driver.Value // memoized values of this fields descriptors for describe
+		idx       uint64         // Positional index in array of fields
+		row       []driver.Value // memoized values of this fields descriptors for describe
+		described bool           // describes the values of a source, the type and length are not declared constraints
 		FieldPb
 		Context map[string]interface{} // During schema discovery of underlying source, may need to store additional info
 	}
@@ -189,6 +215,14 @@ func NewSchemaSource(schemaName string, ds Source) *Schema {
 	return m
 }
 
+// Version of this schema, incremented on each change persisted by a
+// PersistentApplyer.
+func (m *Schema) Version() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.version
+}
+
 // Since Is this schema object been refreshed within time window described by @dur time ago ?
 func (m *Schema) Since(dur time.Duration) bool {
 	if m.lastRefreshed.IsZero() {
@@ -342,13 +376,6 @@ func (m *Schema) dropTable(tbl *Table) error {
 	// u.Warnf("%p drop %s %v", m, m.Name, m.Tables())
 	//u.Infof("infoschema %#v", m.InfoSchema)
 
-	tl := make([]string, 0, len(m.tableNames))
-	for _, tn := range m.tableNames {
-		if tbl.Name != tn {
-			tl = append(tl, tn)
-		}
-	}
-
 	ts := m.tableSchemas[tbl.Name]
 	if ts != nil {
 		if as, ok := ts.DS.(Alter); ok {
@@ -357,11 +384,16 @@ func (m *Schema) dropTable(tbl *Table) error {
 				return err
 			}
 		}
+		if ts != m {
+			// the child schema owning this table must forget it as well
+			// or the refresh of the parent would add it right back
+			ts.mu.Lock()
+			ts.removeTableUnlocked(tbl.Name)
+			ts.mu.Unlock()
+		}
 	}
 
-	delete(m.tableMap, tbl.Name)
-	delete(m.tableSchemas, tbl.Name)
-	m.tableNames = tl
+	m.removeTableUnlocked(tbl.Name)
 
 	if salter, ok := m.InfoSchema.DS.(Alter); ok {
 		err := salter.DropTable(tbl.Name)
@@ -374,6 +406,32 @@ func (m *Schema) dropTable(tbl *Table) error {
 	return nil
 }
 
+func (m *Schema) removeTableUnlocked(name string) {
+	tl := make([]string, 0, len(m.tableNames))
+	for _, tn := range m.tableNames {
+		if name != tn {
+			tl = append(tl, tn)
+		}
+	}
+	delete(m.tableMap, name)
+	delete(m.tableSchemas, name)
+	m.tableNames = tl
+}
+
+// setTableUnlocked set the table as provided by (child) schema ss
+// replacing any previous definition.
+func (m *Schema) setTableUnlocked(ss *Schema, tbl *Table) {
+	m.tableMap[tbl.Name] = tbl
+	m.tableSchemas[tbl.Name] = ss
+	for _, tn := range m.tableNames {
+		if tn == tbl.Name {
+			return
+		}
+	}
+	m.tableNames = append(m.tableNames, tbl.Name)
+	sort.Strings(m.tableNames)
+}
+
 func (m *Schema) addTable(tbl *Table) error {
 
 	// u.Debugf("schema:%p AddTable %#v", m, tbl)
@@ -398,6 +456,7 @@ func (m *Schema) addTable(tbl *Table) error {
 	tbl.init(m)
 
 	m.tableMap[tbl.Name] = tbl
+	m.tableSchemas[tbl.Name] = m
 
 	m.addschemaForTableUnlocked(tbl.Name, tbl.Schema)
 	return nil
@@ -530,7 +589,7 @@ func (m *Table) AddField(fld *Field) {
 
 // AddFieldType describe and register a new column
 func (m *Table) AddFieldType(name string, valType value.ValueType) {
-	m.AddField(&Field{FieldPb: FieldPb{Type: uint32(valType), Name: name}})
+	m.AddField(&Field{FieldPb: FieldPb{Type: uint32(valType), Name: name}, described: true})
 }
 
 // Column get the Underlying data type.
@@ -572,6 +631,31 @@ func (m *Table) SetColumnsFromFields() {
 // Columns list of all column names.
 func (m *Table) Columns() []string { return m.cols }
 
+// TableType the type of table as shown in SHOW FULL TABLES, one of
+// "BASE TABLE", "VIEW", "MATERIALIZED VIEW", "CONTINUOUS VIEW".
+func (m *Table) TableType() string {
+	switch {
+	case m.View == nil:
+		return "BASE TABLE"
+	case m.View.Materialized:
+		return "MATERIALIZED VIEW"
+	case m.View.Continuous:
+		return "CONTINUOUS VIEW"
+	}
+	return "VIEW"
+}
+
+// Keyword the keyword of CREATE, DROP statements for this kind of view.
+func (m *View) Keyword() string {
+	switch {
+	case m.Materialized:
+		return "MATERIALIZED VIEW"
+	case m.Continuous:
+		return "CONTINUOUSVIEW"
+	}
+	return "VIEW"
+}
+
 // AsRows return all fields suiteable as list of values for Describe/Show statements.
 func (m *Table) AsRows() [][]driver.Value {
 	if len(m.rows) > 0 {
@@ -618,8 +702,32 @@ func (m *Table) AddContext(key string, value interface{}) {
 	m.Context[key] = value
 }
 
+// Marshal the table as protobuf TablePb including its fields.
 func (m *Table) Marshal() ([]byte, error) {
-	return proto.Marshal(&m.TablePb)
+	pb := m.TablePb
+	pb.Fieldpbs = make([]*FieldPb, len(m.Fields))
+	for i, f := range m.Fields {
+		fpb := f.FieldPb
+		pb.Fieldpbs[i] = &fpb
+	}
+	return proto.Marshal(&pb)
+}
+
+// NewTableFromPb the table of protobuf TablePb @pb, see Marshal.
+func NewTableFromPb(pb []byte) (*Table, error) {
+	tpb := TablePb{}
+	if err := proto.Unmarshal(pb, &tpb); err != nil {
+		return nil, err
+	}
+	m := NewTable(tpb.Name)
+	fieldpbs := tpb.Fieldpbs
+	tpb.Fieldpbs = nil
+	m.TablePb = tpb
+	for _, fpb := range fieldpbs {
+		m.AddField(&Field{FieldPb: *fpb})
+	}
+	m.SetColumnsFromFields()
+	return m, nil
 }
 
 func NewFieldBase(name string, valType value.ValueType, size int, desc string) *Field {
@@ -630,7 +738,7 @@ func NewFieldBase(name string, valType value.ValueType, size int, desc string) *
 		Type:        uint32(valType),
 		NativeType:  uint32(valType), // You need to over-ride this to change it
 	}
-	return &Field{FieldPb: f}
+	return &Field{FieldPb: f, described: true}
 }
 func NewField(name string, valType value.ValueType, size int, allowNulls bool, defaultVal driver.Value, key, collation, description string) *Field {
 	jb, _ := json.Marshal(defaultVal)
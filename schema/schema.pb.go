@@ -334,6 +334,7 @@ type Index struct {
 	PrimaryKey    bool     `protobuf:"varint,3,opt,name=primaryKey" json:"primaryKey,omitempty"`
 	HashPartition []string `protobuf:"bytes,4,rep,name=hashPartition" json:"hashPartition,omitempty"`
 	PartitionSize int32    `protobuf:"varint,5,opt,name=partitionSize" json:"partitionSize,omitempty"`
+	Unique        bool     `protobuf:"varint,6,opt,name=unique" json:"unique,omitempty"`
 }
 
 func (m *Index) Reset()                    { *m = Index{} }
@@ -376,6 +377,13 @@ func (m *Index) GetPartitionSize() int32 {
 	return 0
 }
 
+func (m *Index) GetUnique() bool {
+	if m != nil {
+		return m.Unique
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*TablePartition)(nil), "schema.TablePartition")
 	proto.RegisterType((*Partition)(nil), "schema.Partition")
@@ -387,40 +395,40 @@ func init() {
 func init() { proto.RegisterFile("schema.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 546 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0x5b, 0x8a, 0xdb, 0x30,
-	0x14, 0xc5, 0x71, 0x12, 0xc7, 0x37, 0x8f, 0x99, 0x11, 0x25, 0xe8, 0xa3, 0x14, 0x63, 0x0a, 0x35,
-	0x14, 0x06, 0x3a, 0xed, 0x0e, 0x86, 0x16, 0xfa, 0xa0, 0x1d, 0xd4, 0xa1, 0xff, 0x72, 0xac, 0xc4,
-	0x62, 0x14, 0xdb, 0x58, 0x6a, 0x49, 0xba, 0x99, 0x6e, 0xa1, 0x7b, 0xe8, 0xc6, 0x8a, 0xae, 0x25,
-	0xc7, 0xa1, 0xf3, 0xd3, 0xaf, 0xdc, 0x73, 0x74, 0x75, 0xae, 0x74, 0x8e, 0x1c, 0x58, 0xe8, 0x4d,
-	0x29, 0xf6, 0xfc, 0xba, 0x69, 0x6b, 0x53, 0x93, 0x69, 0x87, 0xd2, 0x3d, 0xac, 0xee, 0x79, 0xae,
-	0xc4, 0x1d, 0x6f, 0x8d, 0x34, 0xb2, 0xae, 0xc8, 0x13, 0x98, 0x18, 0xcb, 0xd0, 0x20, 0x09, 0xb2,
-	0x98, 0x75, 0x80, 0x10, 0x18, 0x3f, 0x88, 0xa3, 0xa6, 0xa3, 0x24, 0xcc, 0x62, 0x86, 0x35, 0x79,
-	0x05, 0xd0, 0xf8, 0x6d, 0x9a, 0x86, 0x49, 0x98, 0xcd, 0x6f, 0xae, 0xae, 0xdd, 0x98, 0x5e, 0x90,
-	0x0d, 0x9a, 0xd2, 0xb7, 0x10, 0x9f, 0x26, 0xad, 0x60, 0x24, 0x0b, 0x37, 0x66, 0x24, 0x0b, 0x3b,
-	0x43, 0x89, 0xad, 0xa1, 0x23, 0x64, 0xb0, 0xb6, 0xa7, 0x69, 0xe5, 0xae, 0x34, 0x34, 0xec, 0x4e,
-	0x83, 0x20, 0xfd, 0x33, 0x82, 0xa8, 0x3b, 0x76, 0x6e, 0x77, 0x55, 0x7c, 0xef, 0x8f, 0x8b, 0x35,
-	0x49, 0x61, 0x61, 0x7f, 0xbf, 0xb4, 0x72, 0x27, 0x2b, 0xae, 0x9c, 0xe2, 0x19, 0x47, 0xd6, 0x30,
-	0x6d, 0x78, 0x2b, 0x2a, 0x2f, 0xed, 0x10, 0xa1, 0x10, 0xdd, 0x96, 0xbc, 0xd5, 0xc2, 0xd0, 0x71,
-	0x12, 0x64, 0x4b, 0xe6, 0x21, 0x79, 0x03, 0x71, 0x7f, 0x15, 0x3a, 0x49, 0x82, 0x6c, 0x7e, 0xb3,
-	0xf6, 0xd7, 0x3d, 0x37, 0x91, 0x9d, 0x1a, 0x49, 0x02, 0xf3, 0x9e, 0xbf, 0x35, 0x74, 0x8a, 0x9a,
-	0x43, 0x8a, 0xbc, 0x80, 0x48, 0x56, 0x85, 0x38, 0x08, 0x4d, 0x23, 0x34, 0x71, 0xe9, 0x55, 0xdf,
-	0x5b, 0x9a, 0xf9, 0x55, 0x2b, 0xb5, 0xa9, 0x2b, 0x23, 0x0e, 0xe6, 0x83, 0xae, 0x2b, 0x3a, 0x4b,
-	0x82, 0x6c, 0xc1, 0x86, 0x14, 0x79, 0x09, 0xb3, 0xad, 0x14, 0xaa, 0x68, 0x72, 0x4d, 0x63, 0xd4,
-	0xba, 0xf0, 0x5a, 0xef, 0x2c, 0x7f, 0x97, 0xb3, 0xbe, 0x21, 0xfd, 0x1d, 0x42, 0xe4, 0xd8, 0x47,
-	0x5d, 0x4c, 0x60, 0x5e, 0x08, 0xbd, 0x69, 0x65, 0x83, 0x37, 0xee, 0x4c, 0x1c, 0x52, 0xe4, 0x12,
-	0xc2, 0x07, 0x71, 0x74, 0x06, 0xda, 0xd2, 0xe6, 0x25, 0x0e, 0xa6, 0xe5, 0xe8, 0x5d, 0xcc, 0x3a,
-	0x60, 0xd5, 0x0b, 0x6e, 0x38, 0x9a, 0x16, 0x33, 0xac, 0xad, 0xff, 0x4a, 0x54, 0x3b, 0x53, 0x3a,
-	0x4b, 0x1c, 0xb2, 0xbd, 0xe6, 0xd8, 0x08, 0x1a, 0x21, 0x8b, 0x35, 0x79, 0x06, 0x50, 0x71, 0x23,
-	0x7f, 0x88, 0x7b, 0xbb, 0x32, 0xc3, 0x95, 0x01, 0x43, 0x9e, 0x42, 0x5c, 0x88, 0xed, 0xa7, 0x4e,
-	0x2e, 0x4e, 0x82, 0x6c, 0xcc, 0x4e, 0x84, 0x9d, 0x54, 0x88, 0xed, 0x37, 0xae, 0xe8, 0x1c, 0x1d,
-	0x73, 0xc8, 0x26, 0xdd, 0x39, 0x5b, 0xd0, 0x65, 0x12, 0x64, 0x33, 0x6f, 0x74, 0x61, 0x57, 0xaa,
-	0xfa, 0xf3, 0x77, 0xa5, 0x34, 0x5d, 0x75, 0x2b, 0x0e, 0xda, 0x49, 0x9b, 0x5a, 0x29, 0x8e, 0x8e,
-	0x5c, 0xe0, 0x75, 0x4e, 0x04, 0xbe, 0xd6, 0x5a, 0x09, 0x4d, 0x2f, 0xf1, 0x33, 0xe9, 0xc0, 0x30,
-	0xdf, 0xab, 0xff, 0xc9, 0x97, 0xfc, 0x93, 0x6f, 0xfa, 0x2b, 0x80, 0x09, 0x6e, 0x7a, 0x34, 0xb0,
-	0x35, 0x4c, 0x31, 0x5c, 0xff, 0x99, 0x3a, 0x64, 0xed, 0x6b, 0x5a, 0xb9, 0xe7, 0xed, 0xf1, 0xa3,
-	0x4b, 0x6b, 0xc6, 0x06, 0x0c, 0x79, 0x0e, 0xcb, 0x92, 0xeb, 0xb2, 0x7f, 0x93, 0x74, 0x8c, 0xdb,
-	0xcf, 0x49, 0xdb, 0xd5, 0xbf, 0xea, 0xaf, 0xf2, 0xa7, 0xc0, 0x34, 0x27, 0xec, 0x9c, 0xcc, 0xa7,
-	0xf8, 0xff, 0xf2, 0xfa, 0x6f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xb8, 0x29, 0x27, 0x0d, 0x6f, 0x04,
-	0x00, 0x00,
+	// 554 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xcb, 0x8a, 0xdb, 0x30,
+	0x14, 0xc5, 0x71, 0x12, 0xdb, 0x37, 0x93, 0x79, 0x88, 0x12, 0xb4, 0x28, 0xc5, 0x98, 0x42, 0x0d,
+	0x85, 0x81, 0x4e, 0xfb, 0x07, 0x43, 0x0b, 0x7d, 0xd0, 0x0e, 0xea, 0xd0, 0xbd, 0x12, 0x2b, 0xb1,
+	0x18, 0x45, 0x76, 0x2d, 0xa5, 0x24, 0xfd, 0xaa, 0xee, 0xfa, 0x01, 0xfd, 0xb1, 0xa2, 0x6b, 0xd9,
+	0x71, 0xe8, 0x6c, 0xba, 0xca, 0x3d, 0x47, 0xd2, 0xb9, 0x57, 0xe7, 0xc8, 0x81, 0x33, 0xb3, 0x2a,
+	0xc5, 0x96, 0x5f, 0xd7, 0x4d, 0x65, 0x2b, 0x32, 0x6d, 0x51, 0xb6, 0x85, 0xf3, 0x7b, 0xbe, 0x54,
+	0xe2, 0x8e, 0x37, 0x56, 0x5a, 0x59, 0x69, 0xf2, 0x04, 0x26, 0xd6, 0x31, 0x34, 0x48, 0x83, 0x3c,
+	0x61, 0x2d, 0x20, 0x04, 0xc6, 0x0f, 0xe2, 0x60, 0xe8, 0x28, 0x0d, 0xf3, 0x84, 0x61, 0x4d, 0x5e,
+	0x01, 0xd4, 0xdd, 0x31, 0x43, 0xc3, 0x34, 0xcc, 0x67, 0x37, 0x57, 0xd7, 0xbe, 0x4d, 0x2f, 0xc8,
+	0x06, 0x9b, 0xb2, 0xb7, 0x90, 0x1c, 0x3b, 0x9d, 0xc3, 0x48, 0x16, 0xbe, 0xcd, 0x48, 0x16, 0xae,
+	0x87, 0x12, 0x6b, 0x4b, 0x47, 0xc8, 0x60, 0xed, 0xa6, 0x69, 0xe4, 0xa6, 0xb4, 0x34, 0x6c, 0xa7,
+	0x41, 0x90, 0xfd, 0x19, 0x41, 0xd4, 0x8e, 0xbd, 0x74, 0xa7, 0x34, 0xdf, 0x76, 0xe3, 0x62, 0x4d,
+	0x32, 0x38, 0x73, 0xbf, 0x5f, 0x1a, 0xb9, 0x91, 0x9a, 0x2b, 0xaf, 0x78, 0xc2, 0x91, 0x05, 0x4c,
+	0x6b, 0xde, 0x08, 0xdd, 0x49, 0x7b, 0x44, 0x28, 0x44, 0xb7, 0x25, 0x6f, 0x8c, 0xb0, 0x74, 0x9c,
+	0x06, 0xf9, 0x9c, 0x75, 0x90, 0xbc, 0x81, 0xa4, 0xbf, 0x0a, 0x9d, 0xa4, 0x41, 0x3e, 0xbb, 0x59,
+	0x74, 0xd7, 0x3d, 0x35, 0x91, 0x1d, 0x37, 0x92, 0x14, 0x66, 0x3d, 0x7f, 0x6b, 0xe9, 0x14, 0x35,
+	0x87, 0x14, 0x79, 0x01, 0x91, 0xd4, 0x85, 0xd8, 0x0b, 0x43, 0x23, 0x34, 0x71, 0xde, 0xa9, 0xbe,
+	0x77, 0x34, 0xeb, 0x56, 0x9d, 0xd4, 0xaa, 0xd2, 0x56, 0xec, 0xed, 0x07, 0x53, 0x69, 0x1a, 0xa7,
+	0x41, 0x7e, 0xc6, 0x86, 0x14, 0x79, 0x09, 0xf1, 0x5a, 0x0a, 0x55, 0xd4, 0x4b, 0x43, 0x13, 0xd4,
+	0xba, 0xe8, 0xb4, 0xde, 0x39, 0xfe, 0x6e, 0xc9, 0xfa, 0x0d, 0xd9, 0xaf, 0x10, 0x22, 0xcf, 0x3e,
+	0xea, 0x62, 0x0a, 0xb3, 0x42, 0x98, 0x55, 0x23, 0x6b, 0xbc, 0x71, 0x6b, 0xe2, 0x90, 0x22, 0x97,
+	0x10, 0x3e, 0x88, 0x83, 0x37, 0xd0, 0x95, 0x2e, 0x2f, 0xb1, 0xb7, 0x0d, 0x47, 0xef, 0x12, 0xd6,
+	0x02, 0xa7, 0x5e, 0x70, 0xcb, 0xd1, 0xb4, 0x84, 0x61, 0xed, 0xfc, 0x57, 0x42, 0x6f, 0x6c, 0xe9,
+	0x2d, 0xf1, 0xc8, 0xed, 0xb5, 0x87, 0x5a, 0xd0, 0x08, 0x59, 0xac, 0xc9, 0x33, 0x00, 0xcd, 0xad,
+	0xfc, 0x21, 0xee, 0xdd, 0x4a, 0x8c, 0x2b, 0x03, 0x86, 0x3c, 0x85, 0xa4, 0x10, 0xeb, 0x4f, 0xad,
+	0x5c, 0x92, 0x06, 0xf9, 0x98, 0x1d, 0x09, 0xd7, 0xa9, 0x10, 0xeb, 0x6f, 0x5c, 0xd1, 0x19, 0x3a,
+	0xe6, 0x91, 0x4b, 0xba, 0x75, 0xb6, 0xa0, 0xf3, 0x34, 0xc8, 0xe3, 0xce, 0xe8, 0xc2, 0xad, 0xe8,
+	0xea, 0xf3, 0x4e, 0x29, 0x43, 0xcf, 0xdb, 0x15, 0x0f, 0x5d, 0xa7, 0x55, 0xa5, 0x14, 0x47, 0x47,
+	0x2e, 0xf0, 0x3a, 0x47, 0x02, 0x5f, 0x6b, 0xa5, 0x84, 0xa1, 0x97, 0xf8, 0x99, 0xb4, 0x60, 0x98,
+	0xef, 0xd5, 0xff, 0xe4, 0x4b, 0xfe, 0xc9, 0x37, 0xfb, 0x1d, 0xc0, 0x04, 0x0f, 0x3d, 0x1a, 0xd8,
+	0x02, 0xa6, 0x18, 0x6e, 0xf7, 0x99, 0x7a, 0xe4, 0xec, 0xab, 0x1b, 0xb9, 0xe5, 0xcd, 0xe1, 0xa3,
+	0x4f, 0x2b, 0x66, 0x03, 0x86, 0x3c, 0x87, 0x79, 0xc9, 0x4d, 0xd9, 0xbf, 0x49, 0x3a, 0xc6, 0xe3,
+	0xa7, 0xa4, 0xdb, 0xd5, 0xbf, 0xea, 0xaf, 0xf2, 0xa7, 0xc0, 0x34, 0x27, 0xec, 0x94, 0x74, 0x33,
+	0xec, 0xb4, 0xfc, 0xbe, 0x13, 0x18, 0x6b, 0xcc, 0x3c, 0x5a, 0x4e, 0xf1, 0x7f, 0xe7, 0xf5, 0xdf,
+	0x01, 0x00, 0x74, 0xd2, 0x6b, 0x22, 0x87, 0x04, 0x00, 0x00,
 }
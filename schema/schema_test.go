@@ -250,6 +250,59 @@ func TestFields(t *testing.T) {
 	assert.NotEqual(t, nil, f.Body())
 	assert.Equal(t, uint64(0), f.Id())
 }
+
+func TestFieldCoerce(t *testing.T) {
+	// declared columns are constraints
+	name := schema.NewField("name", value.StringType, 5, false, nil, "", "", "")
+	v, err := name.Coerce("bob")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "bob", v)
+	_, err = name.Coerce("bobbyjoe")
+	assert.Equal(t, schema.ErrTooLong, err)
+	_, err = name.Coerce(nil)
+	assert.Equal(t, schema.ErrNotNull, err)
+	_, err = name.OmittedValue()
+	assert.Equal(t, schema.ErrNoDefault, err)
+
+	age := schema.NewField("age", value.IntType, 0, true, 21, "", "", "")
+	v, err = age.Coerce("42")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(42), v)
+	_, err = age.Coerce("old")
+	assert.NotEqual(t, nil, err)
+	v, err = age.Coerce(nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, v)
+	v, err = age.OmittedValue()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(21), v)
+
+	created := schema.NewField("created", value.TimeType, 0, true, nil, "", "", "")
+	v, err = created.Coerce("2020-01-02T03:04:05Z")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), v)
+	v, err = created.OmittedValue()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, v)
+
+	// fields describing a sources values convert what they can
+	described := schema.NewFieldBase("id", value.IntType, 64, "int")
+	v, err = described.Coerce("abc-123")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "abc-123", v)
+	v, err = described.OmittedValue()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, v)
+	described.NoNulls = true
+	v, err = described.OmittedValue()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, v)
+
+	cerr := &schema.ConstraintError{Table: "users", Column: "name", Row: 2, Err: schema.ErrNotNull}
+	assert.Equal(t, `column "name" of "users" at row 2: cannot be null`, cerr.Error())
+	assert.Equal(t, schema.ErrNotNull, cerr.Unwrap())
+}
+
 func TestConfig(t *testing.T) {
 	c := schema.NewSourceConfig("test", "test")
 	assert.NotEqual(t, nil, c)
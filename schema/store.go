@@ -0,0 +1,323 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	u "github.com/araddon/gou"
+)
+
+var (
+	// Ensure our PersistentApplyer is an Applyer
+	_ Applyer = (*PersistentApplyer)(nil)
+	// Ensure FileSchemaStore is a SchemaStore
+	_ SchemaStore = (*FileSchemaStore)(nil)
+)
+
+type (
+	// SchemaStore persists schemas so they survive a restart, see
+	// PersistentApplyer.
+	SchemaStore interface {
+		// Load all of the stored schemas
+		Load() ([]*StoredSchema, error)
+		// Put replaces the stored schema of the same name
+		Put(s *StoredSchema) error
+		// Delete the stored schema of @name
+		Delete(name string) error
+	}
+
+	// StoredSchema the persisted form of a schema.  Tables are the
+	// protobuf TablePb of the tables created through DDL on its source.
+	StoredSchema struct {
+		Name    string        `json:"name"`
+		Version uint64        `json:"version"`
+		Conf    *ConfigSource `json:"conf,omitempty"`
+		Tables  [][]byte      `json:"tables,omitempty"`
+	}
+
+	// FileSchemaStore is a SchemaStore of a single json file, re-written
+	// on each change.
+	FileSchemaStore struct {
+		path string
+		mu   sync.Mutex
+	}
+
+	// PersistentApplyer applies schema changes with an Applyer, then writes
+	// the schemas that can be re-created to a SchemaStore: those created
+	// from a ConfigSource (CREATE SOURCE, config files) and the tables
+	// created on sources supporting DDL.  Each change increments the
+	// Version of the schema.
+	//
+	//    store := schema.NewFileSchemaStore("/var/lib/qlbridge/schema.json")
+	//    pa := schema.NewPersistentApplyer(schema.NewApplyer(provider), store)
+	//    reg.SetApplyer(pa)
+	//    // after the source types are registered
+	//    err := pa.Load()
+	PersistentApplyer struct {
+		Applyer
+		reg     *Registry
+		store   SchemaStore
+		mu      sync.Mutex
+		loading bool
+	}
+)
+
+// NewFileSchemaStore a store of the schemas in json file @path.
+func NewFileSchemaStore(path string) *FileSchemaStore {
+	return &FileSchemaStore{path: path}
+}
+
+// Load the stored schemas, none if the file does not exist yet.
+func (m *FileSchemaStore) Load() ([]*StoredSchema, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(stored))
+	for name := range stored {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schemas := make([]*StoredSchema, len(names))
+	for i, name := range names {
+		schemas[i] = stored[name]
+	}
+	return schemas, nil
+}
+
+// Put replaces the stored schema of the same name.
+func (m *FileSchemaStore) Put(s *StoredSchema) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, err := m.read()
+	if err != nil {
+		return err
+	}
+	stored[s.Name] = s
+	return m.write(stored)
+}
+
+// Delete the stored schema of @name.
+func (m *FileSchemaStore) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, err := m.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := stored[name]; !ok {
+		return nil
+	}
+	delete(stored, name)
+	return m.write(stored)
+}
+
+func (m *FileSchemaStore) read() (map[string]*StoredSchema, error) {
+	stored := make(map[string]*StoredSchema)
+	by, err := ioutil.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return stored, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(by, &stored); err != nil {
+		return nil, fmt.Errorf("could not read schema store %q: %v", m.path, err)
+	}
+	return stored, nil
+}
+
+// write the schemas to a temp file renamed over the store so a crash
+// mid-write leaves the previous version.
+func (m *FileSchemaStore) write(stored map[string]*StoredSchema) error {
+	by, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(m.path), filepath.Base(m.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(by); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), m.path)
+}
+
+// NewPersistentApplyer applies changes with @applyer and persists them
+// to @store.
+func NewPersistentApplyer(applyer Applyer, store SchemaStore) *PersistentApplyer {
+	return &PersistentApplyer{Applyer: applyer, store: store}
+}
+
+// Init the applyer with its registry.
+func (m *PersistentApplyer) Init(r *Registry) {
+	m.reg = r
+	m.Applyer.Init(r)
+}
+
+// AddOrUpdateOnSchema apply the new or updated table or schema @v of @s
+// then persist the schema changed.
+func (m *PersistentApplyer) AddOrUpdateOnSchema(s *Schema, v interface{}) error {
+	if err := m.Applyer.AddOrUpdateOnSchema(s, v); err != nil {
+		return err
+	}
+	if child, ok := v.(*Schema); ok {
+		// a new child schema, or s == v a new or refreshed schema
+		return m.save(child)
+	}
+	return m.save(s)
+}
+
+// Drop apply the drop of table or schema @v from @s then persist it.
+func (m *PersistentApplyer) Drop(s *Schema, v interface{}) error {
+	if err := m.Applyer.Drop(s, v); err != nil {
+		return err
+	}
+	if ds, ok := v.(*Schema); ok {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.store.Delete(ds.Name)
+	}
+	return m.save(s)
+}
+
+// save increment the version of @s and persist it if it can be
+// re-created.
+func (m *PersistentApplyer) save(s *Schema) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.loading || s.Name == "schema" {
+		return nil
+	}
+	s.mu.Lock()
+	s.version++
+	ss := &StoredSchema{Name: s.Name, Version: s.version, Conf: s.Conf}
+	s.mu.Unlock()
+
+	if _, ok := s.DS.(SourceDDL); ok {
+		for _, name := range s.DS.Tables() {
+			tbl, err := s.DS.Table(name)
+			if err != nil || tbl == nil || tbl.View != nil {
+				continue
+			}
+			pb, err := tbl.Marshal()
+			if err != nil {
+				return err
+			}
+			ss.Tables = append(ss.Tables, pb)
+		}
+	}
+	if ss.Conf == nil && len(ss.Tables) == 0 {
+		return nil
+	}
+	return m.store.Put(ss)
+}
+
+// Load the stored schemas into the registry, the source types of their
+// configs must be registered.  Schemas already registered, such as by
+// config files or RegisterSourceAsSchema, get the tables they are
+// missing re-created.
+func (m *PersistentApplyer) Load() error {
+	stored, err := m.store.Load()
+	if err != nil {
+		return err
+	}
+	// parent schemas before the child sources added to them
+	sort.SliceStable(stored, func(i, j int) bool {
+		return !isChildConf(stored[i].Conf) && isChildConf(stored[j].Conf)
+	})
+
+	m.mu.Lock()
+	m.loading = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.loading = false
+		m.mu.Unlock()
+	}()
+
+	for _, ss := range stored {
+		s := m.storedSchema(ss)
+		if s == nil {
+			if ss.Conf == nil {
+				u.Warnf("stored schema %q has no source config, skipping", ss.Name)
+				continue
+			}
+			if err := m.reg.SchemaAddFromConfig(ss.Conf); err != nil {
+				return fmt.Errorf("could not load stored schema %q: %v", ss.Name, err)
+			}
+			if s = m.storedSchema(ss); s == nil {
+				return fmt.Errorf("could not find stored schema %q after loading", ss.Name)
+			}
+		}
+		if err := m.loadTables(s, ss); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.version = ss.Version
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// storedSchema the registered schema of @ss, nil if none.
+func (m *PersistentApplyer) storedSchema(ss *StoredSchema) *Schema {
+	if isChildConf(ss.Conf) {
+		if parent, ok := m.reg.Schema(ss.Conf.Schema); ok {
+			if child, err := parent.Schema(ss.Name); err == nil {
+				return child
+			}
+		}
+		return nil
+	}
+	s, _ := m.reg.Schema(ss.Name)
+	return s
+}
+
+// loadTables re-create the stored tables of @ss missing from @s.
+func (m *PersistentApplyer) loadTables(s *Schema, ss *StoredSchema) error {
+	if len(ss.Tables) == 0 {
+		return nil
+	}
+	ddl, ok := s.DS.(SourceDDL)
+	if !ok {
+		return fmt.Errorf("source %T of stored schema %q does not support CREATE TABLE", s.DS, ss.Name)
+	}
+	for _, pb := range ss.Tables {
+		tbl, err := NewTableFromPb(pb)
+		if err != nil {
+			return fmt.Errorf("could not read stored table of %q: %v", ss.Name, err)
+		}
+		if existing, _ := s.DS.Table(tbl.Name); existing == nil {
+			if err = ddl.CreateTable(tbl); err != nil {
+				return err
+			}
+		}
+		created, err := s.DS.Table(tbl.Name)
+		if err != nil {
+			return err
+		}
+		if err = m.reg.SchemaTableAdd(s, created); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isChildConf(conf *ConfigSource) bool {
+	return conf != nil && conf.Schema != "" && conf.Schema != conf.Name
+}
@@ -0,0 +1,89 @@
+package schema_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fuhongbo/qlbridge/datasource"
+	"github.com/fuhongbo/qlbridge/datasource/memdb"
+	"github.com/fuhongbo/qlbridge/lex"
+	"github.com/fuhongbo/qlbridge/schema"
+	"github.com/fuhongbo/qlbridge/value"
+)
+
+// newStoreRegistry a registry persisting to @path, with an empty memdb
+// source type as if the process had just started.
+func newStoreRegistry(t *testing.T, path string) *schema.Registry {
+	pa := schema.NewPersistentApplyer(schema.NewApplyer(datasource.SchemaDBStoreProvider),
+		schema.NewFileSchemaStore(path))
+	reg := schema.NewRegistry(pa)
+	pa.Init(reg)
+	reg.SourceTypeAdd("memdb", memdb.NewSource())
+	assert.Equal(t, nil, pa.Load())
+	return reg
+}
+
+func TestPersistentApplyer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlbridge_store")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "schema.json")
+
+	reg := newStoreRegistry(t, path)
+	err = reg.SchemaAddFromConfig(&schema.ConfigSource{Name: "app", SourceType: "memdb"})
+	assert.Equal(t, nil, err)
+	s, ok := reg.Schema("app")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), s.Version())
+
+	tbl := schema.NewTable("users")
+	tbl.AddField(schema.NewField("id", value.IntType, 8, false, nil, "PRI", "", ""))
+	tbl.AddField(schema.NewField("name", value.StringType, 50, false, nil, "", "", ""))
+	tbl.SetColumnsFromFields()
+	assert.Equal(t, nil, s.DS.(schema.SourceDDL).CreateTable(tbl))
+	created, err := s.DS.Table("users")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, reg.SchemaTableAdd(s, created))
+	assert.Equal(t, uint64(2), s.Version())
+
+	// restart, the source and its table are re-created
+	reg = newStoreRegistry(t, path)
+	s, ok = reg.Schema("app")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), s.Version())
+	assert.Equal(t, "memdb", s.Conf.SourceType)
+	tbl, err = s.Table("users")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "name"}, tbl.Columns())
+	assert.Equal(t, uint32(50), tbl.FieldMap["name"].Length)
+	assert.True(t, tbl.FieldMap["id"].NoNulls)
+
+	// dropped sources stay dropped
+	assert.Equal(t, nil, reg.SchemaDrop("app", "app", lex.TokenSource))
+	reg = newStoreRegistry(t, path)
+	_, ok = reg.Schema("app")
+	assert.False(t, ok)
+}
+
+func TestTableMarshal(t *testing.T) {
+	tbl := schema.NewTable("users")
+	tbl.AddField(schema.NewFieldBase("user_id", value.IntType, 64, "int"))
+	tbl.AddField(schema.NewFieldBase("email", value.StringType, 255, "varchar"))
+	tbl.SetColumnsFromFields()
+
+	pb, err := tbl.Marshal()
+	assert.Equal(t, nil, err)
+	tbl2, err := schema.NewTableFromPb(pb)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "users", tbl2.Name)
+	assert.Equal(t, []string{"user_id", "email"}, tbl2.Columns())
+	assert.Equal(t, value.StringType, tbl2.FieldMap["email"].ValueType())
+	assert.Equal(t, uint32(255), tbl2.FieldMap["email"].Length)
+
+	_, err = schema.NewTableFromPb([]byte("not a table"))
+	assert.NotEqual(t, nil, err)
+}
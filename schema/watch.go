@@ -0,0 +1,172 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+const (
+	// EventSourceAdded a schema, or a child source of one, was added
+	EventSourceAdded EventType = iota + 1
+	// EventSourceDropped a schema was dropped
+	EventSourceDropped
+	// EventTableAdded a table was added to a schema
+	EventTableAdded
+	// EventTableAltered the definition of a table was replaced, ie ALTER
+	EventTableAltered
+	// EventTableDropped a table was dropped from a schema
+	EventTableDropped
+)
+
+type (
+	// EventType the kind of schema change of an Event
+	EventType uint8
+
+	// Event a change to the schema of a Registry, sent to its Watchers.
+	Event struct {
+		Type EventType
+		// Schema the name of the schema changed, for a child source
+		// added it is the parent schema.
+		Schema string
+		// Name of the table, or of the source, added/altered/dropped
+		Name string
+		// Table the new table for EventTableAdded, EventTableAltered
+		Table *Table
+	}
+
+	// Watcher is called with each change applied to a registry, such as
+	// to invalidate cached plans, refresh views or update a metadata UI.
+	// Watchers are called in the order they were added, after the change
+	// was applied and outside of registry locks, so they may read the
+	// registry.  They must not block for long.
+	Watcher func(ev *Event)
+
+	watcher struct {
+		id int
+		fn Watcher
+	}
+)
+
+// String the name of the event type.
+func (m EventType) String() string {
+	switch m {
+	case EventSourceAdded:
+		return "source_added"
+	case EventSourceDropped:
+		return "source_dropped"
+	case EventTableAdded:
+		return "table_added"
+	case EventTableAltered:
+		return "table_altered"
+	case EventTableDropped:
+		return "table_dropped"
+	}
+	return fmt.Sprintf("EventType(%d)", uint8(m))
+}
+
+// String describe the event.
+func (m *Event) String() string {
+	return fmt.Sprintf("%s %s.%s", m.Type, m.Schema, m.Name)
+}
+
+// Watch registers @fn to be called with each schema change to this
+// registry.  The returned func removes it.
+func (m *Registry) Watch(fn Watcher) (stop func()) {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+	m.watchID++
+	id := m.watchID
+	m.watchers = append(m.watchers, watcher{id: id, fn: fn})
+	return func() {
+		m.watchMu.Lock()
+		defer m.watchMu.Unlock()
+		watchers := make([]watcher, 0, len(m.watchers))
+		for _, w := range m.watchers {
+			if w.id != id {
+				watchers = append(watchers, w)
+			}
+		}
+		m.watchers = watchers
+	}
+}
+
+// notify the watchers of @events.
+func (m *Registry) notify(events ...*Event) {
+	if len(events) == 0 {
+		return
+	}
+	m.watchMu.RLock()
+	watchers := m.watchers
+	m.watchMu.RUnlock()
+	for _, ev := range events {
+		for _, w := range watchers {
+			w.fn(ev)
+		}
+	}
+}
+
+// tableDiff events of the tables of schema @s added, altered and dropped
+// between the field sets @before and @after, see tableFields.
+func tableDiff(s *Schema, before, after map[string]string) []*Event {
+	var events []*Event
+	for _, name := range sortedNames(before) {
+		if _, ok := after[name]; !ok {
+			events = append(events, &Event{Type: EventTableDropped, Schema: s.Name, Name: name})
+		}
+	}
+	for _, name := range sortedNames(after) {
+		fields, existed := before[name]
+		if existed && fields == after[name] {
+			continue
+		}
+		ev := &Event{Type: EventTableAdded, Schema: s.Name, Name: name}
+		if existed {
+			ev.Type = EventTableAltered
+		}
+		ev.Table, _ = s.Table(name)
+		events = append(events, ev)
+	}
+	return events
+}
+
+// tableFields the field set of each table of @s by table name, to
+// diff the tables before and after a change.
+func tableFields(s *Schema) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tables := make(map[string]string, len(s.tableNames))
+	for _, name := range s.tableNames {
+		tables[name] = fieldSet(s.tableMap[name])
+	}
+	return tables
+}
+
+// fieldSet describes the name, type and nullability of the fields of @tbl.
+func fieldSet(tbl *Table) string {
+	if tbl == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	for _, f := range tbl.Fields {
+		fmt.Fprintf(&buf, "%s %d %d %v,", f.Name, f.Type, f.Length, f.NoNulls)
+	}
+	return buf.String()
+}
+
+func sortedNames(tables map[string]string) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rootSchema the registered schema @s is a child source of, or @s.
+func rootSchema(s *Schema) *Schema {
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
@@ -88,8 +88,8 @@ func RunTestSuite(t TestingT) {
 	// - ensure we can evaluate against "NULL"
 	// - extra paren in where
 	// - `db`.`col` syntax
-	TestSelect(t, "SELECT user_id FROM users WHERE (`users.user_id` != NULL)",
-		[][]driver.Value{{"hT2impsabc345c"}, {"9Ip1aKbeZe2njCDM"}, {"hT2impsOPUREcVPc"}},
+	TestSelect(t, "SELECT user_id FROM users WHERE (`users.user_id` != NULL) ORDER BY user_id",
+		[][]driver.Value{{"9Ip1aKbeZe2njCDM"}, {"hT2impsOPUREcVPc"}, {"hT2impsabc345c"}},
 	)
 	TestSelect(t, "SELECT email FROM users WHERE interests != NULL)",
 		[][]driver.Value{{"aaron@email.com"}, {"bob@email.com"}},
@@ -172,8 +172,8 @@ func RunSimpleSuite(t TestingT) {
 	// - ensure we can evaluate against "NULL"
 	// - extra paren in where
 	// - `db`.`col` syntax
-	TestSelect(t, "SELECT user_id FROM users WHERE (`users.user_id` != NULL)",
-		[][]driver.Value{{"hT2impsabc345c"}, {"9Ip1aKbeZe2njCDM"}, {"hT2impsOPUREcVPc"}},
+	TestSelect(t, "SELECT user_id FROM users WHERE (`users.user_id` != NULL) ORDER BY user_id",
+		[][]driver.Value{{"9Ip1aKbeZe2njCDM"}, {"hT2impsOPUREcVPc"}, {"hT2impsabc345c"}},
 	)
 	TestSelect(t, "SELECT email FROM users WHERE interests != NULL)",
 		[][]driver.Value{{"aaron@email.com"}, {"bob@email.com"}},